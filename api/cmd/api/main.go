@@ -2,15 +2,31 @@ package api
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
 	"github.com/adamkirk/panoptes/internal/api"
 	"github.com/spf13/cobra"
 	"go.uber.org/fx"
 )
 
+type ProjectionsEngine interface {
+	CatchUpAll() error
+}
+
+type IngestionQueue interface {
+	Work(ctx context.Context)
+}
+
+type ProjectionsConfig interface {
+	ProjectionsPollInterval() time.Duration
+}
+
 func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
 	opts = append(opts, []fx.Option{
 		fx.Invoke(startServer),
+		fx.Invoke(startProjections),
+		fx.Invoke(startIngestion),
 	}...)
 
 	fx.New(
@@ -18,9 +34,13 @@ func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
 	).Run()
 }
 
-func startServer(lc fx.Lifecycle, srv *api.Server) {
+func startServer(lc fx.Lifecycle, srv *api.Server, permissions api.PermissionsRepo) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
+			if err := srv.VerifyScopes(permissions); err != nil {
+				return err
+			}
+
 			go srv.Start()
 			return nil
 		},
@@ -29,3 +49,76 @@ func startServer(lc fx.Lifecycle, srv *api.Server) {
 		},
 	})
 }
+
+// startProjections keeps the projections up to date with the event stream, by
+// periodically applying any new events. The engine locks each projection while
+// catching it up, so this waits for a rebuild from the CLI rather than racing
+// it.
+func startProjections(lc fx.Lifecycle, engine ProjectionsEngine, cfg ProjectionsConfig) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				defer close(stopped)
+
+				ticker := time.NewTicker(cfg.ProjectionsPollInterval())
+				defer ticker.Stop()
+
+				for {
+					if err := engine.CatchUpAll(); err != nil {
+						slog.Error("failed to update projections", "error", err)
+					}
+
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+					}
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(done)
+
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+			}
+
+			return nil
+		},
+	})
+}
+
+// startIngestion processes queued webhook deliveries into the event stream.
+// Deliveries being processed are finished before stopping, anything left is
+// picked up next time.
+func startIngestion(lc fx.Lifecycle, queue IngestionQueue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(stopped)
+				queue.Work(ctx)
+			}()
+
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+
+			select {
+			case <-stopped:
+			case <-stopCtx.Done():
+			}
+
+			return nil
+		},
+	})
+}
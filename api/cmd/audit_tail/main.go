@@ -0,0 +1,198 @@
+package audittail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+// pollInterval is how often new events are checked for when following.
+const pollInterval = time.Second
+
+// followBatch is the most events fetched per poll when following.
+const followBatch = 500
+
+type AuditService interface {
+	List(filter audit.Filter) ([]*audit.Event, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      AuditService
+	args     []string
+	done     chan struct{}
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc AuditService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+		done:     make(chan struct{}),
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	close(act.done)
+	return nil
+}
+
+func printEvent(e *audit.Event) {
+	printLine := color.Cyan
+
+	if e.Outcome != audit.OutcomeSuccess {
+		printLine = color.Yellow
+	}
+
+	actor := e.Actor.Type
+
+	if e.Actor.ID != "" {
+		actor = fmt.Sprintf("%s:%s", actor, e.Actor.ID)
+	}
+
+	target := "-"
+
+	if e.TargetID != "" {
+		target = fmt.Sprintf("%s:%s", e.TargetType, e.TargetID)
+	}
+
+	details, err := json.Marshal(e.Details)
+
+	if err != nil {
+		details = []byte("{}")
+	}
+
+	printLine(
+		"%d\t%s\t%s\t%s\tactor=%s\ttarget=%s\tip=%s\trequest=%s\t%s",
+		e.Seq,
+		e.OccurredAt.UTC().Format(time.RFC3339),
+		e.Action,
+		e.Outcome,
+		actor,
+		target,
+		e.Request.SourceIP,
+		e.Request.ID,
+		details,
+	)
+}
+
+func (act *Action) run() {
+	lines, err := act.cmd.Flags().GetInt("lines")
+
+	if err != nil {
+		color.Red("Failed to get lines option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	follow, err := act.cmd.Flags().GetBool("follow")
+
+	if err != nil {
+		color.Red("Failed to get follow option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	events, err := act.svc.List(audit.Filter{
+		Limit: lines,
+	})
+
+	if err != nil {
+		color.Red("Failed to list audit events: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	// Listed newest first, but printed oldest first like tail.
+	slices.Reverse(events)
+
+	var lastSeq int64
+
+	for _, e := range events {
+		printEvent(e)
+		lastSeq = e.Seq
+	}
+
+	if !follow {
+		act.sh.Shutdown()
+		return
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-act.done:
+			return
+		case <-ticker.C:
+		}
+
+		after := lastSeq
+		events, err := act.svc.List(audit.Filter{
+			AfterSeq: &after,
+			Limit: followBatch,
+		})
+
+		if err != nil {
+			color.Red("Failed to list audit events: %s", err.Error())
+			act.sh.Shutdown(fx.ExitCode(1))
+			return
+		}
+
+		for _, e := range events {
+			printEvent(e)
+			lastSeq = e.Seq
+		}
+	}
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
@@ -0,0 +1,164 @@
+package backfillgithub
+
+import (
+	"context"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/backfill"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type Backfiller interface {
+	Backfill(ctx context.Context, q backfill.GithubQuery) (*backfill.GithubReport, error)
+}
+
+// ProjectionsEngine applies the backfilled events to the projections, which
+// the API server would otherwise do the next time it polls.
+type ProjectionsEngine interface {
+	CatchUpAll() error
+}
+
+type Action struct {
+	sh         fx.Shutdowner
+	cmd        *cobra.Command
+	backfiller Backfiller
+	engine     ProjectionsEngine
+	args       []string
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	backfiller Backfiller,
+	engine ProjectionsEngine,
+	input *actionInput,
+) *Action {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	act := &Action{
+		sh:         sh,
+		cmd:        input.cmd,
+		backfiller: backfiller,
+		engine:     engine,
+		args:       input.args,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+// stop abandons the backfill when interrupted, it carries on from the last
+// checkpoint when run again.
+func (act *Action) stop(ctx context.Context) error {
+	act.cancel()
+	return nil
+}
+
+// parseSince accepts a date, or a time for more precision.
+func parseSince(val string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, val); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, val)
+}
+
+func (act *Action) run() {
+	org, err := act.cmd.Flags().GetString("org")
+
+	if err != nil {
+		color.Red("Failed to get org option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	repos, err := act.cmd.Flags().GetStringSlice("repo")
+
+	if err != nil {
+		color.Red("Failed to get repo option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	sinceVal, err := act.cmd.Flags().GetString("since")
+
+	if err != nil {
+		color.Red("Failed to get since option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	since, err := parseSince(sinceVal)
+
+	if err != nil {
+		color.Red("Since must be a date (2006-01-02) or time (2006-01-02T15:04:05Z): %s", sinceVal)
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Backfilling github history since %s, this may take a while...", since.Format(time.RFC3339))
+
+	report, err := act.backfiller.Backfill(act.ctx, backfill.GithubQuery{
+		Org:          org,
+		Repositories: repos,
+		Since:        since,
+		Progress: func(repository string, stage string, webhooks int) {
+			color.Yellow("%s: %s, %d webhooks", repository, stage, webhooks)
+		},
+	})
+
+	if err != nil {
+		color.Red("Failed to backfill, run again to carry on from where it stopped: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Backfilled %d webhooks from %d repositories", report.Webhooks, report.Repositories)
+	color.Cyan("Updating projections...")
+
+	// The backfill itself is done, so this isn't a reason to run it again.
+	if err := act.engine.CatchUpAll(); err != nil {
+		color.Yellow("Failed to update projections, the API server will update them when it next polls: %s", err.Error())
+	}
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
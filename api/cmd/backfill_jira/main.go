@@ -0,0 +1,140 @@
+package backfilljira
+
+import (
+	"context"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/backfill"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type Backfiller interface {
+	Backfill(ctx context.Context, q backfill.JiraQuery) (*backfill.JiraReport, error)
+}
+
+type Action struct {
+	sh         fx.Shutdowner
+	cmd        *cobra.Command
+	backfiller Backfiller
+	args       []string
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	backfiller Backfiller,
+	input *actionInput,
+) *Action {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	act := &Action{
+		sh:         sh,
+		cmd:        input.cmd,
+		backfiller: backfiller,
+		args:       input.args,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+// stop abandons the backfill when interrupted, it carries on from the last
+// checkpoint when run again.
+func (act *Action) stop(ctx context.Context) error {
+	act.cancel()
+	return nil
+}
+
+// parseSince accepts a date, or a time for more precision.
+func parseSince(val string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, val); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, val)
+}
+
+func (act *Action) run() {
+	project, err := act.cmd.Flags().GetString("project")
+
+	if err != nil {
+		color.Red("Failed to get project option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	sinceVal, err := act.cmd.Flags().GetString("since")
+
+	if err != nil {
+		color.Red("Failed to get since option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	since, err := parseSince(sinceVal)
+
+	if err != nil {
+		color.Red("Since must be a date (2006-01-02) or time (2006-01-02T15:04:05Z): %s", sinceVal)
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Backfilling jira history for %s since %s, this may take a while...", project, since.Format(time.RFC3339))
+
+	report, err := act.backfiller.Backfill(act.ctx, backfill.JiraQuery{
+		Project: project,
+		Since:   since,
+		Progress: func(project string, issues int, total int) {
+			color.Yellow("%s: %d/%d issues", project, issues, total)
+		},
+	})
+
+	if err != nil {
+		color.Red("Failed to backfill, run again to carry on from where it stopped: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Backfilled %d webhooks from %d issues", report.Webhooks, report.Issues)
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
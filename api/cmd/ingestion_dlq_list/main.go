@@ -0,0 +1,115 @@
+package ingestiondlqlist
+
+import (
+	"context"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type DeadLettersService interface {
+	DeadLetters(limit int) ([]*ingestion.DeadLetter, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      DeadLettersService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc DeadLettersService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	limit, err := act.cmd.Flags().GetInt("limit")
+
+	if err != nil {
+		color.Red("Failed to get limit option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	letters, err := act.svc.DeadLetters(limit)
+
+	if err != nil {
+		color.Red("Failed to list dead letters: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	if len(letters) == 0 {
+		color.Cyan("There are no dead letters")
+		act.sh.Shutdown()
+		return
+	}
+
+	for _, dl := range letters {
+		color.Yellow(
+			"%s\tintegration=%s\tevent=%s\tdelivery=%s\tattempts=%d\tfailed=%s\terror=%s",
+			dl.ID,
+			dl.Integration,
+			dl.Event,
+			dl.DeliveryID,
+			dl.Attempts,
+			dl.FailedAt.Format(time.RFC3339),
+			dl.LastError,
+		)
+	}
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
@@ -0,0 +1,117 @@
+package ingestiondlqretry
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type DeadLettersService interface {
+	RetryDeadLetters(ids ...uuid.UUID) (int, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      DeadLettersService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc DeadLettersService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	all, err := act.cmd.Flags().GetBool("all")
+
+	if err != nil {
+		color.Red("Failed to get all option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	if all == (len(act.args) > 0) {
+		color.Red("Give either the ids of the dead letters to retry, or --all")
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	ids := []uuid.UUID{}
+
+	for _, arg := range act.args {
+		id, err := uuid.Parse(arg)
+
+		if err != nil {
+			color.Red("Not a valid uuid: %s", arg)
+			act.sh.Shutdown(fx.ExitCode(1))
+			return
+		}
+
+		ids = append(ids, id)
+	}
+
+	retried, err := act.svc.RetryDeadLetters(ids...)
+
+	if err != nil {
+		color.Red("Failed to retry dead letters: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Queued %d dead letters to be retried by the API server", retried)
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
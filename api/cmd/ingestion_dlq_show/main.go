@@ -0,0 +1,117 @@
+package ingestiondlqshow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type DeadLettersService interface {
+	DeadLetter(id uuid.UUID) (*ingestion.DeadLetter, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      DeadLettersService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc DeadLettersService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	id, err := uuid.Parse(act.args[0])
+
+	if err != nil {
+		color.Red("The id must be a valid uuid")
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	dl, err := act.svc.DeadLetter(id)
+
+	if err != nil {
+		color.Red("Failed to get dead letter: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	payload, err := json.MarshalIndent(dl.Payload, "", "  ")
+
+	if err != nil {
+		color.Red("Failed to encode payload: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("ID:          %s", dl.ID)
+	color.Cyan("Integration: %s", dl.Integration)
+	color.Cyan("Event:       %s", dl.Event)
+	color.Cyan("Delivery:    %s", dl.DeliveryID)
+	color.Cyan("Attempts:    %d", dl.Attempts)
+	color.Cyan("Received:    %s", dl.ReceivedAt.Format(time.RFC3339))
+	color.Cyan("Failed:      %s", dl.FailedAt.Format(time.RFC3339))
+	color.Yellow("Error:       %s", dl.LastError)
+	fmt.Println(string(payload))
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
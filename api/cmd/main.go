@@ -7,16 +7,41 @@ import (
 	"strings"
 
 	apicmd "github.com/adamkirk/panoptes/cmd/api"
+	audittail "github.com/adamkirk/panoptes/cmd/audit_tail"
+	backfillgithub "github.com/adamkirk/panoptes/cmd/backfill_github"
+	backfilljira "github.com/adamkirk/panoptes/cmd/backfill_jira"
+	ingestiondlqlist "github.com/adamkirk/panoptes/cmd/ingestion_dlq_list"
+	ingestiondlqretry "github.com/adamkirk/panoptes/cmd/ingestion_dlq_retry"
+	ingestiondlqshow "github.com/adamkirk/panoptes/cmd/ingestion_dlq_show"
+	projectionsrebuild "github.com/adamkirk/panoptes/cmd/projections_rebuild"
+	rolescreate "github.com/adamkirk/panoptes/cmd/roles_create"
+	rolesgrant "github.com/adamkirk/panoptes/cmd/roles_grant"
+	roleslist "github.com/adamkirk/panoptes/cmd/roles_list"
+	rolesrevoke "github.com/adamkirk/panoptes/cmd/roles_revoke"
+	serviceaccountscreate "github.com/adamkirk/panoptes/cmd/service_accounts_create"
+	serviceaccountsdeactivate "github.com/adamkirk/panoptes/cmd/service_accounts_deactivate"
+	serviceaccountslist "github.com/adamkirk/panoptes/cmd/service_accounts_list"
 	superuserscreate "github.com/adamkirk/panoptes/cmd/superusers_create"
 	tokensgenerate "github.com/adamkirk/panoptes/cmd/tokens_generate"
+	tokenslist "github.com/adamkirk/panoptes/cmd/tokens_list"
+	tokensrevoke "github.com/adamkirk/panoptes/cmd/tokens_revoke"
 	"github.com/adamkirk/panoptes/internal/api"
 	v1 "github.com/adamkirk/panoptes/internal/api/v1"
 	"github.com/adamkirk/panoptes/internal/config"
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/backfill"
+	"github.com/adamkirk/panoptes/internal/domain/correlation"
+	"github.com/adamkirk/panoptes/internal/domain/dora"
 	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/domain/projections"
 	"github.com/adamkirk/panoptes/internal/domain/users"
 	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/adamkirk/panoptes/internal/repository/opensearch"
 	"github.com/adamkirk/panoptes/internal/repository/postgres"
 	"github.com/adamkirk/panoptes/internal/util/encryption"
+	"github.com/adamkirk/panoptes/internal/util/githubapi"
+	"github.com/adamkirk/panoptes/internal/util/jiraapi"
+	"github.com/adamkirk/panoptes/internal/util/oidc"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -75,6 +100,203 @@ var tokensGenerateCmd = &cobra.Command{
 	},
 }
 
+var tokensRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revokes an access token, so it can no longer be used",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tokensrevoke.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var tokensListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists a user's access tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		tokenslist.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var rolesCmd = &cobra.Command{
+	Use:   "roles",
+	Short: "Commands for managing roles and their permissions.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var rolesCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates a role",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		rolescreate.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var rolesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the roles and their permissions",
+	Run: func(cmd *cobra.Command, args []string) {
+		roleslist.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var rolesGrantCmd = &cobra.Command{
+	Use:   "grant <role> <permission>...",
+	Short: "Grants permissions to a role",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		rolesgrant.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var rolesRevokeCmd = &cobra.Command{
+	Use:   "revoke <role> <permission>...",
+	Short: "Revokes permissions from a role",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		rolesrevoke.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var serviceAccountsCmd = &cobra.Command{
+	Use:   "service-accounts",
+	Short: "Commands for managing service accounts, which are for integrations rather than people.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var serviceAccountsCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates a service account",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serviceaccountscreate.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var serviceAccountsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the service accounts and their roles",
+	Run: func(cmd *cobra.Command, args []string) {
+		serviceaccountslist.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var serviceAccountsDeactivateCmd = &cobra.Command{
+	Use:   "deactivate <id>",
+	Short: "Deactivates a service account, so its tokens stop working",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serviceaccountsdeactivate.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Commands for reading the audit log.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Prints the most recent audit events, optionally following new ones",
+	Run: func(cmd *cobra.Command, args []string) {
+		audittail.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var ingestionCmd = &cobra.Command{
+	Use:   "ingestion",
+	Short: "Commands for managing ingestion of webhooks.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var ingestionDlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Commands for inspecting and retrying webhook deliveries that failed to process.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var ingestionDlqListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the dead letters, most recently failed first",
+	Run: func(cmd *cobra.Command, args []string) {
+		ingestiondlqlist.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var ingestionDlqShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Shows a dead letter, including its payload",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ingestiondlqshow.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var ingestionDlqRetryCmd = &cobra.Command{
+	Use:   "retry [<id>...]",
+	Short: "Puts dead letters back on the queue, to be processed by the API server",
+	Run: func(cmd *cobra.Command, args []string) {
+		ingestiondlqretry.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Commands for filling in the history from before the webhooks were set up.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var backfillGithubCmd = &cobra.Command{
+	Use:   "github",
+	Short: "Backfills pull requests, reviews, deployments and commits from the github API",
+	Long:  `Backfills pull requests, reviews, deployments and commits from the github API.
+
+The backfilled events are applied to the projections once the backfill is done.
+If that fails, they're picked up the next time the API server updates the
+projections, there's no need to rebuild them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		backfillgithub.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var backfillJiraCmd = &cobra.Command{
+	Use:   "jira",
+	Short: "Backfills issues and their changelogs from the jira API",
+	Run: func(cmd *cobra.Command, args []string) {
+		backfilljira.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
+var projectionsCmd = &cobra.Command{
+	Use:   "projections",
+	Short: "Commands for managing projections.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var projectionsRebuildCmd = &cobra.Command{
+	Use:   "rebuild <name>",
+	Short: "Truncates a projection and replays the whole event stream into it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		projectionsrebuild.Handler(SharedOpts(appCfg), cmd, args)
+	},
+}
+
 func newFs() afero.Fs {
 	return afero.NewOsFs()
 }
@@ -88,6 +310,12 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 				fx.As(new(api.ApiServerConfig)),
 			),
 		),
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(ingestion.WebhookSecretStore)),
+			),
+		),
 		fx.Provide(api.NewServer),
 		fx.Provide(
 			fx.Annotate(
@@ -109,6 +337,13 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 				fx.ResultTags(`group:"api.v1.controllers"`),
 			),
 		),
+		fx.Provide(
+			fx.Annotate(
+				v1.NewMetricsController,
+				fx.As(new(api.Controller)),
+				fx.ResultTags(`group:"api.v1.controllers"`),
+			),
+		),
 		fx.Provide(
 			fx.Annotate(
 				v1.NewUsersController,
@@ -116,13 +351,135 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 				fx.ResultTags(`group:"api.v1.controllers"`),
 			),
 		),
+		fx.Provide(
+			fx.Annotate(
+				v1.NewAuthController,
+				fx.As(new(api.Controller)),
+				fx.ResultTags(`group:"api.v1.controllers"`),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				v1.NewRolesController,
+				fx.As(new(api.Controller)),
+				fx.ResultTags(`group:"api.v1.controllers"`),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				v1.NewServiceAccountsController,
+				fx.As(new(api.Controller)),
+				fx.ResultTags(`group:"api.v1.controllers"`),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				v1.NewTokensController,
+				fx.As(new(api.Controller)),
+				fx.ResultTags(`group:"api.v1.controllers"`),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				v1.NewAuditController,
+				fx.As(new(api.Controller)),
+				fx.ResultTags(`group:"api.v1.controllers"`),
+			),
+		),
 
 		fx.Provide(
 			fx.Annotate(
 				ingestion.NewGithubIngestor,
+				fx.As(fx.Self()),
+				fx.As(new(backfill.GithubIngestor)),
 				fx.As(new(v1.GithubIngestor)),
 			),
 		),
+		fx.Provide(
+			fx.Annotate(
+				ingestion.NewGitlabIngestor,
+				fx.As(fx.Self()),
+				fx.As(new(v1.GitlabIngestor)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				ingestion.NewBitbucketIngestor,
+				fx.As(fx.Self()),
+				fx.As(new(v1.BitbucketIngestor)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				ingestion.NewJiraIngestor,
+				fx.As(fx.Self()),
+				fx.As(new(backfill.JiraIngestor)),
+				fx.As(new(v1.JiraIngestor)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(ingestion.QueueConfig)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				ingestion.NewQueue,
+				fx.As(new(v1.IngestionQueue)),
+				fx.As(new(apicmd.IngestionQueue)),
+				fx.As(new(ingestiondlqlist.DeadLettersService)),
+				fx.As(new(ingestiondlqshow.DeadLettersService)),
+				fx.As(new(ingestiondlqretry.DeadLettersService)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				func(cfg *config.Config) *githubapi.Client {
+					return githubapi.NewClient(githubapi.Config{
+						BaseURL: cfg.Backfill.Github.APIURL,
+						Token: cfg.Backfill.Github.Token,
+					})
+				},
+				fx.As(new(backfill.GithubAPI)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				backfill.NewGithub,
+				fx.As(new(backfillgithub.Backfiller)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				func(cfg *config.Config) *jiraapi.Client {
+					return jiraapi.NewClient(jiraapi.Config{
+						URL: cfg.Backfill.Jira.URL,
+						Email: cfg.Backfill.Jira.Email,
+						Token: cfg.Backfill.Jira.Token,
+					})
+				},
+				fx.As(new(backfill.JiraAPI)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				backfill.NewJira,
+				fx.As(new(backfilljira.Backfiller)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(correlation.Config)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				correlation.NewLinker,
+				fx.As(new(ingestion.ChangeRequestLinker)),
+			),
+		),
 
 		fx.Provide(
 			fx.Annotate(
@@ -130,14 +487,78 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 					return encryption.NewBcrypter(encryption.WithCost(cfg.Auth.Bcrypt.Cost))
 				},
 				fx.As(new(users.Encrypter)),
+				fx.As(new(users.HashVerifier)),
 				fx.As(new(api.TokenVerifier)),
 			),
 		),
+		fx.Provide(
+			fx.Annotate(
+				func (cfg *config.Config) *encryption.JWTSigner {
+					return encryption.NewJWTSigner(cfg.AuthSessionsSigningKey())
+				},
+				fx.As(new(users.SessionSigner)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(users.SessionsConfig)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				users.NewSessionsService,
+				fx.As(new(v1.SessionsService)),
+				fx.As(new(api.SessionAuthenticator)),
+				fx.As(new(users.SessionStarter)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				func(cfg *config.Config) *oidc.Client {
+					return oidc.NewClient(oidc.Config{
+						IssuerURL: cfg.Auth.Oidc.IssuerURL,
+						ClientID: cfg.Auth.Oidc.ClientID,
+						ClientSecret: cfg.Auth.Oidc.ClientSecret,
+						RedirectURL: cfg.Auth.Oidc.RedirectURL,
+						Scopes: cfg.Auth.Oidc.Scopes,
+						StateKey: cfg.AuthSessionsSigningKey(),
+					})
+				},
+				fx.As(new(users.OIDCProvider)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(users.OIDCConfig)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				users.NewOIDCService,
+				fx.As(new(v1.OIDCService)),
+			),
+		),
+
+		fx.Provide(
+			fx.Annotate(
+				audit.NewService,
+				fx.As(new(users.AuditRecorder)),
+				fx.As(new(api.AuditRecorder)),
+				fx.As(new(v1.AuditRecorder)),
+				fx.As(new(v1.AuditService)),
+				fx.As(new(audittail.AuditService)),
+			),
+		),
 
 		fx.Provide(
 			fx.Annotate(
 				users.NewAccessTokensService,
 				fx.As(new(tokensgenerate.TokensService)),
+				fx.As(new(tokensrevoke.TokensService)),
+				fx.As(new(tokenslist.TokensService)),
+				fx.As(new(v1.TokensService)),
 			),
 		),
 
@@ -149,7 +570,67 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 			),
 		),
 
+		fx.Provide(
+			fx.Annotate(
+				users.NewServiceAccountsService,
+				fx.As(new(serviceaccountscreate.ServiceAccountsService)),
+				fx.As(new(serviceaccountslist.ServiceAccountsService)),
+				fx.As(new(serviceaccountsdeactivate.ServiceAccountsService)),
+				fx.As(new(v1.ServiceAccountsService)),
+			),
+		),
+
+		fx.Provide(
+			fx.Annotate(
+				users.NewRolesService,
+				fx.As(new(rolescreate.RolesService)),
+				fx.As(new(roleslist.RolesService)),
+				fx.As(new(rolesgrant.RolesService)),
+				fx.As(new(rolesrevoke.RolesService)),
+				fx.As(new(v1.RolesService)),
+			),
+		),
+
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(dora.Config)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				dora.NewService,
+				fx.As(new(v1.DoraService)),
+			),
+		),
+
 		fx.Provide(validation.NewValidator),
+
+		fx.Provide(
+			fx.Annotate(
+				projections.NewChangeRequestsProjector,
+				fx.As(new(projections.Projector)),
+				fx.ResultTags(`group:"projections.projectors"`),
+			),
+		),
+
+		fx.Provide(
+			fx.Annotate(
+				func(projectors []projections.Projector, events projections.EventStore, checkpoints projections.CheckpointsRepo, locker projections.Locker) *projections.Engine {
+					return projections.NewEngine(projectors, events, checkpoints, locker, projections.WithBatchSize(cfg.Projections.BatchSize))
+				},
+				fx.ParamTags(`group:"projections.projectors"`),
+				fx.As(new(projectionsrebuild.ProjectionsEngine)),
+				fx.As(new(apicmd.ProjectionsEngine)),
+				fx.As(new(backfillgithub.ProjectionsEngine)),
+			),
+		),
+		fx.Provide(
+			fx.Annotate(
+				buildConfig,
+				fx.As(new(apicmd.ProjectionsConfig)),
+			),
+		),
 	}
 
 	if !cfg.EventStoreDbDriver().IsKnown() {
@@ -157,6 +638,11 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 		os.Exit(1)
 	}
 
+	if !cfg.ProjectionDbDriver().IsKnown() {
+		slog.Error("Unknown projection db driver", "driver", string(appCfg.ProjectionDbDriver()))
+		os.Exit(1)
+	}
+
 	// Register difference implementations based on configured driver
 	if cfg.EventStoreDbDriver().IsPostgres() {
 		opts = append(opts, []fx.Option{
@@ -169,18 +655,65 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 				fx.Annotate(
 					postgres.NewGithubWebhooksRepository,
 					fx.As(new(ingestion.GithubIngestorRepo)),
+					fx.As(new(dora.GithubWebhooksRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewGitlabWebhooksRepository,
+					fx.As(new(ingestion.GitlabIngestorRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewBitbucketWebhooksRepository,
+					fx.As(new(ingestion.BitbucketIngestorRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewJiraWebhooksRepository,
+					fx.As(new(ingestion.JiraIngestorRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewIngestionQueueRepository,
+					fx.As(new(ingestion.QueueRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewBackfillCheckpointsRepository,
+					fx.As(new(backfill.CheckpointsRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewChangeRequestTaskLinksRepository,
+					fx.As(new(correlation.LinksRepo)),
 				),
 			),
 			fx.Provide(
 				fx.Annotate(
 					postgres.NewUsersRepository,
 					fx.As(new(users.UsersRepo)),
+					fx.As(new(users.OIDCUsersRepo)),
+					fx.As(new(users.ServiceAccountsRepo)),
 				),
 			),
 			fx.Provide(
 				fx.Annotate(
 					postgres.NewRolesRepository,
 					fx.As(new(users.RolesRepo)),
+					fx.As(new(users.RolesStore)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewPermissionsRepository,
+					fx.As(new(api.PermissionsRepo)),
+					fx.As(new(users.PermissionsRepo)),
 				),
 			),
 			fx.Provide(
@@ -190,8 +723,86 @@ func SharedOpts(cfg *config.Config) []fx.Option {
 					fx.As(new(api.AuthRepo)),
 				),
 			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewUserSessionsRepository,
+					fx.As(new(users.SessionsRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewAuditEventsRepository,
+					fx.As(new(audit.Repo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewChangeRequestsStreamRepository,
+					fx.As(new(projections.EventStore)),
+				),
+			),
+			// Projections are locked in the event store, as it's shared by
+			// everything working on them, whatever the projection db is.
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewAdvisoryLocker,
+					fx.As(new(projections.Locker)),
+				),
+			),
+		}...)
+	}
+
+	// The projection db may be a different database to the event store, even
+	// when they use the same driver.
+	if cfg.ProjectionDbDriver().IsPostgres() {
+		opts = append(opts, []fx.Option{
+			fx.Provide(
+				fx.Annotate(
+					func (cfg *config.Config) *postgres.Connector {
+						return postgres.NewConnector(cfg.Db.Projection.Postgres)
+					},
+					fx.ResultTags(`name:"projection"`),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewProjectionCheckpointsRepository,
+					fx.ParamTags(`name:"projection"`),
+					fx.As(new(projections.CheckpointsRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					postgres.NewChangeRequestsProjectionRepository,
+					fx.ParamTags(`name:"projection"`),
+					fx.As(new(projections.ChangeRequestsStore)),
+				),
+			),
 		}...)
 	}
+
+	if cfg.ProjectionDbDriver().IsOpensearch() {
+		opts = append(opts, []fx.Option{
+			fx.Provide(
+				func (cfg *config.Config) *opensearch.Client {
+					return opensearch.NewClient(cfg.Db.Projection.Opensearch)
+				},
+			),
+			fx.Provide(
+				fx.Annotate(
+					opensearch.NewProjectionCheckpointsRepository,
+					fx.As(new(projections.CheckpointsRepo)),
+				),
+			),
+			fx.Provide(
+				fx.Annotate(
+					opensearch.NewChangeRequestsProjectionRepository,
+					fx.As(new(projections.ChangeRequestsStore)),
+				),
+			),
+		}...)
+	}
+
 	return opts
 }
 
@@ -221,6 +832,30 @@ func init() {
 	tokensGenerateCmd.Flags().StringP("user", "u", "", "The ID of the user the token belongs to. The token will have the same permissions as the given user.")
 	tokensGenerateCmd.Flags().Int("expire", 6*30, "Days that the token to be valid for. -1 makes it valid forever.")
 
+	tokensListCmd.Flags().StringP("user", "u", "", "The ID of the user to list tokens for.")
+	tokensListCmd.MarkFlagRequired("user")
+
+	rolesCreateCmd.Flags().StringSliceP("permission", "p", []string{}, "A permission to grant the role, can be given more than once.")
+
+	serviceAccountsCreateCmd.Flags().StringSliceP("role", "r", []string{}, "A role to give the service account, can be given more than once.")
+
+	ingestionDlqListCmd.Flags().IntP("limit", "n", 50, "The most dead letters to list.")
+	ingestionDlqRetryCmd.Flags().Bool("all", false, "Retry every dead letter.")
+
+	backfillGithubCmd.Flags().String("org", "", "The organisation (or user) that owns the repositories.")
+	backfillGithubCmd.Flags().StringSliceP("repo", "r", []string{}, "A repository to backfill, can be given more than once. Every repository in the organisation is backfilled if not given.")
+	backfillGithubCmd.Flags().String("since", "", "How far back to backfill, as a date (2006-01-02) or time (2006-01-02T15:04:05Z).")
+	backfillGithubCmd.MarkFlagRequired("org")
+	backfillGithubCmd.MarkFlagRequired("since")
+
+	backfillJiraCmd.Flags().String("project", "", "The key of the project to backfill e.g. ABC.")
+	backfillJiraCmd.Flags().String("since", "", "How far back to backfill, as a date (2006-01-02) or time (2006-01-02T15:04:05Z).")
+	backfillJiraCmd.MarkFlagRequired("project")
+	backfillJiraCmd.MarkFlagRequired("since")
+
+	auditTailCmd.Flags().IntP("lines", "n", 20, "The number of most recent events to print.")
+	auditTailCmd.Flags().BoolP("follow", "f", false, "Keep printing new events as they're recorded.")
+
 	superusersCreateCmd.Flags().StringP("email", "e", "", "Users email address")
 	superusersCreateCmd.Flags().StringP("first-name", "f", "", "Users first name")
 	superusersCreateCmd.Flags().StringP("last-name", "l", "", "Users last name")
@@ -233,6 +868,35 @@ func init() {
 	rootCmd.AddCommand(apiServeCmd)
 	rootCmd.AddCommand(tokensCmd)
 	tokensCmd.AddCommand(tokensGenerateCmd)
+	tokensCmd.AddCommand(tokensRevokeCmd)
+	tokensCmd.AddCommand(tokensListCmd)
+
+	rootCmd.AddCommand(projectionsCmd)
+	projectionsCmd.AddCommand(projectionsRebuildCmd)
+
+	rootCmd.AddCommand(rolesCmd)
+	rolesCmd.AddCommand(rolesCreateCmd)
+	rolesCmd.AddCommand(rolesListCmd)
+	rolesCmd.AddCommand(rolesGrantCmd)
+	rolesCmd.AddCommand(rolesRevokeCmd)
+
+	rootCmd.AddCommand(serviceAccountsCmd)
+	serviceAccountsCmd.AddCommand(serviceAccountsCreateCmd)
+	serviceAccountsCmd.AddCommand(serviceAccountsListCmd)
+	serviceAccountsCmd.AddCommand(serviceAccountsDeactivateCmd)
+
+	rootCmd.AddCommand(ingestionCmd)
+	ingestionCmd.AddCommand(ingestionDlqCmd)
+	ingestionDlqCmd.AddCommand(ingestionDlqListCmd)
+	ingestionDlqCmd.AddCommand(ingestionDlqShowCmd)
+	ingestionDlqCmd.AddCommand(ingestionDlqRetryCmd)
+
+	rootCmd.AddCommand(backfillCmd)
+	backfillCmd.AddCommand(backfillGithubCmd)
+	backfillCmd.AddCommand(backfillJiraCmd)
+
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditTailCmd)
 
 	rootCmd.AddCommand(superusersCmd)
 	superusersCmd.AddCommand(superusersCreateCmd)
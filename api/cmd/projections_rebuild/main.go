@@ -0,0 +1,102 @@
+package projectionsrebuild
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type ProjectionsEngine interface {
+	Names() []string
+	Rebuild(name string) (int, error)
+}
+
+type Action struct {
+	sh     fx.Shutdowner
+	cmd    *cobra.Command
+	engine ProjectionsEngine
+	args   []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	engine ProjectionsEngine,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:     sh,
+		cmd:    input.cmd,
+		engine: engine,
+		args:   input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	name := act.args[0]
+
+	color.Cyan("Rebuilding projection '%s', this may take a while...", name)
+
+	count, err := act.engine.Rebuild(name)
+
+	if errors.Is(err, projections.ErrUnknownProjection) {
+		color.Red("Unknown projection '%s', available projections: %s", name, strings.Join(act.engine.Names(), ", "))
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	if err != nil {
+		color.Red("Failed to rebuild projection: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Rebuilt '%s' from %d events", name, count)
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
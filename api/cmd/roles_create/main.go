@@ -0,0 +1,101 @@
+package rolescreate
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type RolesService interface {
+	Create(dto users.CreateRoleDTO) (*users.Role, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      RolesService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc RolesService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	permissions, err := act.cmd.Flags().GetStringSlice("permission")
+
+	if err != nil {
+		color.Red("Failed to get permission option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	role, err := act.svc.Create(users.CreateRoleDTO{
+		Name: act.args[0],
+		Permissions: permissions,
+	})
+
+	if err != nil {
+		color.Red("Failed to create role: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Created role '%s' (%s) with permissions: %s", role.Name, role.ID, strings.Join(permissions, ", "))
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
@@ -0,0 +1,101 @@
+package roleslist
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type RolesService interface {
+	List() ([]*users.Role, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      RolesService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc RolesService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	roles, err := act.svc.List()
+
+	if err != nil {
+		color.Red("Failed to list roles: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	for _, r := range roles {
+		permissions := util.Map[*users.Permission, string](func (p *users.Permission) string {
+			return p.Name
+		}, r.Permissions)
+
+		if r.Name == users.RoleSuperuser {
+			permissions = []string{"everything"}
+		}
+
+		color.Cyan("%s\tid=%s\tpermissions=%s", r.Name, r.ID, strings.Join(permissions, ","))
+	}
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
@@ -0,0 +1,105 @@
+package serviceaccountscreate
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type ServiceAccountsService interface {
+	Create(ctx context.Context, dto users.CreateServiceAccountDTO) (*users.User, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      ServiceAccountsService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc ServiceAccountsService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	ctx := audit.CLIContext()
+
+	roles, err := act.cmd.Flags().GetStringSlice("role")
+
+	if err != nil {
+		color.Red("Failed to get role option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	account, err := act.svc.Create(ctx, users.CreateServiceAccountDTO{
+		Name: act.args[0],
+		Roles: roles,
+	})
+
+	if err != nil {
+		color.Red("Failed to create service account: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Created service account '%s' (%s) with roles: %s", account.Name, account.ID, strings.Join(roles, ", "))
+	color.Cyan("Generate a token for it with: tokens generate --user %s", account.ID)
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
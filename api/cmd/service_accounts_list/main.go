@@ -0,0 +1,106 @@
+package serviceaccountslist
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type ServiceAccountsService interface {
+	List(dto users.ListDTO) (*users.UsersPage, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      ServiceAccountsService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc ServiceAccountsService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	for page := 1; ; page++ {
+		result, err := act.svc.List(users.ListDTO{
+			Page: page,
+			PerPage: 100,
+		})
+
+		if err != nil {
+			color.Red("Failed to list service accounts: %s", err.Error())
+			act.sh.Shutdown(fx.ExitCode(1))
+			return
+		}
+
+		for _, a := range result.Users {
+			roles := util.Map[*users.Role, string](func (r *users.Role) string {
+				return r.Name
+			}, a.Roles)
+
+			color.Cyan("%s\tid=%s\tactive=%t\troles=%s", a.Name, a.ID, a.IsActive(), strings.Join(roles, ","))
+		}
+
+		if page*100 >= result.Total {
+			break
+		}
+	}
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
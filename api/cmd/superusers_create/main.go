@@ -3,6 +3,7 @@ package superuserscreate
 import (
 	"context"
 
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/adamkirk/panoptes/internal/domain/users"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -10,7 +11,7 @@ import (
 )
 
 type UsersService interface {
-	Create(def users.CreateDTO) (*users.User, error)
+	Create(ctx context.Context, def users.CreateDTO) (*users.User, error)
 }
 
 type Action struct {
@@ -56,6 +57,8 @@ func (act *Action) stop(ctx context.Context) error {
 }
 
 func (act *Action) run() {
+	ctx := audit.CLIContext()
+
 	password, err := act.cmd.Flags().GetString("password")
 
 	if err != nil {
@@ -88,7 +91,7 @@ func (act *Action) run() {
 		return
 	}
 	
-	user, err := act.svc.Create(users.CreateDTO{
+	user, err := act.svc.Create(ctx, users.CreateDTO{
 		FirstName: firstName,
 		LastName: lastName,
 		Email: email,
@@ -3,6 +3,7 @@ package tokensgenerate
 import (
 	"context"
 
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/adamkirk/panoptes/internal/domain/users"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
@@ -11,7 +12,7 @@ import (
 )
 
 type TokensService interface {
-	Create(def users.TokenDefinition) (*users.AccessToken, error)
+	Create(ctx context.Context, def users.TokenDefinition) (*users.AccessToken, error)
 }
 
 type Action struct {
@@ -57,6 +58,8 @@ func (act *Action) stop(ctx context.Context) error {
 }
 
 func (act *Action) run() {
+	ctx := audit.CLIContext()
+
 	user, err := act.cmd.Flags().GetString("user")
 
 	if err != nil {
@@ -91,7 +94,7 @@ func (act *Action) run() {
 		return
 	}
 
-	token, err := act.svc.Create(users.TokenDefinition{
+	token, err := act.svc.Create(ctx, users.TokenDefinition{
 		ExpiryDays: expire,
 		UserID: id,
 	})
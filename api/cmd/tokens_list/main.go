@@ -0,0 +1,147 @@
+package tokenslist
+
+import (
+	"context"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type TokensService interface {
+	ListForUser(userID uuid.UUID) ([]*users.AccessToken, error)
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      TokensService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc TokensService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func formatTime(t *time.Time, fallback string) string {
+	if t == nil {
+		return fallback
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (act *Action) run() {
+	user, err := act.cmd.Flags().GetString("user")
+
+	if err != nil {
+		color.Red("Failed to get user option: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	id, err := uuid.Parse(user)
+
+	if err != nil {
+		color.Red("The user option must be a valid uuid")
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	tokens, err := act.svc.ListForUser(id)
+
+	if err != nil {
+		color.Red("Failed to list tokens: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	if len(tokens) == 0 {
+		color.Cyan("The user has no tokens")
+		act.sh.Shutdown()
+		return
+	}
+
+	now := dt.NowUTC()
+
+	for _, t := range tokens {
+		status := "active"
+
+		if t.IsRevoked() {
+			status = "revoked"
+		} else if t.IsExpired(now) {
+			status = "expired"
+		}
+
+		printLine := color.Cyan
+
+		if status != "active" {
+			printLine = color.Yellow
+		}
+
+		printLine(
+			"%s\tstatus=%s\tcreated=%s\texpires=%s\tlast_used=%s",
+			t.ID,
+			status,
+			formatTime(t.CreatedAt, "unknown"),
+			formatTime(t.ExpireAt, "never"),
+			formatTime(t.LastUsedAt, "never"),
+		)
+	}
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
@@ -0,0 +1,101 @@
+package tokensrevoke
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+type TokensService interface {
+	Revoke(ctx context.Context, id string) error
+}
+
+type Action struct {
+	sh       fx.Shutdowner
+	cmd      *cobra.Command
+	svc      TokensService
+	args     []string
+}
+
+type actionInput struct {
+	cmd  *cobra.Command
+	args []string
+}
+
+func newAction(
+	lc fx.Lifecycle,
+	sh fx.Shutdowner,
+	svc TokensService,
+	input *actionInput,
+) *Action {
+	act := &Action{
+		sh:       sh,
+		cmd:      input.cmd,
+		svc:      svc,
+		args:     input.args,
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: act.start,
+		OnStop:  act.stop,
+	})
+
+	return act
+}
+
+func (act *Action) start(ctx context.Context) error {
+	go act.run()
+	return nil
+}
+
+func (act *Action) stop(ctx context.Context) error {
+	return nil
+}
+
+func (act *Action) run() {
+	ctx := audit.CLIContext()
+
+	id := act.args[0]
+
+	err := act.svc.Revoke(ctx, id)
+
+	if errors.Is(err, users.ErrAccessTokenNotFound) {
+		color.Red("No access token found with ID '%s'", id)
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	if err != nil {
+		color.Red("Failed to revoke token: %s", err.Error())
+		act.sh.Shutdown(fx.ExitCode(1))
+		return
+	}
+
+	color.Cyan("Revoked token '%s'", id)
+
+	act.sh.Shutdown()
+}
+
+func Handler(opts []fx.Option, cmd *cobra.Command, args []string) {
+	opts = append(opts, []fx.Option{
+		// Prevents all the logging noise when building the service container
+		fx.NopLogger,
+		fx.Provide(func() *actionInput {
+			return &actionInput{
+				cmd:  cmd,
+				args: args,
+			}
+		}),
+		fx.Provide(newAction),
+		fx.Invoke(func(*Action) {}),
+	}...)
+
+	fx.New(
+		opts...,
+	).Run()
+}
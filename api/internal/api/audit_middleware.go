@@ -0,0 +1,22 @@
+package api
+
+import (
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/labstack/echo/v4"
+)
+
+// auditRequestMiddleware makes the request ID and source IP available to
+// anything audited during the request. It needs to run after the RequestID
+// middleware, which sets the ID on the response.
+func auditRequestMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := audit.WithRequest(c.Request().Context(), audit.Request{
+			ID: c.Response().Header().Get(echo.HeaderXRequestID),
+			SourceIP: c.RealIP(),
+		})
+
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+type RateLimitConfig interface {
+	ApiServerRateLimitEnabled() bool
+
+	// ApiServerRateLimitPerIP and ApiServerRateLimitPerKey are the requests
+	// per minute and the burst.
+	ApiServerRateLimitPerIP() (int, int)
+	ApiServerRateLimitPerKey() (int, int)
+	ApiServerLockoutMaxFailures() int
+	ApiServerLockoutWindow() time.Duration
+	ApiServerLockoutDuration() time.Duration
+}
+
+type authFailures struct {
+	count       int
+	since       time.Time
+	lockedUntil time.Time
+}
+
+// AuthLimiter throttles authentication, so that bad credentials can't be used
+// to exhaust the CPU hashing them, or to guess credentials. State is kept in
+// memory, so each API server limits separately.
+type AuthLimiter struct {
+	enabled bool
+	ips     *middleware.RateLimiterMemoryStore
+	keys    *middleware.RateLimiterMemoryStore
+
+	maxFailures int
+	window      time.Duration
+	duration    time.Duration
+
+	mu          sync.Mutex
+	failures    map[string]*authFailures
+	lastCleanup time.Time
+	getNow      func() time.Time
+}
+
+// AllowIP is whether a request from the IP is within the rate limit.
+func (l *AuthLimiter) AllowIP(ip string) bool {
+	if !l.enabled {
+		return true
+	}
+
+	allowed, _ := l.ips.Allow(ip)
+
+	return allowed
+}
+
+// AllowKey is whether a request with the access key id is within the rate
+// limit, whichever IP it comes from.
+func (l *AuthLimiter) AllowKey(id string) bool {
+	if !l.enabled {
+		return true
+	}
+
+	allowed, _ := l.keys.Allow(id)
+
+	return allowed
+}
+
+func (l *AuthLimiter) lockoutEnabled() bool {
+	return l.enabled && l.maxFailures > 0
+}
+
+// LockedOut returns how long the IP is locked out for, if it is.
+func (l *AuthLimiter) LockedOut(ip string) (time.Duration, bool) {
+	if !l.lockoutEnabled() {
+		return 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[ip]
+
+	if !ok {
+		return 0, false
+	}
+
+	remaining := f.lockedUntil.Sub(l.getNow())
+
+	return remaining, remaining > 0
+}
+
+// Failed counts a failed attempt to authenticate from the IP, returning
+// whether it has caused the IP to be locked out.
+func (l *AuthLimiter) Failed(ip string) bool {
+	if !l.lockoutEnabled() {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.getNow()
+	l.cleanup(now)
+
+	f, ok := l.failures[ip]
+
+	if !ok || now.Sub(f.since) > l.window {
+		f = &authFailures{since: now}
+		l.failures[ip] = f
+	}
+
+	f.count++
+
+	if f.count < l.maxFailures || now.Before(f.lockedUntil) {
+		return false
+	}
+
+	f.lockedUntil = now.Add(l.duration)
+
+	return true
+}
+
+// cleanup forgets failures that no longer count towards or cause a lockout,
+// at most once per window.
+func (l *AuthLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.window {
+		return
+	}
+
+	for ip, f := range l.failures {
+		if now.Sub(f.since) > l.window && now.After(f.lockedUntil) {
+			delete(l.failures, ip)
+		}
+	}
+
+	l.lastCleanup = now
+}
+
+// perMinute converts requests per minute to a rate, a negative value removes
+// the limit.
+func perMinute(requests int) rate.Limit {
+	if requests < 0 {
+		return rate.Inf
+	}
+
+	return rate.Limit(float64(requests) / 60)
+}
+
+func NewAuthLimiter(cfg RateLimitConfig) *AuthLimiter {
+	ipRate, ipBurst := cfg.ApiServerRateLimitPerIP()
+	keyRate, keyBurst := cfg.ApiServerRateLimitPerKey()
+
+	return &AuthLimiter{
+		enabled: cfg.ApiServerRateLimitEnabled(),
+		ips: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate: perMinute(ipRate),
+			Burst: ipBurst,
+		}),
+		keys: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate: perMinute(keyRate),
+			Burst: keyBurst,
+		}),
+		maxFailures: cfg.ApiServerLockoutMaxFailures(),
+		window: cfg.ApiServerLockoutWindow(),
+		duration: cfg.ApiServerLockoutDuration(),
+		failures: map[string]*authFailures{},
+		lastCleanup: dt.NowUTC(),
+		getNow: dt.NowUTC,
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a time that tests can move forward.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) getNow() time.Time {
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func newTestLimiter(cfg *rateLimitConfig, c *clock) *AuthLimiter {
+	l := NewAuthLimiter(cfg)
+	l.getNow = c.getNow
+	l.lastCleanup = c.now
+
+	return l
+}
+
+func TestAuthLimiterLockout(t *testing.T) {
+	// step is either a failure from the ip, or a check that it's locked out
+	// (or not), after moving the clock on.
+	type step struct {
+		advance time.Duration
+		ip      string
+		fail    bool
+
+		// started is whether the failure should start a lockout, locked is
+		// whether the ip should be locked out after the step.
+		started bool
+		locked  bool
+	}
+
+	tests := []struct {
+		name  string
+		cfg   *rateLimitConfig
+		steps []step
+	}{
+		{
+			name: "locks out on the last allowed failure",
+			cfg: lockoutAfter(3),
+			steps: []step{
+				{ip: "a", fail: true},
+				{ip: "a", fail: true},
+				{ip: "a", fail: true, started: true, locked: true},
+			},
+		},
+		{
+			name: "only the failure that starts the lockout reports it",
+			cfg: lockoutAfter(2),
+			steps: []step{
+				{ip: "a", fail: true},
+				{ip: "a", fail: true, started: true, locked: true},
+				{ip: "a", fail: true, locked: true},
+				{advance: 30 * time.Second, ip: "a", fail: true, locked: true},
+			},
+		},
+		{
+			name: "lockout ends after the duration",
+			cfg: lockoutAfter(2),
+			steps: []step{
+				{ip: "a", fail: true},
+				{ip: "a", fail: true, started: true, locked: true},
+				{advance: 59 * time.Second, ip: "a", locked: true},
+				{advance: time.Second, ip: "a"},
+			},
+		},
+		{
+			name: "failures outside the window don't count",
+			cfg: lockoutAfter(2),
+			steps: []step{
+				{ip: "a", fail: true},
+				{advance: time.Minute + time.Second, ip: "a", fail: true},
+				{advance: time.Second, ip: "a", fail: true, started: true, locked: true},
+			},
+		},
+		{
+			name: "ips are locked out separately",
+			cfg: lockoutAfter(2),
+			steps: []step{
+				{ip: "a", fail: true},
+				{ip: "b", fail: true},
+				{ip: "a", fail: true, started: true, locked: true},
+				{ip: "b"},
+			},
+		},
+		{
+			name: "no lockout without a max",
+			cfg: lockoutAfter(0),
+			steps: []step{
+				{ip: "a", fail: true},
+				{ip: "a", fail: true},
+				{ip: "a", fail: true},
+			},
+		},
+		{
+			name: "no lockout when disabled",
+			cfg: func() *rateLimitConfig {
+				cfg := lockoutAfter(1)
+				cfg.enabled = false
+				return cfg
+			}(),
+			steps: []step{
+				{ip: "a", fail: true},
+				{ip: "a", fail: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClock()
+			l := newTestLimiter(tt.cfg, c)
+
+			for i, s := range tt.steps {
+				c.advance(s.advance)
+
+				if s.fail {
+					if started := l.Failed(s.ip); started != s.started {
+						t.Errorf("step %d: expected lockout started to be %t, got %t", i, s.started, started)
+					}
+				}
+
+				if _, locked := l.LockedOut(s.ip); locked != s.locked {
+					t.Errorf("step %d: expected locked out to be %t, got %t", i, s.locked, locked)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthLimiterLockedOutRemaining(t *testing.T) {
+	c := newClock()
+	l := newTestLimiter(lockoutAfter(1), c)
+
+	l.Failed("a")
+	c.advance(20 * time.Second)
+
+	remaining, locked := l.LockedOut("a")
+
+	if !locked || remaining != 40*time.Second {
+		t.Errorf("expected to be locked out for 40s, got %s (%t)", remaining, locked)
+	}
+}
+
+func TestAuthLimiterCleanup(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		kept    []string
+	}{
+		{
+			name: "keeps everything within the window",
+			advance: 30 * time.Second,
+			kept: []string{"failed", "locked"},
+		},
+		{
+			name: "keeps ips that are still locked out",
+			advance: time.Minute + time.Second,
+			kept: []string{"locked"},
+		},
+		{
+			name: "forgets everything once the lockout ends",
+			advance: 2*time.Minute + time.Second,
+			kept: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := lockoutAfter(2)
+			cfg.duration = 2 * time.Minute
+
+			c := newClock()
+			l := newTestLimiter(cfg, c)
+
+			l.Failed("failed")
+			l.Failed("locked")
+			l.Failed("locked")
+
+			// Cleaning up happens when failures are counted.
+			c.advance(tt.advance)
+			l.Failed("other")
+			delete(l.failures, "other")
+
+			if len(l.failures) != len(tt.kept) {
+				t.Errorf("expected %d ips to be kept, got %d", len(tt.kept), len(l.failures))
+			}
+
+			for _, ip := range tt.kept {
+				if _, ok := l.failures[ip]; !ok {
+					t.Errorf("expected %s to be kept", ip)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthLimiterRateLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *rateLimitConfig
+		do   func(l *AuthLimiter) []bool
+		want []bool
+	}{
+		{
+			name: "ip burst",
+			cfg: &rateLimitConfig{enabled: true, perIP: [2]int{1, 2}, perKey: [2]int{-1, 0}},
+			do: func(l *AuthLimiter) []bool {
+				return []bool{l.AllowIP("a"), l.AllowIP("a"), l.AllowIP("a"), l.AllowIP("b")}
+			},
+			want: []bool{true, true, false, true},
+		},
+		{
+			name: "key burst is shared across ips",
+			cfg: &rateLimitConfig{enabled: true, perIP: [2]int{-1, 0}, perKey: [2]int{1, 1}},
+			do: func(l *AuthLimiter) []bool {
+				return []bool{l.AllowIP("a"), l.AllowKey("key"), l.AllowIP("b"), l.AllowKey("key"), l.AllowKey("other")}
+			},
+			want: []bool{true, true, true, false, true},
+		},
+		{
+			name: "ip and key limits are separate",
+			cfg: &rateLimitConfig{enabled: true, perIP: [2]int{1, 1}, perKey: [2]int{1, 1}},
+			do: func(l *AuthLimiter) []bool {
+				return []bool{l.AllowIP("a"), l.AllowKey("a"), l.AllowIP("a"), l.AllowKey("a")}
+			},
+			want: []bool{true, true, false, false},
+		},
+		{
+			name: "negative rates are unlimited",
+			cfg: &rateLimitConfig{enabled: true, perIP: [2]int{-1, 0}, perKey: [2]int{-1, 0}},
+			do: func(l *AuthLimiter) []bool {
+				return []bool{l.AllowIP("a"), l.AllowIP("a"), l.AllowKey("a"), l.AllowKey("a")}
+			},
+			want: []bool{true, true, true, true},
+		},
+		{
+			name: "disabled",
+			cfg: &rateLimitConfig{perIP: [2]int{1, 1}, perKey: [2]int{1, 1}},
+			do: func(l *AuthLimiter) []bool {
+				return []bool{l.AllowIP("a"), l.AllowIP("a"), l.AllowKey("a"), l.AllowKey("a")}
+			},
+			want: []bool{true, true, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.do(newTestLimiter(tt.cfg, newClock()))
+
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("request %d: expected allowed to be %t, got %t", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
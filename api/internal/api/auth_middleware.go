@@ -1,45 +1,370 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util/dt"
 	"github.com/danielgtaylor/huma/v2"
 )
 
+// minMasterTokenLength is the shortest master token we don't warn about.
+const minMasterTokenLength = 32
+
 type AuthConfig interface {
-	GetMasterToken() string
+	AuthMasterToken() string
+	AuthMasterTokenEnabled() bool
+}
+
+// masterTokenMatches compares the digests rather than the tokens, so that the
+// comparison takes the same time whatever the length of the given token.
+func masterTokenMatches(cfg AuthConfig, given string) bool {
+	if !cfg.AuthMasterTokenEnabled() || cfg.AuthMasterToken() == "" {
+		return false
+	}
+
+	expected := sha256.Sum256([]byte(cfg.AuthMasterToken()))
+	actual := sha256.Sum256([]byte(given))
+
+	return subtle.ConstantTimeCompare(expected[:], actual[:]) == 1
+}
+
+// warnAboutMasterToken logs if the master token is enabled but can't be used,
+// or is easy to guess.
+func warnAboutMasterToken(cfg AuthConfig) {
+	if !cfg.AuthMasterTokenEnabled() {
+		return
+	}
+
+	if cfg.AuthMasterToken() == "" {
+		slog.Warn("the master token is enabled but not set, so can't be used")
+		return
+	}
+
+	if len(cfg.AuthMasterToken()) < minMasterTokenLength {
+		slog.Warn(
+			"the master token is weak, use a random value of at least the minimum length",
+			"min_length", minMasterTokenLength,
+		)
+		return
+	}
+
+	slog.Warn("the master token is enabled, disable it once setup is complete")
+}
+
+// withPrincipal makes the principal available to handlers, through
+// users.PrincipalFrom, and includes it in the access log and as the actor for
+// anything audited.
+func withPrincipal(ctx huma.Context, p *users.Principal) huma.Context {
+	recordPrincipal(ctx.Context(), p)
+
+	c := users.WithPrincipal(ctx.Context(), p)
+	c = audit.WithActor(c, principalActor(p))
+
+	return huma.WithContext(ctx, c)
+}
+
+func principalActor(p *users.Principal) audit.Actor {
+	return audit.Actor{
+		Type: p.Type(),
+		ID: p.ID(),
+		TokenID: p.TokenID,
+	}
+}
+
+type AuditRecorder interface {
+	Record(ctx context.Context, e audit.Event)
+}
+
+// auditable is whether every call to the operation should be audited, see
+// operations.OptAudit. These are recorded with the operation ID as the action,
+// alongside anything the services record themselves.
+func auditable(op *huma.Operation) bool {
+	v, _ := op.Metadata[operations.OptAudit].(bool)
+
+	return v
+}
+
+// authAttempt is whether the operation checks credentials itself, see
+// operations.OptAuthAttempt.
+func authAttempt(op *huma.Operation) bool {
+	v, _ := op.Metadata[operations.OptAuthAttempt].(bool)
+
+	return v
+}
+
+// sourceIP is the client's IP, as resolved by echo, falling back to the peer's
+// address.
+func sourceIP(ctx huma.Context) string {
+	if ip := audit.RequestFrom(ctx.Context()).SourceIP; ip != "" {
+		return ip
+	}
+
+	return ctx.RemoteAddr()
+}
+
+func operationDetails(ctx huma.Context) map[string]any {
+	return map[string]any{
+		"operation": ctx.Operation().OperationID,
+		"method": ctx.Method(),
+		"path": ctx.URL().Path,
+	}
 }
 
 type AuthMiddleware struct {
 	api huma.API
 }
 
+// lastUsedThrottle is how stale a token's last_used_at has to be before it's
+// updated, so that we don't write on every request.
+const lastUsedThrottle = 5 * time.Minute
+
 type AuthRepo interface {
 	ByID(id string) (*users.AccessToken, error)
+
+	// Touch sets when the token was last used, unless it's already been set
+	// to a time after since.
+	Touch(id string, at time.Time, since time.Time) error
+}
+
+type SessionAuthenticator interface {
+	Authenticate(sessionToken string) (*users.Session, error)
 }
 
 type TokenVerifier interface {
 	HashMatches(hash string, val string) (bool)
 }
 
-func NewAuthMiddleware(api huma.API, repo AuthRepo, verifier TokenVerifier) func(ctx huma.Context, next func(huma.Context)) {
+func NewAuthMiddleware(api huma.API, cfg AuthConfig, repo AuthRepo, verifier TokenVerifier, sessions SessionAuthenticator, auditor AuditRecorder, limiter *AuthLimiter) func(ctx huma.Context, next func(huma.Context)) {
 	return func (ctx huma.Context, next func(huma.Context)) {
+		ip := sourceIP(ctx)
+
+		// failed counts towards locking out the IP, recording the lockout
+		// when it starts rather than every rejected request, so that the audit
+		// log can't be flooded.
+		failed := func() {
+			if !limiter.Failed(ip) {
+				return
+			}
+
+			auditor.Record(ctx.Context(), audit.Event{
+				Action: "auth.lockout",
+				Outcome: audit.OutcomeDenied,
+				Actor: &audit.Actor{Type: audit.ActorAnonymous},
+				Details: operationDetails(ctx),
+			})
+		}
+
+		// lockedOut responds if the IP is locked out for failing to
+		// authenticate too many times.
+		lockedOut := func() bool {
+			remaining, locked := limiter.LockedOut(ip)
+
+			if !locked {
+				return false
+			}
+
+			ctx.SetHeader("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+			huma.WriteErr(api, ctx, http.StatusTooManyRequests, "Too many failed attempts to authenticate, try again later.")
+
+			return true
+		}
+
+		// throttled responds if the IP is locked out or over its rate limit.
+		// It's checked before any credentials are, as checking them is what's
+		// expensive.
+		throttled := func() bool {
+			if lockedOut() {
+				return true
+			}
+
+			if !limiter.AllowIP(ip) {
+				huma.WriteErr(api, ctx, http.StatusTooManyRequests, "Too many requests, try again later.")
+				return true
+			}
+
+			return false
+		}
+
+		// fail records failed authentication before responding. The reason is
+		// only for the audit log, the client just gets the message.
+		fail := func(reason string, msg string, details map[string]any) {
+			d := operationDetails(ctx)
+			d["reason"] = reason
+
+			for k, v := range details {
+				d[k] = v
+			}
+
+			auditor.Record(ctx.Context(), audit.Event{
+				Action: "auth.failed",
+				Outcome: audit.OutcomeFailure,
+				Actor: &audit.Actor{Type: audit.ActorAnonymous},
+				Details: d,
+			})
+
+			failed()
+			huma.WriteErr(api, ctx, http.StatusUnauthorized, msg)
+		}
+
 		authRequired := false
+		signatureAllowed := false
 
-		var neededScopes []string
+		// Each security requirement with scopes is an alternative, having
+		// all the scopes of any one of them is enough.
+		var neededScopes [][]string
 		for _, opScheme := range ctx.Operation().Security {
-			var ok bool
-
-			if neededScopes, ok = opScheme["scopes"]; ok {
+			if scopes, ok := opScheme["scopes"]; ok {
+				neededScopes = append(neededScopes, scopes)
 				authRequired = true
-				break
+			}
+
+			if _, ok := opScheme[operations.SecurityWebhookSignature]; ok {
+				signatureAllowed = true
 			}
 		}
 
 		if ! authRequired {
+			if !authAttempt(ctx.Operation()) {
+				next(ctx)
+				return
+			}
+
+			if throttled() {
+				return
+			}
+
 			next(ctx)
+
+			if ctx.Status() == http.StatusUnauthorized {
+				failed()
+			}
+
+			return
+		}
+
+		allowed := func(u *users.User) bool {
+			for _, scopes := range neededScopes {
+				if u.Can(scopes) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		deny := func(p *users.Principal) {
+			d := operationDetails(ctx)
+			d["scopes"] = neededScopes
+			actor := principalActor(p)
+
+			auditor.Record(ctx.Context(), audit.Event{
+				Action: "auth.denied",
+				Outcome: audit.OutcomeDenied,
+				Actor: &actor,
+				Details: d,
+			})
+
+			huma.WriteErr(api, ctx, http.StatusForbidden, "Not authorized to perform this action.")
+		}
+
+		proceed := func(p *users.Principal) {
+			pctx := withPrincipal(ctx, p)
+			next(pctx)
+
+			if !auditable(ctx.Operation()) {
+				return
+			}
+
+			outcome := audit.OutcomeSuccess
+
+			if pctx.Status() >= http.StatusBadRequest {
+				outcome = audit.OutcomeFailure
+			}
+
+			d := operationDetails(ctx)
+			d["status"] = pctx.Status()
+
+			auditor.Record(pctx.Context(), audit.Event{
+				Action: ctx.Operation().OperationID,
+				Outcome: outcome,
+				Details: d,
+			})
+		}
+
+		if signatureAllowed {
+			header, _ := ctx.Operation().Metadata[operations.OptSignatureHeader].(string)
+
+			// The signature can only be checked against the raw body, which
+			// isn't read until the handler runs, so verifying it is left to the
+			// handler. It responds 401 if it doesn't match, which counts as
+			// failing to authenticate like any other bad credentials.
+			//
+			// Providers deliver every webhook from a handful of IPs, and don't
+			// all redeliver ones that fail, so signed deliveries aren't held to
+			// the per IP rate limit. Only the lockout applies, so that bad
+			// signatures can't be used to burn CPU.
+			if header != "" && ctx.Header(header) != "" {
+				if lockedOut() {
+					return
+				}
+
+				next(ctx)
+
+				if ctx.Status() == http.StatusUnauthorized {
+					failed()
+				}
+
+				return
+			}
+		}
+
+		if throttled() {
+			return
+		}
+
+		if bearer, found := strings.CutPrefix(ctx.Header("Authorization"), "Bearer "); found {
+			// The master token has every permission, so there are no scopes to
+			// check. Anything else should be a session token from logging in.
+			if masterTokenMatches(cfg, bearer) {
+				proceed(&users.Principal{Master: true})
+				return
+			}
+
+			session, err := sessions.Authenticate(bearer)
+
+			if errors.Is(err, users.ErrInvalidSession) {
+				fail("invalid_session", "Not authorized to perform this action.", nil)
+				return
+			}
+
+			if err != nil {
+				slog.Error("failed to get session", "error", err)
+				huma.WriteErr(api, ctx, http.StatusInternalServerError, "failed to verify session token")
+				return
+			}
+
+			p := &users.Principal{
+				User: session.User,
+				SessionID: session.ID.String(),
+			}
+
+			if allowed(session.User) {
+				proceed(p)
+				return
+			}
+
+			deny(p)
 			return
 		}
 
@@ -47,6 +372,11 @@ func NewAuthMiddleware(api huma.API, repo AuthRepo, verifier TokenVerifier) func
 		token := ctx.Header("X-Access-Key-Token")
 
 		if key != "" && token != "" {
+			if !limiter.AllowKey(key) {
+				huma.WriteErr(api, ctx, http.StatusTooManyRequests, "Too many requests, try again later.")
+				return
+			}
+
 			accessToken, err := repo.ByID(key)
 
 			if err != nil {
@@ -55,24 +385,54 @@ func NewAuthMiddleware(api huma.API, repo AuthRepo, verifier TokenVerifier) func
 				return
 			}
 
-			if ! verifier.HashMatches(accessToken.SecretHash, token) {
-				huma.WriteErr(api, ctx, http.StatusUnauthorized, "Not authorized to perform this action.")
+			attempted := map[string]any{"key_id": key}
+
+			if accessToken == nil || ! verifier.HashMatches(accessToken.SecretHash, token) {
+				fail("invalid_token", "Not authorized to perform this action.", attempted)
 				return
 			}
 
-			if accessToken.User.Can(neededScopes) {
-				next(ctx)
+			now := dt.NowUTC()
+
+			if accessToken.IsRevoked() {
+				fail("token_revoked", "Access token has been revoked.", attempted)
+				return
 			}
-			
-			huma.WriteErr(api, ctx, http.StatusUnauthorized, "Not authorized to perform this action.")
-			
-			return
-		}
 
-		if len(neededScopes) > 0 {
-			// TOOD: check the scopes, this way is a bit bonkers
+			if accessToken.IsExpired(now) {
+				fail("token_expired", "Access token has expired.", attempted)
+				return
+			}
+
+			if !accessToken.User.IsActive() {
+				fail("user_deactivated", "User has been deactivated.", attempted)
+				return
+			}
+
+			since := now.Add(-lastUsedThrottle)
+
+			if accessToken.LastUsedAt == nil || accessToken.LastUsedAt.Before(since) {
+				// Not worth failing the request over.
+				if err := repo.Touch(accessToken.ID, now, since); err != nil {
+					slog.Error("failed to update when access token was last used", "error", err)
+				}
+			}
+
+			p := &users.Principal{
+				User: accessToken.User,
+				TokenID: accessToken.ID,
+			}
+
+			if allowed(accessToken.User) {
+				proceed(p)
+				return
+			}
+
+			deny(p)
+
+			return
 		}
 
-		huma.WriteErr(api, ctx, http.StatusUnauthorized, "Not auth mechanism found")
+		fail("no_credentials", "Not auth mechanism found", nil)
 	}
 }
\ No newline at end of file
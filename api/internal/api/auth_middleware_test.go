@@ -0,0 +1,791 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/api/v1"
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util/oidc"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/google/uuid"
+)
+
+type authConfig struct {
+	masterToken string
+	masterTokenEnabled bool
+}
+
+func (c *authConfig) AuthMasterToken() string {
+	return c.masterToken
+}
+
+func (c *authConfig) AuthMasterTokenEnabled() bool {
+	return c.masterTokenEnabled
+}
+
+type rateLimitConfig struct {
+	enabled bool
+	perIP [2]int
+	perKey [2]int
+	maxFailures int
+	window time.Duration
+	duration time.Duration
+}
+
+func (c *rateLimitConfig) ApiServerRateLimitEnabled() bool {
+	return c.enabled
+}
+
+func (c *rateLimitConfig) ApiServerRateLimitPerIP() (int, int) {
+	return c.perIP[0], c.perIP[1]
+}
+
+func (c *rateLimitConfig) ApiServerRateLimitPerKey() (int, int) {
+	return c.perKey[0], c.perKey[1]
+}
+
+func (c *rateLimitConfig) ApiServerLockoutMaxFailures() int {
+	return c.maxFailures
+}
+
+func (c *rateLimitConfig) ApiServerLockoutWindow() time.Duration {
+	return c.window
+}
+
+func (c *rateLimitConfig) ApiServerLockoutDuration() time.Duration {
+	return c.duration
+}
+
+// lockoutAfter is a limiter config without rate limits, that locks out after
+// the number of failures.
+func lockoutAfter(failures int) *rateLimitConfig {
+	return &rateLimitConfig{
+		enabled: true,
+		perIP: [2]int{-1, 0},
+		perKey: [2]int{-1, 0},
+		maxFailures: failures,
+		window: time.Minute,
+		duration: time.Minute,
+	}
+}
+
+type authRepo struct {
+	tokens  map[string]*users.AccessToken
+	touched []string
+}
+
+func (r *authRepo) ByID(id string) (*users.AccessToken, error) {
+	return r.tokens[id], nil
+}
+
+func (r *authRepo) Touch(id string, at time.Time, since time.Time) error {
+	r.touched = append(r.touched, id)
+
+	return nil
+}
+
+// plainVerifier treats the hash as the secret itself.
+type plainVerifier struct {
+	calls int
+}
+
+func (v *plainVerifier) HashMatches(hash string, val string) bool {
+	v.calls++
+
+	return hash == val
+}
+
+type sessionAuthenticator struct {
+	sessions map[string]*users.Session
+}
+
+func (a *sessionAuthenticator) Authenticate(token string) (*users.Session, error) {
+	s, ok := a.sessions[token]
+
+	if !ok {
+		return nil, users.ErrInvalidSession
+	}
+
+	return s, nil
+}
+
+type auditRecorder struct {
+	events []audit.Event
+}
+
+func (r *auditRecorder) Record(ctx context.Context, e audit.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *auditRecorder) actions() []string {
+	actions := []string{}
+
+	for _, e := range r.events {
+		actions = append(actions, e.Action+":"+string(e.Outcome))
+	}
+
+	return actions
+}
+
+// authHarness is an API with the auth middleware in front of it, using fakes
+// for everything the middleware depends on.
+type authHarness struct {
+	api      humatest.TestAPI
+	cfg      *authConfig
+	repo     *authRepo
+	verifier *plainVerifier
+	sessions *sessionAuthenticator
+	auditor  *auditRecorder
+}
+
+func newAuthHarness(t *testing.T, limits *rateLimitConfig) *authHarness {
+	t.Helper()
+
+	_, api := humatest.New(t, huma.DefaultConfig("Panoptes", "v1"))
+
+	h := &authHarness{
+		api: api,
+		cfg: &authConfig{},
+		repo: &authRepo{tokens: map[string]*users.AccessToken{}},
+		verifier: &plainVerifier{},
+		sessions: &sessionAuthenticator{sessions: map[string]*users.Session{}},
+		auditor: &auditRecorder{},
+	}
+
+	if limits == nil {
+		limits = &rateLimitConfig{}
+	}
+
+	api.UseMiddleware(NewAuthMiddleware(api, h.cfg, h.repo, h.verifier, h.sessions, h.auditor, NewAuthLimiter(limits)))
+
+	return h
+}
+
+type sessionsService struct {
+	calls int
+}
+
+func (s *sessionsService) Login(dto users.LoginDTO) (*users.SessionTokens, error) {
+	s.calls++
+
+	return nil, users.ErrInvalidCredentials
+}
+
+func (s *sessionsService) Refresh(refreshToken string) (*users.SessionTokens, error) {
+	s.calls++
+
+	return nil, users.ErrInvalidSession
+}
+
+func (s *sessionsService) Logout(refreshToken string) error {
+	s.calls++
+
+	return users.ErrInvalidSession
+}
+
+type oidcService struct {
+	calls int
+}
+
+func (s *oidcService) Begin() (*oidc.Login, error) {
+	return nil, users.ErrOIDCDisabled
+}
+
+func (s *oidcService) Complete(ctx context.Context, dto users.OIDCCallbackDTO) (*users.SessionTokens, error) {
+	s.calls++
+
+	return nil, users.ErrOIDCLogin
+}
+
+func TestAuthAttemptsLockOut(t *testing.T) {
+	tests := []struct {
+		name string
+		do func(api humatest.TestAPI) int
+	}{
+		{
+			name: "login",
+			do: func(api humatest.TestAPI) int {
+				return api.Post("/auth/login", map[string]any{"email": "a@example.com", "password": "wrong"}).Code
+			},
+		},
+		{
+			name: "refresh",
+			do: func(api humatest.TestAPI) int {
+				return api.Post("/auth/refresh", map[string]any{"refresh_token": "bad"}).Code
+			},
+		},
+		{
+			name: "logout",
+			do: func(api humatest.TestAPI) int {
+				return api.Post("/auth/logout", map[string]any{"refresh_token": "bad"}).Code
+			},
+		},
+		{
+			name: "oidc callback",
+			do: func(api humatest.TestAPI) int {
+				return api.Get("/auth/oidc/callback?code=bad&state=bad").Code
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAuthHarness(t, lockoutAfter(3))
+			sessions := &sessionsService{}
+			logins := &oidcService{}
+			v1.NewAuthController(sessions, logins).RegisterRoutes(h.api)
+
+			for i := 0; i < 3; i++ {
+				if code := tt.do(h.api); code != http.StatusUnauthorized {
+					t.Fatalf("attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, code)
+				}
+			}
+
+			if code := tt.do(h.api); code != http.StatusTooManyRequests {
+				t.Fatalf("expected status %d once locked out, got %d", http.StatusTooManyRequests, code)
+			}
+
+			if calls := sessions.calls + logins.calls; calls != 3 {
+				t.Errorf("expected the handler to be called 3 times, got %d", calls)
+			}
+		})
+	}
+}
+
+type signedRequest struct {
+	Signature string `header:"X-Hub-Signature-256"`
+}
+
+// registerWebhook registers an operation that accepts access tokens or a
+// signature, like the ingestion webhooks, with a handler that only accepts the
+// signature "valid".
+func registerWebhook(api huma.API) {
+	huma.Register[signedRequest, struct{}](api, huma.Operation{
+		OperationID: "test.webhook",
+		Method: http.MethodPost,
+		Path: "/webhook",
+		Metadata: map[string]any{
+			operations.OptSignatureHeader: "X-Hub-Signature-256",
+		},
+		Security: []map[string][]string{
+			{"scopes": {"ingest.github"}},
+			{operations.SecurityWebhookSignature: {}},
+		},
+	}, func(ctx context.Context, req *signedRequest) (*struct{}, error) {
+		if req.Signature != "valid" {
+			return nil, huma.Error401Unauthorized("invalid signature")
+		}
+
+		return nil, nil
+	})
+}
+
+func TestSignedWebhooksAreNotRateLimited(t *testing.T) {
+	limits := lockoutAfter(3)
+	limits.perIP = [2]int{1, 1}
+
+	h := newAuthHarness(t, limits)
+	registerWebhook(h.api)
+
+	for i := 0; i < 10; i++ {
+		if code := h.api.Post("/webhook", "X-Hub-Signature-256: valid").Code; code != http.StatusNoContent {
+			t.Fatalf("delivery %d: expected status %d, got %d", i+1, http.StatusNoContent, code)
+		}
+	}
+
+	// Requests without a signature still count towards the per IP limit.
+	if code := h.api.Post("/webhook").Code; code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, code)
+	}
+
+	if code := h.api.Post("/webhook").Code; code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestBadWebhookSignaturesLockOut(t *testing.T) {
+	h := newAuthHarness(t, lockoutAfter(3))
+	registerWebhook(h.api)
+
+	for i := 0; i < 3; i++ {
+		if code := h.api.Post("/webhook", "X-Hub-Signature-256: invalid").Code; code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, code)
+		}
+	}
+
+	res := h.api.Post("/webhook", "X-Hub-Signature-256: valid")
+
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d once locked out, got %d", http.StatusTooManyRequests, res.Code)
+	}
+
+	if res.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+}
+
+// userWith is an active user with a role granting the permissions.
+func userWith(permissions ...string) *users.User {
+	r := &users.Role{Name: "test"}
+
+	for _, p := range permissions {
+		r.Permissions = append(r.Permissions, &users.Permission{Name: p})
+	}
+
+	return &users.User{
+		ID: uuid.New(),
+		Type: users.UserTypeUser,
+		Roles: []*users.Role{r},
+	}
+}
+
+// login gives the user a session, returning the session token.
+func (h *authHarness) login(u *users.User) string {
+	token := "session-" + u.ID.String()
+	h.sessions.sessions[token] = &users.Session{ID: uuid.New(), User: u}
+
+	return token
+}
+
+// protected counts the calls to an operation, and who made them.
+type protected struct {
+	calls     int
+	principal *users.Principal
+}
+
+// registerProtected registers an operation at /protected with the security
+// requirements.
+func registerProtected(api huma.API, security ...map[string][]string) *protected {
+	p := &protected{}
+
+	huma.Register[struct{}, struct{}](api, huma.Operation{
+		OperationID: "test.protected",
+		Method: http.MethodGet,
+		Path: "/protected",
+		Security: security,
+	}, func(ctx context.Context, req *struct{}) (*struct{}, error) {
+		p.calls++
+		p.principal = users.PrincipalFrom(ctx)
+
+		return nil, nil
+	})
+
+	return p
+}
+
+// registerAudited registers operations at /audited, which succeeds, and
+// /audited/failing, which errors, that are both audited.
+func registerAudited(api huma.API) {
+	for _, path := range []string{"/audited", "/audited/failing"} {
+		huma.Register[struct{}, struct{}](api, huma.Operation{
+			OperationID: "test" + strings.ReplaceAll(path, "/", "."),
+			Method: http.MethodGet,
+			Path: path,
+			Metadata: map[string]any{
+				operations.OptAudit: true,
+			},
+			Security: []map[string][]string{{"scopes": {"a"}}},
+		}, func(ctx context.Context, req *struct{}) (*struct{}, error) {
+			if strings.HasSuffix(path, "failing") {
+				return nil, huma.Error500InternalServerError("failed")
+			}
+
+			return nil, nil
+		})
+	}
+}
+
+func TestAuthAudit(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		header   func(h *authHarness) string
+		attempts int
+		want     []string
+
+		// reason is why authentication failed, if it did.
+		reason string
+	}{
+		{
+			name: "no credentials",
+			path: "/audited",
+			header: func(h *authHarness) string { return "" },
+			want: []string{"auth.failed:failure"},
+			reason: "no_credentials",
+		},
+		{
+			name: "invalid session",
+			path: "/audited",
+			header: func(h *authHarness) string { return "Authorization: Bearer wrong" },
+			want: []string{"auth.failed:failure"},
+			reason: "invalid_session",
+		},
+		{
+			name: "access key without a token",
+			path: "/audited",
+			header: func(h *authHarness) string { return "X-Access-Key-ID: unknown" },
+			want: []string{"auth.failed:failure"},
+			reason: "no_credentials",
+		},
+		{
+			// The lockout is only recorded when it starts, not for each
+			// request it rejects.
+			name: "locked out",
+			path: "/audited",
+			header: func(h *authHarness) string { return "Authorization: Bearer wrong" },
+			attempts: 3,
+			want: []string{"auth.failed:failure", "auth.failed:failure", "auth.lockout:denied"},
+			reason: "invalid_session",
+		},
+		{
+			name: "without the scopes",
+			path: "/audited",
+			header: func(h *authHarness) string { return "Authorization: Bearer " + h.login(userWith("b")) },
+			want: []string{"auth.denied:denied"},
+		},
+		{
+			name: "allowed",
+			path: "/audited",
+			header: func(h *authHarness) string { return "Authorization: Bearer " + h.login(userWith("a")) },
+			want: []string{"test.audited:success"},
+		},
+		{
+			name: "allowed but failed",
+			path: "/audited/failing",
+			header: func(h *authHarness) string { return "Authorization: Bearer " + h.login(userWith("a")) },
+			want: []string{"test.audited.failing:failure"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAuthHarness(t, lockoutAfter(2))
+			registerAudited(h.api)
+
+			args := []any{}
+
+			if header := tt.header(h); header != "" {
+				args = append(args, header)
+			}
+
+			for range max(tt.attempts, 1) {
+				h.api.Get(tt.path, args...)
+			}
+
+			if got := h.auditor.actions(); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v to be audited, got %v", tt.want, got)
+			}
+
+			if tt.reason == "" {
+				return
+			}
+
+			if e := h.auditor.events[0]; e.Actor == nil || e.Actor.Type != audit.ActorAnonymous || e.Details["reason"] != tt.reason {
+				t.Errorf("expected an anonymous failure because of %s, got %+v", tt.reason, e)
+			}
+		})
+	}
+}
+
+func TestScopeAlternatives(t *testing.T) {
+	tests := []struct {
+		name        string
+		security    []map[string][]string
+		permissions []string
+		want        int
+	}{
+		{
+			name: "single requirement",
+			security: []map[string][]string{{"scopes": {"a"}}},
+			permissions: []string{"a"},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "single requirement needs every scope",
+			security: []map[string][]string{{"scopes": {"a", "b"}}},
+			permissions: []string{"a"},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "first alternative",
+			security: []map[string][]string{{"scopes": {"a"}}, {"scopes": {"b", "c"}}},
+			permissions: []string{"a"},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "last alternative",
+			security: []map[string][]string{{"scopes": {"a"}}, {"scopes": {"b", "c"}}},
+			permissions: []string{"b", "c"},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "part of an alternative",
+			security: []map[string][]string{{"scopes": {"a"}}, {"scopes": {"b", "c"}}},
+			permissions: []string{"c"},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "no alternative",
+			security: []map[string][]string{{"scopes": {"a"}}, {"scopes": {"b"}}},
+			permissions: []string{"c"},
+			want: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAuthHarness(t, nil)
+			op := registerProtected(h.api, tt.security...)
+			token := h.login(userWith(tt.permissions...))
+
+			res := h.api.Get("/protected", "Authorization: Bearer "+token)
+
+			if res.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, res.Code)
+			}
+
+			if called := op.calls > 0; called != (tt.want == http.StatusNoContent) {
+				t.Errorf("expected the handler being called to be %t, got %t", !called, called)
+			}
+		})
+	}
+}
+
+func TestBearerTokens(t *testing.T) {
+	const master = "a-master-token-that-is-long-enough"
+
+	tests := []struct {
+		name    string
+		enabled bool
+		header  func(h *authHarness) string
+		want    int
+		master  bool
+	}{
+		{
+			name: "no credentials",
+			enabled: true,
+			header: func(h *authHarness) string { return "" },
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "valid master token",
+			enabled: true,
+			header: func(h *authHarness) string { return "Bearer " + master },
+			want: http.StatusNoContent,
+			master: true,
+		},
+		{
+			name: "wrong master token",
+			enabled: true,
+			header: func(h *authHarness) string { return "Bearer " + master + "x" },
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "master token prefix",
+			enabled: true,
+			header: func(h *authHarness) string { return "Bearer " + master[:10] },
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "master token when disabled",
+			enabled: false,
+			header: func(h *authHarness) string { return "Bearer " + master },
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "session with the scopes",
+			enabled: true,
+			header: func(h *authHarness) string { return "Bearer " + h.login(userWith("a")) },
+			want: http.StatusNoContent,
+		},
+		{
+			name: "session without the scopes",
+			enabled: true,
+			header: func(h *authHarness) string { return "Bearer " + h.login(userWith("b")) },
+			want: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAuthHarness(t, nil)
+			h.cfg.masterToken = master
+			h.cfg.masterTokenEnabled = tt.enabled
+			op := registerProtected(h.api, map[string][]string{"scopes": {"a"}})
+
+			args := []any{}
+
+			if header := tt.header(h); header != "" {
+				args = append(args, "Authorization: "+header)
+			}
+
+			res := h.api.Get("/protected", args...)
+
+			if res.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, res.Code)
+			}
+
+			if tt.want != http.StatusNoContent {
+				if op.calls != 0 {
+					t.Errorf("expected the handler not to be called, it was called %d times", op.calls)
+				}
+
+				return
+			}
+
+			if op.calls != 1 {
+				t.Fatalf("expected the handler to be called once, got %d", op.calls)
+			}
+
+			if op.principal == nil || op.principal.Master != tt.master {
+				t.Errorf("expected a principal with master %t, got %+v", tt.master, op.principal)
+			}
+		})
+	}
+}
+
+func TestUnauthorizedWritesOneResponse(t *testing.T) {
+	h := newAuthHarness(t, nil)
+	registerProtected(h.api, map[string][]string{"scopes": {"a"}})
+
+	res := h.api.Get("/protected", "Authorization: Bearer wrong")
+
+	var body map[string]any
+
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected a single error response, got %q: %s", res.Body.String(), err)
+	}
+}
+
+func TestAccessKeys(t *testing.T) {
+	ago := func(d time.Duration) *time.Time {
+		at := time.Now().UTC().Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name    string
+		token   *users.AccessToken
+		secret  string
+		want    int
+		reason  string
+		touched bool
+	}{
+		{
+			name: "valid",
+			token: &users.AccessToken{SecretHash: "secret", User: userWith("a")},
+			secret: "secret",
+			want: http.StatusNoContent,
+			touched: true,
+		},
+		{
+			name: "unknown key",
+			secret: "secret",
+			want: http.StatusUnauthorized,
+			reason: "invalid_token",
+		},
+		{
+			name: "wrong secret",
+			token: &users.AccessToken{SecretHash: "secret", User: userWith("a")},
+			secret: "wrong",
+			want: http.StatusUnauthorized,
+			reason: "invalid_token",
+		},
+		{
+			name: "revoked",
+			token: &users.AccessToken{SecretHash: "secret", RevokedAt: ago(time.Hour), User: userWith("a")},
+			secret: "secret",
+			want: http.StatusUnauthorized,
+			reason: "token_revoked",
+		},
+		{
+			name: "expired",
+			token: &users.AccessToken{SecretHash: "secret", ExpireAt: ago(time.Second), User: userWith("a")},
+			secret: "secret",
+			want: http.StatusUnauthorized,
+			reason: "token_expired",
+		},
+		{
+			name: "not expired yet",
+			token: &users.AccessToken{SecretHash: "secret", ExpireAt: ago(-time.Hour), User: userWith("a")},
+			secret: "secret",
+			want: http.StatusNoContent,
+			touched: true,
+		},
+		{
+			name: "deactivated owner",
+			token: &users.AccessToken{SecretHash: "secret", User: func() *users.User {
+				u := userWith("a")
+				u.DeactivatedAt = ago(time.Hour)
+				return u
+			}()},
+			secret: "secret",
+			want: http.StatusUnauthorized,
+			reason: "user_deactivated",
+		},
+		{
+			name: "without the scopes",
+			token: &users.AccessToken{SecretHash: "secret", User: userWith("b")},
+			secret: "secret",
+			want: http.StatusForbidden,
+			touched: true,
+		},
+		{
+			name: "used recently",
+			token: &users.AccessToken{SecretHash: "secret", LastUsedAt: ago(lastUsedThrottle - time.Minute), User: userWith("a")},
+			secret: "secret",
+			want: http.StatusNoContent,
+			touched: false,
+		},
+		{
+			name: "not used recently",
+			token: &users.AccessToken{SecretHash: "secret", LastUsedAt: ago(lastUsedThrottle + time.Minute), User: userWith("a")},
+			secret: "secret",
+			want: http.StatusNoContent,
+			touched: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAuthHarness(t, nil)
+			op := registerProtected(h.api, map[string][]string{"scopes": {"a"}})
+
+			if tt.token != nil {
+				tt.token.ID = "key"
+				h.repo.tokens["key"] = tt.token
+			}
+
+			res := h.api.Get("/protected", "X-Access-Key-ID: key", "X-Access-Key-Token: "+tt.secret)
+
+			if res.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, res.Code)
+			}
+
+			if called := op.calls > 0; called != (tt.want == http.StatusNoContent) {
+				t.Errorf("expected the handler being called to be %t, got %t", !called, called)
+			}
+
+			if touched := len(h.repo.touched) > 0; touched != tt.touched {
+				t.Errorf("expected last used to be updated to be %t, got %t", tt.touched, touched)
+			}
+
+			if tt.reason == "" {
+				return
+			}
+
+			if len(h.auditor.events) != 1 || h.auditor.events[0].Details["reason"] != tt.reason {
+				t.Errorf("expected a failure to be audited with reason %q, got %v", tt.reason, h.auditor.events)
+			}
+		})
+	}
+}
@@ -5,10 +5,26 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/adamkirk/panoptes/internal/domain/users"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
+type accessLogKey struct{}
+
+// accessLogPrincipal is filled in by the auth middleware, as the principal is
+// only known once the request has been through it.
+type accessLogPrincipal struct {
+	principal *users.Principal
+}
+
+// recordPrincipal includes the principal in the request's access log.
+func recordPrincipal(ctx context.Context, p *users.Principal) {
+	if rec, ok := ctx.Value(accessLogKey{}).(*accessLogPrincipal); ok {
+		rec.principal = p
+	}
+}
+
 // setupLoggingMiddleare adds a curtom logger to the given echo server
 // We're purposely using slgo here so that it will default to whatever type of
 // logger we initially used .e.g JSON or TEXT
@@ -36,6 +52,10 @@ func buildLoggingMiddleware(format string) echo.MiddlewareFunc {
 		LogMethod:    true,
 		LogRequestID: true,
 		HandleError:  true, // forwards error to the global error handler, so it can decide appropriate status code
+		BeforeNextFunc: func(c echo.Context) {
+			ctx := context.WithValue(c.Request().Context(), accessLogKey{}, &accessLogPrincipal{})
+			c.SetRequest(c.Request().WithContext(ctx))
+		},
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
 
 			level := slog.LevelInfo
@@ -46,6 +66,14 @@ func buildLoggingMiddleware(format string) echo.MiddlewareFunc {
 				level = slog.LevelError
 			}
 
+			principalType := "anonymous"
+			principalID := ""
+
+			if rec, ok := c.Request().Context().Value(accessLogKey{}).(*accessLogPrincipal); ok && rec.principal != nil {
+				principalType = rec.principal.Type()
+				principalID = rec.principal.ID()
+			}
+
 			logger.LogAttrs(context.Background(), level, "REQUEST",
 				slog.String("uri", v.URI),
 				slog.Int("status", v.Status),
@@ -59,6 +87,8 @@ func buildLoggingMiddleware(format string) echo.MiddlewareFunc {
 				// Convert to milliseconds
 				slog.Float64("duration", float64(v.Latency.Microseconds())/1000),
 				slog.String("err", errorMsg),
+				slog.String("principal_type", principalType),
+				slog.String("principal_id", principalID),
 			)
 			return nil
 		},
@@ -1,4 +1,22 @@
 package operations
 
 const OptDisableNotFound = "DisableNotFound"
-const OptDisableAllDefaults = "DisableAllDefaults"
\ No newline at end of file
+const OptDisableAllDefaults = "DisableAllDefaults"
+
+// SecurityWebhookSignature is a security scheme for operations that can be
+// called by a 3rd party that signs its payloads, instead of using one of our
+// access tokens. The handler is responsible for verifying the signature.
+const SecurityWebhookSignature = "webhookSignature"
+
+// OptAudit records every call to the operation in the audit log, for
+// privileged operations like managing users.
+const OptAudit = "Audit"
+
+// OptAuthAttempt is for operations that check credentials themselves, like
+// logging in. They're rate limited like operations that need authenticating,
+// and their 401s count towards locking out the client.
+const OptAuthAttempt = "AuthAttempt"
+
+// OptSignatureHeader is the header that holds the signature for operations
+// using the SecurityWebhookSignature scheme.
+const OptSignatureHeader = "SignatureHeader"
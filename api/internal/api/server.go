@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/adamkirk/panoptes/internal/api/operations"
 	"github.com/danielgtaylor/huma/v2"
@@ -37,11 +39,47 @@ type ApiServerConfig interface {
 	ApiServerDebugErrorsEnabled() bool
 
 	AuthMasterToken() string
+	AuthMasterTokenEnabled() bool
+
+	RateLimitConfig
+	ApiServerTokenCacheTTL() time.Duration
+}
+
+type PermissionsRepo interface {
+	Names() ([]string, error)
 }
 
 type Server struct {
 	cfg ApiServerConfig
 	e   *echo.Echo
+
+	// scopes are all the scopes required by the registered operations.
+	scopes []string
+}
+
+// VerifyScopes checks that there's a permission for every scope required by an
+// operation. Without one, nobody but a superuser could ever be granted access
+// to the operation, which is almost certainly a missing migration.
+func (s *Server) VerifyScopes(repo PermissionsRepo) error {
+	names, err := repo.Names()
+
+	if err != nil {
+		return fmt.Errorf("failed to get permissions: %w", err)
+	}
+
+	missing := []string{}
+
+	for _, scope := range s.scopes {
+		if !slices.Contains(names, scope) && !slices.Contains(missing, scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("no permissions exist for scopes: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
 
 func (s *Server) Start() error {
@@ -135,13 +173,18 @@ func ConfigureDefaultResponses(api *huma.OpenAPI, op *huma.Operation) {
 	}
 }
 
-func NewServer(v1Api *V1Api, cfg ApiServerConfig, authRepo AuthRepo, verifier TokenVerifier) *Server {
+func NewServer(v1Api *V1Api, cfg ApiServerConfig, authRepo AuthRepo, verifier TokenVerifier, sessions SessionAuthenticator, auditor AuditRecorder) *Server {
 	e := echo.New()
 	
 	e.HideBanner = true
 	e.HidePort = true
+
+	// Only trust X-Forwarded-For from proxies on private networks, otherwise
+	// clients could spoof their IP to get around rate limits.
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Use(middleware.RequestID())
+	e.Use(auditRequestMiddleware)
 
 	if cfg.ApiServerAccessLogEnabled() {
 		e.Use(buildLoggingMiddleware(cfg.ApiServerAccessLogFormat()))
@@ -151,9 +194,17 @@ func NewServer(v1Api *V1Api, cfg ApiServerConfig, authRepo AuthRepo, verifier To
 	api := e.Group(apiBase)
 	apiCfg := huma.DefaultConfig("Panoptes", v1Api.Version())
 	hg := humaecho.NewWithGroup(e, api, apiCfg)
-	hg.UseMiddleware(NewAuthMiddleware(hg, authRepo, verifier))
+	hg.UseMiddleware(NewAuthMiddleware(hg, cfg, authRepo, newCachingVerifier(verifier, cfg.ApiServerTokenCacheTTL()), sessions, auditor, NewAuthLimiter(cfg)))
+	warnAboutMasterToken(cfg)
+
+	scopes := []string{}
 
 	hg.OpenAPI().OnAddOperation = append(hg.OpenAPI().OnAddOperation, ConfigureDefaultResponses)
+	hg.OpenAPI().OnAddOperation = append(hg.OpenAPI().OnAddOperation, func(api *huma.OpenAPI, op *huma.Operation) {
+		for _, scheme := range op.Security {
+			scopes = append(scopes, scheme["scopes"]...)
+		}
+	})
 	// Needed to get the docs displaying properly.
 	apiCfg.OpenAPI.Servers = []*huma.Server{
 		{
@@ -168,6 +219,7 @@ func NewServer(v1Api *V1Api, cfg ApiServerConfig, authRepo AuthRepo, verifier To
 	srv := &Server{
 		cfg: cfg,
 		e:   e,
+		scopes: scopes,
 	}
 
 	return srv
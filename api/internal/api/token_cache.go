@@ -0,0 +1,72 @@
+package api
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/util/dt"
+)
+
+// maxCachedVerifications stops the cache growing without bound, it's cleared
+// when full, which only costs some extra hashing.
+const maxCachedVerifications = 10000
+
+// cachingVerifier remembers successful verifications for a short time, so that
+// valid callers don't pay for bcrypt on every request. Only a digest of the
+// hash and secret is kept, and failures are never cached.
+//
+// Tokens are still looked up on every request, so revoking or expiring a
+// token, or deactivating its user, takes effect straight away.
+type cachingVerifier struct {
+	verifier TokenVerifier
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]time.Time
+	getNow  func() time.Time
+}
+
+func (v *cachingVerifier) HashMatches(hash string, val string) bool {
+	if v.ttl <= 0 {
+		return v.verifier.HashMatches(hash, val)
+	}
+
+	// The hash is length prefixed, so that no other pair of hash and secret
+	// can make the same key.
+	key := sha256.Sum256([]byte(strconv.Itoa(len(hash)) + ":" + hash + val))
+	now := v.getNow()
+
+	v.mu.Lock()
+	expiry, ok := v.entries[key]
+	v.mu.Unlock()
+
+	if ok && now.Before(expiry) {
+		return true
+	}
+
+	if !v.verifier.HashMatches(hash, val) {
+		return false
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.entries) >= maxCachedVerifications {
+		v.entries = map[[sha256.Size]byte]time.Time{}
+	}
+
+	v.entries[key] = now.Add(v.ttl)
+
+	return true
+}
+
+func newCachingVerifier(verifier TokenVerifier, ttl time.Duration) *cachingVerifier {
+	return &cachingVerifier{
+		verifier: verifier,
+		ttl: ttl,
+		entries: map[[sha256.Size]byte]time.Time{},
+		getNow: dt.NowUTC,
+	}
+}
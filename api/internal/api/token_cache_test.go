@@ -0,0 +1,132 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestVerifier(ttl time.Duration, c *clock) (*cachingVerifier, *plainVerifier) {
+	inner := &plainVerifier{}
+	v := newCachingVerifier(inner, ttl)
+	v.getNow = c.getNow
+
+	return v, inner
+}
+
+func TestCachingVerifier(t *testing.T) {
+	// check is a verification after moving the clock on, with whether it
+	// should match and whether it should have been hashed again.
+	type check struct {
+		advance time.Duration
+		hash    string
+		val     string
+		matches bool
+		hashed  bool
+	}
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		checks []check
+	}{
+		{
+			name: "caches matches",
+			ttl: time.Minute,
+			checks: []check{
+				{hash: "secret", val: "secret", matches: true, hashed: true},
+				{hash: "secret", val: "secret", matches: true},
+				{advance: 59 * time.Second, hash: "secret", val: "secret", matches: true},
+			},
+		},
+		{
+			name: "expires after the ttl",
+			ttl: time.Minute,
+			checks: []check{
+				{hash: "secret", val: "secret", matches: true, hashed: true},
+				{advance: time.Minute, hash: "secret", val: "secret", matches: true, hashed: true},
+				{hash: "secret", val: "secret", matches: true},
+			},
+		},
+		{
+			name: "never caches failures",
+			ttl: time.Minute,
+			checks: []check{
+				{hash: "secret", val: "wrong", hashed: true},
+				{hash: "secret", val: "wrong", hashed: true},
+			},
+		},
+		{
+			name: "keyed by both the hash and the value",
+			ttl: time.Minute,
+			checks: []check{
+				{hash: "secret", val: "secret", matches: true, hashed: true},
+				{hash: "other", val: "secret", hashed: true},
+				{hash: "secret", val: "other", hashed: true},
+			},
+		},
+		{
+			name: "the key can't be forged by moving the separator",
+			ttl: time.Minute,
+			checks: []check{
+				{hash: "a\x00b", val: "a\x00b", matches: true, hashed: true},
+				{hash: "a", val: "b\x00a\x00b", hashed: true},
+			},
+		},
+		{
+			name: "disabled without a ttl",
+			ttl: 0,
+			checks: []check{
+				{hash: "secret", val: "secret", matches: true, hashed: true},
+				{hash: "secret", val: "secret", matches: true, hashed: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClock()
+			v, inner := newTestVerifier(tt.ttl, c)
+
+			for i, chk := range tt.checks {
+				c.advance(chk.advance)
+				calls := inner.calls
+
+				if matches := v.HashMatches(chk.hash, chk.val); matches != chk.matches {
+					t.Errorf("check %d: expected matches to be %t, got %t", i, chk.matches, matches)
+				}
+
+				if hashed := inner.calls > calls; hashed != chk.hashed {
+					t.Errorf("check %d: expected hashed to be %t, got %t", i, chk.hashed, hashed)
+				}
+			}
+		})
+	}
+}
+
+func TestCachingVerifierClearsWhenFull(t *testing.T) {
+	c := newClock()
+	v, inner := newTestVerifier(time.Minute, c)
+
+	v.HashMatches("first", "first")
+
+	for i := 1; i < maxCachedVerifications; i++ {
+		v.entries[[32]byte{byte(i), byte(i >> 8)}] = c.now.Add(time.Minute)
+	}
+
+	if len(v.entries) != maxCachedVerifications {
+		t.Fatalf("expected %d entries, got %d", maxCachedVerifications, len(v.entries))
+	}
+
+	v.HashMatches("new", "new")
+
+	if len(v.entries) != 1 {
+		t.Errorf("expected the cache to be cleared, leaving 1 entry, got %d", len(v.entries))
+	}
+
+	calls := inner.calls
+	v.HashMatches("first", "first")
+
+	if inner.calls == calls {
+		t.Error("expected verifications from before clearing to be hashed again")
+	}
+}
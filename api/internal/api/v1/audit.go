@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type AuditService interface {
+	List(filter audit.Filter) ([]*audit.Event, error)
+}
+
+type AuditController struct {
+	svc AuditService
+}
+
+func (c *AuditController) RegisterRoutes(api huma.API) {
+	huma.Register[ListAuditEventsRequest, ListAuditEventsResponse](api, huma.Operation{
+		OperationID:  "v1.audit.list",
+		Method:       http.MethodGet,
+		Path:         "/audit",
+		Summary:      "List Audit Events",
+		Description:  "Events are returned newest first, unless after_seq is given, in which case the events after it are returned oldest first. Use the seq of the last event as after_seq to follow the log.",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"audit.list"}},
+		},
+	}, ErrorHandler(true, c.List))
+}
+
+func NewAuditController(svc AuditService) *AuditController {
+	return &AuditController{
+		svc: svc,
+	}
+}
+
+type AuditActorBody struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	TokenID string `json:"token_id,omitempty"`
+}
+
+type AuditEventBody struct {
+	ID         uuid.UUID      `json:"id"`
+	Seq        int64          `json:"seq"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Action     string         `json:"action"`
+	Outcome    string         `json:"outcome"`
+	Actor      AuditActorBody `json:"actor"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   string         `json:"target_id,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	SourceIP   string         `json:"source_ip,omitempty"`
+	Details    map[string]any `json:"details"`
+}
+
+func newAuditEventBody(e *audit.Event) *AuditEventBody {
+	return &AuditEventBody{
+		ID: e.ID,
+		Seq: e.Seq,
+		OccurredAt: e.OccurredAt,
+		Action: e.Action,
+		Outcome: string(e.Outcome),
+		Actor: AuditActorBody{
+			Type: e.Actor.Type,
+			ID: e.Actor.ID,
+			TokenID: e.Actor.TokenID,
+		},
+		TargetType: e.TargetType,
+		TargetID: e.TargetID,
+		RequestID: e.Request.ID,
+		SourceIP: e.Request.SourceIP,
+		Details: e.Details,
+	}
+}
+
+type ListAuditEventsRequest struct {
+	Action     string    `query:"action"`
+	Outcome    string    `query:"outcome" enum:"success,failure,denied"`
+	ActorType  string    `query:"actor_type"`
+	ActorID    string    `query:"actor_id"`
+	TargetType string    `query:"target_type"`
+	TargetID   string    `query:"target_id"`
+	Since      time.Time `query:"since" doc:"Only events at or after this time."`
+	Until      time.Time `query:"until" doc:"Only events before this time."`
+	AfterSeq   int64     `query:"after_seq" minimum:"0" doc:"Only events after this seq, oldest first."`
+	Limit      int       `query:"limit" default:"50" minimum:"1" maximum:"500"`
+}
+
+type ListAuditEventsResponse struct {
+	Body struct {
+		Items []*AuditEventBody `json:"items"`
+	}
+}
+
+func (c *AuditController) List(ctx context.Context, req *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	filter := audit.Filter{
+		Action: req.Action,
+		Outcome: req.Outcome,
+		ActorType: req.ActorType,
+		ActorID: req.ActorID,
+		TargetType: req.TargetType,
+		TargetID: req.TargetID,
+		Limit: req.Limit,
+	}
+
+	if !req.Since.IsZero() {
+		filter.Since = &req.Since
+	}
+
+	if !req.Until.IsZero() {
+		filter.Until = &req.Until
+	}
+
+	if req.AfterSeq > 0 {
+		filter.AfterSeq = &req.AfterSeq
+	}
+
+	events, err := c.svc.List(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListAuditEventsResponse{}
+	resp.Body.Items = util.Map[*audit.Event, *AuditEventBody](newAuditEventBody, events)
+
+	return resp, nil
+}
@@ -0,0 +1,259 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/api/v1/responses"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util/oidc"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// oidcCookie holds the login state between redirecting to the identity
+// provider and it redirecting back.
+const oidcCookie = "panoptes_oidc"
+const oidcCookiePath = "/api/v1/auth/oidc"
+
+type SessionsService interface {
+	Login(dto users.LoginDTO) (*users.SessionTokens, error)
+	Refresh(refreshToken string) (*users.SessionTokens, error)
+	Logout(refreshToken string) error
+}
+
+type OIDCService interface {
+	Begin() (*oidc.Login, error)
+	Complete(ctx context.Context, dto users.OIDCCallbackDTO) (*users.SessionTokens, error)
+}
+
+type AuthController struct {
+	svc  SessionsService
+	oidc OIDCService
+}
+
+func (c *AuthController) RegisterRoutes(api huma.API) {
+	huma.Register[LoginRequest, SessionResponse](api, huma.Operation{
+		OperationID:  "v1.auth.login",
+		Method:       http.MethodPost,
+		Path:         "/auth/login",
+		Summary:      "Log in",
+		Description:  "Starts a session, send the session token as 'Authorization: Bearer <token>' until it expires, then use the refresh token to get a new pair of tokens.",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAuthAttempt: true,
+			operations.OptDisableNotFound: true,
+		},
+	}, ErrorHandler(true, c.Login))
+
+	huma.Register[RefreshSessionRequest, SessionResponse](api, huma.Operation{
+		OperationID:  "v1.auth.refresh",
+		Method:       http.MethodPost,
+		Path:         "/auth/refresh",
+		Summary:      "Refresh a session",
+		Description:  "Swaps a refresh token for a new pair of tokens. Refresh tokens can only be used once, using one again ends the session.",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAuthAttempt: true,
+			operations.OptDisableNotFound: true,
+		},
+	}, ErrorHandler(true, c.Refresh))
+
+	huma.Register[LogoutRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.auth.logout",
+		Method:       http.MethodPost,
+		Path:         "/auth/logout",
+		Summary:      "Log out",
+		Description:  "Ends the session, its session token stops working straight away.",
+		DefaultStatus: http.StatusNoContent,
+		Metadata: map[string]any{
+			operations.OptAuthAttempt: true,
+			operations.OptDisableNotFound: true,
+		},
+	}, ErrorHandler(true, c.Logout))
+
+	huma.Register[struct{}, OIDCLoginResponse](api, huma.Operation{
+		OperationID:  "v1.auth.oidc.login",
+		Method:       http.MethodGet,
+		Path:         "/auth/oidc/login",
+		Summary:      "Log in with the identity provider",
+		Description:  "Redirects to the identity provider, which redirects back to the callback once logged in.",
+		DefaultStatus: http.StatusFound,
+		Metadata: map[string]any{
+			operations.OptDisableNotFound: true,
+		},
+	}, ErrorHandler(true, c.OIDCLogin))
+
+	huma.Register[OIDCCallbackRequest, OIDCCallbackResponse](api, huma.Operation{
+		OperationID:  "v1.auth.oidc.callback",
+		Method:       http.MethodGet,
+		Path:         "/auth/oidc/callback",
+		Summary:      "Finish logging in with the identity provider",
+		Description:  "Where the identity provider redirects back to, starts a session in the same way as logging in with a password. Users are created the first time they log in.",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAuthAttempt: true,
+			operations.OptDisableNotFound: true,
+		},
+	}, ErrorHandler(true, c.OIDCCallback))
+}
+
+func NewAuthController(svc SessionsService, oidc OIDCService) *AuthController {
+	return &AuthController{
+		svc: svc,
+		oidc: oidc,
+	}
+}
+
+type SessionBody struct {
+	SessionToken     string    `json:"session_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at" doc:"When the session can no longer be refreshed, and you need to log in again."`
+}
+
+type SessionResponse struct {
+	Body *SessionBody
+}
+
+func newSessionResponse(t *users.SessionTokens) *SessionResponse {
+	return &SessionResponse{
+		Body: &SessionBody{
+			SessionToken: t.SessionToken,
+			TokenType: "Bearer",
+			ExpiresAt: t.SessionExpireAt,
+			RefreshToken: t.RefreshToken,
+			RefreshExpiresAt: t.Session.ExpireAt,
+		},
+	}
+}
+
+type LoginRequest struct {
+	Body struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+}
+
+func (c *AuthController) Login(ctx context.Context, req *LoginRequest) (*SessionResponse, error) {
+	t, err := c.svc.Login(users.LoginDTO{
+		Email: req.Body.Email,
+		Password: req.Body.Password,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newSessionResponse(t), nil
+}
+
+type RefreshSessionRequest struct {
+	Body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+}
+
+func (c *AuthController) Refresh(ctx context.Context, req *RefreshSessionRequest) (*SessionResponse, error) {
+	t, err := c.svc.Refresh(req.Body.RefreshToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newSessionResponse(t), nil
+}
+
+type LogoutRequest struct {
+	Body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+}
+
+func (c *AuthController) Logout(ctx context.Context, req *LogoutRequest) (*responses.NoContent, error) {
+	if err := c.svc.Logout(req.Body.RefreshToken); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusNoContent,
+	}, nil
+}
+
+type OIDCLoginResponse struct {
+	Status    int
+	Location  string      `header:"Location"`
+	SetCookie http.Cookie `header:"Set-Cookie"`
+}
+
+func (c *AuthController) OIDCLogin(ctx context.Context, req *struct{}) (*OIDCLoginResponse, error) {
+	login, err := c.oidc.Begin()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLoginResponse{
+		Status: http.StatusFound,
+		Location: login.URL,
+		SetCookie: http.Cookie{
+			Name: oidcCookie,
+			Value: login.Cookie,
+			Path: oidcCookiePath,
+			Expires: login.ExpireAt,
+			HttpOnly: true,
+			Secure: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}, nil
+}
+
+type OIDCCallbackRequest struct {
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+	Cookie           string `cookie:"panoptes_oidc"`
+}
+
+type OIDCCallbackResponse struct {
+	SetCookie http.Cookie `header:"Set-Cookie"`
+	Body      *SessionBody
+}
+
+func (c *AuthController) OIDCCallback(ctx context.Context, req *OIDCCallbackRequest) (*OIDCCallbackResponse, error) {
+	if req.Error != "" {
+		return nil, huma.Error401Unauthorized(strings.TrimSpace("the identity provider returned an error: " + req.Error + " " + req.ErrorDescription))
+	}
+
+	t, err := c.oidc.Complete(ctx, users.OIDCCallbackDTO{
+		Cookie: req.Cookie,
+		State: req.State,
+		Code: req.Code,
+	})
+
+	if errors.Is(err, users.ErrUserDeactivated) {
+		return nil, huma.Error403Forbidden(err.Error())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCCallbackResponse{
+		// The login state is single use.
+		SetCookie: http.Cookie{
+			Name: oidcCookie,
+			Path: oidcCookiePath,
+			MaxAge: -1,
+			HttpOnly: true,
+			Secure: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		Body: newSessionResponse(t).Body,
+	}, nil
+}
@@ -2,8 +2,41 @@ package v1
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/danielgtaylor/huma/v2"
 )
 
+// statusErrors maps domain errors to the status code they should be reported
+// with, the error message is shown to the client.
+var statusErrors = []struct {
+	err    error
+	status int
+}{
+	{users.ErrUserNotFound, http.StatusNotFound},
+	{users.ErrAccessTokenNotFound, http.StatusNotFound},
+	{users.ErrEmailInUse, http.StatusConflict},
+	{users.ErrRoleNotFound, http.StatusUnprocessableEntity},
+	{users.ErrSuperuserRole, http.StatusUnprocessableEntity},
+	{users.ErrRolesManagedByIdP, http.StatusUnprocessableEntity},
+	{users.ErrRoleExists, http.StatusConflict},
+	{users.ErrPermissionNotFound, http.StatusUnprocessableEntity},
+	{users.ErrInvalidExpiry, http.StatusUnprocessableEntity},
+	{users.ErrUserDeactivated, http.StatusUnprocessableEntity},
+	{users.ErrForbidden, http.StatusForbidden},
+	{users.ErrInvalidCredentials, http.StatusUnauthorized},
+	{users.ErrInvalidSession, http.StatusUnauthorized},
+	{users.ErrSessionsDisabled, http.StatusServiceUnavailable},
+	{users.ErrOIDCLogin, http.StatusUnauthorized},
+	{users.ErrOIDCDisabled, http.StatusServiceUnavailable},
+	{users.ErrServiceAccountNotFound, http.StatusNotFound},
+	{users.ErrNameInUse, http.StatusConflict},
+}
+
 func ErrorHandler[Req any, Resp any](debugErrors bool, handler func(context.Context, *Req) (*Resp, error)) (func (ctx context.Context, req *Req) (*Resp, error)) {
 	return func (ctx context.Context, req *Req) (*Resp, error) {
 		resp, err :=  handler(ctx, req)
@@ -12,7 +45,40 @@ func ErrorHandler[Req any, Resp any](debugErrors bool, handler func(context.Cont
 			return resp, nil
 		}
 
-		// TODO: Add error hanlding logic
-		return resp, err
+		// Already a response, e.g. from huma.Error404NotFound.
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			return resp, err
+		}
+
+		var validationErr validation.ValidationError
+		if errors.As(err, &validationErr) {
+			details := []error{}
+
+			for _, fieldErr := range validationErr.Errs {
+				for _, msg := range fieldErr.Errors {
+					details = append(details, &huma.ErrorDetail{
+						Message:  msg,
+						Location: "body." + fieldErr.Key,
+					})
+				}
+			}
+
+			return nil, huma.Error422UnprocessableEntity("validation failed", details...)
+		}
+
+		for _, se := range statusErrors {
+			if errors.Is(err, se.err) {
+				return nil, huma.NewError(se.status, err.Error())
+			}
+		}
+
+		slog.Error("unhandled error", "error", err)
+
+		if debugErrors {
+			return nil, huma.Error500InternalServerError(err.Error())
+		}
+
+		return nil, huma.Error500InternalServerError("internal server error")
 	}
 }
@@ -2,26 +2,82 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/adamkirk/panoptes/internal/api/operations"
 	"github.com/adamkirk/panoptes/internal/api/v1/responses"
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/adamkirk/panoptes/internal/domain/ingestion"
 	"github.com/danielgtaylor/huma/v2"
 )
 
 type GithubIngestor interface {
-	Process(e ingestion.GithubEvent) error
+	Verify(body []byte, signature string) error
 }
 
 type GithubWebhookRequest struct {
 	GithubEvent string `header:"X-GitHub-Event" required:"true"`
 	GithubDelivery string `header:"X-GitHub-Delivery" required:"true"`
+	Signature string `header:"X-Hub-Signature-256" doc:"HMAC-SHA256 signature of the body, required when not using an access token"`
 	Body map[string]any `doc:"Any webhook structure that github may send"`
+	RawBody []byte
+}
+
+type GitlabIngestor interface {
+	Verify(token string) error
+}
+
+type GitlabWebhookRequest struct {
+	GitlabEvent string `header:"X-Gitlab-Event" required:"true"`
+	GitlabDelivery string `header:"X-Gitlab-Event-UUID"`
+	Token string `header:"X-Gitlab-Token" doc:"The secret token configured on the webhook, required when not using an access token"`
+	Body map[string]any `doc:"Any webhook structure that gitlab may send"`
+}
+
+type BitbucketIngestor interface {
+	Verify(body []byte, signature string) error
+}
+
+type BitbucketWebhookRequest struct {
+	BitbucketEvent string `header:"X-Event-Key" required:"true"`
+	BitbucketDelivery string `header:"X-Request-UUID"`
+	Signature string `header:"X-Hub-Signature" doc:"HMAC-SHA256 signature of the body, required when not using an access token"`
+	Body map[string]any `doc:"Any webhook structure that bitbucket may send"`
+	RawBody []byte
+}
+
+type JiraIngestor interface {
+	Verify(body []byte, signature string) error
+}
+
+type AuditRecorder interface {
+	Record(ctx context.Context, e audit.Event)
+}
+
+// IngestionQueue durably stores deliveries to be processed later, so that
+// we can respond before the source gives up waiting.
+type IngestionQueue interface {
+	EnqueueGithub(e ingestion.GithubEvent) error
+	EnqueueGitlab(e ingestion.GitlabEvent) error
+	EnqueueBitbucket(e ingestion.BitbucketEvent) error
+	EnqueueJira(e ingestion.JiraEvent) error
+}
+
+type JiraWebhookRequest struct {
+	JiraDelivery string `header:"X-Atlassian-Webhook-Identifier"`
+	Signature string `header:"X-Hub-Signature" doc:"HMAC-SHA256 signature of the body, required when not using an access token"`
+	Body map[string]any `doc:"Any webhook structure that jira may send"`
+	RawBody []byte
 }
 
 type IngestionController struct {
 	github GithubIngestor
+	gitlab GitlabIngestor
+	bitbucket BitbucketIngestor
+	jira JiraIngestor
+	queue IngestionQueue
+	auditor AuditRecorder
 }
 
 func (c *IngestionController) RegisterRoutes(api huma.API) {
@@ -30,34 +86,197 @@ func (c *IngestionController) RegisterRoutes(api huma.API) {
 		Method:       http.MethodPost,
 		Path:         "/ingestion/github",
 		Summary:      "Ingest a webhook event from github",
-		DefaultStatus: http.StatusNoContent,
+		Description:  "The event is queued to be processed, so is accepted before it's been stored in the event stream.",
+		DefaultStatus: http.StatusAccepted,
 		Metadata: map[string]any{
 			operations.OptDisableNotFound: true,
+			operations.OptSignatureHeader: "X-Hub-Signature-256",
 		},
 		Security: []map[string][]string{
 			{"scopes": {"ingest.github"}},
+			{operations.SecurityWebhookSignature: {}},
 		},
 	}, ErrorHandler(true, c.IngestGithubWebhook))
+
+	huma.Register[GitlabWebhookRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.ingest.gitlab",
+		Method:       http.MethodPost,
+		Path:         "/ingestion/gitlab",
+		Summary:      "Ingest a webhook event from gitlab",
+		Description:  "The event is queued to be processed, so is accepted before it's been stored in the event stream. Merge request, note and pipeline hooks are understood, anything else is stored but otherwise ignored.",
+		DefaultStatus: http.StatusAccepted,
+		Metadata: map[string]any{
+			operations.OptDisableNotFound: true,
+			operations.OptSignatureHeader: "X-Gitlab-Token",
+		},
+		Security: []map[string][]string{
+			{"scopes": {"ingest.gitlab"}},
+			{operations.SecurityWebhookSignature: {}},
+		},
+	}, ErrorHandler(true, c.IngestGitlabWebhook))
+
+	huma.Register[BitbucketWebhookRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.ingest.bitbucket",
+		Method:       http.MethodPost,
+		Path:         "/ingestion/bitbucket",
+		Summary:      "Ingest a webhook event from bitbucket cloud",
+		Description:  "The event is queued to be processed, so is accepted before it's been stored in the event stream. Pull request events are understood, anything else is stored but otherwise ignored.",
+		DefaultStatus: http.StatusAccepted,
+		Metadata: map[string]any{
+			operations.OptDisableNotFound: true,
+			operations.OptSignatureHeader: "X-Hub-Signature",
+		},
+		Security: []map[string][]string{
+			{"scopes": {"ingest.bitbucket"}},
+			{operations.SecurityWebhookSignature: {}},
+		},
+	}, ErrorHandler(true, c.IngestBitbucketWebhook))
+
+	huma.Register[JiraWebhookRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.ingest.jira",
+		Method:       http.MethodPost,
+		Path:         "/ingestion/jira",
+		Summary:      "Ingest a webhook event from jira",
+		Description:  "The event is queued to be processed, so is accepted before it's been stored in the event stream.",
+		DefaultStatus: http.StatusAccepted,
+		Metadata: map[string]any{
+			operations.OptDisableNotFound: true,
+			operations.OptSignatureHeader: "X-Hub-Signature",
+		},
+		Security: []map[string][]string{
+			{"scopes": {"ingest.jira"}},
+			{operations.SecurityWebhookSignature: {}},
+		},
+	}, ErrorHandler(true, c.IngestJiraWebhook))
 }
 
-func NewIngestController(gh GithubIngestor) *IngestionController {
+func NewIngestController(gh GithubIngestor, gitlab GitlabIngestor, bitbucket BitbucketIngestor, jira JiraIngestor, queue IngestionQueue, auditor AuditRecorder) *IngestionController {
 	return &IngestionController{
 		github: gh,
+		gitlab: gitlab,
+		bitbucket: bitbucket,
+		jira: jira,
+		queue: queue,
+		auditor: auditor,
 	}
 }
 
+// verificationFailed audits a webhook that didn't match its secret, like any
+// other failure to authenticate, before refusing it. The request, including
+// where it came from, is recorded with the event.
+func (c *IngestionController) verificationFailed(ctx context.Context, integration string, reason string, msg string) error {
+	c.auditor.Record(ctx, audit.Event{
+		Action: "auth.failed",
+		Outcome: audit.OutcomeFailure,
+		Actor: &audit.Actor{Type: audit.ActorAnonymous},
+		Details: map[string]any{
+			"reason": reason,
+			"ingestor": integration,
+		},
+	})
+
+	return huma.Error401Unauthorized(msg)
+}
+
 func (c *IngestionController) IngestGithubWebhook(ctx context.Context, req *GithubWebhookRequest) (*responses.NoContent, error) {
+	if req.Signature != "" {
+		if err := c.github.Verify(req.RawBody, req.Signature); err != nil {
+			if errors.Is(err, ingestion.ErrInvalidSignature) {
+				return nil, c.verificationFailed(ctx, ingestion.IntegrationGithub, "invalid_signature", "Webhook signature does not match.")
+			}
+
+			return nil, err
+		}
+	}
+
 	e := ingestion.GithubEvent{
 		Payload: req.Body,
 		DeliveryID: req.GithubDelivery,
 		Event: req.GithubEvent,
 	}
 
-	if err := c.github.Process(e); err != nil {
+	if err := c.queue.EnqueueGithub(e); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusAccepted,
+	}, nil
+}
+
+func (c *IngestionController) IngestGitlabWebhook(ctx context.Context, req *GitlabWebhookRequest) (*responses.NoContent, error) {
+	if req.Token != "" {
+		if err := c.gitlab.Verify(req.Token); err != nil {
+			if errors.Is(err, ingestion.ErrInvalidSignature) {
+				return nil, c.verificationFailed(ctx, ingestion.IntegrationGitlab, "invalid_token", "Webhook token does not match.")
+			}
+
+			return nil, err
+		}
+	}
+
+	e := ingestion.GitlabEvent{
+		Payload: req.Body,
+		DeliveryID: req.GitlabDelivery,
+		Event: req.GitlabEvent,
+	}
+
+	if err := c.queue.EnqueueGitlab(e); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusAccepted,
+	}, nil
+}
+
+func (c *IngestionController) IngestBitbucketWebhook(ctx context.Context, req *BitbucketWebhookRequest) (*responses.NoContent, error) {
+	if req.Signature != "" {
+		if err := c.bitbucket.Verify(req.RawBody, req.Signature); err != nil {
+			if errors.Is(err, ingestion.ErrInvalidSignature) {
+				return nil, c.verificationFailed(ctx, ingestion.IntegrationBitbucket, "invalid_signature", "Webhook signature does not match.")
+			}
+
+			return nil, err
+		}
+	}
+
+	e := ingestion.BitbucketEvent{
+		Payload: req.Body,
+		DeliveryID: req.BitbucketDelivery,
+		Event: req.BitbucketEvent,
+	}
+
+	if err := c.queue.EnqueueBitbucket(e); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusAccepted,
+	}, nil
+}
+
+func (c *IngestionController) IngestJiraWebhook(ctx context.Context, req *JiraWebhookRequest) (*responses.NoContent, error) {
+	if req.Signature != "" {
+		if err := c.jira.Verify(req.RawBody, req.Signature); err != nil {
+			if errors.Is(err, ingestion.ErrInvalidSignature) {
+				return nil, c.verificationFailed(ctx, ingestion.IntegrationJira, "invalid_signature", "Webhook signature does not match.")
+			}
+
+			return nil, err
+		}
+	}
+
+	e := ingestion.JiraEvent{
+		Payload: req.Body,
+		DeliveryID: req.JiraDelivery,
+	}
+
+	if err := c.queue.EnqueueJira(e); err != nil {
 		return nil, err
 	}
 
 	return &responses.NoContent{
-		Status: http.StatusNoContent,
+		Status: http.StatusAccepted,
 	}, nil
 }
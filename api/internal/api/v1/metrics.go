@@ -0,0 +1,185 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/dora"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const defaultDoraWindow = 30 * 24 * time.Hour
+
+type DoraService interface {
+	Report(q dora.Query) (*dora.Report, error)
+}
+
+type MetricsController struct {
+	svc    DoraService
+	getNow func() time.Time
+}
+
+func (c *MetricsController) RegisterRoutes(api huma.API) {
+	security := []map[string][]string{
+		{"scopes": {"metrics.dora.get"}},
+	}
+
+	huma.Register[DoraRequest, DoraReportResponse](api, huma.Operation{
+		OperationID:   "v1.metrics.dora.get",
+		Method:        http.MethodGet,
+		Path:          "/metrics/dora",
+		Summary:       "Get all four DORA metrics",
+		DefaultStatus: http.StatusOK,
+		Security:      security,
+	}, ErrorHandler(true, c.Report))
+
+	huma.Register[DoraRequest, DoraDeploymentFrequencyResponse](api, huma.Operation{
+		OperationID:   "v1.metrics.dora.deployment_frequency.get",
+		Method:        http.MethodGet,
+		Path:          "/metrics/dora/deployment-frequency",
+		Summary:       "Get the deployment frequency",
+		DefaultStatus: http.StatusOK,
+		Security:      security,
+	}, ErrorHandler(true, c.DeploymentFrequency))
+
+	huma.Register[DoraRequest, DoraLeadTimeResponse](api, huma.Operation{
+		OperationID:   "v1.metrics.dora.lead_time.get",
+		Method:        http.MethodGet,
+		Path:          "/metrics/dora/lead-time",
+		Summary:       "Get the lead time for changes",
+		DefaultStatus: http.StatusOK,
+		Security:      security,
+	}, ErrorHandler(true, c.LeadTime))
+
+	huma.Register[DoraRequest, DoraChangeFailureRateResponse](api, huma.Operation{
+		OperationID:   "v1.metrics.dora.change_failure_rate.get",
+		Method:        http.MethodGet,
+		Path:          "/metrics/dora/change-failure-rate",
+		Summary:       "Get the change failure rate",
+		DefaultStatus: http.StatusOK,
+		Security:      security,
+	}, ErrorHandler(true, c.ChangeFailureRate))
+
+	huma.Register[DoraRequest, DoraTimeToRestoreResponse](api, huma.Operation{
+		OperationID:   "v1.metrics.dora.time_to_restore.get",
+		Method:        http.MethodGet,
+		Path:          "/metrics/dora/time-to-restore",
+		Summary:       "Get the time to restore service",
+		DefaultStatus: http.StatusOK,
+		Security:      security,
+	}, ErrorHandler(true, c.TimeToRestore))
+}
+
+func NewMetricsController(svc DoraService) *MetricsController {
+	return &MetricsController{
+		svc:    svc,
+		getNow: dt.NowUTC,
+	}
+}
+
+type DoraRequest struct {
+	From       time.Time `query:"from" doc:"Start of the window (inclusive), defaults to 30 days before to."`
+	To         time.Time `query:"to" doc:"End of the window (exclusive), defaults to now."`
+	Repository []string  `query:"repository" doc:"Limit to these repositories e.g. org/repo."`
+	Team       string    `query:"team" doc:"Limit to the repositories of a team from the config."`
+}
+
+type DoraReportResponse struct {
+	Body *dora.Report
+}
+
+type DoraDeploymentFrequencyResponse struct {
+	Body dora.DeploymentFrequency
+}
+
+type DoraLeadTimeResponse struct {
+	Body dora.LeadTime
+}
+
+type DoraChangeFailureRateResponse struct {
+	Body dora.ChangeFailureRate
+}
+
+type DoraTimeToRestoreResponse struct {
+	Body dora.TimeToRestore
+}
+
+func (c *MetricsController) report(req *DoraRequest) (*dora.Report, error) {
+	to := req.To
+
+	if to.IsZero() {
+		to = c.getNow()
+	}
+
+	from := req.From
+
+	if from.IsZero() {
+		from = to.Add(-defaultDoraWindow)
+	}
+
+	r, err := c.svc.Report(dora.Query{
+		From:         from,
+		To:           to,
+		Repositories: req.Repository,
+		Team:         req.Team,
+	})
+
+	if errors.Is(err, dora.ErrInvalidWindow) || errors.Is(err, dora.ErrUnknownTeam) {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	return r, err
+}
+
+func (c *MetricsController) Report(ctx context.Context, req *DoraRequest) (*DoraReportResponse, error) {
+	r, err := c.report(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoraReportResponse{Body: r}, nil
+}
+
+func (c *MetricsController) DeploymentFrequency(ctx context.Context, req *DoraRequest) (*DoraDeploymentFrequencyResponse, error) {
+	r, err := c.report(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoraDeploymentFrequencyResponse{Body: r.DeploymentFrequency}, nil
+}
+
+func (c *MetricsController) LeadTime(ctx context.Context, req *DoraRequest) (*DoraLeadTimeResponse, error) {
+	r, err := c.report(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoraLeadTimeResponse{Body: r.LeadTime}, nil
+}
+
+func (c *MetricsController) ChangeFailureRate(ctx context.Context, req *DoraRequest) (*DoraChangeFailureRateResponse, error) {
+	r, err := c.report(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoraChangeFailureRateResponse{Body: r.ChangeFailureRate}, nil
+}
+
+func (c *MetricsController) TimeToRestore(ctx context.Context, req *DoraRequest) (*DoraTimeToRestoreResponse, error) {
+	r, err := c.report(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoraTimeToRestoreResponse{Body: r.TimeToRestore}, nil
+}
@@ -0,0 +1,206 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/api/v1/responses"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type RolesService interface {
+	List() ([]*users.Role, error)
+	Create(dto users.CreateRoleDTO) (*users.Role, error)
+	Grant(dto users.RolePermissionsDTO) (*users.Role, error)
+	Revoke(dto users.RolePermissionsDTO) (*users.Role, error)
+}
+
+type RolesController struct {
+	svc RolesService
+}
+
+func (c *RolesController) RegisterRoutes(api huma.API) {
+	huma.Register[struct{}, ListRolesResponse](api, huma.Operation{
+		OperationID:  "v1.roles.list",
+		Method:       http.MethodGet,
+		Path:         "/roles",
+		Summary:      "List Roles",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"roles.list"}},
+		},
+	}, ErrorHandler(true, c.List))
+
+	huma.Register[CreateRoleRequest, RoleResponse](api, huma.Operation{
+		OperationID:  "v1.roles.create",
+		Method:       http.MethodPost,
+		Path:         "/roles",
+		Summary:      "Create a Role",
+		DefaultStatus: http.StatusCreated,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"roles.create"}},
+		},
+	}, ErrorHandler(true, c.Create))
+
+	huma.Register[GrantRolePermissionsRequest, RoleResponse](api, huma.Operation{
+		OperationID:  "v1.roles.permissions.grant",
+		Method:       http.MethodPost,
+		Path:         "/roles/{name}/permissions",
+		Summary:      "Grant Permissions to a Role",
+		Description:  "Permissions the role already has are ignored.",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"roles.permissions.grant"}},
+		},
+	}, ErrorHandler(true, c.Grant))
+
+	huma.Register[RevokeRolePermissionRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.roles.permissions.revoke",
+		Method:       http.MethodDelete,
+		Path:         "/roles/{name}/permissions/{permission}",
+		Summary:      "Revoke a Permission from a Role",
+		DefaultStatus: http.StatusNoContent,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"roles.permissions.revoke"}},
+		},
+	}, ErrorHandler(true, c.Revoke))
+}
+
+func NewRolesController(svc RolesService) *RolesController {
+	return &RolesController{
+		svc: svc,
+	}
+}
+
+type RoleBody struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Permissions []string  `json:"permissions"`
+}
+
+func newRoleBody(r *users.Role) *RoleBody {
+	return &RoleBody{
+		ID: r.ID,
+		Name: r.Name,
+		Permissions: util.Map[*users.Permission, string](func (p *users.Permission) string {
+			return p.Name
+		}, r.Permissions),
+	}
+}
+
+type RoleResponse struct {
+	Body *RoleBody
+}
+
+type ListRolesResponse struct {
+	Body struct {
+		Items []*RoleBody `json:"items"`
+	}
+}
+
+func (c *RolesController) List(ctx context.Context, req *struct{}) (*ListRolesResponse, error) {
+	roles, err := c.svc.List()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListRolesResponse{}
+	resp.Body.Items = util.Map[*users.Role, *RoleBody](newRoleBody, roles)
+
+	return resp, nil
+}
+
+type CreateRoleRequest struct {
+	Body struct {
+		Name        string   `json:"name"`
+		Permissions []string `json:"permissions,omitempty"`
+	}
+}
+
+func (c *RolesController) Create(ctx context.Context, req *CreateRoleRequest) (*RoleResponse, error) {
+	r, err := c.svc.Create(users.CreateRoleDTO{
+		Name: req.Body.Name,
+		Permissions: req.Body.Permissions,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &RoleResponse{
+		Body: newRoleBody(r),
+	}, nil
+}
+
+// roleNotFound reports a missing role as a 404 rather than a 422, as it's in
+// the path rather than the body.
+func roleNotFound(err error) error {
+	if errors.Is(err, users.ErrRoleNotFound) {
+		return huma.Error404NotFound(err.Error())
+	}
+
+	return err
+}
+
+type GrantRolePermissionsRequest struct {
+	Name string `path:"name" required:"true"`
+
+	Body struct {
+		Permissions []string `json:"permissions" minItems:"1"`
+	}
+}
+
+func (c *RolesController) Grant(ctx context.Context, req *GrantRolePermissionsRequest) (*RoleResponse, error) {
+	r, err := c.svc.Grant(users.RolePermissionsDTO{
+		Role: req.Name,
+		Permissions: req.Body.Permissions,
+	})
+
+	if err != nil {
+		return nil, roleNotFound(err)
+	}
+
+	return &RoleResponse{
+		Body: newRoleBody(r),
+	}, nil
+}
+
+type RevokeRolePermissionRequest struct {
+	Name       string `path:"name" required:"true"`
+	Permission string `path:"permission" required:"true"`
+}
+
+func (c *RolesController) Revoke(ctx context.Context, req *RevokeRolePermissionRequest) (*responses.NoContent, error) {
+	_, err := c.svc.Revoke(users.RolePermissionsDTO{
+		Role: req.Name,
+		Permissions: []string{req.Permission},
+	})
+
+	if err != nil {
+		return nil, roleNotFound(err)
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusNoContent,
+	}, nil
+}
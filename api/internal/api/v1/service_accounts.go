@@ -0,0 +1,326 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/api/v1/responses"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type ServiceAccountsService interface {
+	Get(dto users.GetDTO) (*users.User, error)
+	List(dto users.ListDTO) (*users.UsersPage, error)
+	Create(ctx context.Context, dto users.CreateServiceAccountDTO) (*users.User, error)
+	Update(ctx context.Context, dto users.UpdateServiceAccountDTO) (*users.User, error)
+	Deactivate(ctx context.Context, id uuid.UUID) error
+	AssignRoles(ctx context.Context, dto users.AssignRolesDTO) (*users.User, error)
+}
+
+type ServiceAccountsController struct {
+	svc ServiceAccountsService
+}
+
+func (c *ServiceAccountsController) RegisterRoutes(api huma.API) {
+	huma.Register[ListServiceAccountsRequest, ListServiceAccountsResponse](api, huma.Operation{
+		OperationID:  "v1.service_accounts.list",
+		Method:       http.MethodGet,
+		Path:         "/service-accounts",
+		Summary:      "List Service Accounts",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"service_accounts.list"}},
+		},
+	}, ErrorHandler(true, c.List))
+
+	huma.Register[CreateServiceAccountRequest, ServiceAccountResponse](api, huma.Operation{
+		OperationID:  "v1.service_accounts.create",
+		Method:       http.MethodPost,
+		Path:         "/service-accounts",
+		Summary:      "Create a Service Account",
+		Description:  "Service accounts are for integrations rather than people, they have no email or password so can only authenticate with access tokens. Create tokens for them with the user_id of the service account.",
+		DefaultStatus: http.StatusCreated,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"service_accounts.create"}},
+		},
+	}, ErrorHandler(true, c.Create))
+
+	huma.Register[GetServiceAccountRequest, ServiceAccountResponse](api, huma.Operation{
+		OperationID:  "v1.service_accounts.get",
+		Method:       http.MethodGet,
+		Path:         "/service-accounts/{id}",
+		Summary:      "Get a Service Account By ID",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"service_accounts.get"}},
+		},
+	}, ErrorHandler(true, c.Get))
+
+	huma.Register[UpdateServiceAccountRequest, ServiceAccountResponse](api, huma.Operation{
+		OperationID:  "v1.service_accounts.update",
+		Method:       http.MethodPatch,
+		Path:         "/service-accounts/{id}",
+		Summary:      "Update a Service Account",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"service_accounts.update"}},
+		},
+	}, ErrorHandler(true, c.Update))
+
+	huma.Register[DeleteServiceAccountRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.service_accounts.delete",
+		Method:       http.MethodDelete,
+		Path:         "/service-accounts/{id}",
+		Summary:      "Deactivate a Service Account",
+		Description:  "Service accounts are never deleted, as other records refer to them. Their tokens stop working until they're reactivated by updating them.",
+		DefaultStatus: http.StatusNoContent,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"service_accounts.delete"}},
+		},
+	}, ErrorHandler(true, c.Delete))
+
+	huma.Register[AssignServiceAccountRolesRequest, ServiceAccountResponse](api, huma.Operation{
+		OperationID:  "v1.service_accounts.roles.assign",
+		Method:       http.MethodPut,
+		Path:         "/service-accounts/{id}/roles",
+		Summary:      "Replace a Service Account's Roles",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"service_accounts.roles.assign"}},
+		},
+	}, ErrorHandler(true, c.AssignRoles))
+}
+
+func NewServiceAccountsController(svc ServiceAccountsService) *ServiceAccountsController {
+	return &ServiceAccountsController{
+		svc: svc,
+	}
+}
+
+type ServiceAccountBody struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Active        bool       `json:"active"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Roles         []string   `json:"roles"`
+}
+
+func newServiceAccountBody(u *users.User) *ServiceAccountBody {
+	return &ServiceAccountBody{
+		ID: u.ID,
+		Name: u.Name,
+		Active: u.IsActive(),
+		DeactivatedAt: u.DeactivatedAt,
+		CreatedAt: u.CreatedAt,
+		Roles: util.Map[*users.Role, string](func (r *users.Role) string {
+			return r.Name
+		}, u.Roles),
+	}
+}
+
+type ServiceAccountResponse struct {
+	Body *ServiceAccountBody
+}
+
+// parseServiceAccountID treats an invalid id the same as one that doesn't
+// exist.
+func parseServiceAccountID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+
+	if err != nil {
+		return uuid.Nil, users.ErrServiceAccountNotFound
+	}
+
+	return parsed, nil
+}
+
+type GetServiceAccountRequest struct {
+	ID string `path:"id" required:"true"`
+}
+
+func (c *ServiceAccountsController) Get(ctx context.Context, req *GetServiceAccountRequest) (*ServiceAccountResponse, error) {
+	id, err := parseServiceAccountID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.svc.Get(users.GetDTO{
+		ID: id,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountResponse{
+		Body: newServiceAccountBody(u),
+	}, nil
+}
+
+type ListServiceAccountsRequest struct {
+	Page    int `query:"page" default:"1" minimum:"1"`
+	PerPage int `query:"per_page" default:"25" minimum:"1" maximum:"100"`
+}
+
+type ListServiceAccountsResponse struct {
+	Body struct {
+		Items   []*ServiceAccountBody `json:"items"`
+		Page    int                   `json:"page"`
+		PerPage int                   `json:"per_page"`
+		Total   int                   `json:"total"`
+	}
+}
+
+func (c *ServiceAccountsController) List(ctx context.Context, req *ListServiceAccountsRequest) (*ListServiceAccountsResponse, error) {
+	page, err := c.svc.List(users.ListDTO{
+		Page: req.Page,
+		PerPage: req.PerPage,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListServiceAccountsResponse{}
+	resp.Body.Items = util.Map[*users.User, *ServiceAccountBody](newServiceAccountBody, page.Users)
+	resp.Body.Page = req.Page
+	resp.Body.PerPage = req.PerPage
+	resp.Body.Total = page.Total
+
+	return resp, nil
+}
+
+type CreateServiceAccountRequest struct {
+	Body struct {
+		Name  string   `json:"name"`
+		Roles []string `json:"roles,omitempty"`
+	}
+}
+
+func (c *ServiceAccountsController) Create(ctx context.Context, req *CreateServiceAccountRequest) (*ServiceAccountResponse, error) {
+	roles := req.Body.Roles
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	u, err := c.svc.Create(ctx, users.CreateServiceAccountDTO{
+		Name: req.Body.Name,
+		Roles: roles,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountResponse{
+		Body: newServiceAccountBody(u),
+	}, nil
+}
+
+type UpdateServiceAccountRequest struct {
+	ID string `path:"id" required:"true"`
+
+	Body struct {
+		Name   *string `json:"name,omitempty"`
+		Active *bool   `json:"active,omitempty" doc:"Set to false to deactivate the service account, or true to reactivate it."`
+	}
+}
+
+func (c *ServiceAccountsController) Update(ctx context.Context, req *UpdateServiceAccountRequest) (*ServiceAccountResponse, error) {
+	id, err := parseServiceAccountID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.svc.Update(ctx, users.UpdateServiceAccountDTO{
+		ID: id,
+		Name: req.Body.Name,
+		Active: req.Body.Active,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountResponse{
+		Body: newServiceAccountBody(u),
+	}, nil
+}
+
+type DeleteServiceAccountRequest struct {
+	ID string `path:"id" required:"true"`
+}
+
+func (c *ServiceAccountsController) Delete(ctx context.Context, req *DeleteServiceAccountRequest) (*responses.NoContent, error) {
+	id, err := parseServiceAccountID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.svc.Deactivate(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusNoContent,
+	}, nil
+}
+
+type AssignServiceAccountRolesRequest struct {
+	ID string `path:"id" required:"true"`
+
+	Body struct {
+		Roles []string `json:"roles"`
+	}
+}
+
+func (c *ServiceAccountsController) AssignRoles(ctx context.Context, req *AssignServiceAccountRolesRequest) (*ServiceAccountResponse, error) {
+	id, err := parseServiceAccountID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.svc.AssignRoles(ctx, users.AssignRolesDTO{
+		ID: id,
+		Roles: req.Body.Roles,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountResponse{
+		Body: newServiceAccountBody(u),
+	}, nil
+}
@@ -0,0 +1,203 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/api/v1/responses"
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type TokensService interface {
+	CreateAs(ctx context.Context, p *users.Principal, def users.TokenDefinition) (*users.AccessToken, error)
+	ListAs(ctx context.Context, p *users.Principal, userID uuid.UUID) ([]*users.AccessToken, error)
+	RevokeAs(ctx context.Context, p *users.Principal, id string) error
+}
+
+type TokensController struct {
+	svc TokensService
+}
+
+func (c *TokensController) RegisterRoutes(api huma.API) {
+	huma.Register[CreateTokenRequest, CreatedTokenResponse](api, huma.Operation{
+		OperationID:  "v1.tokens.create",
+		Method:       http.MethodPost,
+		Path:         "/tokens",
+		Summary:      "Create an Access Token",
+		Description:  "Creates a token for the authenticated user, or for a service account with the tokens.manage permission. The secret is only ever returned here.",
+		DefaultStatus: http.StatusCreated,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"tokens.create"}},
+		},
+	}, ErrorHandler(true, c.Create))
+
+	huma.Register[ListTokensRequest, ListTokensResponse](api, huma.Operation{
+		OperationID:  "v1.tokens.list",
+		Method:       http.MethodGet,
+		Path:         "/tokens",
+		Summary:      "List Access Tokens",
+		Description:  "Lists the tokens of the authenticated user, or of a service account with the tokens.manage permission. Expired and revoked tokens are included.",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"tokens.list"}},
+		},
+	}, ErrorHandler(true, c.List))
+
+	huma.Register[RevokeTokenRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.tokens.revoke",
+		Method:       http.MethodDelete,
+		Path:         "/tokens/{id}",
+		Summary:      "Revoke an Access Token",
+		Description:  "Tokens are never deleted, revoked tokens can no longer authenticate.",
+		DefaultStatus: http.StatusNoContent,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"tokens.revoke"}},
+		},
+	}, ErrorHandler(true, c.Revoke))
+}
+
+func NewTokensController(svc TokensService) *TokensController {
+	return &TokensController{
+		svc: svc,
+	}
+}
+
+// TokenBody is how a token is represented in responses, without the secret or
+// its hash.
+type TokenBody struct {
+	ID         string     `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ExpireAt   *time.Time `json:"expire_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
+
+func newTokenBody(t *users.AccessToken) *TokenBody {
+	return &TokenBody{
+		ID: t.ID,
+		UserID: t.User.ID,
+		ExpireAt: t.ExpireAt,
+		RevokedAt: t.RevokedAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// targetUserID is the user the request is for, the principal themselves unless
+// another user is given.
+func targetUserID(p *users.Principal, given string) (uuid.UUID, error) {
+	if given != "" {
+		return parseUserID(given)
+	}
+
+	if p == nil || p.User == nil {
+		return uuid.Nil, huma.Error422UnprocessableEntity("user_id is required when not authenticated as a user")
+	}
+
+	return p.User.ID, nil
+}
+
+type CreateTokenRequest struct {
+	Body struct {
+		UserID     string `json:"user_id,omitempty" doc:"Defaults to the authenticated user."`
+		ExpiryDays int    `json:"expiry_days" doc:"Days until the token expires, -1 for never."`
+	}
+}
+
+type CreatedTokenBody struct {
+	TokenBody
+
+	Secret string `json:"secret" doc:"Only returned when the token is created, it can't be retrieved again."`
+}
+
+type CreatedTokenResponse struct {
+	Body *CreatedTokenBody
+}
+
+func (c *TokensController) Create(ctx context.Context, req *CreateTokenRequest) (*CreatedTokenResponse, error) {
+	p := users.PrincipalFrom(ctx)
+
+	userID, err := targetUserID(p, req.Body.UserID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := c.svc.CreateAs(ctx, p, users.TokenDefinition{
+		ExpiryDays: req.Body.ExpiryDays,
+		UserID: userID,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedTokenResponse{
+		Body: &CreatedTokenBody{
+			TokenBody: *newTokenBody(t),
+			Secret: *t.Secret,
+		},
+	}, nil
+}
+
+type ListTokensRequest struct {
+	UserID string `query:"user_id" doc:"Defaults to the authenticated user."`
+}
+
+type ListTokensResponse struct {
+	Body struct {
+		Items []*TokenBody `json:"items"`
+	}
+}
+
+func (c *TokensController) List(ctx context.Context, req *ListTokensRequest) (*ListTokensResponse, error) {
+	p := users.PrincipalFrom(ctx)
+
+	userID, err := targetUserID(p, req.UserID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := c.svc.ListAs(ctx, p, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListTokensResponse{}
+	resp.Body.Items = util.Map[*users.AccessToken, *TokenBody](newTokenBody, tokens)
+
+	return resp, nil
+}
+
+type RevokeTokenRequest struct {
+	ID string `path:"id" required:"true"`
+}
+
+func (c *TokensController) Revoke(ctx context.Context, req *RevokeTokenRequest) (*responses.NoContent, error) {
+	if err := c.svc.RevokeAs(ctx, users.PrincipalFrom(ctx), req.ID); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusNoContent,
+	}, nil
+}
@@ -3,9 +3,13 @@ package v1
 import (
 	"context"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/adamkirk/panoptes/internal/api/operations"
+	"github.com/adamkirk/panoptes/internal/api/v1/responses"
 	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/util"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
@@ -13,6 +17,11 @@ import (
 
 type UsersService interface {
 	Get(dto users.GetDTO) (*users.User, error)
+	List(dto users.ListDTO) (*users.UsersPage, error)
+	Create(ctx context.Context, dto users.CreateDTO) (*users.User, error)
+	Update(ctx context.Context, dto users.UpdateDTO) (*users.User, error)
+	Deactivate(ctx context.Context, id uuid.UUID) error
+	AssignRoles(ctx context.Context, dto users.AssignRolesDTO) (*users.User, error)
 }
 
 type UsersController struct {
@@ -20,19 +29,92 @@ type UsersController struct {
 }
 
 func (c *UsersController) RegisterRoutes(api huma.API) {
-	huma.Register[GetUserRequest, GetUserResponse](api, huma.Operation{
+	huma.Register[ListUsersRequest, ListUsersResponse](api, huma.Operation{
+		OperationID:  "v1.users.list",
+		Method:       http.MethodGet,
+		Path:         "/users",
+		Summary:      "List Users",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"users.list"}},
+		},
+	}, ErrorHandler(true, c.List))
+
+	huma.Register[CreateUserRequest, UserResponse](api, huma.Operation{
+		OperationID:  "v1.users.create",
+		Method:       http.MethodPost,
+		Path:         "/users",
+		Summary:      "Create a User",
+		DefaultStatus: http.StatusCreated,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+			operations.OptDisableNotFound: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"users.create"}},
+		},
+	}, ErrorHandler(true, c.Create))
+
+	huma.Register[GetUserRequest, UserResponse](api, huma.Operation{
 		OperationID:  "v1.users.get",
 		Method:       http.MethodGet,
 		Path:         "/users/{id}",
 		Summary:      "Get a User By ID",
 		DefaultStatus: http.StatusOK,
 		Metadata: map[string]any{
-			operations.OptDisableNotFound: true,
+			operations.OptAudit: true,
 		},
 		Security: []map[string][]string{
 			{"scopes": {"users.get"}},
 		},
 	}, ErrorHandler(true, c.Get))
+
+	huma.Register[UpdateUserRequest, UserResponse](api, huma.Operation{
+		OperationID:  "v1.users.update",
+		Method:       http.MethodPatch,
+		Path:         "/users/{id}",
+		Summary:      "Update a User",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"users.update"}},
+		},
+	}, ErrorHandler(true, c.Update))
+
+	huma.Register[DeleteUserRequest, responses.NoContent](api, huma.Operation{
+		OperationID:  "v1.users.delete",
+		Method:       http.MethodDelete,
+		Path:         "/users/{id}",
+		Summary:      "Deactivate a User",
+		Description:  "Users are never deleted, as other records refer to them. Deactivated users (and their tokens) can no longer authenticate, they can be reactivated by updating them.",
+		DefaultStatus: http.StatusNoContent,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"users.delete"}},
+		},
+	}, ErrorHandler(true, c.Delete))
+
+	huma.Register[AssignUserRolesRequest, UserResponse](api, huma.Operation{
+		OperationID:  "v1.users.roles.assign",
+		Method:       http.MethodPut,
+		Path:         "/users/{id}/roles",
+		Summary:      "Replace a User's Roles",
+		DefaultStatus: http.StatusOK,
+		Metadata: map[string]any{
+			operations.OptAudit: true,
+		},
+		Security: []map[string][]string{
+			{"scopes": {"users.roles.assign"}},
+		},
+	}, ErrorHandler(true, c.AssignRoles))
 }
 
 func NewUsersController(svc UsersService) *UsersController {
@@ -41,30 +123,226 @@ func NewUsersController(svc UsersService) *UsersController {
 	}
 }
 
+// UserBody is how a user is represented in responses, deliberately leaving out
+// the password hash.
+type UserBody struct {
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	Active        bool       `json:"active"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Roles         []string   `json:"roles"`
+}
+
+func newUserBody(u *users.User) *UserBody {
+	return &UserBody{
+		ID: u.ID,
+		Email: u.Email,
+		FirstName: u.FirstName,
+		LastName: u.LastName,
+		Active: u.IsActive(),
+		DeactivatedAt: u.DeactivatedAt,
+		CreatedAt: u.CreatedAt,
+		Roles: util.Map[*users.Role, string](func (r *users.Role) string {
+			return r.Name
+		}, u.Roles),
+	}
+}
+
+type UserResponse struct {
+	Body *UserBody
+}
+
+// parseUserID treats an invalid id the same as one that doesn't exist.
+func parseUserID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+
+	if err != nil {
+		return uuid.Nil, users.ErrUserNotFound
+	}
+
+	return parsed, nil
+}
+
 type GetUserRequest struct {
 	ID string `path:"id" required:"true"`
 }
 
-type GetUserResponse struct {
-	Body *users.User
+func (c *UsersController) Get(ctx context.Context, req *GetUserRequest) (*UserResponse, error) {
+	id, err := parseUserID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.svc.Get(users.GetDTO{
+		ID: id,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserResponse{
+		Body: newUserBody(u),
+	}, nil
+}
+
+type ListUsersRequest struct {
+	Page    int `query:"page" default:"1" minimum:"1"`
+	PerPage int `query:"per_page" default:"25" minimum:"1" maximum:"100"`
 }
 
-func (c *UsersController) Get(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
+type ListUsersResponse struct {
+	Body struct {
+		Items   []*UserBody `json:"items"`
+		Page    int         `json:"page"`
+		PerPage int         `json:"per_page"`
+		Total   int         `json:"total"`
+	}
+}
 
-	id, err := uuid.Parse(req.ID)
+func (c *UsersController) List(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	page, err := c.svc.List(users.ListDTO{
+		Page: req.Page,
+		PerPage: req.PerPage,
+	})
 
 	if err != nil {
-		return nil, huma.Error404NotFound(err.Error())
+		return nil, err
 	}
 
-	u, err := c.svc.Get(users.GetDTO{
+	resp := &ListUsersResponse{}
+	resp.Body.Items = util.Map[*users.User, *UserBody](newUserBody, page.Users)
+	resp.Body.Page = req.Page
+	resp.Body.PerPage = req.PerPage
+	resp.Body.Total = page.Total
+
+	return resp, nil
+}
+
+type CreateUserRequest struct {
+	Body struct {
+		Email     string   `json:"email"`
+		FirstName string   `json:"first_name"`
+		LastName  string   `json:"last_name"`
+		Password  string   `json:"password"`
+		Roles     []string `json:"roles,omitempty"`
+	}
+}
+
+func (c *UsersController) Create(ctx context.Context, req *CreateUserRequest) (*UserResponse, error) {
+	if slices.Contains(req.Body.Roles, users.RoleSuperuser) {
+		return nil, users.ErrSuperuserRole
+	}
+
+	roles := req.Body.Roles
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	u, err := c.svc.Create(ctx, users.CreateDTO{
+		Email: req.Body.Email,
+		FirstName: req.Body.FirstName,
+		LastName: req.Body.LastName,
+		Password: req.Body.Password,
+		Roles: roles,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserResponse{
+		Body: newUserBody(u),
+	}, nil
+}
+
+type UpdateUserRequest struct {
+	ID string `path:"id" required:"true"`
+
+	Body struct {
+		Email     *string `json:"email,omitempty"`
+		FirstName *string `json:"first_name,omitempty"`
+		LastName  *string `json:"last_name,omitempty"`
+		Password  *string `json:"password,omitempty"`
+		Active    *bool   `json:"active,omitempty" doc:"Set to false to deactivate the user, or true to reactivate them."`
+	}
+}
+
+func (c *UsersController) Update(ctx context.Context, req *UpdateUserRequest) (*UserResponse, error) {
+	id, err := parseUserID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.svc.Update(ctx, users.UpdateDTO{
+		ID: id,
+		Email: req.Body.Email,
+		FirstName: req.Body.FirstName,
+		LastName: req.Body.LastName,
+		Password: req.Body.Password,
+		Active: req.Body.Active,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserResponse{
+		Body: newUserBody(u),
+	}, nil
+}
+
+type DeleteUserRequest struct {
+	ID string `path:"id" required:"true"`
+}
+
+func (c *UsersController) Delete(ctx context.Context, req *DeleteUserRequest) (*responses.NoContent, error) {
+	id, err := parseUserID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.svc.Deactivate(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return &responses.NoContent{
+		Status: http.StatusNoContent,
+	}, nil
+}
+
+type AssignUserRolesRequest struct {
+	ID string `path:"id" required:"true"`
+
+	Body struct {
+		Roles []string `json:"roles"`
+	}
+}
+
+func (c *UsersController) AssignRoles(ctx context.Context, req *AssignUserRolesRequest) (*UserResponse, error) {
+	id, err := parseUserID(req.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.svc.AssignRoles(ctx, users.AssignRolesDTO{
 		ID: id,
+		Roles: req.Body.Roles,
 	})
 
 	if err != nil {
 		return nil, err
 	}
-	return &GetUserResponse{
-		Body: u,
+
+	return &UserResponse{
+		Body: newUserBody(u),
 	}, nil
 }
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/google/uuid"
+)
+
+// usersService only creates users, recording what it was asked to create.
+type usersService struct {
+	created []users.CreateDTO
+}
+
+func (s *usersService) Get(dto users.GetDTO) (*users.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *usersService) List(dto users.ListDTO) (*users.UsersPage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *usersService) Create(ctx context.Context, dto users.CreateDTO) (*users.User, error) {
+	s.created = append(s.created, dto)
+
+	return &users.User{ID: uuid.New(), Email: dto.Email}, nil
+}
+
+func (s *usersService) Update(ctx context.Context, dto users.UpdateDTO) (*users.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *usersService) Deactivate(ctx context.Context, id uuid.UUID) error {
+	return errors.New("not implemented")
+}
+
+func (s *usersService) AssignRoles(ctx context.Context, dto users.AssignRolesDTO) (*users.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestCreateUserRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{
+			name: "no roles",
+			want: http.StatusCreated,
+		},
+		{
+			name: "roles",
+			roles: []string{"viewer"},
+			want: http.StatusCreated,
+		},
+		{
+			name: "superuser",
+			roles: []string{users.RoleSuperuser},
+			want: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "superuser amongst other roles",
+			roles: []string{"viewer", users.RoleSuperuser},
+			want: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, api := humatest.New(t, huma.DefaultConfig("Panoptes", "v1"))
+			svc := &usersService{}
+			NewUsersController(svc).RegisterRoutes(api)
+
+			res := api.Post("/users", map[string]any{
+				"email": "a@example.com",
+				"first_name": "A",
+				"last_name": "User",
+				"password": "password",
+				"roles": tt.roles,
+			})
+
+			if res.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, res.Code)
+			}
+
+			if created := len(svc.created) > 0; created != (tt.want == http.StatusCreated) {
+				t.Errorf("expected the user created to be %t, got %t", !created, created)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@
 // unmarshalling the yaml to map[string]interface. access_log is one example
 package config
 
+import "time"
+
 type EventStoreDbDriver string
 type ProjectionDbDriver string
 
@@ -61,10 +63,43 @@ type ConfigApiServerAccessLog struct {
 	Format  string
 }
 
+type ConfigApiServerRateLimitRule struct {
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	Burst             int
+}
+
+type ConfigApiServerLockout struct {
+	// MaxFailures is how many failed authentication attempts an IP can make
+	// within the window before it's locked out, 0 disables the lockout.
+	MaxFailures int `mapstructure:"max_failures"`
+
+	// Window is the number of seconds failures are counted over.
+	Window int
+
+	// Duration is the number of seconds an IP is locked out for.
+	Duration int
+}
+
+// ConfigApiServerRateLimit limits requests that need authenticating, before
+// any credentials are checked. Limits are kept in memory, so are per API
+// server rather than shared.
+type ConfigApiServerRateLimit struct {
+	Enabled bool
+	PerIP   ConfigApiServerRateLimitRule `mapstructure:"per_ip"`
+	PerKey  ConfigApiServerRateLimitRule `mapstructure:"per_key"`
+	Lockout ConfigApiServerLockout
+}
+
 type ConfigApiServer struct {
 	DebugErrorsEnabled bool `yaml:"debug_errors_enabled" mapstructure:"debug_errors_enabled"`
 	Port               int
 	AccessLog          ConfigApiServerAccessLog `yaml:"access_log" mapstructure:"access_log"`
+	RateLimit          ConfigApiServerRateLimit `mapstructure:"rate_limit"`
+
+	// TokenCacheTTL is how many seconds a successfully verified access token
+	// secret is remembered for, so that it isn't hashed on every request. 0
+	// disables the cache.
+	TokenCacheTTL int `mapstructure:"token_cache_ttl"`
 }
 
 type ConfigApi struct {
@@ -114,9 +149,38 @@ type ConfigDbEventStore struct {
 	Postgres ConfigDbPostgres
 }
 
+type ConfigDbOpensearch struct {
+	Addresses          []string
+	Username           string
+	Password           string
+	IndexPrefix        string `mapstructure:"index_prefix"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+func (cfg ConfigDbOpensearch) OpensearchAddresses() []string {
+	return cfg.Addresses
+}
+
+func (cfg ConfigDbOpensearch) OpensearchUsername() string {
+	return cfg.Username
+}
+
+func (cfg ConfigDbOpensearch) OpensearchPassword() string {
+	return cfg.Password
+}
+
+func (cfg ConfigDbOpensearch) OpensearchIndexPrefix() string {
+	return cfg.IndexPrefix
+}
+
+func (cfg ConfigDbOpensearch) OpensearchInsecureSkipVerify() bool {
+	return cfg.InsecureSkipVerify
+}
+
 type ConfigDbProjection struct {
 	Driver  ProjectionDbDriver
 	Postgres ConfigDbPostgres
+	Opensearch ConfigDbOpensearch
 }
 
 type ConfigDb struct {
@@ -125,16 +189,185 @@ type ConfigDb struct {
 }
 
 type ConfigAuth struct {
+	// MasterToken is a bootstrap credential with full privileges, sent as
+	// 'Authorization: Bearer <token>'. It's intended for automating first time
+	// setup, so should be disabled once real users and tokens exist.
 	MasterToken string `mapstructure:"master_token"`
+	MasterTokenEnabled bool `mapstructure:"master_token_enabled"`
 	Bcrypt ConfigAuthBcrypt
+	Sessions ConfigAuthSessions
+	Oidc ConfigAuthOidc
+}
+
+type ConfigAuthOidc struct {
+	Enabled bool
+	IssuerURL string `mapstructure:"issuer_url"`
+	ClientID string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+
+	// RedirectURL must point at the callback operation, and be registered with
+	// the provider.
+	RedirectURL string `mapstructure:"redirect_url"`
+	Scopes []string
+
+	// GroupsClaim is the id token claim holding the user's groups.
+	GroupsClaim string `mapstructure:"groups_claim"`
+
+	// DefaultRoles are given to everyone that logs in with the provider. If
+	// there are any, or any RoleMappings, the roles of users that log in with
+	// the provider are managed there, and can't be assigned through the API.
+	DefaultRoles []string `mapstructure:"default_roles"`
+	RoleMappings []ConfigAuthOidcRoleMapping `mapstructure:"role_mappings"`
+
+	// AutoLinkAccounts links someone logging in for the first time to the
+	// existing user with the same email, if the provider has verified it.
+	// Superusers are never linked.
+	AutoLinkAccounts bool `mapstructure:"auto_link_accounts"`
+}
+
+// ConfigAuthOidcRoleMapping gives members of the group the roles. It's a list
+// rather than a map as viper lowercases map keys, and group names often
+// aren't.
+type ConfigAuthOidcRoleMapping struct {
+	Group string
+	Roles []string
+}
+
+type ConfigAuthSessions struct {
+	// SigningKey signs the session tokens issued when logging in, logging in
+	// is disabled without one.
+	SigningKey string `mapstructure:"signing_key"`
+
+	// TTL is how many minutes a session token is valid for, before it needs
+	// refreshing.
+	TTL int `mapstructure:"ttl"`
+
+	// RefreshTTL is how many days a session can be refreshed for, before
+	// logging in again.
+	RefreshTTL int `mapstructure:"refresh_ttl"`
 }
 
 type ConfigAuthBcrypt struct {
 	Cost int
 }
 
+type ConfigIngestionIntegration struct {
+	// WebhookSecrets are the shared secrets used to sign webhook payloads.
+	// Payloads signed with any of them are accepted, so when rotating, add the
+	// new secret alongside the old one and remove the old one once the source
+	// has been switched over.
+	WebhookSecrets []string `mapstructure:"webhook_secrets"`
+}
+
+type ConfigIngestionQueue struct {
+	// Workers is how many deliveries are processed at once, by each API
+	// server.
+	Workers int
+
+	// MaxAttempts is how many times a delivery is tried before it's moved to
+	// the dead letters.
+	MaxAttempts int `mapstructure:"max_attempts"`
+
+	// Backoff is the seconds to wait before retrying a failed delivery, it
+	// doubles with each attempt up to MaxBackoff.
+	Backoff    int
+	MaxBackoff int `mapstructure:"max_backoff"`
+
+	// PollInterval is how often (in seconds) idle workers check for
+	// deliveries, e.g. those queued by another API server.
+	PollInterval int `mapstructure:"poll_interval"`
+
+	// Lease is the seconds a worker has to process a delivery, before it's
+	// assumed to have died and the delivery is tried again.
+	Lease int
+}
+
+type ConfigIngestion struct {
+	Github    ConfigIngestionIntegration
+	Gitlab    ConfigIngestionIntegration
+	Bitbucket ConfigIngestionIntegration
+	Jira      ConfigIngestionIntegration
+	Queue     ConfigIngestionQueue
+}
+
+type ConfigBackfillGithub struct {
+	// APIURL is only different for github enterprise server, e.g.
+	// https://github.example.com/api/v3
+	APIURL string `mapstructure:"api_url"`
+
+	// Token needs read access to the repositories' contents, pull requests
+	// and deployments.
+	Token string
+}
+
+type ConfigBackfillJira struct {
+	// URL of the site e.g. https://acme.atlassian.net
+	URL string
+
+	// Email of the account the token belongs to, for jira cloud. Leave it
+	// empty for jira server and data center, where the token is a personal
+	// access token.
+	Email string
+	Token string
+}
+
+type ConfigBackfill struct {
+	Github ConfigBackfillGithub
+	Jira   ConfigBackfillJira
+}
+
+type ConfigCorrelation struct {
+	// ProjectKeyPatterns are regular expressions for the project part of a task
+	// key, e.g. 'ABC' would find ABC-123. Narrowing this down to your actual
+	// project keys avoids false positives such as UTF-8.
+	//
+	// Keys are found in change requests' titles, branches, descriptions and
+	// commit messages. Github webhooks don't include commit messages, so those
+	// are only searched for github when backfilling.
+	ProjectKeyPatterns []string `mapstructure:"project_key_patterns"`
+}
+
+type ConfigProjections struct {
+	// BatchSize is how many events are read and applied to a projection at a
+	// time.
+	BatchSize int `mapstructure:"batch_size"`
+
+	// PollInterval is how often (in seconds) the API server checks the event
+	// stream for new events to apply to the projections.
+	PollInterval int `mapstructure:"poll_interval"`
+}
+
+type ConfigDoraTeam struct {
+	Name         string
+	Repositories []string
+}
+
+type ConfigDora struct {
+	// ProductionEnvironments are the github deployment environments that count
+	// as production, deployments to anything else are ignored.
+	ProductionEnvironments []string `mapstructure:"production_environments"`
+
+	// ReleasesAsDeployments counts published releases as deployments, for
+	// repositories that don't use github deployments.
+	ReleasesAsDeployments bool `mapstructure:"releases_as_deployments"`
+
+	// Teams groups repositories (by full name e.g. org/repo) so that metrics
+	// can be reported for a team.
+	Teams []ConfigDoraTeam
+
+	// LookbackDays is how far before a report's window to look for the
+	// deployment before it, changes that landed before then aren't counted
+	// towards lead time.
+	LookbackDays int `mapstructure:"lookback_days"`
+}
+
 type Config struct {
 	Auth ConfigAuth
+	Ingestion      ConfigIngestion
+	Backfill       ConfigBackfill
+	Correlation    ConfigCorrelation
+	Dora           ConfigDora
+	Projections    ConfigProjections
 	Logging        ConfigLogging
 	Api            ConfigApi
 	Db             ConfigDb
@@ -164,6 +397,34 @@ func (c *Config) ApiServerDebugErrorsEnabled() bool {
 	return c.Api.Server.DebugErrorsEnabled
 }
 
+func (c *Config) ApiServerRateLimitEnabled() bool {
+	return c.Api.Server.RateLimit.Enabled
+}
+
+func (c *Config) ApiServerRateLimitPerIP() (int, int) {
+	return c.Api.Server.RateLimit.PerIP.RequestsPerMinute, c.Api.Server.RateLimit.PerIP.Burst
+}
+
+func (c *Config) ApiServerRateLimitPerKey() (int, int) {
+	return c.Api.Server.RateLimit.PerKey.RequestsPerMinute, c.Api.Server.RateLimit.PerKey.Burst
+}
+
+func (c *Config) ApiServerLockoutMaxFailures() int {
+	return c.Api.Server.RateLimit.Lockout.MaxFailures
+}
+
+func (c *Config) ApiServerLockoutWindow() time.Duration {
+	return time.Duration(c.Api.Server.RateLimit.Lockout.Window) * time.Second
+}
+
+func (c *Config) ApiServerLockoutDuration() time.Duration {
+	return time.Duration(c.Api.Server.RateLimit.Lockout.Duration) * time.Second
+}
+
+func (c *Config) ApiServerTokenCacheTTL() time.Duration {
+	return time.Duration(c.Api.Server.TokenCacheTTL) * time.Second
+}
+
 func (c *Config) EventStoreDbDriver() EventStoreDbDriver {
 	return c.Db.EventStore.Driver
 }
@@ -176,6 +437,118 @@ func (c *Config) AuthMasterToken() string {
 	return c.Auth.MasterToken
 }
 
+func (c *Config) AuthMasterTokenEnabled() bool {
+	return c.Auth.MasterTokenEnabled
+}
+
+func (c *Config) AuthSessionsSigningKey() string {
+	return c.Auth.Sessions.SigningKey
+}
+
+func (c *Config) AuthSessionsTTL() time.Duration {
+	return time.Duration(c.Auth.Sessions.TTL) * time.Minute
+}
+
+func (c *Config) AuthSessionsRefreshTTL() time.Duration {
+	return time.Duration(c.Auth.Sessions.RefreshTTL) * 24 * time.Hour
+}
+
+func (c *Config) AuthOidcEnabled() bool {
+	return c.Auth.Oidc.Enabled
+}
+
+func (c *Config) AuthOidcGroupsClaim() string {
+	return c.Auth.Oidc.GroupsClaim
+}
+
+func (c *Config) AuthOidcDefaultRoles() []string {
+	return c.Auth.Oidc.DefaultRoles
+}
+
+func (c *Config) AuthOidcAutoLinkAccounts() bool {
+	return c.Auth.Oidc.AutoLinkAccounts
+}
+
+// AuthOidcRoleMappings maps each group to the roles its members get.
+func (c *Config) AuthOidcRoleMappings() map[string][]string {
+	mappings := map[string][]string{}
+
+	for _, m := range c.Auth.Oidc.RoleMappings {
+		mappings[m.Group] = append(mappings[m.Group], m.Roles...)
+	}
+
+	return mappings
+}
+
+func (c *Config) CorrelationProjectKeyPatterns() []string {
+	return c.Correlation.ProjectKeyPatterns
+}
+
+func (c *Config) ProjectionsPollInterval() time.Duration {
+	return time.Duration(c.Projections.PollInterval) * time.Second
+}
+
+func (c *Config) DoraProductionEnvironments() []string {
+	return c.Dora.ProductionEnvironments
+}
+
+func (c *Config) DoraReleasesAsDeployments() bool {
+	return c.Dora.ReleasesAsDeployments
+}
+
+func (c *Config) DoraLookback() time.Duration {
+	return time.Duration(c.Dora.LookbackDays) * 24 * time.Hour
+}
+
+func (c *Config) DoraTeamRepositories(team string) ([]string, bool) {
+	for _, t := range c.Dora.Teams {
+		if t.Name == team {
+			return t.Repositories, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Config) IngestionQueueWorkers() int {
+	return c.Ingestion.Queue.Workers
+}
+
+func (c *Config) IngestionQueueMaxAttempts() int {
+	return c.Ingestion.Queue.MaxAttempts
+}
+
+func (c *Config) IngestionQueueBackoff() time.Duration {
+	return time.Duration(c.Ingestion.Queue.Backoff) * time.Second
+}
+
+func (c *Config) IngestionQueueMaxBackoff() time.Duration {
+	return time.Duration(c.Ingestion.Queue.MaxBackoff) * time.Second
+}
+
+func (c *Config) IngestionQueuePollInterval() time.Duration {
+	return time.Duration(c.Ingestion.Queue.PollInterval) * time.Second
+}
+
+func (c *Config) IngestionQueueLease() time.Duration {
+	return time.Duration(c.Ingestion.Queue.Lease) * time.Second
+}
+
+func (c *Config) WebhookSecrets(integration string) []string {
+	switch integration {
+	case "github":
+		return c.Ingestion.Github.WebhookSecrets
+	case "gitlab":
+		return c.Ingestion.Gitlab.WebhookSecrets
+	case "bitbucket":
+		return c.Ingestion.Bitbucket.WebhookSecrets
+	case "jira":
+		return c.Ingestion.Jira.WebhookSecrets
+	}
+
+	return nil
+}
+
 func NewDefault() *Config {
 	return &Config{
 		Logging: ConfigLogging{
@@ -190,12 +563,66 @@ func NewDefault() *Config {
 					Enabled: true,
 					Format:  "json",
 				},
+				RateLimit: ConfigApiServerRateLimit{
+					Enabled: true,
+					PerIP: ConfigApiServerRateLimitRule{
+						RequestsPerMinute: 300,
+						Burst: 60,
+					},
+					PerKey: ConfigApiServerRateLimitRule{
+						RequestsPerMinute: 600,
+						Burst: 100,
+					},
+					Lockout: ConfigApiServerLockout{
+						MaxFailures: 10,
+						Window: 300,
+						Duration: 900,
+					},
+				},
+				TokenCacheTTL: 60,
 			},
 		},
 		Auth: ConfigAuth{
+			MasterTokenEnabled: false,
 			Bcrypt: ConfigAuthBcrypt{
 				Cost: 12,
 			},
+			Sessions: ConfigAuthSessions{
+				TTL: 15,
+				RefreshTTL: 30,
+			},
+			Oidc: ConfigAuthOidc{
+				Enabled: false,
+				Scopes: []string{"openid", "email", "profile"},
+				GroupsClaim: "groups",
+				AutoLinkAccounts: false,
+			},
+		},
+		Ingestion: ConfigIngestion{
+			Queue: ConfigIngestionQueue{
+				Workers: 4,
+				MaxAttempts: 8,
+				Backoff: 5,
+				MaxBackoff: 3600,
+				PollInterval: 5,
+				Lease: 300,
+			},
+		},
+		Backfill: ConfigBackfill{
+			Github: ConfigBackfillGithub{
+				APIURL: "https://api.github.com",
+			},
+		},
+		Correlation: ConfigCorrelation{
+			ProjectKeyPatterns: []string{"[A-Z][A-Z0-9_]+"},
+		},
+		Dora: ConfigDora{
+			ProductionEnvironments: []string{"production"},
+			LookbackDays: 90,
+		},
+		Projections: ConfigProjections{
+			BatchSize: 500,
+			PollInterval: 10,
 		},
 		Db: ConfigDb{
 			EventStore: ConfigDbEventStore{
@@ -221,6 +648,10 @@ func NewDefault() *Config {
 					Schema: "public",
 					ConnectionRetries: 3,
 				},
+				Opensearch: ConfigDbOpensearch{
+					Addresses: []string{"http://opensearch:9200"},
+					IndexPrefix: "panoptes_",
+				},
 			},
 		},
 	}
@@ -0,0 +1,173 @@
+// Package audit keeps an append-only record of security relevant actions, who
+// did them, and where from.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+
+	// OutcomeFailure is for errors and failed authentication.
+	OutcomeFailure Outcome = "failure"
+
+	// OutcomeDenied is for principals that authenticated, but aren't allowed
+	// to do what they tried to.
+	OutcomeDenied Outcome = "denied"
+)
+
+const (
+	// ActorAnonymous is used when the request wasn't authenticated.
+	ActorAnonymous = "anonymous"
+
+	// ActorCLI is used for anything done with the CLI, by someone with
+	// access to the server.
+	ActorCLI = "cli"
+
+	// ActorSystem is used when there's no actor at all, e.g. background jobs.
+	ActorSystem = "system"
+)
+
+// Actor is who did the action, Type is a principal type e.g. user or
+// service_account.
+type Actor struct {
+	Type    string
+	ID      string
+	TokenID string
+}
+
+// Request is where an action came from, for actions made through the API.
+type Request struct {
+	ID       string
+	SourceIP string
+}
+
+type Event struct {
+	ID         uuid.UUID
+	Seq        int64
+	OccurredAt time.Time
+	Action     string
+	Outcome    Outcome
+
+	// Actor defaults to the actor from the context when recorded.
+	Actor      *Actor
+	TargetType string
+	TargetID   string
+	Request    Request
+
+	// Details are anything else specific to the action, never secrets.
+	Details map[string]any
+}
+
+type actorKey struct{}
+type requestKey struct{}
+
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+func ActorFrom(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return a
+	}
+
+	return Actor{Type: ActorSystem}
+}
+
+// CLIContext is the context for actions taken with the CLI.
+func CLIContext() context.Context {
+	return WithActor(context.Background(), Actor{Type: ActorCLI})
+}
+
+func WithRequest(ctx context.Context, r Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+func RequestFrom(ctx context.Context) Request {
+	r, _ := ctx.Value(requestKey{}).(Request)
+
+	return r
+}
+
+type Repo interface {
+	Create(e *Event) error
+	List(filter Filter) ([]*Event, error)
+}
+
+type Service struct {
+	repo      Repo
+	validator *validation.Validator
+	getNow    func() time.Time
+}
+
+// Record appends the event to the audit log, filling in the actor and request
+// from the context. Failing to record is logged rather than returned, as by
+// then the action has already happened.
+func (svc *Service) Record(ctx context.Context, e Event) {
+	e.ID = uuid.New()
+	e.OccurredAt = svc.getNow()
+	e.Request = RequestFrom(ctx)
+
+	if e.Actor == nil {
+		actor := ActorFrom(ctx)
+		e.Actor = &actor
+	}
+
+	if e.Details == nil {
+		e.Details = map[string]any{}
+	}
+
+	if err := svc.repo.Create(&e); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", e.Action, "outcome", e.Outcome, "actor_type", e.Actor.Type, "actor_id", e.Actor.ID, "target_id", e.TargetID)
+	}
+}
+
+// Filter only applies the fields that are set.
+type Filter struct {
+	Action     string
+	Outcome    string `validate:"omitempty,oneof=success failure denied"`
+	ActorType  string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+
+	// AfterSeq returns events after the given one, oldest first, for following
+	// the log. Otherwise the newest events are returned first.
+	AfterSeq *int64
+	Limit    int `validate:"min=1,max=500"`
+}
+
+func (svc *Service) List(filter Filter) ([]*Event, error) {
+	if err := svc.validator.Validate(filter); err != nil {
+		return nil, err
+	}
+
+	return svc.repo.List(filter)
+}
+
+type ServiceOpt func(*Service)
+
+func NewService(repo Repo, validator *validation.Validator, opts... ServiceOpt) *Service {
+	svc := &Service{
+		repo: repo,
+		validator: validator,
+		getNow: dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	return svc
+}
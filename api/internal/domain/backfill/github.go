@@ -0,0 +1,631 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+)
+
+const (
+	GithubStagePulls       = "pulls"
+	GithubStageDeployments = "deployments"
+	GithubStageCommits     = "commits"
+)
+
+// githubPageSize is the most github allows.
+const githubPageSize = 100
+
+type GithubAPI interface {
+	// Get decodes the response into dest, returning the url of the next page
+	// if there is one.
+	Get(ctx context.Context, path string, dest any) (string, error)
+}
+
+type GithubIngestor interface {
+	Process(e ingestion.GithubEvent) error
+}
+
+type GithubQuery struct {
+	Org string
+
+	// Repositories to backfill, by name or full name (e.g. api or acme/api),
+	// every repository in the org when empty.
+	Repositories []string
+
+	Since time.Time
+
+	// Progress is called after each page, with how many webhooks have been
+	// synthesised for the stage so far.
+	Progress func(repository string, stage string, webhooks int)
+}
+
+type GithubReport struct {
+	Repositories int
+	Webhooks     int
+}
+
+// These only declare the parts of github's API responses that we need, the
+// rest is passed through untouched into the synthesised webhooks.
+
+type githubRepository struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type githubPullRequest struct {
+	ID        int64          `json:"id"`
+	Number    int            `json:"number"`
+	User      map[string]any `json:"user"`
+	MergedBy  map[string]any `json:"merged_by"`
+	CreatedAt *time.Time     `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	ClosedAt  *time.Time     `json:"closed_at"`
+	MergedAt  *time.Time     `json:"merged_at"`
+}
+
+type githubReview struct {
+	ID          int64          `json:"id"`
+	State       string         `json:"state"`
+	User        map[string]any `json:"user"`
+	SubmittedAt *time.Time     `json:"submitted_at"`
+}
+
+type githubReviewComment struct {
+	ID        int64          `json:"id"`
+	User      map[string]any `json:"user"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type githubDeployment struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type githubDeploymentStatus struct {
+	ID        int64          `json:"id"`
+	Creator   map[string]any `json:"creator"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type githubGitUser struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+type githubCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message   string        `json:"message"`
+		Author    githubGitUser `json:"author"`
+		Committer githubGitUser `json:"committer"`
+	} `json:"commit"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+}
+
+// decode round trips the item through json rather than picking through the
+// map by hand.
+func decode(item map[string]any, dest any) error {
+	raw, err := json.Marshal(item)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, dest)
+}
+
+type GithubOpt func(*Github)
+
+func WithGithubCustomNowProvider(f func() time.Time) GithubOpt {
+	return func(g *Github) {
+		g.getNow = f
+	}
+}
+
+type Github struct {
+	api         GithubAPI
+	ingestor    GithubIngestor
+	checkpoints CheckpointsRepo
+	getNow      func() time.Time
+}
+
+// githubRun is the backfill of a single repository.
+type githubRun struct {
+	*Github
+
+	ctx      context.Context
+	q        GithubQuery
+	repo     githubRepository
+	rawRepo  map[string]any
+	stage    string
+	webhooks int
+}
+
+// Backfill synthesises webhooks for the pull requests (with their commits,
+// reviews and review comments) updated since q.Since, and the deployments and commits to
+// the default branch made since then. Each stage of each repository saves a
+// checkpoint after every page, so an interrupted backfill carries on where it
+// left off when run again with the same since.
+func (g *Github) Backfill(ctx context.Context, q GithubQuery) (*GithubReport, error) {
+	repos, err := g.repositories(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &GithubReport{}
+
+	for _, raw := range repos {
+		run := &githubRun{
+			Github:  g,
+			ctx:     ctx,
+			q:       q,
+			rawRepo: raw,
+		}
+
+		if err := decode(raw, &run.repo); err != nil {
+			return nil, err
+		}
+
+		stages := []struct {
+			name string
+			fn   func() error
+		}{
+			{GithubStagePulls, run.pulls},
+			{GithubStageDeployments, run.deployments},
+			{GithubStageCommits, run.commits},
+		}
+
+		for _, s := range stages {
+			run.stage = s.name
+			run.webhooks = 0
+
+			if err := s.fn(); err != nil {
+				return nil, fmt.Errorf("backfilling %s of %s: %w", s.name, run.repo.FullName, err)
+			}
+
+			report.Webhooks += run.webhooks
+		}
+
+		report.Repositories++
+	}
+
+	return report, nil
+}
+
+// all fetches every page.
+func (g *Github) all(ctx context.Context, path string) ([]map[string]any, error) {
+	items := []map[string]any{}
+
+	for path != "" {
+		page := []map[string]any{}
+		next, err := g.api.Get(ctx, path, &page)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page...)
+		path = next
+	}
+
+	return items, nil
+}
+
+func (g *Github) repositories(ctx context.Context, q GithubQuery) ([]map[string]any, error) {
+	if len(q.Repositories) == 0 {
+		return g.all(ctx, fmt.Sprintf("orgs/%s/repos?type=all&per_page=%d", url.PathEscape(q.Org), githubPageSize))
+	}
+
+	repos := []map[string]any{}
+
+	for _, name := range q.Repositories {
+		if !strings.Contains(name, "/") {
+			name = q.Org + "/" + name
+		}
+
+		repo := map[string]any{}
+
+		if _, err := g.api.Get(ctx, "repos/"+name, &repo); err != nil {
+			return nil, err
+		}
+
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
+
+func (r *githubRun) path(format string, args ...any) string {
+	return "repos/" + r.repo.FullName + "/" + fmt.Sprintf(format, args...)
+}
+
+func (r *githubRun) checkpoint() string {
+	return checkpointName(ingestion.IntegrationGithub, r.repo.FullName, r.stage, r.q.Since)
+}
+
+func (r *githubRun) progress() {
+	if r.q.Progress != nil {
+		r.q.Progress(r.repo.FullName, r.stage, r.webhooks)
+	}
+}
+
+// emit passes the synthesised webhook to the ingestor. The delivery id only
+// depends on what the webhook is for, so backfilling the same thing twice
+// stores it once. It's received when it happened, as far as we can tell.
+func (r *githubRun) emit(event string, key string, at time.Time, payload map[string]any) error {
+	payload["repository"] = r.rawRepo
+
+	err := r.ingestor.Process(ingestion.GithubEvent{
+		Payload:    payload,
+		Event:      event,
+		DeliveryID: "backfill:" + event + ":" + key,
+		ReceivedAt: at.UTC(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	r.webhooks++
+
+	return nil
+}
+
+// pulls lists the pull requests most recently updated first, so can stop at
+// the first one not updated since. Everything about those that were is
+// backfilled, even if it happened before since, as a pull request's events
+// don't make sense without it being opened.
+func (r *githubRun) pulls() error {
+	first := r.path("pulls?state=all&sort=updated&direction=desc&per_page=%d", githubPageSize)
+
+	return pages(r.checkpoints, r.getNow, r.checkpoint(), first, func(cursor string) (string, bool, error) {
+		page := []map[string]any{}
+		next, err := r.api.Get(r.ctx, cursor, &page)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		for _, item := range page {
+			var pr githubPullRequest
+
+			if err := decode(item, &pr); err != nil {
+				return "", false, err
+			}
+
+			if pr.UpdatedAt.Before(r.q.Since) {
+				r.progress()
+				return next, true, nil
+			}
+
+			if err := r.pull(pr.Number); err != nil {
+				return "", false, err
+			}
+		}
+
+		r.progress()
+
+		return next, false, nil
+	})
+}
+
+func (r *githubRun) pull(number int) error {
+	// The list doesn't include everything e.g. whether it was merged.
+	raw := map[string]any{}
+
+	if _, err := r.api.Get(r.ctx, r.path("pulls/%d", number), &raw); err != nil {
+		return err
+	}
+
+	var pr githubPullRequest
+
+	if err := decode(raw, &pr); err != nil {
+		return err
+	}
+
+	id := strconv.FormatInt(pr.ID, 10)
+
+	if pr.CreatedAt != nil {
+		err := r.emit("pull_request", "opened:"+id, *pr.CreatedAt, map[string]any{
+			"action":       "opened",
+			"number":       pr.Number,
+			"pull_request": raw,
+			"sender":       pr.User,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if pr.ClosedAt != nil {
+		// We can't tell who closed it, only who merged it.
+		closed := map[string]any{
+			"action":       "closed",
+			"number":       pr.Number,
+			"pull_request": raw,
+		}
+
+		if pr.MergedBy != nil {
+			closed["sender"] = pr.MergedBy
+		}
+
+		if err := r.emit("pull_request", "closed:"+id, *pr.ClosedAt, closed); err != nil {
+			return err
+		}
+	}
+
+	if err := r.pullCommits(pr, raw); err != nil {
+		return err
+	}
+
+	reviews, err := r.all(r.ctx, r.path("pulls/%d/reviews?per_page=%d", number, githubPageSize))
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range reviews {
+		var review githubReview
+
+		if err := decode(item, &review); err != nil {
+			return err
+		}
+
+		// Pending reviews haven't been submitted yet.
+		if review.SubmittedAt == nil || strings.EqualFold(review.State, "pending") {
+			continue
+		}
+
+		// The API shouts the state, webhooks don't.
+		item["state"] = strings.ToLower(review.State)
+
+		err := r.emit("pull_request_review", "submitted:"+strconv.FormatInt(review.ID, 10), *review.SubmittedAt, map[string]any{
+			"action":       "submitted",
+			"review":       item,
+			"pull_request": raw,
+			"sender":       review.User,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	comments, err := r.all(r.ctx, r.path("pulls/%d/comments?per_page=%d", number, githubPageSize))
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range comments {
+		var comment githubReviewComment
+
+		if err := decode(item, &comment); err != nil {
+			return err
+		}
+
+		err := r.emit("pull_request_review_comment", "created:"+strconv.FormatInt(comment.ID, 10), comment.CreatedAt, map[string]any{
+			"action":       "created",
+			"comment":      item,
+			"pull_request": raw,
+			"sender":       comment.User,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pullCommits synthesises a push to the pull request for each of its commits,
+// at the time it was authored, as that's when the work was done. Github only
+// lists the first 250 commits of a pull request, which are the ones we care
+// about.
+func (r *githubRun) pullCommits(pr githubPullRequest, raw map[string]any) error {
+	commits, err := r.all(r.ctx, r.path("pulls/%d/commits?per_page=%d", pr.Number, githubPageSize))
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range commits {
+		var c githubCommit
+
+		if err := decode(item, &c); err != nil {
+			return err
+		}
+
+		before := ""
+
+		if len(c.Parents) > 0 {
+			before = c.Parents[0].SHA
+		}
+
+		// The webhook's time comes from when the pull request was updated.
+		at := c.Commit.Author.Date
+		snapshot := make(map[string]any, len(raw))
+
+		for k, v := range raw {
+			snapshot[k] = v
+		}
+
+		snapshot["updated_at"] = at.UTC().Format(time.RFC3339)
+
+		err := r.emit("pull_request", "synchronize:"+strconv.FormatInt(pr.ID, 10)+":"+c.SHA, at, map[string]any{
+			"action":       "synchronize",
+			"number":       pr.Number,
+			"before":       before,
+			"after":        c.SHA,
+			"pull_request": snapshot,
+			"sender":       pr.User,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deployments are listed newest first, so can stop at the first one made
+// before since.
+func (r *githubRun) deployments() error {
+	first := r.path("deployments?per_page=%d", githubPageSize)
+
+	return pages(r.checkpoints, r.getNow, r.checkpoint(), first, func(cursor string) (string, bool, error) {
+		page := []map[string]any{}
+		next, err := r.api.Get(r.ctx, cursor, &page)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		for _, item := range page {
+			var d githubDeployment
+
+			if err := decode(item, &d); err != nil {
+				return "", false, err
+			}
+
+			if d.CreatedAt.Before(r.q.Since) {
+				r.progress()
+				return next, true, nil
+			}
+
+			if err := r.deployment(d, item); err != nil {
+				return "", false, err
+			}
+		}
+
+		r.progress()
+
+		return next, false, nil
+	})
+}
+
+func (r *githubRun) deployment(d githubDeployment, raw map[string]any) error {
+	statuses, err := r.all(r.ctx, r.path("deployments/%d/statuses?per_page=%d", d.ID, githubPageSize))
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range statuses {
+		var status githubDeploymentStatus
+
+		if err := decode(item, &status); err != nil {
+			return err
+		}
+
+		err := r.emit("deployment_status", strconv.FormatInt(status.ID, 10), status.CreatedAt, map[string]any{
+			"action":            "created",
+			"deployment":        raw,
+			"deployment_status": item,
+			"sender":            status.Creator,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commits to the default branch become a push each, as the API doesn't tell
+// us how they were pushed. They're pushed when they were committed, which for
+// a merged pull request is when it was merged.
+func (r *githubRun) commits() error {
+	first := r.path(
+		"commits?sha=%s&since=%s&per_page=%d",
+		url.QueryEscape(r.repo.DefaultBranch),
+		url.QueryEscape(r.q.Since.UTC().Format(time.RFC3339)),
+		githubPageSize,
+	)
+
+	return pages(r.checkpoints, r.getNow, r.checkpoint(), first, func(cursor string) (string, bool, error) {
+		page := []map[string]any{}
+		next, err := r.api.Get(r.ctx, cursor, &page)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		for _, item := range page {
+			var c githubCommit
+
+			if err := decode(item, &c); err != nil {
+				return "", false, err
+			}
+
+			before := ""
+
+			if len(c.Parents) > 0 {
+				before = c.Parents[0].SHA
+			}
+
+			commit := map[string]any{
+				"id":        c.SHA,
+				"message":   c.Commit.Message,
+				"timestamp": c.Commit.Committer.Date,
+				"url":       c.HTMLURL,
+				"author": map[string]any{
+					"name":  c.Commit.Author.Name,
+					"email": c.Commit.Author.Email,
+				},
+				"committer": map[string]any{
+					"name":  c.Commit.Committer.Name,
+					"email": c.Commit.Committer.Email,
+				},
+			}
+
+			err := r.emit("push", c.SHA, c.Commit.Committer.Date, map[string]any{
+				"ref":         "refs/heads/" + r.repo.DefaultBranch,
+				"before":      before,
+				"after":       c.SHA,
+				"deleted":     false,
+				"head_commit": commit,
+				"commits":     []any{commit},
+			})
+
+			if err != nil {
+				return "", false, err
+			}
+		}
+
+		r.progress()
+
+		return next, false, nil
+	})
+}
+
+func NewGithub(api GithubAPI, ingestor GithubIngestor, checkpoints CheckpointsRepo, opts ...GithubOpt) *Github {
+	g := &Github{
+		api:         api,
+		ingestor:    ingestor,
+		checkpoints: checkpoints,
+		getNow:      dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
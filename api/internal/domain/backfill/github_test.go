@@ -0,0 +1,307 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/util/githubapi"
+)
+
+// fakeGithub serves canned responses for github's API. Lists are split into
+// pages linked by the Link header, the same as github does it.
+type fakeGithub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	objects  map[string]any
+	lists    map[string][][]any
+	requests []string
+}
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	f := &fakeGithub{
+		objects: map[string]any{},
+		lists:   map[string][][]any{},
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeGithub) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil {
+		page = 1
+	}
+
+	f.requests = append(f.requests, path+"#"+strconv.Itoa(page))
+
+	if obj, ok := f.objects[path]; ok {
+		json.NewEncoder(w).Encode(obj)
+		return
+	}
+
+	pages := f.lists[path]
+
+	if page > len(pages) {
+		// Anything we've not set up is an empty list.
+		w.Write([]byte("[]"))
+		return
+	}
+
+	if page < len(pages) {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+
+		w.Header().Set("Link", `<`+f.URL+next.String()+`>; rel="next"`)
+	}
+
+	json.NewEncoder(w).Encode(pages[page-1])
+}
+
+// flush returns the pages requested so far, as path#page, and clears them.
+func (f *fakeGithub) flush() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := f.requests
+	f.requests = nil
+
+	return requests
+}
+
+type githubIngestor struct {
+	events []ingestion.GithubEvent
+
+	// failOn fails the delivery with the id, once.
+	failOn string
+}
+
+func (i *githubIngestor) Process(e ingestion.GithubEvent) error {
+	if e.DeliveryID == i.failOn {
+		i.failOn = ""
+		return errors.New("database is down")
+	}
+
+	i.events = append(i.events, e)
+
+	return nil
+}
+
+func (i *githubIngestor) deliveries() []string {
+	ids := make([]string, len(i.events))
+
+	for n, e := range i.events {
+		ids[n] = e.DeliveryID
+	}
+
+	slices.Sort(ids)
+	i.events = nil
+
+	return ids
+}
+
+var since = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func pull(id int, number int, updatedAt string) map[string]any {
+	return map[string]any{
+		"id":         id,
+		"number":     number,
+		"user":       map[string]any{"login": "alice"},
+		"created_at": "2024-03-01T09:00:00Z",
+		"updated_at": updatedAt,
+	}
+}
+
+// githubHistory sets up a repository with two pull requests updated since,
+// one on each of the first two pages, followed by one that wasn't.
+func githubHistory(f *fakeGithub) {
+	f.objects["repos/acme/app"] = map[string]any{"full_name": "acme/app", "default_branch": "main"}
+
+	f.lists["repos/acme/app/pulls"] = [][]any{
+		{pull(101, 1, "2024-03-10T09:00:00Z")},
+		{pull(102, 2, "2024-03-05T09:00:00Z")},
+		{pull(103, 3, "2024-02-01T09:00:00Z")},
+		{pull(104, 4, "2024-01-01T09:00:00Z")},
+	}
+
+	merged := pull(101, 1, "2024-03-10T09:00:00Z")
+	merged["merged"] = true
+	merged["closed_at"] = "2024-03-10T09:00:00Z"
+	merged["merged_at"] = "2024-03-10T09:00:00Z"
+	merged["merged_by"] = map[string]any{"login": "bob"}
+
+	f.objects["repos/acme/app/pulls/1"] = merged
+	f.objects["repos/acme/app/pulls/2"] = pull(102, 2, "2024-03-05T09:00:00Z")
+
+	f.lists["repos/acme/app/pulls/1/commits"] = [][]any{
+		{map[string]any{"sha": "c1", "parents": []any{map[string]any{"sha": "base"}}, "commit": map[string]any{"author": map[string]any{"date": "2024-02-28T09:00:00Z"}}}},
+		{map[string]any{"sha": "c2", "parents": []any{map[string]any{"sha": "c1"}}, "commit": map[string]any{"author": map[string]any{"date": "2024-03-02T09:00:00Z"}}}},
+	}
+
+	f.lists["repos/acme/app/pulls/1/reviews"] = [][]any{{
+		map[string]any{"id": 5, "state": "APPROVED", "submitted_at": "2024-03-09T09:00:00Z"},
+		map[string]any{"id": 6, "state": "PENDING"},
+	}}
+
+	f.lists["repos/acme/app/pulls/1/comments"] = [][]any{{
+		map[string]any{"id": 7, "created_at": "2024-03-08T09:00:00Z"},
+	}}
+
+	f.lists["repos/acme/app/deployments"] = [][]any{{
+		map[string]any{"id": 9, "created_at": "2024-03-02T09:00:00Z"},
+		map[string]any{"id": 8, "created_at": "2024-02-01T09:00:00Z"},
+	}}
+
+	f.lists["repos/acme/app/deployments/9/statuses"] = [][]any{{
+		map[string]any{"id": 90, "state": "success", "created_at": "2024-03-02T09:05:00Z"},
+	}}
+
+	f.lists["repos/acme/app/commits"] = [][]any{{
+		map[string]any{"sha": "m1", "commit": map[string]any{"committer": map[string]any{"date": "2024-03-10T09:00:00Z"}}},
+	}}
+}
+
+func newGithubBackfill(f *fakeGithub, ingestor *githubIngestor, checkpoints *checkpointsRepo) *Github {
+	api := githubapi.NewClient(githubapi.Config{BaseURL: f.URL})
+
+	return NewGithub(api, ingestor, checkpoints, WithGithubCustomNowProvider(getNow))
+}
+
+func TestGithubBackfill(t *testing.T) {
+	f := newFakeGithub(t)
+	githubHistory(f)
+
+	ingestor := &githubIngestor{}
+	checkpoints := newCheckpointsRepo()
+
+	report, err := newGithubBackfill(f, ingestor, checkpoints).Backfill(context.Background(), GithubQuery{
+		Org:          "acme",
+		Repositories: []string{"app"},
+		Since:        since,
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received := map[string]time.Time{}
+
+	for _, e := range ingestor.events {
+		received[e.DeliveryID] = e.ReceivedAt
+
+		if e.Event == "pull_request_review" && e.Payload["review"].(map[string]any)["state"] != "approved" {
+			t.Errorf("expected the review state in lower case, got %v", e.Payload["review"])
+		}
+
+		if e.Payload["repository"].(map[string]any)["full_name"] != "acme/app" {
+			t.Errorf("expected the repository on %s", e.DeliveryID)
+		}
+	}
+
+	want := []string{
+		"backfill:deployment_status:90",
+		"backfill:pull_request:closed:101",
+		"backfill:pull_request:opened:101",
+		"backfill:pull_request:opened:102",
+		"backfill:pull_request:synchronize:101:c1",
+		"backfill:pull_request:synchronize:101:c2",
+		"backfill:pull_request_review:submitted:5",
+		"backfill:pull_request_review_comment:created:7",
+		"backfill:push:m1",
+	}
+
+	if got := ingestor.deliveries(); !slices.Equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+
+	if report.Repositories != 1 || report.Webhooks != len(want) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// Commits are pushed to the pull request when they were authored.
+	if at := received["backfill:pull_request:synchronize:101:c1"]; !at.Equal(time.Date(2024, 2, 28, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the commit to be received when it was authored, got %v", at)
+	}
+
+	requests := f.flush()
+
+	// Pull requests are listed most recently updated first, so the page after
+	// the first one too old isn't needed.
+	if !slices.Contains(requests, "repos/acme/app/pulls#3") || slices.Contains(requests, "repos/acme/app/pulls#4") {
+		t.Errorf("expected to stop paging at the first old pull request, requested %v", requests)
+	}
+
+	for _, stage := range []string{GithubStagePulls, GithubStageDeployments, GithubStageCommits} {
+		c := checkpoints.checkpoints[checkpointName(ingestion.IntegrationGithub, "acme/app", stage, since)]
+
+		if c == nil || !c.Completed() {
+			t.Errorf("expected the %s stage to be completed, got %+v", stage, c)
+		}
+	}
+}
+
+func TestGithubBackfillResumes(t *testing.T) {
+	f := newFakeGithub(t)
+	githubHistory(f)
+
+	// The second pull request is on the second page, so the first page has
+	// been checkpointed by the time it fails.
+	ingestor := &githubIngestor{failOn: "backfill:pull_request:opened:102"}
+	checkpoints := newCheckpointsRepo()
+	backfill := newGithubBackfill(f, ingestor, checkpoints)
+	q := GithubQuery{Org: "acme", Repositories: []string{"app"}, Since: since}
+
+	if _, err := backfill.Backfill(context.Background(), q); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	ingestor.deliveries()
+	f.flush()
+
+	if _, err := backfill.Backfill(context.Background(), q); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requests := f.flush()
+
+	if slices.Contains(requests, "repos/acme/app/pulls#1") || slices.Contains(requests, "repos/acme/app/pulls/1#1") {
+		t.Errorf("expected the first page not to be fetched again, requested %v", requests)
+	}
+
+	if got := ingestor.deliveries(); !slices.Contains(got, "backfill:pull_request:opened:102") || slices.Contains(got, "backfill:pull_request:opened:101") {
+		t.Errorf("expected to carry on from the second page, delivered %v", got)
+	}
+
+	// Once everything is backfilled, running it again does nothing.
+	if _, err := backfill.Backfill(context.Background(), q); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if requests := f.flush(); !slices.Equal(requests, []string{"repos/acme/app#1"}) {
+		t.Errorf("expected only the repository to be fetched, requested %v", requests)
+	}
+
+	if got := ingestor.deliveries(); len(got) != 0 {
+		t.Errorf("expected nothing to be delivered, delivered %v", got)
+	}
+}
@@ -0,0 +1,460 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+)
+
+const JiraStageIssues = "issues"
+
+// jiraPageSize is the most jira returns for a search with the changelog
+// expanded.
+const jiraPageSize = 100
+
+type JiraAPI interface {
+	Get(ctx context.Context, path string, dest any) error
+}
+
+type JiraIngestor interface {
+	Process(e ingestion.JiraEvent) error
+}
+
+type JiraQuery struct {
+	Project string
+	Since   time.Time
+
+	// Progress is called after each page, with how many issues have been
+	// backfilled so far.
+	Progress func(project string, issues int, total int)
+}
+
+type JiraReport struct {
+	Issues   int
+	Webhooks int
+}
+
+// These only declare the parts of jira's API responses that we need, the rest
+// is passed through untouched into the synthesised webhooks.
+
+type jiraChangeItem struct {
+	Field      string  `json:"field"`
+	FieldID    string  `json:"fieldId"`
+	From       *string `json:"from"`
+	FromString *string `json:"fromString"`
+}
+
+type jiraHistory struct {
+	ID      string           `json:"id"`
+	Author  map[string]any   `json:"author"`
+	Created string           `json:"created"`
+	Items   []jiraChangeItem `json:"items"`
+	raw     map[string]any
+
+	at time.Time
+}
+
+type jiraChangelog struct {
+	Total     int              `json:"total"`
+	Histories []map[string]any `json:"histories"`
+}
+
+type jiraIssue struct {
+	ID        string         `json:"id"`
+	Key       string         `json:"key"`
+	Self      string         `json:"self"`
+	Fields    map[string]any `json:"fields"`
+	Changelog *jiraChangelog `json:"changelog"`
+}
+
+type jiraSearchResults struct {
+	StartAt int              `json:"startAt"`
+	Total   int              `json:"total"`
+	Issues  []map[string]any `json:"issues"`
+}
+
+type jiraChangelogPage struct {
+	IsLast bool             `json:"isLast"`
+	Total  int              `json:"total"`
+	Values []map[string]any `json:"values"`
+}
+
+type jiraStatus struct {
+	ID             string         `json:"id"`
+	StatusCategory map[string]any `json:"statusCategory"`
+}
+
+// jiraTimeLayouts are the formats jira uses for dates, it isn't quite RFC3339
+// as the offset has no colon e.g. 2024-01-02T10:00:00.000+0000
+var jiraTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339Nano,
+}
+
+func parseJiraTime(val string) (time.Time, error) {
+	var err error
+
+	for _, layout := range jiraTimeLayouts {
+		var parsed time.Time
+
+		if parsed, err = time.Parse(layout, val); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+type JiraOpt func(*Jira)
+
+func WithJiraCustomNowProvider(f func() time.Time) JiraOpt {
+	return func(j *Jira) {
+		j.getNow = f
+	}
+}
+
+type Jira struct {
+	api         JiraAPI
+	ingestor    JiraIngestor
+	checkpoints CheckpointsRepo
+	getNow      func() time.Time
+}
+
+// jiraRun is the backfill of a single project.
+type jiraRun struct {
+	*Jira
+
+	ctx context.Context
+	q   JiraQuery
+
+	// categories are the status categories by status id, as the changelog
+	// only has the name and id of past statuses.
+	categories map[string]map[string]any
+
+	report *JiraReport
+}
+
+// Backfill synthesises the webhooks jira would have sent for the issues in the
+// project updated since q.Since, an issue_created and then an issue_updated
+// for each entry in its changelog, timestamped when the change was made.
+//
+// The issues are searched for oldest first, as issues that are updated while
+// backfilling don't move, and a checkpoint is saved after each page, so an
+// interrupted backfill carries on where it left off when run again with the
+// same since.
+func (j *Jira) Backfill(ctx context.Context, q JiraQuery) (*JiraReport, error) {
+	run := &jiraRun{
+		Jira:   j,
+		ctx:    ctx,
+		q:      q,
+		report: &JiraReport{},
+	}
+
+	if err := run.statuses(); err != nil {
+		return nil, err
+	}
+
+	name := checkpointName(ingestion.IntegrationJira, q.Project, JiraStageIssues, q.Since)
+	// JQL dates are in the user's timezone, which we don't know, so this may be
+	// out by a few hours either way.
+	jql := fmt.Sprintf(
+		`project = "%s" AND updated >= "%s" ORDER BY created ASC, key ASC`,
+		strings.ReplaceAll(q.Project, `"`, `\"`),
+		q.Since.Format("2006-01-02 15:04"),
+	)
+
+	err := pages(j.checkpoints, j.getNow, name, "0", func(cursor string) (string, bool, error) {
+		startAt, err := strconv.Atoi(cursor)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		path := fmt.Sprintf(
+			"rest/api/2/search?jql=%s&startAt=%d&maxResults=%d&expand=changelog",
+			url.QueryEscape(jql),
+			startAt,
+			jiraPageSize,
+		)
+
+		var results jiraSearchResults
+
+		if err := j.api.Get(ctx, path, &results); err != nil {
+			return "", false, err
+		}
+
+		for _, raw := range results.Issues {
+			if err := run.issue(raw); err != nil {
+				return "", false, err
+			}
+		}
+
+		next := startAt + len(results.Issues)
+
+		if q.Progress != nil {
+			q.Progress(q.Project, next, results.Total)
+		}
+
+		if len(results.Issues) == 0 || next >= results.Total {
+			return "", true, nil
+		}
+
+		return strconv.Itoa(next), false, nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("backfilling issues of %s: %w", q.Project, err)
+	}
+
+	return run.report, nil
+}
+
+func (r *jiraRun) statuses() error {
+	statuses := []jiraStatus{}
+
+	if err := r.api.Get(r.ctx, "rest/api/2/status", &statuses); err != nil {
+		return err
+	}
+
+	r.categories = map[string]map[string]any{}
+
+	for _, s := range statuses {
+		r.categories[s.ID] = s.StatusCategory
+	}
+
+	return nil
+}
+
+// changelog is every entry in the issue's changelog, oldest first. Jira cloud
+// only includes the most recent entries in search results, the rest have to
+// be fetched separately.
+func (r *jiraRun) changelog(issue jiraIssue) ([]map[string]any, error) {
+	if issue.Changelog == nil {
+		return []map[string]any{}, nil
+	}
+
+	if len(issue.Changelog.Histories) >= issue.Changelog.Total {
+		return issue.Changelog.Histories, nil
+	}
+
+	histories := []map[string]any{}
+
+	for {
+		var page jiraChangelogPage
+
+		path := fmt.Sprintf("rest/api/2/issue/%s/changelog?startAt=%d&maxResults=%d", url.PathEscape(issue.ID), len(histories), jiraPageSize)
+
+		if err := r.api.Get(r.ctx, path, &page); err != nil {
+			return nil, err
+		}
+
+		histories = append(histories, page.Values...)
+
+		if page.IsLast || len(page.Values) == 0 || len(histories) >= page.Total {
+			return histories, nil
+		}
+	}
+}
+
+func (r *jiraRun) issue(raw map[string]any) error {
+	var issue jiraIssue
+
+	if err := decode(raw, &issue); err != nil {
+		return err
+	}
+
+	rawHistories, err := r.changelog(issue)
+
+	if err != nil {
+		return err
+	}
+
+	histories := make([]*jiraHistory, len(rawHistories))
+
+	for i, item := range rawHistories {
+		h := &jiraHistory{raw: item}
+
+		if err := decode(item, h); err != nil {
+			return err
+		}
+
+		if h.at, err = parseJiraTime(h.Created); err != nil {
+			return err
+		}
+
+		histories[i] = h
+	}
+
+	sort.SliceStable(histories, func(a, b int) bool {
+		return histories[a].at.Before(histories[b].at)
+	})
+
+	// The issue is as it is now, so work back through the changelog to find
+	// how it was after each change, and when it was created.
+	fields := issue.Fields
+	after := make([]map[string]any, len(histories))
+
+	for i := len(histories) - 1; i >= 0; i-- {
+		after[i] = fields
+		fields = r.undo(fields, histories[i].Items)
+	}
+
+	created, _ := issue.Fields["created"].(string)
+	createdAt, err := parseJiraTime(created)
+
+	if err != nil {
+		return err
+	}
+
+	creator, _ := issue.Fields["creator"].(map[string]any)
+
+	if creator == nil {
+		creator, _ = issue.Fields["reporter"].(map[string]any)
+	}
+
+	err = r.emit("created:"+issue.ID, createdAt, map[string]any{
+		"timestamp":             createdAt.UnixMilli(),
+		"webhookEvent":          "jira:issue_created",
+		"issue_event_type_name": "issue_created",
+		"user":                  creator,
+		"issue":                 issueAt(issue, fields),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for i, h := range histories {
+		err := r.emit("updated:"+h.ID, h.at, map[string]any{
+			"timestamp":             h.at.UnixMilli(),
+			"webhookEvent":          "jira:issue_updated",
+			"issue_event_type_name": "issue_generic",
+			"user":                  h.Author,
+			"issue":                 issueAt(issue, after[i]),
+			"changelog": map[string]any{
+				"id":    h.ID,
+				"items": h.raw["items"],
+			},
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	r.report.Issues++
+
+	return nil
+}
+
+// undo returns the fields as they were before the changes, for those we can
+// work out from the changelog. Anything else is left as it is now.
+func (r *jiraRun) undo(fields map[string]any, items []jiraChangeItem) map[string]any {
+	before := make(map[string]any, len(fields))
+
+	for k, v := range fields {
+		before[k] = v
+	}
+
+	for _, item := range items {
+		field := item.FieldID
+
+		if field == "" {
+			field = strings.ToLower(item.Field)
+		}
+
+		from := derefString(item.From)
+		fromString := derefString(item.FromString)
+
+		switch field {
+		case "summary":
+			before["summary"] = fromString
+
+		case "status":
+			before["status"] = map[string]any{
+				"id":             from,
+				"name":           fromString,
+				"statusCategory": r.categories[from],
+			}
+
+		case "assignee":
+			if item.From == nil && item.FromString == nil {
+				before["assignee"] = nil
+				continue
+			}
+
+			// Cloud identifies users by account id, server by name.
+			before["assignee"] = map[string]any{
+				"accountId":   from,
+				"name":        from,
+				"displayName": fromString,
+			}
+
+		case "priority", "issuetype":
+			before[field] = map[string]any{
+				"id":   from,
+				"name": fromString,
+			}
+		}
+	}
+
+	return before
+}
+
+func issueAt(issue jiraIssue, fields map[string]any) map[string]any {
+	return map[string]any{
+		"id":     issue.ID,
+		"key":    issue.Key,
+		"self":   issue.Self,
+		"fields": fields,
+	}
+}
+
+// emit passes the synthesised webhook to the ingestor. The delivery id only
+// depends on what the webhook is for, so backfilling the same thing twice
+// stores it once.
+func (r *jiraRun) emit(key string, at time.Time, payload map[string]any) error {
+	err := r.ingestor.Process(ingestion.JiraEvent{
+		Payload:    payload,
+		DeliveryID: "backfill:" + key,
+		ReceivedAt: at,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	r.report.Webhooks++
+
+	return nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func NewJira(api JiraAPI, ingestor JiraIngestor, checkpoints CheckpointsRepo, opts ...JiraOpt) *Jira {
+	j := &Jira{
+		api:         api,
+		ingestor:    ingestor,
+		checkpoints: checkpoints,
+		getNow:      dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	return j
+}
@@ -0,0 +1,307 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/util/jiraapi"
+)
+
+// fakeJira serves a project's issues a page at a time, however many are asked
+// for, as jira is allowed to.
+type fakeJira struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	issues     []map[string]any
+	changelogs map[string][]map[string]any
+	requests   []string
+}
+
+func newFakeJira(t *testing.T) *fakeJira {
+	f := &fakeJira{changelogs: map[string][]map[string]any{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeJira) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	f.requests = append(f.requests, path+"#"+strconv.Itoa(startAt))
+
+	var resp any
+
+	switch {
+	case path == "rest/api/2/status":
+		resp = []map[string]any{
+			{"id": "1", "statusCategory": map[string]any{"key": "new"}},
+			{"id": "3", "statusCategory": map[string]any{"key": "indeterminate"}},
+			{"id": "5", "statusCategory": map[string]any{"key": "done"}},
+		}
+
+	case path == "rest/api/2/search":
+		if !strings.Contains(r.URL.Query().Get("jql"), `project = "PROJ"`) {
+			http.Error(w, "unexpected jql", http.StatusBadRequest)
+			return
+		}
+
+		issues := []map[string]any{}
+
+		if startAt < len(f.issues) {
+			issues = append(issues, f.issues[startAt])
+		}
+
+		resp = map[string]any{"startAt": startAt, "total": len(f.issues), "issues": issues}
+
+	case strings.HasSuffix(path, "/changelog"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "rest/api/2/issue/"), "/changelog")
+		histories := f.changelogs[id]
+		values := []map[string]any{}
+
+		if startAt < len(histories) {
+			values = append(values, histories[startAt])
+		}
+
+		resp = map[string]any{"isLast": startAt+1 >= len(histories), "total": len(histories), "values": values}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeJira) flush() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := f.requests
+	f.requests = nil
+
+	return requests
+}
+
+type jiraIngestor struct {
+	events []ingestion.JiraEvent
+
+	// failOn fails the delivery with the id, once.
+	failOn string
+}
+
+func (i *jiraIngestor) Process(e ingestion.JiraEvent) error {
+	if e.DeliveryID == i.failOn {
+		i.failOn = ""
+		return errors.New("database is down")
+	}
+
+	i.events = append(i.events, e)
+
+	return nil
+}
+
+func (i *jiraIngestor) take() []ingestion.JiraEvent {
+	events := i.events
+	i.events = nil
+
+	return events
+}
+
+func deliveryIDs(events []ingestion.JiraEvent) []string {
+	ids := make([]string, len(events))
+
+	for n, e := range events {
+		ids[n] = e.DeliveryID
+	}
+
+	return ids
+}
+
+func history(id string, created string, items ...map[string]any) map[string]any {
+	return map[string]any{
+		"id":      id,
+		"author":  map[string]any{"displayName": "Alice"},
+		"created": created,
+		"items":   items,
+	}
+}
+
+// jiraProject sets up two issues, the first with more changelog than the
+// search includes.
+func jiraProject(f *fakeJira) {
+	statusChange := history("1", "2024-03-02T10:00:00.000+0000",
+		map[string]any{"field": "status", "fieldId": "status", "from": "1", "fromString": "To Do", "to": "3", "toString": "In Progress"},
+	)
+	doneChange := history("2", "2024-03-03T10:00:00.000+0000",
+		map[string]any{"field": "status", "fieldId": "status", "from": "3", "fromString": "In Progress", "to": "5", "toString": "Done"},
+		map[string]any{"field": "assignee", "fieldId": "assignee", "from": nil, "fromString": nil, "to": "alice", "toString": "Alice"},
+	)
+
+	f.issues = []map[string]any{
+		{
+			"id":  "10001",
+			"key": "PROJ-1",
+			"fields": map[string]any{
+				"summary":  "Do the thing",
+				"created":  "2024-03-01T10:00:00.000+0000",
+				"status":   map[string]any{"id": "5", "name": "Done", "statusCategory": map[string]any{"key": "done"}},
+				"assignee": map[string]any{"accountId": "alice", "displayName": "Alice"},
+				"reporter": map[string]any{"displayName": "Bob"},
+			},
+			// Search results only include the most recent changes.
+			"changelog": map[string]any{"total": 2, "histories": []any{doneChange}},
+		},
+		{
+			"id":  "10002",
+			"key": "PROJ-2",
+			"fields": map[string]any{
+				"summary": "Do another thing",
+				"created": "2024-03-04T10:00:00.000+0000",
+				"status":  map[string]any{"id": "1", "name": "To Do", "statusCategory": map[string]any{"key": "new"}},
+			},
+			"changelog": map[string]any{"total": 0, "histories": []any{}},
+		},
+	}
+
+	f.changelogs["10001"] = []map[string]any{statusChange, doneChange}
+}
+
+func newJiraBackfill(f *fakeJira, ingestor *jiraIngestor, checkpoints *checkpointsRepo) *Jira {
+	api := jiraapi.NewClient(jiraapi.Config{URL: f.URL, Email: "alice@example.com", Token: "token"})
+
+	return NewJira(api, ingestor, checkpoints, WithJiraCustomNowProvider(getNow))
+}
+
+// issueStatus is the status the synthesised webhook's issue was in.
+func issueStatus(e ingestion.JiraEvent) (string, string) {
+	fields := e.Payload["issue"].(map[string]any)["fields"].(map[string]any)
+	status := fields["status"].(map[string]any)
+	category, _ := status["statusCategory"].(map[string]any)
+
+	return status["name"].(string), category["key"].(string)
+}
+
+func TestJiraBackfill(t *testing.T) {
+	f := newFakeJira(t)
+	jiraProject(f)
+
+	ingestor := &jiraIngestor{}
+	checkpoints := newCheckpointsRepo()
+
+	report, err := newJiraBackfill(f, ingestor, checkpoints).Backfill(context.Background(), JiraQuery{Project: "PROJ", Since: since})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Issues != 2 || report.Webhooks != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	events := ingestor.take()
+
+	tests := []struct {
+		deliveryID     string
+		webhookEvent   string
+		status         string
+		statusCategory string
+		assigned       bool
+	}{
+		{deliveryID: "backfill:created:10001", webhookEvent: "jira:issue_created", status: "To Do", statusCategory: "new"},
+		{deliveryID: "backfill:updated:1", webhookEvent: "jira:issue_updated", status: "In Progress", statusCategory: "indeterminate"},
+		{deliveryID: "backfill:updated:2", webhookEvent: "jira:issue_updated", status: "Done", statusCategory: "done", assigned: true},
+		{deliveryID: "backfill:created:10002", webhookEvent: "jira:issue_created", status: "To Do", statusCategory: "new"},
+	}
+
+	if got := deliveryIDs(events); len(got) != len(tests) {
+		t.Fatalf("delivered %v", got)
+	}
+
+	// Each webhook has the issue as it was after the change, worked back from
+	// how it is now.
+	for i, tt := range tests {
+		t.Run(tt.deliveryID, func(t *testing.T) {
+			e := events[i]
+
+			if e.DeliveryID != tt.deliveryID || e.Payload["webhookEvent"] != tt.webhookEvent {
+				t.Fatalf("delivered %s (%v), want %s (%s)", e.DeliveryID, e.Payload["webhookEvent"], tt.deliveryID, tt.webhookEvent)
+			}
+
+			if status, category := issueStatus(e); status != tt.status || category != tt.statusCategory {
+				t.Errorf("status = %s (%s), want %s (%s)", status, category, tt.status, tt.statusCategory)
+			}
+
+			assignee := e.Payload["issue"].(map[string]any)["fields"].(map[string]any)["assignee"]
+
+			if (assignee != nil) != tt.assigned {
+				t.Errorf("assignee = %v, want assigned %t", assignee, tt.assigned)
+			}
+		})
+	}
+
+	if !slices.Contains(f.flush(), "rest/api/2/issue/10001/changelog#1") {
+		t.Error("expected the rest of the changelog to be fetched")
+	}
+
+	c := checkpoints.checkpoints[checkpointName(ingestion.IntegrationJira, "PROJ", JiraStageIssues, since)]
+
+	if c == nil || !c.Completed() {
+		t.Errorf("expected the backfill to be completed, got %+v", c)
+	}
+}
+
+func TestJiraBackfillResumes(t *testing.T) {
+	f := newFakeJira(t)
+	jiraProject(f)
+
+	// The second issue is on the second page, so the first page has been
+	// checkpointed by the time it fails.
+	ingestor := &jiraIngestor{failOn: "backfill:created:10002"}
+	checkpoints := newCheckpointsRepo()
+	backfill := newJiraBackfill(f, ingestor, checkpoints)
+	q := JiraQuery{Project: "PROJ", Since: since}
+
+	if _, err := backfill.Backfill(context.Background(), q); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	ingestor.take()
+	f.flush()
+
+	report, err := backfill.Backfill(context.Background(), q)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := deliveryIDs(ingestor.take()); !slices.Equal(got, []string{"backfill:created:10002"}) || report.Issues != 1 {
+		t.Errorf("expected to carry on from the second issue, delivered %v", got)
+	}
+
+	if requests := f.flush(); !slices.Equal(requests, []string{"rest/api/2/status#0", "rest/api/2/search#1"}) {
+		t.Errorf("expected to only fetch the second page, requested %v", requests)
+	}
+
+	// Once everything is backfilled, running it again does nothing.
+	if _, err := backfill.Backfill(context.Background(), q); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if requests := f.flush(); !slices.Equal(requests, []string{"rest/api/2/status#0"}) {
+		t.Errorf("expected no issues to be fetched, requested %v", requests)
+	}
+}
@@ -0,0 +1,96 @@
+// Package backfill fills in the history from before we started receiving
+// webhooks, by reading it from the sources' APIs and synthesising the webhooks
+// they would have sent. These go through the same ingestors as real ones, so
+// they produce the same events, and as both the events and the synthesised
+// deliveries have stable ids, anything we already have is ignored.
+package backfill
+
+import (
+	"strings"
+	"time"
+)
+
+type Checkpoint struct {
+	Name string
+
+	// Cursor is where to carry on from, empty to start from the beginning.
+	Cursor string
+
+	// CompletedAt is set once there's nothing left to backfill.
+	CompletedAt *time.Time
+	UpdatedAt   time.Time
+}
+
+func (c *Checkpoint) Completed() bool {
+	return c.CompletedAt != nil
+}
+
+type CheckpointsRepo interface {
+	// Get returns nil if nothing has been backfilled under the name yet.
+	Get(name string) (*Checkpoint, error)
+	Save(c *Checkpoint) error
+}
+
+// checkpointName identifies a backfill, including since so that backfilling
+// further back in time doesn't skip what was completed for a later since.
+func checkpointName(integration string, scope string, stage string, since time.Time) string {
+	return strings.Join([]string{
+		integration,
+		scope,
+		stage,
+		since.UTC().Format(time.RFC3339),
+	}, ":")
+}
+
+// pages works through the pages from the checkpoint onwards, saving the
+// checkpoint after each one. Fetch returns the cursor for the next page, and
+// whether to stop early because everything after is too old.
+func pages(checkpoints CheckpointsRepo, getNow func() time.Time, name string, first string, fetch func(cursor string) (next string, stop bool, err error)) error {
+	c, err := checkpoints.Get(name)
+
+	if err != nil {
+		return err
+	}
+
+	if c == nil {
+		c = &Checkpoint{
+			Name: name,
+		}
+	}
+
+	if c.Completed() {
+		return nil
+	}
+
+	cursor := c.Cursor
+
+	if cursor == "" {
+		cursor = first
+	}
+
+	for {
+		next, stop, err := fetch(cursor)
+
+		if err != nil {
+			return err
+		}
+
+		c.Cursor = next
+		c.UpdatedAt = getNow()
+
+		if next == "" || stop {
+			completedAt := c.UpdatedAt
+			c.CompletedAt = &completedAt
+		}
+
+		if err := checkpoints.Save(c); err != nil {
+			return err
+		}
+
+		if c.Completed() {
+			return nil
+		}
+
+		cursor = next
+	}
+}
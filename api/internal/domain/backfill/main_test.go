@@ -0,0 +1,137 @@
+package backfill
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type checkpointsRepo struct {
+	checkpoints map[string]*Checkpoint
+}
+
+func newCheckpointsRepo() *checkpointsRepo {
+	return &checkpointsRepo{checkpoints: map[string]*Checkpoint{}}
+}
+
+func (r *checkpointsRepo) Get(name string) (*Checkpoint, error) {
+	c, ok := r.checkpoints[name]
+
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *c
+
+	return &copied, nil
+}
+
+func (r *checkpointsRepo) Save(c *Checkpoint) error {
+	copied := *c
+	r.checkpoints[c.Name] = &copied
+
+	return nil
+}
+
+var now = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+func getNow() time.Time {
+	return now
+}
+
+func TestPages(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	completedAt := now.Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		checkpoint *Checkpoint
+
+		// next is the cursor after each page, stopAt stops early at the page
+		// and failAt fails the page.
+		next   map[string]string
+		stopAt string
+		failAt string
+
+		wantFetched   []string
+		wantCursor    string
+		wantCompleted bool
+		wantErr       error
+	}{
+		{
+			name:          "from the start",
+			next:          map[string]string{"1": "2", "2": "3", "3": ""},
+			wantFetched:   []string{"1", "2", "3"},
+			wantCompleted: true,
+		},
+		{
+			name:          "carries on from the checkpoint",
+			checkpoint:    &Checkpoint{Name: "test", Cursor: "2"},
+			next:          map[string]string{"1": "2", "2": "3", "3": ""},
+			wantFetched:   []string{"2", "3"},
+			wantCompleted: true,
+		},
+		{
+			name:          "stops early",
+			next:          map[string]string{"1": "2", "2": "3", "3": ""},
+			stopAt:        "2",
+			wantFetched:   []string{"1", "2"},
+			wantCompleted: true,
+		},
+		{
+			name:        "failing keeps the checkpoint at the failed page",
+			next:        map[string]string{"1": "2", "2": "3", "3": ""},
+			failAt:      "2",
+			wantFetched: []string{"1", "2"},
+			wantCursor:  "2",
+			wantErr:     errFetch,
+		},
+		{
+			name:          "already completed",
+			checkpoint:    &Checkpoint{Name: "test", CompletedAt: &completedAt},
+			next:          map[string]string{"1": ""},
+			wantCompleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkpoints := newCheckpointsRepo()
+
+			if tt.checkpoint != nil {
+				checkpoints.Save(tt.checkpoint)
+			}
+
+			fetched := []string{}
+
+			err := pages(checkpoints, getNow, "test", "1", func(cursor string) (string, bool, error) {
+				fetched = append(fetched, cursor)
+
+				if cursor == tt.failAt {
+					return "", false, errFetch
+				}
+
+				return tt.next[cursor], cursor == tt.stopAt, nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("pages() = %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(fetched, tt.wantFetched) {
+				t.Errorf("fetched %v, want %v", fetched, tt.wantFetched)
+			}
+
+			c := checkpoints.checkpoints["test"]
+
+			if c == nil {
+				t.Fatal("expected a checkpoint to be saved")
+			}
+
+			if c.Completed() != tt.wantCompleted || (!tt.wantCompleted && c.Cursor != tt.wantCursor) {
+				t.Errorf("checkpoint = %+v, want cursor %q and completed %t", c, tt.wantCursor, tt.wantCompleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package changerequests defines the normalised events that make up the change
+// requests stream. Integrations (github etc.) translate their own payloads into
+// these, so that everything downstream only has to understand one shape.
+package changerequests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const EventOpened EventType = "opened"
+const EventClosed EventType = "closed"
+const EventMerged EventType = "merged"
+const EventReopened EventType = "reopened"
+const EventReadyForReview EventType = "ready_for_review"
+const EventConvertedToDraft EventType = "converted_to_draft"
+const EventCommitsPushed EventType = "commits_pushed"
+const EventReviewSubmitted EventType = "review_submitted"
+const EventReviewDismissed EventType = "review_dismissed"
+const EventReviewCommentAdded EventType = "review_comment_added"
+
+type ReviewState string
+
+const ReviewStateApproved ReviewState = "approved"
+const ReviewStateChangesRequested ReviewState = "changes_requested"
+const ReviewStateCommented ReviewState = "commented"
+const ReviewStateDismissed ReviewState = "dismissed"
+
+// eventIDNamespace is used to derive event ids, it must never change or
+// previously stored events will no longer be recognised as duplicates.
+var eventIDNamespace = uuid.MustParse("f4eb985f-f03a-4709-b0a4-985309f7ff50")
+
+// ChangeRequest is a snapshot of the change request at the time of the event.
+type ChangeRequest struct {
+	Number       int        `json:"number"`
+	Title        string     `json:"title"`
+	Body         string     `json:"body"`
+	URL          string     `json:"url"`
+	Author       string     `json:"author"`
+	Repository   string     `json:"repository"`
+	BaseBranch   string     `json:"base_branch"`
+	HeadBranch   string     `json:"head_branch"`
+	HeadSHA      string     `json:"head_sha"`
+	IsDraft      bool       `json:"is_draft"`
+	Additions    int        `json:"additions"`
+	Deletions    int        `json:"deletions"`
+	ChangedFiles int        `json:"changed_files"`
+	Commits      int        `json:"commits"`
+	CreatedAt    *time.Time `json:"created_at"`
+	MergedAt     *time.Time `json:"merged_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+}
+
+type Review struct {
+	ID          string      `json:"id"`
+	Author      string      `json:"author"`
+	State       ReviewState `json:"state"`
+	SubmittedAt *time.Time  `json:"submitted_at"`
+}
+
+type Comment struct {
+	ID        string     `json:"id"`
+	Author    string     `json:"author"`
+	Body      string     `json:"body"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type Commit struct {
+	SHA         string     `json:"sha"`
+	Message     string     `json:"message"`
+	Author      string     `json:"author"`
+	CommittedAt *time.Time `json:"committed_at"`
+}
+
+type Payload struct {
+	ChangeRequest ChangeRequest `json:"change_request"`
+
+	// Actor is whoever caused the event, which isn't necessarily the author of
+	// the change request.
+	Actor string `json:"actor"`
+
+	// Only set for review events.
+	Review *Review `json:"review,omitempty"`
+
+	// Only set for review comment events.
+	Comment *Comment `json:"comment,omitempty"`
+
+	// Only set when the source tells us about the commits involved.
+	Commits []Commit `json:"commits,omitempty"`
+}
+
+type Event struct {
+	ID          uuid.UUID
+	AggregateID string
+	Type        EventType
+	OccurredAt  time.Time
+
+	// SourceID is the id of the raw record (e.g. a webhook) the event was
+	// translated from.
+	SourceID          *uuid.UUID
+	SourceIntegration string
+
+	// Sequence is the event's position in the stream, in the order events were
+	// recorded rather than occurred. It's only set on events read back from the
+	// stream.
+	Sequence int64
+
+	Payload Payload
+}
+
+// NewEventID derives an id for an event from the things that identify it, so
+// that the same event reaching us twice (say from a webhook and a backfill)
+// ends up with the same id. The discriminator is for anything else needed to
+// tell events apart e.g. the id of a review.
+func NewEventID(integration string, aggregateID string, t EventType, occurredAt time.Time, discriminator string) uuid.UUID {
+	name := strings.Join([]string{
+		integration,
+		aggregateID,
+		string(t),
+		occurredAt.UTC().Format(time.RFC3339Nano),
+		discriminator,
+	}, "|")
+
+	return uuid.NewSHA1(eventIDNamespace, []byte(name))
+}
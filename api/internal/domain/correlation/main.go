@@ -0,0 +1,168 @@
+// Package correlation links change requests to the tasks they relate to, by
+// finding task keys (e.g. ABC-123) in the things people write when making a
+// change: titles, branch names, descriptions and commit messages.
+//
+// Commit messages are only searched when an event carries them. Github's pull
+// request webhooks only give the new head sha, and push webhooks aren't tied to
+// a pull request so aren't ingested, so for github commit messages are only
+// found by backfilling.
+package correlation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+const FoundInTitle = "title"
+const FoundInBranch = "branch"
+const FoundInBody = "body"
+const FoundInCommit = "commit"
+
+type Config interface {
+	CorrelationProjectKeyPatterns() []string
+}
+
+type Link struct {
+	ID                uuid.UUID
+	ChangeRequestID   string
+	SourceIntegration string
+	TaskKey           string
+	FoundIn           string
+	CreatedAt         time.Time
+}
+
+type LinksRepo interface {
+	// Create stores the links, skipping any that already exist.
+	Create(links []*Link) error
+}
+
+type linkSource struct {
+	foundIn string
+	text    string
+}
+
+type LinkerOpt func(*Linker)
+
+// WithCustomNowProvider allows you to override the way we generate a timestamp
+// for now. By default it will use the dt.NowUTC function.
+func WithCustomNowProvider(f func() time.Time) LinkerOpt {
+	return func(l *Linker) {
+		l.getNow = f
+	}
+}
+
+type Linker struct {
+	repo    LinksRepo
+	keyExpr *regexp.Regexp
+	getNow  func() time.Time
+}
+
+// ExtractKeys finds all the task keys in the text, in the order they appear,
+// without duplicates.
+func (l *Linker) ExtractKeys(text string) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+
+	for _, key := range l.keyExpr.FindAllString(text, -1) {
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Link records a link between each change request and any task keys found in
+// the events for it.
+func (l *Linker) Link(events []changerequests.Event) error {
+	links := []*Link{}
+	seen := map[string]bool{}
+
+	for _, e := range events {
+		sources := []linkSource{
+			{FoundInTitle, e.Payload.ChangeRequest.Title},
+			{FoundInBranch, e.Payload.ChangeRequest.HeadBranch},
+			{FoundInBody, e.Payload.ChangeRequest.Body},
+		}
+
+		for _, commit := range e.Payload.Commits {
+			sources = append(sources, linkSource{FoundInCommit, commit.Message})
+		}
+
+		for _, source := range sources {
+			for _, key := range l.ExtractKeys(source.text) {
+				id := strings.Join([]string{e.SourceIntegration, e.AggregateID, key}, "|")
+
+				if seen[id] {
+					continue
+				}
+
+				seen[id] = true
+				links = append(links, &Link{
+					ID:                uuid.New(),
+					ChangeRequestID:   e.AggregateID,
+					SourceIntegration: e.SourceIntegration,
+					TaskKey:           key,
+					FoundIn:           source.foundIn,
+					CreatedAt:         l.getNow(),
+				})
+			}
+		}
+	}
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	return l.repo.Create(links)
+}
+
+// buildKeyExpr builds a single expression matching a key for any of the given
+// project key patterns e.g. ABC would match ABC-123.
+func buildKeyExpr(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("at least one project key pattern is required")
+	}
+
+	groups := make([]string, len(patterns))
+
+	for i, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid project key pattern '%s': %w", p, err)
+		}
+
+		groups[i] = fmt.Sprintf("(?:%s)", p)
+	}
+
+	return regexp.Compile(fmt.Sprintf(`\b(?:%s)-[1-9][0-9]*\b`, strings.Join(groups, "|")))
+}
+
+func NewLinker(cfg Config, repo LinksRepo, opts ...LinkerOpt) (*Linker, error) {
+	keyExpr, err := buildKeyExpr(cfg.CorrelationProjectKeyPatterns())
+
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Linker{
+		repo:    repo,
+		keyExpr: keyExpr,
+		getNow:  dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,194 @@
+package correlation
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type config []string
+
+func (c config) CorrelationProjectKeyPatterns() []string {
+	return c
+}
+
+type linksRepo struct {
+	calls int
+	links []*Link
+}
+
+func (r *linksRepo) Create(links []*Link) error {
+	r.calls++
+	r.links = append(r.links, links...)
+
+	return nil
+}
+
+// defaultPatterns matches the default config.
+var defaultPatterns = config{"[A-Z][A-Z0-9_]+"}
+
+func newTestLinker(t *testing.T, patterns config, repo *linksRepo) *Linker {
+	t.Helper()
+
+	l, err := NewLinker(patterns, repo, WithCustomNowProvider(func() time.Time { return now }))
+
+	if err != nil {
+		t.Fatalf("failed to create linker: %s", err)
+	}
+
+	return l
+}
+
+func TestBuildKeyExpr(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		valid    bool
+	}{
+		{name: "default", patterns: defaultPatterns, valid: true},
+		{name: "several", patterns: []string{"ABC", "XY[0-9]"}, valid: true},
+		{name: "none", patterns: []string{}},
+		{name: "invalid", patterns: []string{"ABC", "[A-Z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildKeyExpr(tt.patterns)
+
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("expected valid to be %t, got error: %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestExtractKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns config
+		text     string
+		want     []string
+	}{
+		{name: "title", patterns: defaultPatterns, text: "ABC-123 fix the thing", want: []string{"ABC-123"}},
+		{name: "branch", patterns: defaultPatterns, text: "feature/ABC-12-the-thing", want: []string{"ABC-12"}},
+		{name: "several in order", patterns: defaultPatterns, text: "XY-2, ABC-1 and XY-1", want: []string{"XY-2", "ABC-1", "XY-1"}},
+		{name: "duplicates", patterns: defaultPatterns, text: "ABC-1 ABC-2 ABC-1", want: []string{"ABC-1", "ABC-2"}},
+		{name: "digits and underscores in the project", patterns: defaultPatterns, text: "A1_B-7", want: []string{"A1_B-7"}},
+		{name: "lowercase", patterns: defaultPatterns, text: "abc-123", want: []string{}},
+		{name: "single letter project", patterns: defaultPatterns, text: "A-1", want: []string{}},
+		{name: "zero", patterns: defaultPatterns, text: "ABC-0", want: []string{}},
+		{name: "leading zero", patterns: defaultPatterns, text: "ABC-012", want: []string{}},
+		{name: "part of a word", patterns: defaultPatterns, text: "ABC-12a xABC-3", want: []string{}},
+		{name: "no number", patterns: defaultPatterns, text: "ABC- ABC", want: []string{}},
+		{name: "empty", patterns: defaultPatterns, text: "", want: []string{}},
+		{name: "default finds false positives", patterns: defaultPatterns, text: "encode as UTF-8", want: []string{"UTF-8"}},
+		{name: "custom", patterns: config{"ABC", "XY"}, text: "ABC-1 XY-2 DEF-3", want: []string{"ABC-1", "XY-2"}},
+		{name: "custom avoids false positives", patterns: config{"ABC"}, text: "encode ABC-1 as UTF-8", want: []string{"ABC-1"}},
+		{name: "custom only matches the whole project", patterns: config{"ABC", "XY"}, text: "XABC-1 ABCD-2 XYZ-3", want: []string{}},
+		{name: "custom regular expression", patterns: config{"OPS[0-9]?"}, text: "OPS-1 OPS2-2 OPS22-3", want: []string{"OPS-1", "OPS2-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestLinker(t, tt.patterns, &linksRepo{}).ExtractKeys(tt.text)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func event(aggregateID string, cr changerequests.ChangeRequest, commitMessages ...string) changerequests.Event {
+	e := changerequests.Event{
+		AggregateID: aggregateID,
+		SourceIntegration: "github",
+		Payload: changerequests.Payload{ChangeRequest: cr},
+	}
+
+	for _, msg := range commitMessages {
+		e.Payload.Commits = append(e.Payload.Commits, changerequests.Commit{Message: msg})
+	}
+
+	return e
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []changerequests.Event
+
+		// want are the links as change request, key and where it was found.
+		want [][3]string
+	}{
+		{
+			name: "every source",
+			events: []changerequests.Event{
+				event("1", changerequests.ChangeRequest{Title: "ABC-1", HeadBranch: "ABC-2", Body: "ABC-3"}, "ABC-4"),
+			},
+			want: [][3]string{
+				{"1", "ABC-1", FoundInTitle},
+				{"1", "ABC-2", FoundInBranch},
+				{"1", "ABC-3", FoundInBody},
+				{"1", "ABC-4", FoundInCommit},
+			},
+		},
+		{
+			name: "first source wins",
+			events: []changerequests.Event{
+				event("1", changerequests.ChangeRequest{Title: "ABC-1", HeadBranch: "ABC-1"}, "ABC-1"),
+			},
+			want: [][3]string{{"1", "ABC-1", FoundInTitle}},
+		},
+		{
+			name: "once per change request",
+			events: []changerequests.Event{
+				event("1", changerequests.ChangeRequest{Title: "ABC-1"}),
+				event("1", changerequests.ChangeRequest{Title: "ABC-1"}),
+				event("2", changerequests.ChangeRequest{Title: "ABC-1"}),
+			},
+			want: [][3]string{
+				{"1", "ABC-1", FoundInTitle},
+				{"2", "ABC-1", FoundInTitle},
+			},
+		},
+		{
+			name: "nothing found",
+			events: []changerequests.Event{
+				event("1", changerequests.ChangeRequest{Title: "fix the thing"}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &linksRepo{}
+
+			if err := newTestLinker(t, defaultPatterns, repo).Link(tt.events); err != nil {
+				t.Fatalf("failed to link: %s", err)
+			}
+
+			if len(tt.want) == 0 && repo.calls != 0 {
+				t.Error("expected nothing to be stored")
+			}
+
+			got := [][3]string{}
+
+			for _, l := range repo.links {
+				got = append(got, [3]string{l.ChangeRequestID, l.TaskKey, l.FoundIn})
+
+				if l.SourceIntegration != "github" || !l.CreatedAt.Equal(now) {
+					t.Errorf("unexpected link: %+v", l)
+				}
+			}
+
+			if len(tt.want) > 0 && !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package dora
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+)
+
+// These only declare the parts of github's payloads that we care about, see:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads
+//
+// The repo cuts the payloads down to just these fields, so reading another
+// one means adding it there too.
+
+type githubRepository struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type githubDeploymentStatusWebhook struct {
+	Deployment struct {
+		ID          int64  `json:"id"`
+		SHA         string `json:"sha"`
+		Environment string `json:"environment"`
+	} `json:"deployment"`
+	DeploymentStatus struct {
+		ID          int64     `json:"id"`
+		State       string    `json:"state"`
+		Environment string    `json:"environment"`
+		CreatedAt   time.Time `json:"created_at"`
+	} `json:"deployment_status"`
+	Repository githubRepository `json:"repository"`
+}
+
+type githubReleaseWebhook struct {
+	Action  string `json:"action"`
+	Release struct {
+		ID              int64      `json:"id"`
+		TargetCommitish string     `json:"target_commitish"`
+		Draft           bool       `json:"draft"`
+		Prerelease      bool       `json:"prerelease"`
+		PublishedAt     *time.Time `json:"published_at"`
+	} `json:"release"`
+	Repository githubRepository `json:"repository"`
+}
+
+type githubCommit struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type githubPushWebhook struct {
+	Ref        string           `json:"ref"`
+	Deleted    bool             `json:"deleted"`
+	HeadCommit *githubCommit    `json:"head_commit"`
+	Commits    []githubCommit   `json:"commits"`
+	Repository githubRepository `json:"repository"`
+}
+
+type githubPullRequestWebhook struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Merged         bool       `json:"merged"`
+		MergeCommitSHA string     `json:"merge_commit_sha"`
+		CreatedAt      *time.Time `json:"created_at"`
+	} `json:"pull_request"`
+	Repository githubRepository `json:"repository"`
+}
+
+// githubEvents are the webhooks needed to work out the metrics, with the
+// actions we care about for each.
+var githubEvents = map[string][]string{
+	"deployment_status": {},
+	"release":           {"published"},
+	"push":              {},
+	"pull_request":      {"closed"},
+}
+
+const deploymentStateSuccess = "success"
+const deploymentStateFailure = "failure"
+const deploymentStateError = "error"
+
+// facts is everything we know about the repositories' deployments and
+// changes, pulled out of the raw webhooks.
+type facts struct {
+	deployments []*Deployment
+	changes     []*Change
+}
+
+// decode round trips the payload through json rather than picking through the
+// map by hand.
+func decode(payload map[string]any, dest any) error {
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, dest)
+}
+
+func collectGithubFacts(webhooks []*ingestion.GithubWebhook, prodEnvs []string, releasesAsDeployments bool) (*facts, error) {
+	isProd := map[string]bool{}
+
+	for _, env := range prodEnvs {
+		isProd[strings.ToLower(env)] = true
+	}
+
+	// Github sends a status for each step of a deployment (queued, in_progress
+	// etc.) and may send the same status more than once if it's redelivered
+	// under a new delivery id, so only the latest final status counts.
+	deployments := map[int64]*Deployment{}
+	statuses := map[int64]int64{}
+	releases := map[int64]bool{}
+
+	pushes := []githubPushWebhook{}
+	prCreatedAt := map[string]time.Time{}
+
+	f := &facts{}
+
+	for _, w := range webhooks {
+		switch w.Event {
+		case "deployment_status":
+			var hook githubDeploymentStatusWebhook
+
+			if err := decode(w.Payload, &hook); err != nil {
+				return nil, err
+			}
+
+			status := hook.DeploymentStatus
+			env := status.Environment
+
+			if env == "" {
+				env = hook.Deployment.Environment
+			}
+
+			if !isProd[strings.ToLower(env)] {
+				continue
+			}
+
+			switch status.State {
+			case deploymentStateSuccess, deploymentStateFailure, deploymentStateError:
+			default:
+				continue
+			}
+
+			existing, ok := deployments[hook.Deployment.ID]
+
+			if ok && (status.CreatedAt.Before(existing.At) || (status.CreatedAt.Equal(existing.At) && status.ID <= statuses[hook.Deployment.ID])) {
+				continue
+			}
+
+			deployments[hook.Deployment.ID] = &Deployment{
+				ID:          strconv.FormatInt(hook.Deployment.ID, 10),
+				Repository:  hook.Repository.FullName,
+				Environment: env,
+				SHA:         hook.Deployment.SHA,
+				Failed:      status.State != deploymentStateSuccess,
+				At:          status.CreatedAt,
+			}
+			statuses[hook.Deployment.ID] = status.ID
+
+		case "release":
+			if !releasesAsDeployments {
+				continue
+			}
+
+			var hook githubReleaseWebhook
+
+			if err := decode(w.Payload, &hook); err != nil {
+				return nil, err
+			}
+
+			r := hook.Release
+
+			if hook.Action != "published" || r.Draft || r.Prerelease || r.PublishedAt == nil || releases[r.ID] {
+				continue
+			}
+
+			releases[r.ID] = true
+			f.deployments = append(f.deployments, &Deployment{
+				ID:          "release:" + strconv.FormatInt(r.ID, 10),
+				Repository:  hook.Repository.FullName,
+				Environment: "release",
+				SHA:         r.TargetCommitish,
+				At:          *r.PublishedAt,
+			})
+
+		case "push":
+			var hook githubPushWebhook
+
+			if err := decode(w.Payload, &hook); err != nil {
+				return nil, err
+			}
+
+			// Only changes that land on the default branch can be deployed.
+			if hook.Deleted || hook.HeadCommit == nil || hook.Ref != "refs/heads/"+hook.Repository.DefaultBranch {
+				continue
+			}
+
+			pushes = append(pushes, hook)
+
+		case "pull_request":
+			var hook githubPullRequestWebhook
+
+			if err := decode(w.Payload, &hook); err != nil {
+				return nil, err
+			}
+
+			pr := hook.PullRequest
+
+			if hook.Action != "closed" || !pr.Merged || pr.MergeCommitSHA == "" || pr.CreatedAt == nil {
+				continue
+			}
+
+			prCreatedAt[pr.MergeCommitSHA] = *pr.CreatedAt
+		}
+	}
+
+	for _, d := range deployments {
+		f.deployments = append(f.deployments, d)
+	}
+
+	sort.SliceStable(pushes, func(i, j int) bool {
+		return pushes[i].HeadCommit.Timestamp.Before(pushes[j].HeadCommit.Timestamp)
+	})
+
+	seenCommits := map[string]bool{}
+
+	for _, p := range pushes {
+		for _, c := range p.Commits {
+			key := p.Repository.FullName + "|" + c.ID
+
+			// Commits can show up in more than one push e.g. after a force
+			// push, we only want the first time they landed.
+			if seenCommits[key] {
+				continue
+			}
+
+			seenCommits[key] = true
+			committedAt := c.Timestamp
+
+			// Squash and merge commits are timestamped when the pull request
+			// was merged, which would hide the time spent on it. The webhook
+			// doesn't tell us when its first commit was made, so the time it
+			// was opened is the best we have.
+			if createdAt, ok := prCreatedAt[c.ID]; ok && createdAt.Before(committedAt) {
+				committedAt = createdAt
+			}
+
+			f.changes = append(f.changes, &Change{
+				Repository:  p.Repository.FullName,
+				SHA:         c.ID,
+				CommittedAt: committedAt,
+				LandedAt:    p.HeadCommit.Timestamp,
+			})
+		}
+	}
+
+	return f, nil
+}
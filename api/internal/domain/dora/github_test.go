@@ -0,0 +1,208 @@
+package dora
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+)
+
+func webhook(t *testing.T, event string, body string) *ingestion.GithubWebhook {
+	t.Helper()
+
+	payload := map[string]any{}
+
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+
+	return &ingestion.GithubWebhook{Event: event, Payload: payload}
+}
+
+const repository = `"repository": {"full_name": "acme/app", "default_branch": "main"}`
+
+func deploymentStatus(t *testing.T, deploymentID int, statusID int, env string, state string, at string) *ingestion.GithubWebhook {
+	return webhook(t, "deployment_status", `{
+		"deployment": {"id": `+strconv.Itoa(deploymentID)+`, "sha": "abc", "environment": "`+env+`"},
+		"deployment_status": {"id": `+strconv.Itoa(statusID)+`, "state": "`+state+`", "created_at": "`+at+`"},
+		`+repository+`
+	}`)
+}
+
+func TestCollectGithubFactsDeployments(t *testing.T) {
+	type want struct {
+		id     string
+		failed bool
+		at     string
+	}
+
+	tests := []struct {
+		name     string
+		releases bool
+		webhooks func(t *testing.T) []*ingestion.GithubWebhook
+		want     []want
+	}{
+		{
+			name: "only final statuses in production count",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					deploymentStatus(t, 1, 10, "production", "in_progress", "2024-01-01T10:00:00Z"),
+					deploymentStatus(t, 1, 11, "production", "success", "2024-01-01T10:05:00Z"),
+					deploymentStatus(t, 2, 20, "staging", "success", "2024-01-01T11:00:00Z"),
+					deploymentStatus(t, 3, 30, "Production", "error", "2024-01-01T12:00:00Z"),
+				}
+			},
+			want: []want{
+				{id: "1", at: "2024-01-01T10:05:00Z"},
+				{id: "3", failed: true, at: "2024-01-01T12:00:00Z"},
+			},
+		},
+		{
+			name: "the latest final status wins, whatever order they arrive in",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					deploymentStatus(t, 1, 12, "production", "success", "2024-01-01T10:10:00Z"),
+					deploymentStatus(t, 1, 11, "production", "failure", "2024-01-01T10:05:00Z"),
+					deploymentStatus(t, 1, 12, "production", "success", "2024-01-01T10:10:00Z"),
+				}
+			},
+			want: []want{
+				{id: "1", at: "2024-01-01T10:10:00Z"},
+			},
+		},
+		{
+			name:     "published releases are deployments when enabled",
+			releases: true,
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					webhook(t, "release", `{"action": "published", "release": {"id": 5, "target_commitish": "main", "published_at": "2024-01-02T10:00:00Z"}, `+repository+`}`),
+					webhook(t, "release", `{"action": "published", "release": {"id": 5, "target_commitish": "main", "published_at": "2024-01-02T10:00:00Z"}, `+repository+`}`),
+					webhook(t, "release", `{"action": "published", "release": {"id": 6, "prerelease": true, "published_at": "2024-01-03T10:00:00Z"}, `+repository+`}`),
+				}
+			},
+			want: []want{
+				{id: "release:5", at: "2024-01-02T10:00:00Z"},
+			},
+		},
+		{
+			name: "releases are ignored by default",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					webhook(t, "release", `{"action": "published", "release": {"id": 5, "published_at": "2024-01-02T10:00:00Z"}, `+repository+`}`),
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := collectGithubFacts(tt.webhooks(t), []string{"production"}, tt.releases)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(f.deployments) != len(tt.want) {
+				t.Fatalf("expected %d deployments, got %d", len(tt.want), len(f.deployments))
+			}
+
+			byID := map[string]*Deployment{}
+
+			for _, d := range f.deployments {
+				byID[d.ID] = d
+			}
+
+			for _, w := range tt.want {
+				d, ok := byID[w.id]
+
+				if !ok {
+					t.Errorf("missing deployment %s", w.id)
+					continue
+				}
+
+				at, _ := time.Parse(time.RFC3339, w.at)
+
+				if d.Failed != w.failed || !d.At.Equal(at) || d.Repository != "acme/app" {
+					t.Errorf("deployment %s = %+v, want failed %t at %s", w.id, d, w.failed, w.at)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectGithubFactsChanges(t *testing.T) {
+	push := func(t *testing.T, ref string, head string, commits string) *ingestion.GithubWebhook {
+		return webhook(t, "push", `{"ref": "`+ref+`", "head_commit": {"id": "head", "timestamp": "`+head+`"}, "commits": [`+commits+`], `+repository+`}`)
+	}
+
+	tests := []struct {
+		name     string
+		webhooks func(t *testing.T) []*ingestion.GithubWebhook
+		want     map[string]string
+	}{
+		{
+			name: "commits pushed to the default branch",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					push(t, "refs/heads/main", "2024-01-01T12:00:00Z", `{"id": "a", "timestamp": "2024-01-01T09:00:00Z"}, {"id": "b", "timestamp": "2024-01-01T10:00:00Z"}`),
+					push(t, "refs/heads/feature", "2024-01-01T12:00:00Z", `{"id": "c", "timestamp": "2024-01-01T11:00:00Z"}`),
+				}
+			},
+			want: map[string]string{"a": "2024-01-01T09:00:00Z", "b": "2024-01-01T10:00:00Z"},
+		},
+		{
+			name: "commits pushed more than once keep the first",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					push(t, "refs/heads/main", "2024-01-02T12:00:00Z", `{"id": "a", "timestamp": "2024-01-01T09:00:00Z"}`),
+					push(t, "refs/heads/main", "2024-01-01T12:00:00Z", `{"id": "a", "timestamp": "2024-01-01T09:00:00Z"}`),
+				}
+			},
+			want: map[string]string{"a": "2024-01-01T09:00:00Z"},
+		},
+		{
+			name: "squash merges count from when the pull request was opened",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					webhook(t, "pull_request", `{"action": "closed", "pull_request": {"merged": true, "merge_commit_sha": "squash", "created_at": "2023-12-30T09:00:00Z"}, `+repository+`}`),
+					push(t, "refs/heads/main", "2024-01-01T12:00:00Z", `{"id": "squash", "timestamp": "2024-01-01T12:00:00Z"}`),
+				}
+			},
+			want: map[string]string{"squash": "2023-12-30T09:00:00Z"},
+		},
+		{
+			name: "deleting the branch isn't a change",
+			webhooks: func(t *testing.T) []*ingestion.GithubWebhook {
+				return []*ingestion.GithubWebhook{
+					webhook(t, "push", `{"ref": "refs/heads/main", "deleted": true, "commits": [], `+repository+`}`),
+				}
+			},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := collectGithubFacts(tt.webhooks(t), []string{"production"}, false)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(f.changes) != len(tt.want) {
+				t.Fatalf("expected %d changes, got %d", len(tt.want), len(f.changes))
+			}
+
+			for _, c := range f.changes {
+				want, ok := tt.want[c.SHA]
+				at, _ := time.Parse(time.RFC3339, want)
+
+				if !ok || !c.CommittedAt.Equal(at) {
+					t.Errorf("change %s committed at %v, want %s", c.SHA, c.CommittedAt, want)
+				}
+			}
+		})
+	}
+}
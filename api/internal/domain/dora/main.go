@@ -0,0 +1,351 @@
+// Package dora works out the four DORA metrics (deployment frequency, lead
+// time for changes, change failure rate and time to restore service) from the
+// raw github webhooks we've ingested.
+//
+// Deployments come from deployment statuses in a production environment (and
+// optionally published releases), changes are the commits pushed to a
+// repository's default branch. A change is considered deployed by the first
+// successful deployment of its repository after it landed, we don't have the
+// commit graph to do any better.
+package dora
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+)
+
+var ErrUnknownTeam = errors.New("unknown team")
+var ErrInvalidWindow = errors.New("from must be before to")
+
+type Config interface {
+	DoraProductionEnvironments() []string
+	DoraReleasesAsDeployments() bool
+	DoraTeamRepositories(team string) ([]string, bool)
+	DoraLookback() time.Duration
+}
+
+type GithubWebhooksFilter struct {
+	// Events maps the events to include to the actions to include for each,
+	// all actions are included for an event with none.
+	Events map[string][]string
+
+	// Repositories limits the webhooks to those for the given repositories (by
+	// full name e.g. org/repo), all repositories are included if empty.
+	Repositories []string
+
+	// Since excludes webhooks that occurred before it.
+	Since time.Time
+}
+
+type GithubWebhooksRepo interface {
+	// List returns the webhooks matching the filter, oldest first. Their
+	// payloads only include the fields this package reads.
+	List(f GithubWebhooksFilter) ([]*ingestion.GithubWebhook, error)
+}
+
+type Deployment struct {
+	ID          string
+	Repository  string
+	Environment string
+	SHA         string
+	Failed      bool
+	At          time.Time
+}
+
+type Change struct {
+	Repository  string
+	SHA         string
+	CommittedAt time.Time
+
+	// LandedAt is when the change was pushed to the default branch.
+	LandedAt time.Time
+}
+
+type Query struct {
+	From         time.Time
+	To           time.Time
+	Repositories []string
+	Team         string
+}
+
+type DurationSummary struct {
+	Count         int     `json:"count"`
+	MeanSeconds   float64 `json:"mean_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	P90Seconds    float64 `json:"p90_seconds"`
+}
+
+type DeploymentFrequency struct {
+	Deployments        int     `json:"deployments"`
+	DaysWithDeployment int     `json:"days_with_deployment"`
+	PerDay             float64 `json:"per_day"`
+	PerWeek            float64 `json:"per_week"`
+}
+
+type LeadTime struct {
+	DurationSummary
+}
+
+type ChangeFailureRate struct {
+	Deployments int     `json:"deployments"`
+	Failed      int     `json:"failed"`
+	Rate        float64 `json:"rate"`
+}
+
+type TimeToRestore struct {
+	DurationSummary
+
+	// Unrestored is how many failures in the window haven't been followed by
+	// a successful deployment yet.
+	Unrestored int `json:"unrestored"`
+}
+
+type Report struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Repositories the report is limited to, empty means all of them.
+	Repositories []string `json:"repositories"`
+
+	DeploymentFrequency DeploymentFrequency `json:"deployment_frequency"`
+	LeadTime            LeadTime            `json:"lead_time"`
+	ChangeFailureRate   ChangeFailureRate   `json:"change_failure_rate"`
+	TimeToRestore       TimeToRestore       `json:"time_to_restore"`
+}
+
+type Service struct {
+	repo GithubWebhooksRepo
+	cfg  Config
+}
+
+func (s *Service) repositories(q Query) ([]string, error) {
+	repos := append([]string{}, q.Repositories...)
+
+	if q.Team == "" {
+		return repos, nil
+	}
+
+	teamRepos, ok := s.cfg.DoraTeamRepositories(q.Team)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTeam, q.Team)
+	}
+
+	return append(repos, teamRepos...), nil
+}
+
+// Report works out all four metrics for the window [From, To).
+//
+// Webhooks from before the window, back as far as the configured lookback, are
+// considered too, as the deployment before the window is needed to work out
+// which changes went out in the first deployment inside it. Those after the
+// window are needed to find when failures inside it were restored.
+func (s *Service) Report(q Query) (*Report, error) {
+	if !q.From.Before(q.To) {
+		return nil, ErrInvalidWindow
+	}
+
+	repos, err := s.repositories(q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.repo.List(GithubWebhooksFilter{
+		Events:       githubEvents,
+		Repositories: repos,
+		Since:        q.From.Add(-s.cfg.DoraLookback()),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := collectGithubFacts(webhooks, s.cfg.DoraProductionEnvironments(), s.cfg.DoraReleasesAsDeployments())
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(f.deployments, func(i, j int) bool {
+		return f.deployments[i].At.Before(f.deployments[j].At)
+	})
+
+	return &Report{
+		From:                q.From,
+		To:                  q.To,
+		Repositories:        repos,
+		DeploymentFrequency: deploymentFrequency(f.deployments, q.From, q.To),
+		LeadTime:            leadTime(f, q.From, q.To),
+		ChangeFailureRate:   changeFailureRate(f.deployments, q.From, q.To),
+		TimeToRestore:       timeToRestore(f.deployments, q.From, q.To),
+	}, nil
+}
+
+func inWindow(t time.Time, from time.Time, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+func deploymentFrequency(deployments []*Deployment, from time.Time, to time.Time) DeploymentFrequency {
+	df := DeploymentFrequency{}
+	days := map[string]bool{}
+
+	for _, d := range deployments {
+		if d.Failed || !inWindow(d.At, from, to) {
+			continue
+		}
+
+		df.Deployments++
+		days[d.At.UTC().Format(time.DateOnly)] = true
+	}
+
+	df.DaysWithDeployment = len(days)
+
+	windowDays := to.Sub(from).Hours() / 24
+	df.PerDay = float64(df.Deployments) / windowDays
+	df.PerWeek = df.PerDay * 7
+
+	return df
+}
+
+// leadTime is the time from each change being committed to it being deployed,
+// for the changes deployed in the window. Deployments must be sorted.
+func leadTime(f *facts, from time.Time, to time.Time) LeadTime {
+	changes := map[string][]*Change{}
+
+	for _, c := range f.changes {
+		changes[c.Repository] = append(changes[c.Repository], c)
+	}
+
+	durations := []time.Duration{}
+	previous := map[string]time.Time{}
+
+	for _, d := range f.deployments {
+		if d.Failed {
+			continue
+		}
+
+		prev, hasPrev := previous[d.Repository]
+		previous[d.Repository] = d.At
+
+		if !inWindow(d.At, from, to) {
+			continue
+		}
+
+		for _, c := range changes[d.Repository] {
+			if c.LandedAt.After(d.At) || (hasPrev && !c.LandedAt.After(prev)) {
+				continue
+			}
+
+			durations = append(durations, max(d.At.Sub(c.CommittedAt), 0))
+		}
+	}
+
+	return LeadTime{summarise(durations)}
+}
+
+func changeFailureRate(deployments []*Deployment, from time.Time, to time.Time) ChangeFailureRate {
+	cfr := ChangeFailureRate{}
+
+	for _, d := range deployments {
+		if !inWindow(d.At, from, to) {
+			continue
+		}
+
+		cfr.Deployments++
+
+		if d.Failed {
+			cfr.Failed++
+		}
+	}
+
+	if cfr.Deployments > 0 {
+		cfr.Rate = float64(cfr.Failed) / float64(cfr.Deployments)
+	}
+
+	return cfr
+}
+
+// timeToRestore is the time from a failed deployment to the next successful
+// deployment to the same environment, for failures in the window. A run of
+// failures counts as one, from the first of them. Deployments must be sorted.
+func timeToRestore(deployments []*Deployment, from time.Time, to time.Time) TimeToRestore {
+	failedAt := map[string]time.Time{}
+	durations := []time.Duration{}
+
+	for _, d := range deployments {
+		key := d.Repository + "|" + d.Environment
+		start, failing := failedAt[key]
+
+		if d.Failed {
+			if !failing {
+				failedAt[key] = d.At
+			}
+
+			continue
+		}
+
+		if !failing {
+			continue
+		}
+
+		delete(failedAt, key)
+
+		if inWindow(start, from, to) {
+			durations = append(durations, d.At.Sub(start))
+		}
+	}
+
+	ttr := TimeToRestore{DurationSummary: summarise(durations)}
+
+	for _, start := range failedAt {
+		if inWindow(start, from, to) {
+			ttr.Unrestored++
+		}
+	}
+
+	return ttr
+}
+
+func summarise(durations []time.Duration) DurationSummary {
+	s := DurationSummary{Count: len(durations)}
+
+	if len(durations) == 0 {
+		return s
+	}
+
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	var total time.Duration
+
+	for _, d := range durations {
+		total += d
+	}
+
+	s.MeanSeconds = total.Seconds() / float64(len(durations))
+	s.MedianSeconds = percentile(durations, 0.5).Seconds()
+	s.P90Seconds = percentile(durations, 0.9).Seconds()
+
+	return s
+}
+
+// percentile uses the nearest rank method, durations must be sorted.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(durations))))
+
+	return durations[max(rank, 1)-1]
+}
+
+func NewService(repo GithubWebhooksRepo, cfg Config) *Service {
+	return &Service{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
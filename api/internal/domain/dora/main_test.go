@@ -0,0 +1,357 @@
+package dora
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+)
+
+var day0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func hours(h int) time.Time {
+	return day0.Add(time.Duration(h) * time.Hour)
+}
+
+func deployed(repo string, env string, at time.Time, failed bool) *Deployment {
+	return &Deployment{Repository: repo, Environment: env, At: at, Failed: failed}
+}
+
+func TestDeploymentFrequency(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployments []*Deployment
+		to          time.Time
+		want        DeploymentFrequency
+	}{
+		{
+			name: "no deployments",
+			to:   hours(7 * 24),
+			want: DeploymentFrequency{},
+		},
+		{
+			name: "successful deployments in the window",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(1), false),
+				deployed("acme/app", "production", hours(2), false),
+				deployed("acme/app", "production", hours(30), false),
+				deployed("acme/app", "production", hours(40), true),
+			},
+			to:   hours(7 * 24),
+			want: DeploymentFrequency{Deployments: 3, DaysWithDeployment: 2, PerDay: 3.0 / 7, PerWeek: 3},
+		},
+		{
+			name: "the window is inclusive of from and exclusive of to",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(-1), false),
+				deployed("acme/app", "production", hours(0), false),
+				deployed("acme/app", "production", hours(48), false),
+			},
+			to:   hours(48),
+			want: DeploymentFrequency{Deployments: 1, DaysWithDeployment: 1, PerDay: 0.5, PerWeek: 3.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deploymentFrequency(tt.deployments, day0, tt.to); got != tt.want {
+				t.Errorf("deploymentFrequency() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeadTime(t *testing.T) {
+	change := func(repo string, committedAt time.Time, landedAt time.Time) *Change {
+		return &Change{Repository: repo, CommittedAt: committedAt, LandedAt: landedAt}
+	}
+
+	tests := []struct {
+		name string
+		f    *facts
+		want DurationSummary
+	}{
+		{
+			name: "changes go out with the next successful deployment",
+			f: &facts{
+				deployments: []*Deployment{
+					deployed("acme/app", "production", hours(10), true),
+					deployed("acme/app", "production", hours(12), false),
+				},
+				changes: []*Change{
+					change("acme/app", hours(0), hours(4)),
+					change("acme/app", hours(8), hours(9)),
+				},
+			},
+			want: DurationSummary{Count: 2, MeanSeconds: 8 * 3600, MedianSeconds: 4 * 3600, P90Seconds: 12 * 3600},
+		},
+		{
+			name: "changes deployed before the window aren't counted again",
+			f: &facts{
+				deployments: []*Deployment{
+					deployed("acme/app", "production", hours(-5), false),
+					deployed("acme/app", "production", hours(5), false),
+				},
+				changes: []*Change{
+					change("acme/app", hours(-10), hours(-6)),
+					change("acme/app", hours(1), hours(2)),
+				},
+			},
+			want: DurationSummary{Count: 1, MeanSeconds: 4 * 3600, MedianSeconds: 4 * 3600, P90Seconds: 4 * 3600},
+		},
+		{
+			name: "changes that landed after the deployment wait for the next",
+			f: &facts{
+				deployments: []*Deployment{
+					deployed("acme/app", "production", hours(5), false),
+				},
+				changes: []*Change{
+					change("acme/app", hours(4), hours(6)),
+				},
+			},
+			want: DurationSummary{},
+		},
+		{
+			name: "deployments only include their own repository's changes",
+			f: &facts{
+				deployments: []*Deployment{
+					deployed("acme/app", "production", hours(5), false),
+				},
+				changes: []*Change{
+					change("acme/other", hours(1), hours(2)),
+				},
+			},
+			want: DurationSummary{},
+		},
+		{
+			name: "changes committed after they were deployed count as no time",
+			f: &facts{
+				deployments: []*Deployment{
+					deployed("acme/app", "production", hours(5), false),
+				},
+				changes: []*Change{
+					change("acme/app", hours(6), hours(4)),
+				},
+			},
+			want: DurationSummary{Count: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leadTime(tt.f, day0, hours(24)); got.DurationSummary != tt.want {
+				t.Errorf("leadTime() = %+v, want %+v", got.DurationSummary, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeFailureRate(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployments []*Deployment
+		want        ChangeFailureRate
+	}{
+		{
+			name: "no deployments",
+			want: ChangeFailureRate{},
+		},
+		{
+			name: "some failed",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(1), false),
+				deployed("acme/app", "production", hours(2), true),
+				deployed("acme/app", "production", hours(3), false),
+				deployed("acme/app", "production", hours(4), false),
+			},
+			want: ChangeFailureRate{Deployments: 4, Failed: 1, Rate: 0.25},
+		},
+		{
+			name: "failures outside the window are ignored",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(-1), true),
+				deployed("acme/app", "production", hours(1), false),
+				deployed("acme/app", "production", hours(25), true),
+			},
+			want: ChangeFailureRate{Deployments: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changeFailureRate(tt.deployments, day0, hours(24)); got != tt.want {
+				t.Errorf("changeFailureRate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeToRestore(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployments []*Deployment
+		want        TimeToRestore
+	}{
+		{
+			name: "restored by the next success",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(1), true),
+				deployed("acme/app", "production", hours(3), false),
+			},
+			want: TimeToRestore{DurationSummary: DurationSummary{Count: 1, MeanSeconds: 2 * 3600, MedianSeconds: 2 * 3600, P90Seconds: 2 * 3600}},
+		},
+		{
+			name: "a run of failures counts from the first",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(1), true),
+				deployed("acme/app", "production", hours(2), true),
+				deployed("acme/app", "production", hours(5), false),
+			},
+			want: TimeToRestore{DurationSummary: DurationSummary{Count: 1, MeanSeconds: 4 * 3600, MedianSeconds: 4 * 3600, P90Seconds: 4 * 3600}},
+		},
+		{
+			name: "only a success in the same environment restores",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(1), true),
+				deployed("acme/app", "production-eu", hours(2), false),
+				deployed("acme/other", "production", hours(3), false),
+			},
+			want: TimeToRestore{Unrestored: 1},
+		},
+		{
+			name: "restored after the window",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(23), true),
+				deployed("acme/app", "production", hours(26), false),
+			},
+			want: TimeToRestore{DurationSummary: DurationSummary{Count: 1, MeanSeconds: 3 * 3600, MedianSeconds: 3 * 3600, P90Seconds: 3 * 3600}},
+		},
+		{
+			name: "failures before the window are ignored",
+			deployments: []*Deployment{
+				deployed("acme/app", "production", hours(-2), true),
+				deployed("acme/app", "production", hours(1), false),
+			},
+			want: TimeToRestore{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timeToRestore(tt.deployments, day0, hours(24)); got != tt.want {
+				t.Errorf("timeToRestore() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1},
+		{p: 0.5, want: 5},
+		{p: 0.9, want: 9},
+		{p: 0.95, want: 10},
+		{p: 1, want: 10},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(durations, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+type webhooksRepo struct {
+	filter   GithubWebhooksFilter
+	webhooks []*ingestion.GithubWebhook
+}
+
+func (r *webhooksRepo) List(f GithubWebhooksFilter) ([]*ingestion.GithubWebhook, error) {
+	r.filter = f
+
+	return r.webhooks, nil
+}
+
+type config struct{}
+
+func (config) DoraProductionEnvironments() []string {
+	return []string{"production"}
+}
+
+func (config) DoraReleasesAsDeployments() bool {
+	return false
+}
+
+func (config) DoraTeamRepositories(team string) ([]string, bool) {
+	if team != "platform" {
+		return nil, false
+	}
+
+	return []string{"acme/infra"}, true
+}
+
+func (config) DoraLookback() time.Duration {
+	return 90 * 24 * time.Hour
+}
+
+func TestServiceReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		q         Query
+		wantRepos []string
+		wantErr   error
+	}{
+		{
+			name:      "repositories and a team",
+			q:         Query{From: day0, To: hours(24), Repositories: []string{"acme/app"}, Team: "platform"},
+			wantRepos: []string{"acme/app", "acme/infra"},
+		},
+		{
+			name:      "everything",
+			q:         Query{From: day0, To: hours(24)},
+			wantRepos: []string{},
+		},
+		{
+			name:    "unknown team",
+			q:       Query{From: day0, To: hours(24), Team: "missing"},
+			wantErr: ErrUnknownTeam,
+		},
+		{
+			name:    "empty window",
+			q:       Query{From: day0, To: day0},
+			wantErr: ErrInvalidWindow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &webhooksRepo{}
+			report, err := NewService(repo, config{}).Report(tt.q)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Report() = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !slices.Equal(report.Repositories, tt.wantRepos) || !slices.Equal(repo.filter.Repositories, tt.wantRepos) {
+				t.Errorf("repositories = %v, filtered by %v, want %v", report.Repositories, repo.filter.Repositories, tt.wantRepos)
+			}
+
+			// Webhooks from before the window are needed to find the previous
+			// deployment, but not all of them.
+			if want := day0.Add(-90 * 24 * time.Hour); !repo.filter.Since.Equal(want) {
+				t.Errorf("since = %v, want %v", repo.filter.Since, want)
+			}
+		})
+	}
+}
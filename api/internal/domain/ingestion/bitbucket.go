@@ -0,0 +1,128 @@
+package ingestion
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+const IntegrationBitbucket = "bitbucket"
+
+type BitbucketIngestorRepo interface {
+	// Create stores the webhook and the events translated from it, returning
+	// false if a webhook with the same delivery id has already been stored, in
+	// which case nothing is written.
+	Create(w *BitbucketWebhook, events []changerequests.Event) (bool, error)
+}
+
+type BitbucketWebhook struct {
+	ID         uuid.UUID
+	DeliveryID string
+	Event      string
+	OccurredAt time.Time
+	Payload    map[string]any
+}
+
+type BitbucketEvent struct {
+	Payload map[string]any
+
+	// Event is the X-Event-Key header e.g. pullrequest:created.
+	Event string
+
+	// DeliveryID is the X-Request-UUID header, without it we can't detect
+	// redeliveries.
+	DeliveryID string
+
+	// ReceivedAt defaults to now, it's set for deliveries that were queued.
+	ReceivedAt time.Time
+}
+
+type BitbucketIngestorOpt func(*BitbucketIngestor)
+
+func WithBitbucketCustomNowProvider(f func() time.Time) BitbucketIngestorOpt {
+	return func(bi *BitbucketIngestor) {
+		bi.getNow = f
+	}
+}
+
+type BitbucketIngestor struct {
+	repo    BitbucketIngestorRepo
+	secrets WebhookSecretStore
+	linker  ChangeRequestLinker
+	getNow  func() time.Time
+}
+
+// Verify checks the X-Hub-Signature header value that bitbucket sends when the
+// webhook has a secret configured, against the raw body of the request.
+func (bi *BitbucketIngestor) Verify(body []byte, signature string) error {
+	return verifySHA256Signature(bi.secrets.WebhookSecrets(IntegrationBitbucket), body, signature)
+}
+
+// Process stores the raw webhook along with the change request events it
+// translates to, ignoring redeliveries.
+func (bi *BitbucketIngestor) Process(e BitbucketEvent) error {
+	w := &BitbucketWebhook{
+		ID:         uuid.New(),
+		DeliveryID: e.DeliveryID,
+		Event:      e.Event,
+		OccurredAt: e.ReceivedAt,
+		Payload:    e.Payload,
+	}
+
+	if w.OccurredAt.IsZero() {
+		w.OccurredAt = bi.getNow()
+	}
+
+	events, err := translateBitbucketWebhook(w)
+
+	if err != nil {
+		// Still keep the raw webhook, so the events can be recovered once
+		// whatever we failed to understand is fixed.
+		slog.Error("failed to translate bitbucket webhook", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	created, err := bi.repo.Create(w, events)
+
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		slog.Debug("ignoring bitbucket redelivery", "delivery_id", e.DeliveryID)
+		return nil
+	}
+
+	// The events are already safely stored, so this isn't worth failing over.
+	if err := bi.linker.Link(events); err != nil {
+		slog.Error("failed to link bitbucket change requests to tasks", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	return nil
+}
+
+func (bi *BitbucketIngestor) ProcessQueued(item *QueueItem) error {
+	return bi.Process(BitbucketEvent{
+		Payload:    item.Payload,
+		Event:      item.Event,
+		DeliveryID: item.DeliveryID,
+		ReceivedAt: item.ReceivedAt,
+	})
+}
+
+func NewBitbucketIngestor(repo BitbucketIngestorRepo, secrets WebhookSecretStore, linker ChangeRequestLinker, opts ...BitbucketIngestorOpt) *BitbucketIngestor {
+	bi := &BitbucketIngestor{
+		repo:    repo,
+		secrets: secrets,
+		linker:  linker,
+		getNow:  dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(bi)
+	}
+
+	return bi
+}
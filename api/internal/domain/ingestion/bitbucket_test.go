@@ -0,0 +1,61 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBitbucketIngestorVerify(t *testing.T) {
+	body := []byte(`{"pullrequest":{"id":1}}`)
+
+	tests := []struct {
+		name      string
+		secrets   []string
+		signature string
+		wantErr   error
+	}{
+		{
+			name:      "valid signature",
+			secrets:   []string{"secret"},
+			signature: "sha256=" + sign("secret", body),
+		},
+		{
+			name:      "signed with a rotated secret",
+			secrets:   []string{"new", "old"},
+			signature: "sha256=" + sign("old", body),
+		},
+		{
+			name:      "signed with the wrong secret",
+			secrets:   []string{"secret"},
+			signature: "sha256=" + sign("other", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "missing algorithm prefix",
+			secrets:   []string{"secret"},
+			signature: sign("secret", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "no signature",
+			secrets:   []string{"secret"},
+			signature: "",
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "no secrets configured",
+			signature: "sha256=" + sign("", body),
+			wantErr:   ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bi := NewBitbucketIngestor(nil, secretStore{IntegrationBitbucket: tt.secrets}, nil)
+
+			if err := bi.Verify(body, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+// These only declare the parts of bitbucket's payloads that we care about, see:
+// https://support.atlassian.com/bitbucket-cloud/docs/event-payloads/
+
+type bitbucketUser struct {
+	UUID        string `json:"uuid"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+// name prefers the nickname, which is the closest bitbucket has to a
+// username, as not every user has one.
+func (u *bitbucketUser) name() string {
+	if u == nil {
+		return ""
+	}
+
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+
+	return u.DisplayName
+}
+
+type bitbucketRepository struct {
+	UUID     string `json:"uuid"`
+	FullName string `json:"full_name"`
+}
+
+type bitbucketLink struct {
+	Href string `json:"href"`
+}
+
+type bitbucketLinks struct {
+	HTML bitbucketLink `json:"html"`
+}
+
+type bitbucketBranch struct {
+	Name string `json:"name"`
+}
+
+type bitbucketCommit struct {
+	Hash string `json:"hash"`
+}
+
+type bitbucketEndpoint struct {
+	Branch bitbucketBranch  `json:"branch"`
+	Commit *bitbucketCommit `json:"commit"`
+}
+
+type bitbucketPullRequest struct {
+	ID          int               `json:"id"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	State       string            `json:"state"`
+	Draft       bool              `json:"draft"`
+	Author      *bitbucketUser    `json:"author"`
+	Source      bitbucketEndpoint `json:"source"`
+	Destination bitbucketEndpoint `json:"destination"`
+	Links       bitbucketLinks    `json:"links"`
+	CreatedOn   *time.Time        `json:"created_on"`
+	UpdatedOn   *time.Time        `json:"updated_on"`
+}
+
+type bitbucketApproval struct {
+	Date *time.Time     `json:"date"`
+	User *bitbucketUser `json:"user"`
+}
+
+type bitbucketCommentContent struct {
+	Raw string `json:"raw"`
+}
+
+type bitbucketComment struct {
+	ID        int64                   `json:"id"`
+	Content   bitbucketCommentContent `json:"content"`
+	User      *bitbucketUser          `json:"user"`
+	CreatedOn *time.Time              `json:"created_on"`
+}
+
+type bitbucketWebhook struct {
+	Actor       *bitbucketUser        `json:"actor"`
+	Repository  bitbucketRepository   `json:"repository"`
+	PullRequest *bitbucketPullRequest `json:"pullrequest"`
+
+	// Only set for approval events.
+	Approval *bitbucketApproval `json:"approval"`
+
+	// Only set for changes requested events.
+	ChangesRequest *bitbucketApproval `json:"changes_request"`
+
+	// Only set for comment events.
+	Comment *bitbucketComment `json:"comment"`
+}
+
+var bitbucketPullRequestEvents = map[string]changerequests.EventType{
+	"pullrequest:created":                 changerequests.EventOpened,
+	"pullrequest:updated":                 changerequests.EventCommitsPushed,
+	"pullrequest:fulfilled":               changerequests.EventMerged,
+	"pullrequest:rejected":                changerequests.EventClosed,
+	"pullrequest:approved":                changerequests.EventReviewSubmitted,
+	"pullrequest:changes_request_created": changerequests.EventReviewSubmitted,
+	"pullrequest:unapproved":              changerequests.EventReviewDismissed,
+	"pullrequest:changes_request_removed": changerequests.EventReviewDismissed,
+	"pullrequest:comment_created":         changerequests.EventReviewCommentAdded,
+}
+
+// translateBitbucketWebhook converts the raw webhook into change request
+// events, the same ones github's produce. Webhooks we don't care about produce
+// no events.
+func translateBitbucketWebhook(w *BitbucketWebhook) ([]changerequests.Event, error) {
+	t, ok := bitbucketPullRequestEvents[w.Event]
+
+	if !ok {
+		return nil, nil
+	}
+
+	// Round trip through json rather than picking through the map by hand.
+	raw, err := json.Marshal(w.Payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var hook bitbucketWebhook
+
+	if err := json.Unmarshal(raw, &hook); err != nil {
+		return nil, err
+	}
+
+	if hook.PullRequest == nil {
+		return nil, nil
+	}
+
+	pr := *hook.PullRequest
+
+	payload := changerequests.Payload{
+		ChangeRequest: bitbucketChangeRequest(pr, hook.Repository),
+		Actor:         hook.Actor.name(),
+	}
+
+	occurredAt := pr.UpdatedOn
+	discriminator := ""
+
+	switch w.Event {
+	case "pullrequest:created":
+		occurredAt = pr.CreatedOn
+
+	// Bitbucket sends this for any change, not just new commits, so the head
+	// commit tells apart the events for different pushes.
+	case "pullrequest:updated":
+		discriminator = payload.ChangeRequest.HeadSHA
+
+	case "pullrequest:fulfilled":
+		payload.ChangeRequest.MergedAt = pr.UpdatedOn
+		payload.ChangeRequest.ClosedAt = pr.UpdatedOn
+
+	case "pullrequest:rejected":
+		payload.ChangeRequest.ClosedAt = pr.UpdatedOn
+
+	// Bitbucket has no review ids, approvals and requests for changes are per
+	// user.
+	case "pullrequest:approved", "pullrequest:unapproved":
+		occurredAt, discriminator = bitbucketReview(&payload, hook.Approval, "approval:", t, changerequests.ReviewStateApproved)
+
+	case "pullrequest:changes_request_created", "pullrequest:changes_request_removed":
+		occurredAt, discriminator = bitbucketReview(&payload, hook.ChangesRequest, "changes_request:", t, changerequests.ReviewStateChangesRequested)
+
+	case "pullrequest:comment_created":
+		if hook.Comment == nil {
+			return nil, nil
+		}
+
+		discriminator = strconv.FormatInt(hook.Comment.ID, 10)
+		occurredAt = hook.Comment.CreatedOn
+
+		payload.Comment = &changerequests.Comment{
+			ID:        discriminator,
+			Author:    hook.Comment.User.name(),
+			Body:      hook.Comment.Content.Raw,
+			CreatedAt: hook.Comment.CreatedOn,
+		}
+	}
+
+	ts := w.OccurredAt
+
+	if occurredAt != nil {
+		ts = occurredAt.UTC()
+	}
+
+	// Pull request ids are only unique within a repository. The repository's
+	// uuid survives it being renamed, unlike its name.
+	repo := hook.Repository.UUID
+
+	if repo == "" {
+		repo = hook.Repository.FullName
+	}
+
+	aggregateID := repo + "#" + strconv.Itoa(pr.ID)
+
+	return []changerequests.Event{
+		{
+			ID:                changerequests.NewEventID(IntegrationBitbucket, aggregateID, t, ts, discriminator),
+			AggregateID:       aggregateID,
+			Type:              t,
+			OccurredAt:        ts,
+			SourceID:          &w.ID,
+			SourceIntegration: IntegrationBitbucket,
+			Payload:           payload,
+		},
+	}, nil
+}
+
+// bitbucketReview sets the review on the payload, returning when it happened
+// and the discriminator for the event.
+func bitbucketReview(payload *changerequests.Payload, approval *bitbucketApproval, prefix string, t changerequests.EventType, state changerequests.ReviewState) (*time.Time, string) {
+	author := payload.Actor
+	var submittedAt *time.Time
+
+	if approval != nil {
+		if name := approval.User.name(); name != "" {
+			author = name
+		}
+
+		submittedAt = approval.Date
+	}
+
+	if t == changerequests.EventReviewDismissed {
+		state = changerequests.ReviewStateDismissed
+	}
+
+	discriminator := prefix + author
+
+	payload.Review = &changerequests.Review{
+		ID:          discriminator,
+		Author:      author,
+		State:       state,
+		SubmittedAt: submittedAt,
+	}
+
+	return submittedAt, discriminator
+}
+
+func bitbucketChangeRequest(pr bitbucketPullRequest, repo bitbucketRepository) changerequests.ChangeRequest {
+	headSHA := ""
+
+	if pr.Source.Commit != nil {
+		headSHA = pr.Source.Commit.Hash
+	}
+
+	return changerequests.ChangeRequest{
+		Number:     pr.ID,
+		Title:      pr.Title,
+		Body:       pr.Description,
+		URL:        pr.Links.HTML.Href,
+		Author:     pr.Author.name(),
+		Repository: repo.FullName,
+		BaseBranch: pr.Destination.Branch.Name,
+		HeadBranch: pr.Source.Branch.Name,
+		HeadSHA:    headSHA,
+		IsDraft:    pr.Draft,
+		CreatedAt:  pr.CreatedOn,
+	}
+}
@@ -0,0 +1,207 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/google/uuid"
+)
+
+const bitbucketPullRequestPayload = `{
+	"id": 9,
+	"title": "Add the thing",
+	"description": "PROJ-1",
+	"author": {"nickname": "alice", "display_name": "Alice"},
+	"source": {"branch": {"name": "feature"}, "commit": {"hash": "ccc"}},
+	"destination": {"branch": {"name": "main"}},
+	"links": {"html": {"href": "https://bitbucket.org/acme/app/pull-requests/9"}},
+	"created_on": "2024-01-01T10:00:00+00:00",
+	"updated_on": "2024-01-02T10:00:00+00:00"
+}`
+
+func bitbucketHook(extra string) string {
+	hook := `{
+		"actor": {"display_name": "Bob"},
+		"repository": {"uuid": "{repo-uuid}", "full_name": "acme/app"},
+		"pullrequest": ` + bitbucketPullRequestPayload
+
+	if extra != "" {
+		hook += ", " + extra
+	}
+
+	return hook + "}"
+}
+
+func TestTranslateBitbucketWebhook(t *testing.T) {
+	receivedAt := mustTime(t, "2024-02-01T00:00:00Z")
+
+	tests := []struct {
+		name     string
+		event    string
+		payload  string
+		wantType changerequests.EventType
+		wantAt   string
+		check    func(t *testing.T, p changerequests.Payload)
+	}{
+		{
+			name:     "created",
+			event:    "pullrequest:created",
+			payload:  bitbucketHook(""),
+			wantType: changerequests.EventOpened,
+			wantAt:   "2024-01-01T10:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				cr := p.ChangeRequest
+
+				if cr.Number != 9 || cr.Author != "alice" || cr.Repository != "acme/app" || cr.HeadSHA != "ccc" || cr.BaseBranch != "main" {
+					t.Errorf("unexpected change request %+v", cr)
+				}
+
+				if p.Actor != "Bob" {
+					t.Errorf("expected the display name without a nickname, got %s", p.Actor)
+				}
+			},
+		},
+		{
+			name:     "updated",
+			event:    "pullrequest:updated",
+			payload:  bitbucketHook(""),
+			wantType: changerequests.EventCommitsPushed,
+			wantAt:   "2024-01-02T10:00:00Z",
+		},
+		{
+			name:     "merged",
+			event:    "pullrequest:fulfilled",
+			payload:  bitbucketHook(""),
+			wantType: changerequests.EventMerged,
+			wantAt:   "2024-01-02T10:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.ChangeRequest.MergedAt == nil || p.ChangeRequest.ClosedAt == nil {
+					t.Errorf("expected merged and closed times, got %+v", p.ChangeRequest)
+				}
+			},
+		},
+		{
+			name:     "declined",
+			event:    "pullrequest:rejected",
+			payload:  bitbucketHook(""),
+			wantType: changerequests.EventClosed,
+			wantAt:   "2024-01-02T10:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.ChangeRequest.MergedAt != nil || p.ChangeRequest.ClosedAt == nil {
+					t.Errorf("expected only a closed time, got %+v", p.ChangeRequest)
+				}
+			},
+		},
+		{
+			name:     "approved",
+			event:    "pullrequest:approved",
+			payload:  bitbucketHook(`"approval": {"date": "2024-01-02T12:00:00+00:00", "user": {"nickname": "carol"}}`),
+			wantType: changerequests.EventReviewSubmitted,
+			wantAt:   "2024-01-02T12:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.Review == nil || p.Review.ID != "approval:carol" || p.Review.State != changerequests.ReviewStateApproved {
+					t.Errorf("unexpected review %+v", p.Review)
+				}
+			},
+		},
+		{
+			name:     "changes requested",
+			event:    "pullrequest:changes_request_created",
+			payload:  bitbucketHook(`"changes_request": {"date": "2024-01-02T12:00:00+00:00", "user": {"nickname": "carol"}}`),
+			wantType: changerequests.EventReviewSubmitted,
+			wantAt:   "2024-01-02T12:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.Review == nil || p.Review.ID != "changes_request:carol" || p.Review.State != changerequests.ReviewStateChangesRequested {
+					t.Errorf("unexpected review %+v", p.Review)
+				}
+			},
+		},
+		{
+			name:     "unapproved",
+			event:    "pullrequest:unapproved",
+			payload:  bitbucketHook(`"approval": {"date": "2024-01-02T13:00:00+00:00", "user": {"nickname": "carol"}}`),
+			wantType: changerequests.EventReviewDismissed,
+			wantAt:   "2024-01-02T13:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.Review == nil || p.Review.ID != "approval:carol" || p.Review.State != changerequests.ReviewStateDismissed {
+					t.Errorf("unexpected review %+v", p.Review)
+				}
+			},
+		},
+		{
+			name:     "comment created",
+			event:    "pullrequest:comment_created",
+			payload:  bitbucketHook(`"comment": {"id": 800, "content": {"raw": "nit"}, "user": {"nickname": "carol"}, "created_on": "2024-01-02T11:00:00+00:00"}`),
+			wantType: changerequests.EventReviewCommentAdded,
+			wantAt:   "2024-01-02T11:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.Comment == nil || p.Comment.ID != "800" || p.Comment.Author != "carol" || p.Comment.Body != "nit" {
+					t.Errorf("unexpected comment %+v", p.Comment)
+				}
+			},
+		},
+		{
+			name:    "comment created without a comment",
+			event:   "pullrequest:comment_created",
+			payload: bitbucketHook(""),
+		},
+		{
+			name:    "without a pull request",
+			event:   "pullrequest:created",
+			payload: `{"repository": {"full_name": "acme/app"}}`,
+		},
+		{
+			name:    "unrelated event",
+			event:   "repo:push",
+			payload: `{"repository": {"full_name": "acme/app"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &BitbucketWebhook{
+				ID:         uuid.New(),
+				Event:      tt.event,
+				OccurredAt: receivedAt,
+				Payload:    decodePayload(t, tt.payload),
+			}
+
+			events, err := translateBitbucketWebhook(w)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.wantType == "" {
+				if len(events) != 0 {
+					t.Fatalf("expected no events, got %+v", events)
+				}
+
+				return
+			}
+
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(events))
+			}
+
+			e := events[0]
+
+			if e.Type != tt.wantType {
+				t.Errorf("type = %s, want %s", e.Type, tt.wantType)
+			}
+
+			if !e.OccurredAt.Equal(mustTime(t, tt.wantAt)) {
+				t.Errorf("occurred at = %v, want %s", e.OccurredAt, tt.wantAt)
+			}
+
+			// Pull request ids are only unique within a repository.
+			if e.AggregateID != "{repo-uuid}#9" || e.SourceIntegration != IntegrationBitbucket {
+				t.Errorf("unexpected source of event %+v", e)
+			}
+
+			if tt.check != nil {
+				tt.check(t, e.Payload)
+			}
+		})
+	}
+}
@@ -1,21 +1,43 @@
 package ingestion
 
 import (
+	"log/slog"
 	"time"
 
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
 	"github.com/adamkirk/panoptes/internal/util/dt"
 	"github.com/google/uuid"
 )
 
+const IntegrationGithub = "github"
 
 type GithubIngestorRepo interface {
-	Create(id uuid.UUID, ts time.Time, payload map[string]any) error
+	// Create stores the webhook and the events translated from it, returning
+	// false if a webhook with the same delivery id has already been stored, in
+	// which case nothing is written.
+	Create(w *GithubWebhook, events []changerequests.Event) (bool, error)
+}
+
+// ChangeRequestLinker links change requests to the tasks they mention.
+type ChangeRequestLinker interface {
+	Link(events []changerequests.Event) error
+}
+
+type GithubWebhook struct {
+	ID uuid.UUID
+	DeliveryID string
+	Event string
+	OccurredAt time.Time
+	Payload map[string]any
 }
 
 type GithubEvent struct {
 	Payload map[string]any
 	Event string
 	DeliveryID string
+
+	// ReceivedAt defaults to now, it's set for deliveries that were queued.
+	ReceivedAt time.Time
 }
 
 type GithubIngestorOpt func(*GithubIngestor)
@@ -31,16 +53,77 @@ func WithCustomNowProvider(f func () time.Time) GithubIngestorOpt {
 
 type GithubIngestor struct {
 	repo GithubIngestorRepo
+	secrets WebhookSecretStore
+	linker ChangeRequestLinker
 	getNow func() time.Time
 }
 
+// Verify checks the X-Hub-Signature-256 header value that github sends against
+// the raw body of the request. This must be done on the raw bytes, as
+// re-encoding the parsed payload won't necessarily produce the same output.
+func (gi *GithubIngestor) Verify(body []byte, signature string) error {
+	return verifySHA256Signature(gi.secrets.WebhookSecrets(IntegrationGithub), body, signature)
+}
+
+// Process stores the raw webhook along with the change request events it
+// translates to. Github will redeliver a webhook with the same delivery id
+// (either automatically or when someone clicks 'Redeliver'), these are ignored
+// so that each delivery is only ever stored once.
 func (gi *GithubIngestor) Process(e GithubEvent) error {
-	return gi.repo.Create(uuid.New(), gi.getNow(), e.Payload)
+	w := &GithubWebhook{
+		ID: uuid.New(),
+		DeliveryID: e.DeliveryID,
+		Event: e.Event,
+		OccurredAt: e.ReceivedAt,
+		Payload: e.Payload,
+	}
+
+	if w.OccurredAt.IsZero() {
+		w.OccurredAt = gi.getNow()
+	}
+
+	events, err := translateGithubWebhook(w)
+
+	if err != nil {
+		// Still keep the raw webhook, so the events can be recovered once
+		// whatever we failed to understand is fixed.
+		slog.Error("failed to translate github webhook", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	created, err := gi.repo.Create(w, events)
+
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		slog.Debug("ignoring github redelivery", "delivery_id", e.DeliveryID)
+		return nil
+	}
+
+	// The events are already safely stored at this point, so failing the
+	// request would only cause github to redeliver something we've got.
+	if err := gi.linker.Link(events); err != nil {
+		slog.Error("failed to link github change requests to tasks", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	return nil
+}
+
+func (gi *GithubIngestor) ProcessQueued(item *QueueItem) error {
+	return gi.Process(GithubEvent{
+		Payload: item.Payload,
+		Event: item.Event,
+		DeliveryID: item.DeliveryID,
+		ReceivedAt: item.ReceivedAt,
+	})
 }
 
-func NewGithubIngestor(repo GithubIngestorRepo, opts... GithubIngestorOpt) *GithubIngestor {
+func NewGithubIngestor(repo GithubIngestorRepo, secrets WebhookSecretStore, linker ChangeRequestLinker, opts... GithubIngestorOpt) *GithubIngestor {
 	gi := &GithubIngestor{
 		repo: repo,
+		secrets: secrets,
+		linker: linker,
 		getNow: dt.NowUTC,
 	}
 
@@ -0,0 +1,223 @@
+package ingestion
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+// secretStore is a WebhookSecretStore with fixed secrets per integration.
+type secretStore map[string][]string
+
+func (s secretStore) WebhookSecrets(integration string) []string {
+	return s[integration]
+}
+
+// sign returns the hex HMAC-SHA256 of the body, as the integrations send it.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGithubIngestorVerify(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+
+	tests := []struct {
+		name      string
+		secrets   []string
+		body      []byte
+		signature string
+		wantErr   error
+	}{
+		{
+			name:      "valid signature",
+			secrets:   []string{"secret"},
+			body:      body,
+			signature: "sha256=" + sign("secret", body),
+		},
+		{
+			name:      "signed with a rotated secret",
+			secrets:   []string{"new", "old"},
+			body:      body,
+			signature: "sha256=" + sign("old", body),
+		},
+		{
+			name:      "signed with the wrong secret",
+			secrets:   []string{"secret"},
+			body:      body,
+			signature: "sha256=" + sign("other", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "body changed after signing",
+			secrets:   []string{"secret"},
+			body:      []byte(`{"action":"closed"}`),
+			signature: "sha256=" + sign("secret", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "missing algorithm prefix",
+			secrets:   []string{"secret"},
+			body:      body,
+			signature: sign("secret", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "sha1 signature",
+			secrets:   []string{"secret"},
+			body:      body,
+			signature: "sha1=" + sign("secret", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "digest isn't hex",
+			secrets:   []string{"secret"},
+			body:      body,
+			signature: "sha256=not-hex",
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "no signature",
+			secrets:   []string{"secret"},
+			body:      body,
+			signature: "",
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "no secrets configured",
+			body:      body,
+			signature: "sha256=" + sign("", body),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "empty secrets are skipped",
+			secrets:   []string{""},
+			body:      body,
+			signature: "sha256=" + sign("", body),
+			wantErr:   ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gi := NewGithubIngestor(nil, secretStore{IntegrationGithub: tt.secrets}, nil)
+
+			if err := gi.Verify(tt.body, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// githubWebhooks stores webhooks like the database does, ignoring delivery ids
+// it's already seen, and events with ids it's already got.
+type githubWebhooks struct {
+	webhooks []*GithubWebhook
+	events   []changerequests.Event
+}
+
+func (r *githubWebhooks) Create(w *GithubWebhook, events []changerequests.Event) (bool, error) {
+	for _, existing := range r.webhooks {
+		if existing.DeliveryID == w.DeliveryID {
+			return false, nil
+		}
+	}
+
+	r.webhooks = append(r.webhooks, w)
+
+	for _, e := range events {
+		seen := false
+
+		for _, existing := range r.events {
+			seen = seen || existing.ID == e.ID
+		}
+
+		if !seen {
+			r.events = append(r.events, e)
+		}
+	}
+
+	return true, nil
+}
+
+type changeRequestLinker struct {
+	linked []changerequests.Event
+}
+
+func (l *changeRequestLinker) Link(events []changerequests.Event) error {
+	l.linked = append(l.linked, events...)
+
+	return nil
+}
+
+func TestGithubIngestorProcessRedelivery(t *testing.T) {
+	received := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	payload := `{"action": "opened", "pull_request": ` + githubPullRequestJSON(false, "", "") + `}`
+
+	tests := []struct {
+		name       string
+		deliveries []GithubEvent
+
+		// webhooks is how many webhooks should be stored, there should only
+		// ever be one set of events.
+		webhooks int
+	}{
+		{
+			name: "redelivered",
+			deliveries: []GithubEvent{
+				{Event: "pull_request", DeliveryID: "1", Payload: decodePayload(t, payload)},
+				{Event: "pull_request", DeliveryID: "1", Payload: decodePayload(t, payload)},
+			},
+			webhooks: 1,
+		},
+		{
+			name: "redelivered after being queued",
+			deliveries: []GithubEvent{
+				{Event: "pull_request", DeliveryID: "1", Payload: decodePayload(t, payload), ReceivedAt: received},
+				{Event: "pull_request", DeliveryID: "1", Payload: decodePayload(t, payload)},
+			},
+			webhooks: 1,
+		},
+		{
+			name: "sent again as a new delivery",
+			deliveries: []GithubEvent{
+				{Event: "pull_request", DeliveryID: "1", Payload: decodePayload(t, payload)},
+				{Event: "pull_request", DeliveryID: "2", Payload: decodePayload(t, payload)},
+			},
+			webhooks: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &githubWebhooks{}
+			linker := &changeRequestLinker{}
+			gi := NewGithubIngestor(repo, secretStore{}, linker, WithCustomNowProvider(func() time.Time { return received.Add(time.Minute) }))
+
+			for _, d := range tt.deliveries {
+				if err := gi.Process(d); err != nil {
+					t.Fatalf("failed to process delivery: %s", err)
+				}
+			}
+
+			if len(repo.webhooks) != tt.webhooks {
+				t.Errorf("expected %d webhooks, got %d", tt.webhooks, len(repo.webhooks))
+			}
+
+			if len(repo.events) != 1 || repo.events[0].Type != changerequests.EventOpened {
+				t.Errorf("expected a single opened event, got %+v", repo.events)
+			}
+
+			if len(linker.linked) != tt.webhooks {
+				t.Errorf("expected only stored webhooks to be linked, got %d", len(linker.linked))
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+// These only declare the parts of github's payloads that we care about, see:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+type githubRef struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+type githubPullRequest struct {
+	ID           int64      `json:"id"`
+	Number       int        `json:"number"`
+	Title        string     `json:"title"`
+	Body         *string    `json:"body"`
+	HTMLURL      string     `json:"html_url"`
+	User         githubUser `json:"user"`
+	Draft        bool       `json:"draft"`
+	Merged       bool       `json:"merged"`
+	Additions    int        `json:"additions"`
+	Deletions    int        `json:"deletions"`
+	ChangedFiles int        `json:"changed_files"`
+	Commits      int        `json:"commits"`
+	Base         githubRef  `json:"base"`
+	Head         githubRef  `json:"head"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	MergedAt     *time.Time `json:"merged_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+}
+
+type githubReview struct {
+	ID          int64      `json:"id"`
+	User        githubUser `json:"user"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+}
+
+type githubReviewComment struct {
+	ID        int64      `json:"id"`
+	User      githubUser `json:"user"`
+	Body      string     `json:"body"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type githubPullRequestWebhook struct {
+	Action      string               `json:"action"`
+	PullRequest githubPullRequest    `json:"pull_request"`
+	Repository  githubRepository     `json:"repository"`
+	Sender      githubUser           `json:"sender"`
+	Review      *githubReview        `json:"review"`
+	Comment     *githubReviewComment `json:"comment"`
+	After       string               `json:"after"`
+}
+
+var githubPullRequestActions = map[string]changerequests.EventType{
+	"opened":             changerequests.EventOpened,
+	"closed":             changerequests.EventClosed,
+	"reopened":           changerequests.EventReopened,
+	"ready_for_review":   changerequests.EventReadyForReview,
+	"converted_to_draft": changerequests.EventConvertedToDraft,
+	"synchronize":        changerequests.EventCommitsPushed,
+}
+
+// translateGithubWebhook converts the raw webhook into change request events.
+// Webhooks we don't care about produce no events.
+func translateGithubWebhook(w *GithubWebhook) ([]changerequests.Event, error) {
+	switch w.Event {
+	case "pull_request", "pull_request_review", "pull_request_review_comment":
+	default:
+		return nil, nil
+	}
+
+	// Round trip through json rather than picking through the map by hand.
+	raw, err := json.Marshal(w.Payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var hook githubPullRequestWebhook
+
+	if err := json.Unmarshal(raw, &hook); err != nil {
+		return nil, err
+	}
+
+	pr := hook.PullRequest
+	payload := changerequests.Payload{
+		ChangeRequest: githubChangeRequest(pr, hook.Repository),
+		Actor:         hook.Sender.Login,
+	}
+
+	var t changerequests.EventType
+	var occurredAt *time.Time
+	var discriminator string
+
+	switch w.Event {
+	case "pull_request":
+		var ok bool
+
+		if t, ok = githubPullRequestActions[hook.Action]; !ok {
+			return nil, nil
+		}
+
+		occurredAt = pr.UpdatedAt
+
+		switch t {
+		case changerequests.EventOpened:
+			occurredAt = pr.CreatedAt
+		case changerequests.EventClosed:
+			occurredAt = pr.ClosedAt
+
+			// Github doesn't have a separate action for merging.
+			if pr.Merged {
+				t = changerequests.EventMerged
+				occurredAt = pr.MergedAt
+			}
+		case changerequests.EventCommitsPushed:
+			discriminator = hook.After
+		}
+
+	case "pull_request_review":
+		if hook.Review == nil {
+			return nil, nil
+		}
+
+		switch hook.Action {
+		case "submitted":
+			t = changerequests.EventReviewSubmitted
+		case "dismissed":
+			t = changerequests.EventReviewDismissed
+		default:
+			return nil, nil
+		}
+
+		occurredAt = hook.Review.SubmittedAt
+		discriminator = strconv.FormatInt(hook.Review.ID, 10)
+
+		// A dismissal is a change to an existing review, so it needs a
+		// different timestamp to the submission.
+		if t == changerequests.EventReviewDismissed {
+			occurredAt = pr.UpdatedAt
+		}
+
+		payload.Review = &changerequests.Review{
+			ID:          discriminator,
+			Author:      hook.Review.User.Login,
+			State:       changerequests.ReviewState(hook.Review.State),
+			SubmittedAt: hook.Review.SubmittedAt,
+		}
+
+	case "pull_request_review_comment":
+		if hook.Comment == nil || hook.Action != "created" {
+			return nil, nil
+		}
+
+		t = changerequests.EventReviewCommentAdded
+		occurredAt = hook.Comment.CreatedAt
+		discriminator = strconv.FormatInt(hook.Comment.ID, 10)
+
+		payload.Comment = &changerequests.Comment{
+			ID:        discriminator,
+			Author:    hook.Comment.User.Login,
+			Body:      hook.Comment.Body,
+			CreatedAt: hook.Comment.CreatedAt,
+		}
+	}
+
+	// Fallback to when we received it if github didn't give us a time.
+	ts := w.OccurredAt
+
+	if occurredAt != nil {
+		ts = *occurredAt
+	}
+
+	// Github doesn't send the commits that were pushed, only the new head, so
+	// the time it was pushed is as close as we get to when it was committed.
+	// Backfilled pushes are synthesised per commit, at the commit's time, and
+	// include the messages, so only backfilling links tasks from commits.
+	if t == changerequests.EventCommitsPushed && hook.After != "" {
+		committedAt := ts
+		payload.Commits = []changerequests.Commit{
+			{
+				SHA:         hook.After,
+				CommittedAt: &committedAt,
+			},
+		}
+	}
+
+	aggregateID := strconv.FormatInt(pr.ID, 10)
+
+	return []changerequests.Event{
+		{
+			ID:                changerequests.NewEventID(IntegrationGithub, aggregateID, t, ts, discriminator),
+			AggregateID:       aggregateID,
+			Type:              t,
+			OccurredAt:        ts,
+			SourceID:          &w.ID,
+			SourceIntegration: IntegrationGithub,
+			Payload:           payload,
+		},
+	}, nil
+}
+
+func githubChangeRequest(pr githubPullRequest, repo githubRepository) changerequests.ChangeRequest {
+	body := ""
+
+	if pr.Body != nil {
+		body = *pr.Body
+	}
+
+	return changerequests.ChangeRequest{
+		Number:       pr.Number,
+		Title:        pr.Title,
+		Body:         body,
+		URL:          pr.HTMLURL,
+		Author:       pr.User.Login,
+		Repository:   repo.FullName,
+		BaseBranch:   pr.Base.Ref,
+		HeadBranch:   pr.Head.Ref,
+		HeadSHA:      pr.Head.SHA,
+		IsDraft:      pr.Draft,
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		ChangedFiles: pr.ChangedFiles,
+		Commits:      pr.Commits,
+		CreatedAt:    pr.CreatedAt,
+		MergedAt:     pr.MergedAt,
+		ClosedAt:     pr.ClosedAt,
+	}
+}
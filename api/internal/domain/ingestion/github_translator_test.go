@@ -0,0 +1,255 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/google/uuid"
+)
+
+// decodePayload parses a webhook body the way the ingestion endpoints do.
+func decodePayload(t *testing.T, body string) map[string]any {
+	t.Helper()
+
+	payload := map[string]any{}
+
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+
+	return payload
+}
+
+func mustTime(t *testing.T, val string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(time.RFC3339, val)
+
+	if err != nil {
+		t.Fatalf("parsing time: %v", err)
+	}
+
+	return parsed
+}
+
+const githubPullRequestPayload = `{
+	"id": 101,
+	"number": 7,
+	"title": "Add the thing",
+	"body": "PROJ-1",
+	"html_url": "https://github.com/acme/app/pull/7",
+	"user": {"login": "alice"},
+	"merged": %t,
+	"commits": 2,
+	"base": {"ref": "main", "sha": "aaa"},
+	"head": {"ref": "feature", "sha": "bbb"},
+	"created_at": "2024-01-01T10:00:00Z",
+	"updated_at": "2024-01-02T10:00:00Z",
+	"merged_at": %s,
+	"closed_at": %s
+}`
+
+func githubPullRequestJSON(merged bool, mergedAt string, closedAt string) string {
+	quote := func(val string) string {
+		if val == "" {
+			return "null"
+		}
+
+		return `"` + val + `"`
+	}
+
+	return fmt.Sprintf(githubPullRequestPayload, merged, quote(mergedAt), quote(closedAt))
+}
+
+func TestTranslateGithubWebhook(t *testing.T) {
+	receivedAt := mustTime(t, "2024-02-01T00:00:00Z")
+	open := githubPullRequestJSON(false, "", "")
+
+	tests := []struct {
+		name     string
+		event    string
+		payload  string
+		wantType changerequests.EventType
+		wantAt   string
+		check    func(t *testing.T, p changerequests.Payload)
+	}{
+		{
+			name:     "opened",
+			event:    "pull_request",
+			payload:  `{"action": "opened", "sender": {"login": "alice"}, "repository": {"full_name": "acme/app"}, "pull_request": ` + open + `}`,
+			wantType: changerequests.EventOpened,
+			wantAt:   "2024-01-01T10:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				cr := p.ChangeRequest
+
+				if cr.Number != 7 || cr.Repository != "acme/app" || cr.Author != "alice" || cr.Body != "PROJ-1" {
+					t.Errorf("unexpected change request %+v", cr)
+				}
+
+				if cr.BaseBranch != "main" || cr.HeadBranch != "feature" || cr.HeadSHA != "bbb" {
+					t.Errorf("unexpected branches %+v", cr)
+				}
+			},
+		},
+		{
+			name:     "closed without merging",
+			event:    "pull_request",
+			payload:  `{"action": "closed", "pull_request": ` + githubPullRequestJSON(false, "", "2024-01-03T10:00:00Z") + `}`,
+			wantType: changerequests.EventClosed,
+			wantAt:   "2024-01-03T10:00:00Z",
+		},
+		{
+			name:     "closed by merging",
+			event:    "pull_request",
+			payload:  `{"action": "closed", "pull_request": ` + githubPullRequestJSON(true, "2024-01-04T10:00:00Z", "2024-01-04T10:00:01Z") + `}`,
+			wantType: changerequests.EventMerged,
+			wantAt:   "2024-01-04T10:00:00Z",
+		},
+		{
+			name:     "commits pushed",
+			event:    "pull_request",
+			payload:  `{"action": "synchronize", "after": "ccc", "pull_request": ` + open + `}`,
+			wantType: changerequests.EventCommitsPushed,
+			wantAt:   "2024-01-02T10:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if len(p.Commits) != 1 || p.Commits[0].SHA != "ccc" {
+					t.Fatalf("expected the pushed head as the commit, got %+v", p.Commits)
+				}
+
+				if !p.Commits[0].CommittedAt.Equal(mustTime(t, "2024-01-02T10:00:00Z")) {
+					t.Errorf("expected the commit at the push, got %v", p.Commits[0].CommittedAt)
+				}
+			},
+		},
+		{
+			name:    "unhandled pull request action",
+			event:   "pull_request",
+			payload: `{"action": "labeled", "pull_request": ` + open + `}`,
+		},
+		{
+			name:     "review submitted",
+			event:    "pull_request_review",
+			payload:  `{"action": "submitted", "review": {"id": 55, "user": {"login": "bob"}, "state": "approved", "submitted_at": "2024-01-02T12:00:00Z"}, "pull_request": ` + open + `}`,
+			wantType: changerequests.EventReviewSubmitted,
+			wantAt:   "2024-01-02T12:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.Review == nil || p.Review.ID != "55" || p.Review.Author != "bob" || p.Review.State != changerequests.ReviewStateApproved {
+					t.Errorf("unexpected review %+v", p.Review)
+				}
+			},
+		},
+		{
+			name:     "review dismissed",
+			event:    "pull_request_review",
+			payload:  `{"action": "dismissed", "review": {"id": 55, "user": {"login": "bob"}, "state": "dismissed", "submitted_at": "2024-01-01T12:00:00Z"}, "pull_request": ` + open + `}`,
+			wantType: changerequests.EventReviewDismissed,
+			wantAt:   "2024-01-02T10:00:00Z",
+		},
+		{
+			name:    "review without a review",
+			event:   "pull_request_review",
+			payload: `{"action": "submitted", "pull_request": ` + open + `}`,
+		},
+		{
+			name:     "review comment added",
+			event:    "pull_request_review_comment",
+			payload:  `{"action": "created", "comment": {"id": 77, "user": {"login": "bob"}, "body": "nit", "created_at": "2024-01-02T11:00:00Z"}, "pull_request": ` + open + `}`,
+			wantType: changerequests.EventReviewCommentAdded,
+			wantAt:   "2024-01-02T11:00:00Z",
+			check: func(t *testing.T, p changerequests.Payload) {
+				if p.Comment == nil || p.Comment.ID != "77" || p.Comment.Body != "nit" {
+					t.Errorf("unexpected comment %+v", p.Comment)
+				}
+			},
+		},
+		{
+			name:    "review comment edited",
+			event:   "pull_request_review_comment",
+			payload: `{"action": "edited", "comment": {"id": 77}, "pull_request": ` + open + `}`,
+		},
+		{
+			name:     "missing timestamp falls back to when it was received",
+			event:    "pull_request",
+			payload:  `{"action": "reopened", "pull_request": {"id": 101}}`,
+			wantType: changerequests.EventReopened,
+			wantAt:   "2024-02-01T00:00:00Z",
+		},
+		{
+			name:    "unrelated event",
+			event:   "push",
+			payload: `{"ref": "refs/heads/main"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &GithubWebhook{
+				ID:         uuid.New(),
+				Event:      tt.event,
+				OccurredAt: receivedAt,
+				Payload:    decodePayload(t, tt.payload),
+			}
+
+			events, err := translateGithubWebhook(w)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.wantType == "" {
+				if len(events) != 0 {
+					t.Fatalf("expected no events, got %+v", events)
+				}
+
+				return
+			}
+
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(events))
+			}
+
+			e := events[0]
+
+			if e.Type != tt.wantType {
+				t.Errorf("type = %s, want %s", e.Type, tt.wantType)
+			}
+
+			if !e.OccurredAt.Equal(mustTime(t, tt.wantAt)) {
+				t.Errorf("occurred at = %v, want %s", e.OccurredAt, tt.wantAt)
+			}
+
+			if e.AggregateID != "101" || e.SourceIntegration != IntegrationGithub || e.SourceID == nil || *e.SourceID != w.ID {
+				t.Errorf("unexpected source of event %+v", e)
+			}
+
+			if tt.check != nil {
+				tt.check(t, e.Payload)
+			}
+		})
+	}
+}
+
+// Redeliveries and backfills must produce the same event ids, or the same
+// change would be counted twice.
+func TestTranslateGithubWebhookIDsAreStable(t *testing.T) {
+	payload := `{"action": "submitted", "review": {"id": 55, "state": "approved", "submitted_at": "2024-01-02T12:00:00Z"}, "pull_request": ` + githubPullRequestJSON(false, "", "") + `}`
+
+	first, err := translateGithubWebhook(&GithubWebhook{ID: uuid.New(), Event: "pull_request_review", Payload: decodePayload(t, payload)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := translateGithubWebhook(&GithubWebhook{ID: uuid.New(), Event: "pull_request_review", Payload: decodePayload(t, payload)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first[0].ID != second[0].ID {
+		t.Errorf("expected the same id for the same review, got %s and %s", first[0].ID, second[0].ID)
+	}
+}
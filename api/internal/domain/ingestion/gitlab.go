@@ -0,0 +1,128 @@
+package ingestion
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+const IntegrationGitlab = "gitlab"
+
+type GitlabIngestorRepo interface {
+	// Create stores the webhook and the events translated from it, returning
+	// false if a webhook with the same delivery id has already been stored, in
+	// which case nothing is written.
+	Create(w *GitlabWebhook, events []changerequests.Event) (bool, error)
+}
+
+type GitlabWebhook struct {
+	ID         uuid.UUID
+	DeliveryID string
+	Event      string
+	OccurredAt time.Time
+	Payload    map[string]any
+}
+
+type GitlabEvent struct {
+	Payload map[string]any
+
+	// Event is the X-Gitlab-Event header e.g. Merge Request Hook.
+	Event string
+
+	// Older versions of gitlab don't send a delivery id, in which case we
+	// can't detect redeliveries.
+	DeliveryID string
+
+	// ReceivedAt defaults to now, it's set for deliveries that were queued.
+	ReceivedAt time.Time
+}
+
+type GitlabIngestorOpt func(*GitlabIngestor)
+
+func WithGitlabCustomNowProvider(f func() time.Time) GitlabIngestorOpt {
+	return func(gi *GitlabIngestor) {
+		gi.getNow = f
+	}
+}
+
+type GitlabIngestor struct {
+	repo    GitlabIngestorRepo
+	secrets WebhookSecretStore
+	linker  ChangeRequestLinker
+	getNow  func() time.Time
+}
+
+// Verify checks the X-Gitlab-Token header. Gitlab doesn't sign payloads, it
+// sends the secret token configured on the webhook as is.
+func (gi *GitlabIngestor) Verify(token string) error {
+	return verifyToken(gi.secrets.WebhookSecrets(IntegrationGitlab), token)
+}
+
+// Process stores the raw webhook along with the change request events it
+// translates to, ignoring redeliveries.
+func (gi *GitlabIngestor) Process(e GitlabEvent) error {
+	w := &GitlabWebhook{
+		ID:         uuid.New(),
+		DeliveryID: e.DeliveryID,
+		Event:      e.Event,
+		OccurredAt: e.ReceivedAt,
+		Payload:    e.Payload,
+	}
+
+	if w.OccurredAt.IsZero() {
+		w.OccurredAt = gi.getNow()
+	}
+
+	events, err := translateGitlabWebhook(w)
+
+	if err != nil {
+		// Still keep the raw webhook, so the events can be recovered once
+		// whatever we failed to understand is fixed.
+		slog.Error("failed to translate gitlab webhook", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	created, err := gi.repo.Create(w, events)
+
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		slog.Debug("ignoring gitlab redelivery", "delivery_id", e.DeliveryID)
+		return nil
+	}
+
+	// The events are already safely stored, so this isn't worth failing over.
+	if err := gi.linker.Link(events); err != nil {
+		slog.Error("failed to link gitlab change requests to tasks", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	return nil
+}
+
+func (gi *GitlabIngestor) ProcessQueued(item *QueueItem) error {
+	return gi.Process(GitlabEvent{
+		Payload:    item.Payload,
+		Event:      item.Event,
+		DeliveryID: item.DeliveryID,
+		ReceivedAt: item.ReceivedAt,
+	})
+}
+
+func NewGitlabIngestor(repo GitlabIngestorRepo, secrets WebhookSecretStore, linker ChangeRequestLinker, opts ...GitlabIngestorOpt) *GitlabIngestor {
+	gi := &GitlabIngestor{
+		repo:    repo,
+		secrets: secrets,
+		linker:  linker,
+		getNow:  dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(gi)
+	}
+
+	return gi
+}
@@ -0,0 +1,65 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGitlabIngestorVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		token   string
+		wantErr error
+	}{
+		{
+			name:    "valid token",
+			secrets: []string{"secret"},
+			token:   "secret",
+		},
+		{
+			name:    "rotated token",
+			secrets: []string{"new", "old"},
+			token:   "old",
+		},
+		{
+			name:    "wrong token",
+			secrets: []string{"secret"},
+			token:   "other",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "prefix of the token",
+			secrets: []string{"secret"},
+			token:   "sec",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "no token",
+			secrets: []string{"secret"},
+			token:   "",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "no secrets configured",
+			token:   "secret",
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "empty secrets are skipped",
+			secrets: []string{""},
+			token:   "",
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gi := NewGitlabIngestor(nil, secretStore{IntegrationGitlab: tt.secrets}, nil)
+
+			if err := gi.Verify(tt.token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+// These only declare the parts of gitlab's payloads that we care about, see:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html
+
+const (
+	gitlabEventMergeRequest = "Merge Request Hook"
+	gitlabEventNote         = "Note Hook"
+	gitlabEventPipeline     = "Pipeline Hook"
+)
+
+// gitlabTime handles the formats gitlab uses for dates, which differ between
+// hooks and versions e.g. 2024-01-02 10:00:00 UTC and 2024-01-02T10:00:00Z
+type gitlabTime struct {
+	time.Time
+}
+
+var gitlabTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05 -0700",
+}
+
+func (t *gitlabTime) UnmarshalJSON(b []byte) error {
+	var raw *string
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	if raw == nil || *raw == "" {
+		return nil
+	}
+
+	var err error
+
+	for _, layout := range gitlabTimeLayouts {
+		var parsed time.Time
+
+		if parsed, err = time.Parse(layout, *raw); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+
+	return err
+}
+
+// ptr is nil for times that weren't given.
+func (t gitlabTime) ptr() *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	ts := t.Time
+
+	return &ts
+}
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type gitlabCommitAuthor struct {
+	Name string `json:"name"`
+}
+
+type gitlabCommit struct {
+	ID        string             `json:"id"`
+	Message   string             `json:"message"`
+	Timestamp gitlabTime         `json:"timestamp"`
+	Author    gitlabCommitAuthor `json:"author"`
+}
+
+// gitlabMergeRequest is the object_attributes of merge request hooks, and the
+// merge_request of note and pipeline hooks, which have fewer fields.
+type gitlabMergeRequest struct {
+	ID             int64         `json:"id"`
+	IID            int           `json:"iid"`
+	Title          string        `json:"title"`
+	Description    *string       `json:"description"`
+	URL            string        `json:"url"`
+	State          string        `json:"state"`
+	Action         string        `json:"action"`
+	SourceBranch   string        `json:"source_branch"`
+	TargetBranch   string        `json:"target_branch"`
+	Draft          bool          `json:"draft"`
+	WorkInProgress bool          `json:"work_in_progress"`
+	OldRev         string        `json:"oldrev"`
+	LastCommit     *gitlabCommit `json:"last_commit"`
+	CreatedAt      gitlabTime    `json:"created_at"`
+	UpdatedAt      gitlabTime    `json:"updated_at"`
+}
+
+type gitlabBoolChange struct {
+	Previous *bool `json:"previous"`
+	Current  *bool `json:"current"`
+}
+
+type gitlabMergeRequestChanges struct {
+	Draft *gitlabBoolChange `json:"draft"`
+
+	// Older versions of gitlab call drafts work in progress.
+	WorkInProgress *gitlabBoolChange `json:"work_in_progress"`
+}
+
+type gitlabNote struct {
+	ID           int64      `json:"id"`
+	Note         string     `json:"note"`
+	NoteableType string     `json:"noteable_type"`
+	System       bool       `json:"system"`
+	CreatedAt    gitlabTime `json:"created_at"`
+}
+
+type gitlabPipeline struct {
+	ID        int64      `json:"id"`
+	SHA       string     `json:"sha"`
+	CreatedAt gitlabTime `json:"created_at"`
+}
+
+type gitlabWebhook struct {
+	User             gitlabUser                `json:"user"`
+	Project          gitlabProject             `json:"project"`
+	ObjectAttributes json.RawMessage           `json:"object_attributes"`
+	Changes          gitlabMergeRequestChanges `json:"changes"`
+	MergeRequest     *gitlabMergeRequest       `json:"merge_request"`
+	Commit           *gitlabCommit             `json:"commit"`
+}
+
+var gitlabMergeRequestActions = map[string]changerequests.EventType{
+	"open":       changerequests.EventOpened,
+	"close":      changerequests.EventClosed,
+	"reopen":     changerequests.EventReopened,
+	"merge":      changerequests.EventMerged,
+	"approval":   changerequests.EventReviewSubmitted,
+	"approved":   changerequests.EventReviewSubmitted,
+	"unapproval": changerequests.EventReviewDismissed,
+	"unapproved": changerequests.EventReviewDismissed,
+}
+
+// translateGitlabWebhook converts the raw webhook into change request events,
+// the same ones github's produce. Webhooks we don't care about produce no
+// events.
+func translateGitlabWebhook(w *GitlabWebhook) ([]changerequests.Event, error) {
+	switch w.Event {
+	case gitlabEventMergeRequest, gitlabEventNote, gitlabEventPipeline:
+	default:
+		return nil, nil
+	}
+
+	// Round trip through json rather than picking through the map by hand.
+	raw, err := json.Marshal(w.Payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var hook gitlabWebhook
+
+	if err := json.Unmarshal(raw, &hook); err != nil {
+		return nil, err
+	}
+
+	switch w.Event {
+	case gitlabEventMergeRequest:
+		return translateGitlabMergeRequest(w, hook)
+	case gitlabEventNote:
+		return translateGitlabNote(w, hook)
+	default:
+		return translateGitlabPipeline(w, hook)
+	}
+}
+
+// gitlabEvent builds an event, falling back to when we received the webhook
+// if gitlab didn't give us a time.
+func gitlabEvent(w *GitlabWebhook, mr gitlabMergeRequest, t changerequests.EventType, occurredAt *time.Time, discriminator string, payload changerequests.Payload) changerequests.Event {
+	ts := w.OccurredAt
+
+	if occurredAt != nil {
+		ts = *occurredAt
+	}
+
+	aggregateID := strconv.FormatInt(mr.ID, 10)
+
+	return changerequests.Event{
+		ID:                changerequests.NewEventID(IntegrationGitlab, aggregateID, t, ts, discriminator),
+		AggregateID:       aggregateID,
+		Type:              t,
+		OccurredAt:        ts,
+		SourceID:          &w.ID,
+		SourceIntegration: IntegrationGitlab,
+		Payload:           payload,
+	}
+}
+
+func translateGitlabMergeRequest(w *GitlabWebhook, hook gitlabWebhook) ([]changerequests.Event, error) {
+	var mr gitlabMergeRequest
+
+	if err := json.Unmarshal(hook.ObjectAttributes, &mr); err != nil {
+		return nil, err
+	}
+
+	cr := gitlabChangeRequest(mr, hook.Project)
+
+	// The author isn't in the payload, only who triggered the hook, which is
+	// the author when it's opened.
+	if mr.Action == "open" {
+		cr.Author = hook.User.Username
+	}
+
+	payload := changerequests.Payload{
+		ChangeRequest: cr,
+		Actor:         hook.User.Username,
+	}
+
+	if mr.Action == "update" {
+		return translateGitlabMergeRequestUpdate(w, hook, mr, payload), nil
+	}
+
+	t, ok := gitlabMergeRequestActions[mr.Action]
+
+	if !ok {
+		return nil, nil
+	}
+
+	occurredAt := mr.UpdatedAt.ptr()
+	discriminator := ""
+
+	switch t {
+	case changerequests.EventOpened:
+		occurredAt = mr.CreatedAt.ptr()
+
+	// Gitlab has no review ids, approvals are per user.
+	case changerequests.EventReviewSubmitted, changerequests.EventReviewDismissed:
+		discriminator = "approval:" + hook.User.Username
+		state := changerequests.ReviewStateApproved
+
+		if t == changerequests.EventReviewDismissed {
+			state = changerequests.ReviewStateDismissed
+		}
+
+		payload.Review = &changerequests.Review{
+			ID:          discriminator,
+			Author:      hook.User.Username,
+			State:       state,
+			SubmittedAt: occurredAt,
+		}
+	}
+
+	return []changerequests.Event{
+		gitlabEvent(w, mr, t, occurredAt, discriminator, payload),
+	}, nil
+}
+
+// translateGitlabMergeRequestUpdate handles gitlab's catch all update action,
+// which can mean new commits, a change of draft status, or both.
+func translateGitlabMergeRequestUpdate(w *GitlabWebhook, hook gitlabWebhook, mr gitlabMergeRequest, payload changerequests.Payload) []changerequests.Event {
+	events := []changerequests.Event{}
+	occurredAt := mr.UpdatedAt.ptr()
+
+	if mr.OldRev != "" && mr.LastCommit != nil {
+		pushed := payload
+		pushed.Commits = []changerequests.Commit{gitlabCommitPayload(*mr.LastCommit)}
+
+		events = append(events, gitlabEvent(w, mr, changerequests.EventCommitsPushed, occurredAt, mr.LastCommit.ID, pushed))
+	}
+
+	draft := hook.Changes.Draft
+
+	if draft == nil {
+		draft = hook.Changes.WorkInProgress
+	}
+
+	if draft != nil && draft.Current != nil {
+		t := changerequests.EventReadyForReview
+
+		if *draft.Current {
+			t = changerequests.EventConvertedToDraft
+		}
+
+		events = append(events, gitlabEvent(w, mr, t, occurredAt, "", payload))
+	}
+
+	return events
+}
+
+func translateGitlabNote(w *GitlabWebhook, hook gitlabWebhook) ([]changerequests.Event, error) {
+	var note gitlabNote
+
+	if err := json.Unmarshal(hook.ObjectAttributes, &note); err != nil {
+		return nil, err
+	}
+
+	// System notes are gitlab's own record of changes, not comments.
+	if note.NoteableType != "MergeRequest" || note.System || hook.MergeRequest == nil {
+		return nil, nil
+	}
+
+	discriminator := strconv.FormatInt(note.ID, 10)
+
+	payload := changerequests.Payload{
+		ChangeRequest: gitlabChangeRequest(*hook.MergeRequest, hook.Project),
+		Actor:         hook.User.Username,
+		Comment: &changerequests.Comment{
+			ID:        discriminator,
+			Author:    hook.User.Username,
+			Body:      note.Note,
+			CreatedAt: note.CreatedAt.ptr(),
+		},
+	}
+
+	return []changerequests.Event{
+		gitlabEvent(w, *hook.MergeRequest, changerequests.EventReviewCommentAdded, note.CreatedAt.ptr(), discriminator, payload),
+	}, nil
+}
+
+// translateGitlabPipeline treats a merge request pipeline as commits being
+// pushed, as that's what triggers one. Each pipeline sends a hook for every
+// status change, these all translate to the same event so only one is kept.
+func translateGitlabPipeline(w *GitlabWebhook, hook gitlabWebhook) ([]changerequests.Event, error) {
+	var pipeline gitlabPipeline
+
+	if err := json.Unmarshal(hook.ObjectAttributes, &pipeline); err != nil {
+		return nil, err
+	}
+
+	if hook.MergeRequest == nil || hook.MergeRequest.ID == 0 {
+		return nil, nil
+	}
+
+	cr := gitlabChangeRequest(*hook.MergeRequest, hook.Project)
+	cr.HeadSHA = pipeline.SHA
+
+	payload := changerequests.Payload{
+		ChangeRequest: cr,
+		Actor:         hook.User.Username,
+	}
+
+	if hook.Commit != nil {
+		payload.Commits = []changerequests.Commit{gitlabCommitPayload(*hook.Commit)}
+	}
+
+	return []changerequests.Event{
+		gitlabEvent(w, *hook.MergeRequest, changerequests.EventCommitsPushed, pipeline.CreatedAt.ptr(), pipeline.SHA, payload),
+	}, nil
+}
+
+func gitlabCommitPayload(c gitlabCommit) changerequests.Commit {
+	return changerequests.Commit{
+		SHA:         c.ID,
+		Message:     c.Message,
+		Author:      c.Author.Name,
+		CommittedAt: c.Timestamp.ptr(),
+	}
+}
+
+func gitlabChangeRequest(mr gitlabMergeRequest, project gitlabProject) changerequests.ChangeRequest {
+	body := ""
+
+	if mr.Description != nil {
+		body = *mr.Description
+	}
+
+	headSHA := ""
+
+	if mr.LastCommit != nil {
+		headSHA = mr.LastCommit.ID
+	}
+
+	return changerequests.ChangeRequest{
+		Number:     mr.IID,
+		Title:      mr.Title,
+		Body:       body,
+		URL:        mr.URL,
+		Repository: project.PathWithNamespace,
+		BaseBranch: mr.TargetBranch,
+		HeadBranch: mr.SourceBranch,
+		HeadSHA:    headSHA,
+		IsDraft:    mr.Draft || mr.WorkInProgress,
+		CreatedAt:  mr.CreatedAt.ptr(),
+	}
+}
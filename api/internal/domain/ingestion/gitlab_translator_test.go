@@ -0,0 +1,218 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/google/uuid"
+)
+
+const gitlabMergeRequestFields = `
+	"id": 301,
+	"iid": 12,
+	"title": "Add the thing",
+	"description": "PROJ-1",
+	"url": "https://gitlab.com/acme/app/-/merge_requests/12",
+	"source_branch": "feature",
+	"target_branch": "main",
+	"last_commit": {"id": "ccc", "message": "wip", "timestamp": "2024-01-02T09:00:00+00:00", "author": {"name": "Alice"}},
+	"created_at": "2024-01-01 10:00:00 UTC",
+	"updated_at": "2024-01-02 10:00:00 UTC"`
+
+func gitlabMergeRequestHook(attrs string, changes string) string {
+	return `{
+		"user": {"username": "alice"},
+		"project": {"path_with_namespace": "acme/app"},
+		"changes": {` + changes + `},
+		"object_attributes": {` + gitlabMergeRequestFields + `, ` + attrs + `}
+	}`
+}
+
+func TestTranslateGitlabWebhook(t *testing.T) {
+	receivedAt := mustTime(t, "2024-02-01T00:00:00Z")
+
+	tests := []struct {
+		name      string
+		event     string
+		payload   string
+		wantTypes []changerequests.EventType
+		wantAt    string
+		check     func(t *testing.T, events []changerequests.Event)
+	}{
+		{
+			name:      "opened",
+			event:     gitlabEventMergeRequest,
+			payload:   gitlabMergeRequestHook(`"action": "open"`, ""),
+			wantTypes: []changerequests.EventType{changerequests.EventOpened},
+			wantAt:    "2024-01-01T10:00:00Z",
+			check: func(t *testing.T, events []changerequests.Event) {
+				cr := events[0].Payload.ChangeRequest
+
+				if cr.Number != 12 || cr.Author != "alice" || cr.Repository != "acme/app" || cr.HeadSHA != "ccc" || cr.BaseBranch != "main" {
+					t.Errorf("unexpected change request %+v", cr)
+				}
+			},
+		},
+		{
+			name:      "merged",
+			event:     gitlabEventMergeRequest,
+			payload:   gitlabMergeRequestHook(`"action": "merge"`, ""),
+			wantTypes: []changerequests.EventType{changerequests.EventMerged},
+			wantAt:    "2024-01-02T10:00:00Z",
+		},
+		{
+			name:      "approved",
+			event:     gitlabEventMergeRequest,
+			payload:   gitlabMergeRequestHook(`"action": "approved"`, ""),
+			wantTypes: []changerequests.EventType{changerequests.EventReviewSubmitted},
+			wantAt:    "2024-01-02T10:00:00Z",
+			check: func(t *testing.T, events []changerequests.Event) {
+				review := events[0].Payload.Review
+
+				if review == nil || review.Author != "alice" || review.State != changerequests.ReviewStateApproved {
+					t.Errorf("unexpected review %+v", review)
+				}
+			},
+		},
+		{
+			name:      "unapproved",
+			event:     gitlabEventMergeRequest,
+			payload:   gitlabMergeRequestHook(`"action": "unapproval"`, ""),
+			wantTypes: []changerequests.EventType{changerequests.EventReviewDismissed},
+			wantAt:    "2024-01-02T10:00:00Z",
+		},
+		{
+			name:      "commits pushed and marked ready in one update",
+			event:     gitlabEventMergeRequest,
+			payload:   gitlabMergeRequestHook(`"action": "update", "oldrev": "bbb"`, `"draft": {"previous": true, "current": false}`),
+			wantTypes: []changerequests.EventType{changerequests.EventCommitsPushed, changerequests.EventReadyForReview},
+			wantAt:    "2024-01-02T10:00:00Z",
+			check: func(t *testing.T, events []changerequests.Event) {
+				commits := events[0].Payload.Commits
+
+				if len(commits) != 1 || commits[0].SHA != "ccc" || !commits[0].CommittedAt.Equal(mustTime(t, "2024-01-02T09:00:00Z")) {
+					t.Errorf("unexpected commits %+v", commits)
+				}
+
+				if len(events[1].Payload.Commits) != 0 {
+					t.Errorf("expected no commits on the draft change")
+				}
+			},
+		},
+		{
+			name:      "converted to draft on an older gitlab",
+			event:     gitlabEventMergeRequest,
+			payload:   gitlabMergeRequestHook(`"action": "update"`, `"work_in_progress": {"previous": false, "current": true}`),
+			wantTypes: []changerequests.EventType{changerequests.EventConvertedToDraft},
+			wantAt:    "2024-01-02T10:00:00Z",
+		},
+		{
+			name:    "update that isn't a push or draft change",
+			event:   gitlabEventMergeRequest,
+			payload: gitlabMergeRequestHook(`"action": "update"`, `"title": {"previous": "a", "current": "b"}`),
+		},
+		{
+			name:    "unhandled action",
+			event:   gitlabEventMergeRequest,
+			payload: gitlabMergeRequestHook(`"action": "label"`, ""),
+		},
+		{
+			name:  "comment on a merge request",
+			event: gitlabEventNote,
+			payload: `{
+				"user": {"username": "bob"},
+				"object_attributes": {"id": 900, "note": "nit", "noteable_type": "MergeRequest", "created_at": "2024-01-02 11:00:00 UTC"},
+				"merge_request": {` + gitlabMergeRequestFields + `}
+			}`,
+			wantTypes: []changerequests.EventType{changerequests.EventReviewCommentAdded},
+			wantAt:    "2024-01-02T11:00:00Z",
+			check: func(t *testing.T, events []changerequests.Event) {
+				comment := events[0].Payload.Comment
+
+				if comment == nil || comment.ID != "900" || comment.Author != "bob" || comment.Body != "nit" {
+					t.Errorf("unexpected comment %+v", comment)
+				}
+			},
+		},
+		{
+			name:  "system note",
+			event: gitlabEventNote,
+			payload: `{
+				"object_attributes": {"id": 900, "note": "added 1 commit", "noteable_type": "MergeRequest", "system": true},
+				"merge_request": {` + gitlabMergeRequestFields + `}
+			}`,
+		},
+		{
+			name:    "comment on an issue",
+			event:   gitlabEventNote,
+			payload: `{"object_attributes": {"id": 900, "note": "hi", "noteable_type": "Issue"}}`,
+		},
+		{
+			name:  "merge request pipeline",
+			event: gitlabEventPipeline,
+			payload: `{
+				"object_attributes": {"id": 42, "sha": "ddd", "created_at": "2024-01-02 12:00:00 UTC"},
+				"merge_request": {` + gitlabMergeRequestFields + `},
+				"commit": {"id": "ddd", "message": "fix", "timestamp": "2024-01-02T11:30:00Z"}
+			}`,
+			wantTypes: []changerequests.EventType{changerequests.EventCommitsPushed},
+			wantAt:    "2024-01-02T12:00:00Z",
+			check: func(t *testing.T, events []changerequests.Event) {
+				p := events[0].Payload
+
+				if p.ChangeRequest.HeadSHA != "ddd" || len(p.Commits) != 1 || p.Commits[0].SHA != "ddd" {
+					t.Errorf("unexpected payload %+v", p)
+				}
+			},
+		},
+		{
+			name:    "branch pipeline",
+			event:   gitlabEventPipeline,
+			payload: `{"object_attributes": {"id": 42, "sha": "ddd"}}`,
+		},
+		{
+			name:    "unrelated event",
+			event:   "Push Hook",
+			payload: `{"ref": "refs/heads/main"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &GitlabWebhook{
+				ID:         uuid.New(),
+				Event:      tt.event,
+				OccurredAt: receivedAt,
+				Payload:    decodePayload(t, tt.payload),
+			}
+
+			events, err := translateGitlabWebhook(w)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(events) != len(tt.wantTypes) {
+				t.Fatalf("expected %d events, got %+v", len(tt.wantTypes), events)
+			}
+
+			for i, e := range events {
+				if e.Type != tt.wantTypes[i] {
+					t.Errorf("event %d type = %s, want %s", i, e.Type, tt.wantTypes[i])
+				}
+
+				if !e.OccurredAt.Equal(mustTime(t, tt.wantAt)) {
+					t.Errorf("event %d occurred at = %v, want %s", i, e.OccurredAt, tt.wantAt)
+				}
+
+				if e.AggregateID != "301" || e.SourceIntegration != IntegrationGitlab {
+					t.Errorf("event %d has unexpected source %+v", i, e)
+				}
+			}
+
+			if tt.check != nil {
+				tt.check(t, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package ingestion
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/tasks"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+const IntegrationJira = "jira"
+
+type JiraIngestorRepo interface {
+	// Create stores the webhook and the events translated from it, returning
+	// false if a webhook with the same delivery id has already been stored, in
+	// which case nothing is written.
+	Create(w *JiraWebhook, events []tasks.Event) (bool, error)
+}
+
+type JiraWebhook struct {
+	ID         uuid.UUID
+	DeliveryID string
+	Event      string
+	OccurredAt time.Time
+	Payload    map[string]any
+}
+
+type JiraEvent struct {
+	Payload map[string]any
+
+	// Not all versions of jira send a delivery id, in which case we can't
+	// detect redeliveries.
+	DeliveryID string
+
+	// ReceivedAt defaults to now, it's set for deliveries that were queued.
+	ReceivedAt time.Time
+}
+
+type JiraIngestorOpt func(*JiraIngestor)
+
+// WithJiraCustomNowProvider allows you to override the way we generate a
+// timestamp for now. By default it will use the db.NowUTC function.
+func WithJiraCustomNowProvider(f func() time.Time) JiraIngestorOpt {
+	return func(ji *JiraIngestor) {
+		ji.getNow = f
+	}
+}
+
+type JiraIngestor struct {
+	repo    JiraIngestorRepo
+	secrets WebhookSecretStore
+	getNow  func() time.Time
+}
+
+// Verify checks the X-Hub-Signature header value that jira sends when the
+// webhook has a secret configured, against the raw body of the request.
+func (ji *JiraIngestor) Verify(body []byte, signature string) error {
+	return verifySHA256Signature(ji.secrets.WebhookSecrets(IntegrationJira), body, signature)
+}
+
+// Process stores the raw webhook along with the task events it translates to.
+func (ji *JiraIngestor) Process(e JiraEvent) error {
+	// Unlike github, jira only tells us what happened in the payload.
+	event, _ := e.Payload["webhookEvent"].(string)
+
+	w := &JiraWebhook{
+		ID:         uuid.New(),
+		DeliveryID: e.DeliveryID,
+		Event:      event,
+		OccurredAt: e.ReceivedAt,
+		Payload:    e.Payload,
+	}
+
+	if w.OccurredAt.IsZero() {
+		w.OccurredAt = ji.getNow()
+	}
+
+	events, err := translateJiraWebhook(w)
+
+	if err != nil {
+		// Still keep the raw webhook, so the events can be recovered once
+		// whatever we failed to understand is fixed.
+		slog.Error("failed to translate jira webhook", "delivery_id", e.DeliveryID, "error", err)
+	}
+
+	created, err := ji.repo.Create(w, events)
+
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		slog.Debug("ignoring jira redelivery", "delivery_id", e.DeliveryID)
+	}
+
+	return nil
+}
+
+func (ji *JiraIngestor) ProcessQueued(item *QueueItem) error {
+	return ji.Process(JiraEvent{
+		Payload: item.Payload,
+		DeliveryID: item.DeliveryID,
+		ReceivedAt: item.ReceivedAt,
+	})
+}
+
+func NewJiraIngestor(repo JiraIngestorRepo, secrets WebhookSecretStore, opts ...JiraIngestorOpt) *JiraIngestor {
+	ji := &JiraIngestor{
+		repo:    repo,
+		secrets: secrets,
+		getNow:  dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(ji)
+	}
+
+	return ji
+}
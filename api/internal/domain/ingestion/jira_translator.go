@@ -0,0 +1,239 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/tasks"
+)
+
+// These only declare the parts of jira's payloads that we care about, see:
+// https://developer.atlassian.com/server/jira/platform/webhooks/
+
+// jiraTime handles the format jira uses for dates, which isn't quite RFC3339
+// as the offset has no colon e.g. 2024-01-02T10:00:00.000+0000
+type jiraTime struct {
+	time.Time
+}
+
+var jiraTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339Nano,
+}
+
+func (t *jiraTime) UnmarshalJSON(b []byte) error {
+	var raw string
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	if raw == "" {
+		return nil
+	}
+
+	var err error
+
+	for _, layout := range jiraTimeLayouts {
+		var parsed time.Time
+
+		if parsed, err = time.Parse(layout, raw); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+
+	return err
+}
+
+type jiraUser struct {
+	AccountID   string `json:"accountId"`
+	DisplayName string `json:"displayName"`
+}
+
+type jiraNamed struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type jiraStatus struct {
+	Name           string    `json:"name"`
+	StatusCategory jiraNamed `json:"statusCategory"`
+}
+
+type jiraIssueFields struct {
+	Summary   string     `json:"summary"`
+	Created   *jiraTime  `json:"created"`
+	Status    jiraStatus `json:"status"`
+	IssueType jiraNamed  `json:"issuetype"`
+	Project   jiraNamed  `json:"project"`
+	Priority  *jiraNamed `json:"priority"`
+	Assignee  *jiraUser  `json:"assignee"`
+	Reporter  *jiraUser  `json:"reporter"`
+}
+
+type jiraIssue struct {
+	ID     string          `json:"id"`
+	Key    string          `json:"key"`
+	Fields jiraIssueFields `json:"fields"`
+}
+
+type jiraChangeItem struct {
+	Field      string  `json:"field"`
+	FromString *string `json:"fromString"`
+	ToString   *string `json:"toString"`
+}
+
+type jiraChangelog struct {
+	ID    string           `json:"id"`
+	Items []jiraChangeItem `json:"items"`
+}
+
+type jiraWebhook struct {
+	Timestamp    int64          `json:"timestamp"`
+	WebhookEvent string         `json:"webhookEvent"`
+	User         *jiraUser      `json:"user"`
+	Issue        jiraIssue      `json:"issue"`
+	Changelog    *jiraChangelog `json:"changelog"`
+}
+
+// Changes to these fields get their own event type, everything else is just an
+// update.
+var jiraFieldEvents = map[string]tasks.EventType{
+	"status":   tasks.EventTransitioned,
+	"assignee": tasks.EventAssigned,
+	"sprint":   tasks.EventSprintChanged,
+}
+
+// translateJiraWebhook converts the raw webhook into task events. A single
+// update can change several fields, each of which becomes its own event.
+func translateJiraWebhook(w *JiraWebhook) ([]tasks.Event, error) {
+	raw, err := json.Marshal(w.Payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var hook jiraWebhook
+
+	if err := json.Unmarshal(raw, &hook); err != nil {
+		return nil, err
+	}
+
+	if hook.Issue.ID == "" {
+		return nil, nil
+	}
+
+	task := jiraTask(hook.Issue)
+	actor := ""
+
+	if hook.User != nil {
+		actor = hook.User.DisplayName
+	}
+
+	// Fallback to when we received it if jira didn't give us a time.
+	ts := w.OccurredAt
+
+	if hook.Timestamp > 0 {
+		ts = time.UnixMilli(hook.Timestamp).UTC()
+	}
+
+	newEvent := func(t tasks.EventType, occurredAt time.Time, discriminator string, change *tasks.Change) tasks.Event {
+		return tasks.Event{
+			ID:                tasks.NewEventID(IntegrationJira, hook.Issue.ID, t, occurredAt, discriminator),
+			AggregateID:       hook.Issue.ID,
+			Type:              t,
+			OccurredAt:        occurredAt,
+			SourceID:          &w.ID,
+			SourceIntegration: IntegrationJira,
+			Payload: tasks.Payload{
+				Task:   task,
+				Actor:  actor,
+				Change: change,
+			},
+		}
+	}
+
+	switch hook.WebhookEvent {
+	case "jira:issue_created":
+		if task.CreatedAt != nil {
+			ts = *task.CreatedAt
+		}
+
+		return []tasks.Event{newEvent(tasks.EventCreated, ts, "", nil)}, nil
+
+	case "jira:issue_deleted":
+		return []tasks.Event{newEvent(tasks.EventDeleted, ts, "", nil)}, nil
+
+	case "jira:issue_updated":
+		// Updates without a changelog are things like comments, which don't
+		// change the task itself.
+		if hook.Changelog == nil {
+			return nil, nil
+		}
+
+		events := make([]tasks.Event, 0, len(hook.Changelog.Items))
+
+		for _, item := range hook.Changelog.Items {
+			t, ok := jiraFieldEvents[strings.ToLower(item.Field)]
+
+			if !ok {
+				t = tasks.EventUpdated
+			}
+
+			events = append(events, newEvent(
+				t,
+				ts,
+				hook.Changelog.ID+"|"+item.Field,
+				&tasks.Change{
+					Field: item.Field,
+					From:  derefString(item.FromString),
+					To:    derefString(item.ToString),
+				},
+			))
+		}
+
+		return events, nil
+	}
+
+	return nil, nil
+}
+
+func jiraTask(issue jiraIssue) tasks.Task {
+	t := tasks.Task{
+		Key:            issue.Key,
+		Project:        issue.Fields.Project.Key,
+		Summary:        issue.Fields.Summary,
+		Type:           issue.Fields.IssueType.Name,
+		Status:         issue.Fields.Status.Name,
+		StatusCategory: issue.Fields.Status.StatusCategory.Key,
+	}
+
+	if issue.Fields.Created != nil && !issue.Fields.Created.IsZero() {
+		created := issue.Fields.Created.Time
+		t.CreatedAt = &created
+	}
+
+	if issue.Fields.Assignee != nil {
+		t.Assignee = issue.Fields.Assignee.DisplayName
+	}
+
+	if issue.Fields.Reporter != nil {
+		t.Reporter = issue.Fields.Reporter.DisplayName
+	}
+
+	if issue.Fields.Priority != nil {
+		t.Priority = issue.Fields.Priority.Name
+	}
+
+	return t
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
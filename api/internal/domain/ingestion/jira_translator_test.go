@@ -0,0 +1,136 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/tasks"
+	"github.com/google/uuid"
+)
+
+const jiraIssuePayload = `{
+	"id": "10001",
+	"key": "PROJ-1",
+	"fields": {
+		"summary": "Do the thing",
+		"created": "2024-01-01T10:00:00.000+0000",
+		"status": {"name": "In Progress", "statusCategory": {"key": "indeterminate"}},
+		"issuetype": {"name": "Story"},
+		"project": {"key": "PROJ"},
+		"priority": {"name": "High"},
+		"assignee": {"displayName": "Alice"},
+		"reporter": {"displayName": "Bob"}
+	}
+}`
+
+func TestTranslateJiraWebhook(t *testing.T) {
+	receivedAt := mustTime(t, "2024-02-01T00:00:00Z")
+
+	type wantEvent struct {
+		typ    tasks.EventType
+		at     string
+		change *tasks.Change
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []wantEvent
+	}{
+		{
+			name:    "issue created",
+			payload: `{"webhookEvent": "jira:issue_created", "timestamp": 1704200400000, "user": {"displayName": "Bob"}, "issue": ` + jiraIssuePayload + `}`,
+			want:    []wantEvent{{typ: tasks.EventCreated, at: "2024-01-01T10:00:00Z"}},
+		},
+		{
+			name:    "issue deleted",
+			payload: `{"webhookEvent": "jira:issue_deleted", "timestamp": 1704200400000, "issue": ` + jiraIssuePayload + `}`,
+			want:    []wantEvent{{typ: tasks.EventDeleted, at: "2024-01-02T13:00:00Z"}},
+		},
+		{
+			name: "each changed field is an event",
+			payload: `{"webhookEvent": "jira:issue_updated", "timestamp": 1704200400000, "issue": ` + jiraIssuePayload + `, "changelog": {"id": "500", "items": [
+				{"field": "status", "fromString": "To Do", "toString": "In Progress"},
+				{"field": "assignee", "fromString": null, "toString": "Alice"},
+				{"field": "Sprint", "fromString": "", "toString": "Sprint 1"},
+				{"field": "summary", "fromString": "Do", "toString": "Do the thing"}
+			]}}`,
+			want: []wantEvent{
+				{typ: tasks.EventTransitioned, at: "2024-01-02T13:00:00Z", change: &tasks.Change{Field: "status", From: "To Do", To: "In Progress"}},
+				{typ: tasks.EventAssigned, at: "2024-01-02T13:00:00Z", change: &tasks.Change{Field: "assignee", From: "", To: "Alice"}},
+				{typ: tasks.EventSprintChanged, at: "2024-01-02T13:00:00Z", change: &tasks.Change{Field: "Sprint", From: "", To: "Sprint 1"}},
+				{typ: tasks.EventUpdated, at: "2024-01-02T13:00:00Z", change: &tasks.Change{Field: "summary", From: "Do", To: "Do the thing"}},
+			},
+		},
+		{
+			name:    "update without a changelog",
+			payload: `{"webhookEvent": "jira:issue_updated", "timestamp": 1704200400000, "issue": ` + jiraIssuePayload + `}`,
+		},
+		{
+			name:    "missing timestamp falls back to when it was received",
+			payload: `{"webhookEvent": "jira:issue_deleted", "issue": ` + jiraIssuePayload + `}`,
+			want:    []wantEvent{{typ: tasks.EventDeleted, at: "2024-02-01T00:00:00Z"}},
+		},
+		{
+			name:    "without an issue",
+			payload: `{"webhookEvent": "comment_created", "timestamp": 1704200400000}`,
+		},
+		{
+			name:    "unhandled event",
+			payload: `{"webhookEvent": "jira:worklog_updated", "timestamp": 1704200400000, "issue": ` + jiraIssuePayload + `}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &JiraWebhook{
+				ID:         uuid.New(),
+				OccurredAt: receivedAt,
+				Payload:    decodePayload(t, tt.payload),
+			}
+
+			events, err := translateJiraWebhook(w)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(events) != len(tt.want) {
+				t.Fatalf("expected %d events, got %d", len(tt.want), len(events))
+			}
+
+			ids := map[uuid.UUID]bool{}
+
+			for i, want := range tt.want {
+				e := events[i]
+
+				if e.Type != want.typ {
+					t.Errorf("event %d type = %s, want %s", i, e.Type, want.typ)
+				}
+
+				if !e.OccurredAt.Equal(mustTime(t, want.at)) {
+					t.Errorf("event %d occurred at = %v, want %s", i, e.OccurredAt, want.at)
+				}
+
+				if want.change != nil && (e.Payload.Change == nil || *e.Payload.Change != *want.change) {
+					t.Errorf("event %d change = %+v, want %+v", i, e.Payload.Change, want.change)
+				}
+
+				task := e.Payload.Task
+
+				if e.AggregateID != "10001" || task.Key != "PROJ-1" || task.Project != "PROJ" || task.StatusCategory != "indeterminate" {
+					t.Errorf("event %d has unexpected task %+v", i, task)
+				}
+
+				if task.Assignee != "Alice" || task.Reporter != "Bob" || task.Priority != "High" {
+					t.Errorf("event %d has unexpected people on the task %+v", i, task)
+				}
+
+				if ids[e.ID] {
+					t.Errorf("event %d has the same id as an earlier event", i)
+				}
+
+				ids[e.ID] = true
+			}
+		})
+	}
+}
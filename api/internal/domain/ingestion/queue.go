@@ -0,0 +1,311 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// QueueItem is a webhook delivery waiting to be processed.
+type QueueItem struct {
+	ID          uuid.UUID
+	Integration string
+	DeliveryID  string
+	Event       string
+	Payload     map[string]any
+	Attempts    int
+	LastError   string
+	AvailableAt time.Time
+	ReceivedAt  time.Time
+}
+
+// DeadLetter is a delivery that failed to process after the maximum attempts.
+type DeadLetter struct {
+	ID          uuid.UUID
+	Integration string
+	DeliveryID  string
+	Event       string
+	Payload     map[string]any
+	Attempts    int
+	LastError   string
+	ReceivedAt  time.Time
+	FailedAt    time.Time
+}
+
+type QueueRepo interface {
+	// Enqueue adds the item, unless a delivery with the same id is already
+	// waiting. Those that have been processed are queued again, the ingestors
+	// ignore them.
+	Enqueue(item *QueueItem) error
+
+	// Claim takes the next available item, locking it until lockedUntil, or
+	// returns nil if there isn't one. Claiming counts as an attempt.
+	Claim(now time.Time, lockedUntil time.Time) (*QueueItem, error)
+
+	// Complete removes a processed item from the queue.
+	Complete(id uuid.UUID) error
+
+	// Retry unlocks the item, making it available again at its AvailableAt.
+	Retry(item *QueueItem) error
+
+	// Bury moves the item to the dead letters.
+	Bury(item *QueueItem, failedAt time.Time) error
+
+	DeadLetters(limit int) ([]*DeadLetter, error)
+	DeadLetter(id uuid.UUID) (*DeadLetter, error)
+
+	// Requeue moves dead letters back to the queue with their attempts reset,
+	// returning how many were moved. All of them are moved if no ids are given.
+	// Any that have been redelivered and queued since are only removed.
+	Requeue(ids []uuid.UUID, now time.Time) (int, error)
+}
+
+// QueueProcessor processes an integration's deliveries once they've been
+// dequeued.
+type QueueProcessor interface {
+	ProcessQueued(item *QueueItem) error
+}
+
+type QueueConfig interface {
+	IngestionQueueWorkers() int
+	IngestionQueueMaxAttempts() int
+	IngestionQueueBackoff() time.Duration
+	IngestionQueueMaxBackoff() time.Duration
+	IngestionQueuePollInterval() time.Duration
+
+	// IngestionQueueLease is how long a worker has to process an item before
+	// it's assumed to have died, and the item can be claimed again.
+	IngestionQueueLease() time.Duration
+}
+
+// Queue durably stores webhook deliveries so that they can be acknowledged
+// straight away, and processed by a pool of workers, retrying failures with
+// an exponential backoff. Deliveries that still fail after the maximum attempts
+// are moved to the dead letters, to be inspected and retried by hand.
+type Queue struct {
+	repo       QueueRepo
+	cfg        QueueConfig
+	processors map[string]QueueProcessor
+	getNow     func() time.Time
+
+	// wake lets workers know about new deliveries, rather than waiting to poll.
+	wake chan struct{}
+}
+
+func (q *Queue) enqueue(integration string, deliveryID string, event string, payload map[string]any) error {
+	now := q.getNow()
+
+	err := q.repo.Enqueue(&QueueItem{
+		ID: uuid.New(),
+		Integration: integration,
+		DeliveryID: deliveryID,
+		Event: event,
+		Payload: payload,
+		AvailableAt: now,
+		ReceivedAt: now,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (q *Queue) EnqueueGithub(e GithubEvent) error {
+	return q.enqueue(IntegrationGithub, e.DeliveryID, e.Event, e.Payload)
+}
+
+func (q *Queue) EnqueueGitlab(e GitlabEvent) error {
+	return q.enqueue(IntegrationGitlab, e.DeliveryID, e.Event, e.Payload)
+}
+
+func (q *Queue) EnqueueBitbucket(e BitbucketEvent) error {
+	return q.enqueue(IntegrationBitbucket, e.DeliveryID, e.Event, e.Payload)
+}
+
+func (q *Queue) EnqueueJira(e JiraEvent) error {
+	event, _ := e.Payload["webhookEvent"].(string)
+
+	return q.enqueue(IntegrationJira, e.DeliveryID, event, e.Payload)
+}
+
+// backoff is how long to wait before the next attempt, doubling with each
+// attempt up to the maximum.
+func (q *Queue) backoff(attempts int) time.Duration {
+	wait := q.cfg.IngestionQueueBackoff()
+
+	for i := 1; i < attempts && wait < q.cfg.IngestionQueueMaxBackoff(); i++ {
+		wait *= 2
+	}
+
+	return min(wait, q.cfg.IngestionQueueMaxBackoff())
+}
+
+func (q *Queue) process(item *QueueItem) error {
+	p, ok := q.processors[item.Integration]
+
+	if !ok {
+		return fmt.Errorf("no processor for integration: %s", item.Integration)
+	}
+
+	return p.ProcessQueued(item)
+}
+
+// next claims and processes the next available item, returning false if there
+// wasn't one.
+func (q *Queue) next() (bool, error) {
+	now := q.getNow()
+	item, err := q.repo.Claim(now, now.Add(q.cfg.IngestionQueueLease()))
+
+	if err != nil {
+		return false, err
+	}
+
+	if item == nil {
+		return false, nil
+	}
+
+	procErr := q.process(item)
+
+	if procErr == nil {
+		return true, q.repo.Complete(item.ID)
+	}
+
+	item.LastError = procErr.Error()
+	now = q.getNow()
+
+	if item.Attempts >= q.cfg.IngestionQueueMaxAttempts() {
+		slog.Error(
+			"giving up on ingestion delivery, moving to dead letters",
+			"id", item.ID,
+			"integration", item.Integration,
+			"delivery_id", item.DeliveryID,
+			"attempts", item.Attempts,
+			"error", procErr,
+		)
+
+		return true, q.repo.Bury(item, now)
+	}
+
+	item.AvailableAt = now.Add(q.backoff(item.Attempts))
+
+	slog.Warn(
+		"failed to process ingestion delivery, will retry",
+		"id", item.ID,
+		"integration", item.Integration,
+		"delivery_id", item.DeliveryID,
+		"attempts", item.Attempts,
+		"retry_at", item.AvailableAt,
+		"error", procErr,
+	)
+
+	return true, q.repo.Retry(item)
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.IngestionQueuePollInterval())
+	defer ticker.Stop()
+
+	for {
+		// Keep going while there's work, only waiting once the queue is empty.
+		for ctx.Err() == nil {
+			found, err := q.next()
+
+			if err != nil {
+				slog.Error("failed to process ingestion queue", "error", err)
+				break
+			}
+
+			if !found {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Work processes the queue with the configured number of workers, until the
+// context is cancelled. It returns once every worker has finished what it was
+// doing.
+func (q *Queue) Work(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for range max(q.cfg.IngestionQueueWorkers(), 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// DeadLetters returns the most recently failed deliveries first.
+func (q *Queue) DeadLetters(limit int) ([]*DeadLetter, error) {
+	return q.repo.DeadLetters(limit)
+}
+
+func (q *Queue) DeadLetter(id uuid.UUID) (*DeadLetter, error) {
+	dl, err := q.repo.DeadLetter(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if dl == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return dl, nil
+}
+
+// RetryDeadLetters puts dead letters back on the queue, with their attempts
+// reset. Every dead letter is retried if no ids are given.
+func (q *Queue) RetryDeadLetters(ids ...uuid.UUID) (int, error) {
+	return q.repo.Requeue(ids, q.getNow())
+}
+
+type QueueOpt func(*Queue)
+
+func NewQueue(repo QueueRepo, cfg QueueConfig, github *GithubIngestor, gitlab *GitlabIngestor, bitbucket *BitbucketIngestor, jira *JiraIngestor, opts ...QueueOpt) *Queue {
+	q := &Queue{
+		repo: repo,
+		cfg: cfg,
+		processors: map[string]QueueProcessor{
+			IntegrationGithub: github,
+			IntegrationGitlab: gitlab,
+			IntegrationBitbucket: bitbucket,
+			IntegrationJira: jira,
+		},
+		getNow: dt.NowUTC,
+		wake: make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
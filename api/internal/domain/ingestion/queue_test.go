@@ -0,0 +1,216 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type queueConfig struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+func (c queueConfig) IngestionQueueWorkers() int {
+	return 1
+}
+
+func (c queueConfig) IngestionQueueMaxAttempts() int {
+	return c.maxAttempts
+}
+
+func (c queueConfig) IngestionQueueBackoff() time.Duration {
+	return c.backoff
+}
+
+func (c queueConfig) IngestionQueueMaxBackoff() time.Duration {
+	return c.maxBackoff
+}
+
+func (c queueConfig) IngestionQueuePollInterval() time.Duration {
+	return time.Second
+}
+
+func (c queueConfig) IngestionQueueLease() time.Duration {
+	return time.Minute
+}
+
+// queueRepo hands out a single item, recording what happens to it.
+type queueRepo struct {
+	QueueRepo
+
+	item        *QueueItem
+	lockedUntil time.Time
+	completed   bool
+	retried     *QueueItem
+	buried      *QueueItem
+	buriedAt    time.Time
+}
+
+func (r *queueRepo) Claim(now time.Time, lockedUntil time.Time) (*QueueItem, error) {
+	if r.item == nil {
+		return nil, nil
+	}
+
+	item := r.item
+	r.item = nil
+	r.lockedUntil = lockedUntil
+	item.Attempts++
+
+	return item, nil
+}
+
+func (r *queueRepo) Complete(id uuid.UUID) error {
+	r.completed = true
+
+	return nil
+}
+
+func (r *queueRepo) Retry(item *QueueItem) error {
+	r.retried = item
+
+	return nil
+}
+
+func (r *queueRepo) Bury(item *QueueItem, failedAt time.Time) error {
+	r.buried = item
+	r.buriedAt = failedAt
+
+	return nil
+}
+
+type queueProcessor struct {
+	err error
+}
+
+func (p queueProcessor) ProcessQueued(item *QueueItem) error {
+	return p.err
+}
+
+func TestQueueBackoff(t *testing.T) {
+	q := &Queue{cfg: queueConfig{backoff: 10 * time.Second, maxBackoff: time.Minute}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestQueueNext(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	failure := errors.New("database is down")
+
+	tests := []struct {
+		name        string
+		item        *QueueItem
+		processErr  error
+		wantFound   bool
+		wantDone    bool
+		wantRetryAt time.Time
+		wantBuried  bool
+		wantError   string
+	}{
+		{
+			name: "empty queue",
+		},
+		{
+			name:      "processed",
+			item:      &QueueItem{Integration: IntegrationGithub},
+			wantFound: true,
+			wantDone:  true,
+		},
+		{
+			name:        "failed on the first attempt",
+			item:        &QueueItem{Integration: IntegrationGithub},
+			processErr:  failure,
+			wantFound:   true,
+			wantRetryAt: now.Add(10 * time.Second),
+			wantError:   failure.Error(),
+		},
+		{
+			name:        "failed again backs off for longer",
+			item:        &QueueItem{Integration: IntegrationGithub, Attempts: 2},
+			processErr:  failure,
+			wantFound:   true,
+			wantRetryAt: now.Add(40 * time.Second),
+			wantError:   failure.Error(),
+		},
+		{
+			name:       "failed on the last attempt",
+			item:       &QueueItem{Integration: IntegrationGithub, Attempts: 4},
+			processErr: failure,
+			wantFound:  true,
+			wantBuried: true,
+			wantError:  failure.Error(),
+		},
+		{
+			name:        "no processor for the integration",
+			item:        &QueueItem{Integration: "svn"},
+			wantFound:   true,
+			wantRetryAt: now.Add(10 * time.Second),
+			wantError:   "no processor for integration: svn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &queueRepo{item: tt.item}
+			q := &Queue{
+				repo:       repo,
+				cfg:        queueConfig{maxAttempts: 5, backoff: 10 * time.Second, maxBackoff: time.Hour},
+				processors: map[string]QueueProcessor{IntegrationGithub: queueProcessor{err: tt.processErr}},
+				getNow:     func() time.Time { return now },
+			}
+
+			found, err := q.next()
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if found != tt.wantFound || repo.completed != tt.wantDone {
+				t.Errorf("found %t and completed %t, want %t and %t", found, repo.completed, tt.wantFound, tt.wantDone)
+			}
+
+			if found && !repo.lockedUntil.Equal(now.Add(time.Minute)) {
+				t.Errorf("expected the item to be leased until %v, got %v", now.Add(time.Minute), repo.lockedUntil)
+			}
+
+			if tt.wantRetryAt.IsZero() != (repo.retried == nil) {
+				t.Fatalf("expected retry at %v, got %+v", tt.wantRetryAt, repo.retried)
+			}
+
+			if repo.retried != nil && !repo.retried.AvailableAt.Equal(tt.wantRetryAt) {
+				t.Errorf("retry at %v, want %v", repo.retried.AvailableAt, tt.wantRetryAt)
+			}
+
+			if tt.wantBuried != (repo.buried != nil) {
+				t.Fatalf("expected buried %t, got %+v", tt.wantBuried, repo.buried)
+			}
+
+			if repo.buried != nil && !repo.buriedAt.Equal(now) {
+				t.Errorf("buried at %v, want %v", repo.buriedAt, now)
+			}
+
+			if tt.item != nil && tt.item.LastError != tt.wantError {
+				t.Errorf("last error = %q, want %q", tt.item.LastError, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package ingestion
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookSecretStore provides the shared secrets that an integration uses to
+// sign its webhook payloads. More than one secret can be returned to allow for
+// rotation, a payload is valid if it matches any of them.
+type WebhookSecretStore interface {
+	WebhookSecrets(integration string) []string
+}
+
+// verifySHA256Signature checks a signature in the form 'sha256=<hex digest>'
+// against the HMAC-SHA256 of the body for each of the given secrets.
+func verifySHA256Signature(secrets []string, body []byte, signature string) error {
+	digest, found := strings.CutPrefix(signature, "sha256=")
+
+	if !found {
+		return ErrInvalidSignature
+	}
+
+	expected, err := hex.DecodeString(digest)
+
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+
+		// hmac.Equal is constant time, so we don't leak how much of the
+		// signature matched.
+		if hmac.Equal(mac.Sum(nil), expected) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// verifyToken checks a token sent as is, rather than a signature, against
+// each of the given secrets.
+func verifyToken(secrets []string, token string) error {
+	if token == "" {
+		return ErrInvalidSignature
+	}
+
+	given := sha256.Sum256([]byte(token))
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+
+		// Comparing digests takes the same time whatever the length of the
+		// token, so we don't leak how much of it matched.
+		expected := sha256.Sum256([]byte(secret))
+
+		if subtle.ConstantTimeCompare(given[:], expected[:]) == 1 {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
@@ -0,0 +1,351 @@
+package projections
+
+import (
+	"slices"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+const ChangeRequestsProjection = "change_requests"
+
+const ChangeRequestStateOpen = "open"
+const ChangeRequestStateClosed = "closed"
+const ChangeRequestStateMerged = "merged"
+
+// ChangeRequestKey identifies a change request, aggregate ids are only unique
+// within an integration.
+type ChangeRequestKey struct {
+	SourceIntegration string
+	ID                string
+}
+
+// ChangeRequest is the read model for a change request, summarising its whole
+// lifecycle so that things like cycle time can be queried without replaying
+// events.
+type ChangeRequest struct {
+	ID                string
+	SourceIntegration string
+
+	Number     int
+	Title      string
+	URL        string
+	Author     string
+	Repository string
+	BaseBranch string
+	HeadBranch string
+	State      string
+	IsDraft    bool
+
+	OpenedAt      *time.Time
+	FirstCommitAt *time.Time
+	FirstReviewAt *time.Time
+	ApprovedAt    *time.Time
+	MergedAt      *time.Time
+	ClosedAt      *time.Time
+
+	// ReopenedAt is when the change request was last reopened, so that a close
+	// from before it that's recorded late doesn't close it again.
+	ReopenedAt *time.Time
+
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	Commits      int
+
+	ReviewCount           int
+	ApprovalCount         int
+	ChangesRequestedCount int
+	CommentCount          int
+
+	// ApprovingReviews are the ids of the approvals counted in ApprovalCount,
+	// so that dismissing one takes it off again.
+	ApprovingReviews []string
+
+	// LastEventAt is when the latest event applied occurred. Events are applied
+	// in the order they were recorded, so one that occurred before this (e.g.
+	// from a backfill) only fills in what's missing, rather than winding the
+	// change request back.
+	LastEventAt time.Time
+
+	// LastEventSeq is the highest sequence applied, events at or before it are
+	// ignored so that applying an event twice doesn't double count.
+	LastEventSeq int64
+}
+
+func (cr *ChangeRequest) Key() ChangeRequestKey {
+	return ChangeRequestKey{
+		SourceIntegration: cr.SourceIntegration,
+		ID:                cr.ID,
+	}
+}
+
+func (cr *ChangeRequest) hasApplied(e changerequests.Event) bool {
+	return e.Sequence <= cr.LastEventSeq
+}
+
+// Apply updates the read model with the event, returning false if the event
+// has already been applied.
+func (cr *ChangeRequest) Apply(e changerequests.Event) bool {
+	if cr.hasApplied(e) {
+		return false
+	}
+
+	snapshot := e.Payload.ChangeRequest
+
+	// Whether this is the latest we've heard of the change request, if not
+	// its snapshot and state are out of date.
+	latest := !e.OccurredAt.Before(cr.LastEventAt)
+
+	// Some payloads (e.g. github reviews) carry a cut down version of the
+	// change request, so only take what's actually there.
+	setIfNotEmpty(&cr.Title, snapshot.Title, latest)
+	setIfNotEmpty(&cr.URL, snapshot.URL, latest)
+	setIfNotEmpty(&cr.Author, snapshot.Author, latest)
+	setIfNotEmpty(&cr.Repository, snapshot.Repository, latest)
+	setIfNotEmpty(&cr.BaseBranch, snapshot.BaseBranch, latest)
+	setIfNotEmpty(&cr.HeadBranch, snapshot.HeadBranch, latest)
+	setIfNotZero(&cr.Number, snapshot.Number, latest)
+	setIfNotZero(&cr.Additions, snapshot.Additions, latest)
+	setIfNotZero(&cr.Deletions, snapshot.Deletions, latest)
+	setIfNotZero(&cr.ChangedFiles, snapshot.ChangedFiles, latest)
+	setIfNotZero(&cr.Commits, snapshot.Commits, latest)
+
+	if cr.State == "" {
+		cr.State = ChangeRequestStateOpen
+	}
+
+	// We may not have seen the change request being opened, e.g. if it was
+	// opened before we started receiving webhooks.
+	cr.OpenedAt = earliest(cr.OpenedAt, snapshot.CreatedAt)
+
+	for _, commit := range e.Payload.Commits {
+		cr.FirstCommitAt = earliest(cr.FirstCommitAt, commit.CommittedAt)
+	}
+
+	occurredAt := e.OccurredAt
+
+	switch e.Type {
+	case changerequests.EventOpened:
+		cr.OpenedAt = earliest(cr.OpenedAt, &occurredAt)
+
+		if latest {
+			cr.IsDraft = snapshot.IsDraft
+		}
+
+	case changerequests.EventReadyForReview:
+		if latest {
+			cr.IsDraft = false
+		}
+
+	case changerequests.EventConvertedToDraft:
+		if latest {
+			cr.IsDraft = true
+		}
+
+	case changerequests.EventReopened:
+		cr.ReopenedAt = latestOf(cr.ReopenedAt, &occurredAt)
+
+		// Unless it's been closed again since.
+		if cr.State == ChangeRequestStateClosed && (cr.ClosedAt == nil || !occurredAt.Before(*cr.ClosedAt)) {
+			cr.State = ChangeRequestStateOpen
+			cr.ClosedAt = nil
+		}
+
+	case changerequests.EventClosed:
+		// Like merging, this stands even if we've since heard of something
+		// later, unless that was it being reopened.
+		if cr.State == ChangeRequestStateMerged || (cr.ReopenedAt != nil && cr.ReopenedAt.After(occurredAt)) {
+			break
+		}
+
+		if cr.State != ChangeRequestStateClosed || cr.ClosedAt == nil || occurredAt.After(*cr.ClosedAt) {
+			cr.State = ChangeRequestStateClosed
+			cr.ClosedAt = &occurredAt
+		}
+
+	case changerequests.EventMerged:
+		// A change request can only be merged once, so this stands even if
+		// we've since heard of something later.
+		cr.State = ChangeRequestStateMerged
+		cr.MergedAt = &occurredAt
+		cr.ClosedAt = &occurredAt
+
+	case changerequests.EventReviewSubmitted:
+		review := e.Payload.Review
+
+		// Authors replying to review comments show up as reviews of their own
+		// change, which would make time to first review look very quick.
+		if review == nil || review.Author == cr.Author {
+			break
+		}
+
+		cr.ReviewCount++
+		cr.FirstReviewAt = earliest(cr.FirstReviewAt, &occurredAt)
+
+		switch review.State {
+		case changerequests.ReviewStateApproved:
+			cr.ApprovalCount++
+			cr.ApprovedAt = earliest(cr.ApprovedAt, &occurredAt)
+
+			if review.ID != "" && !slices.Contains(cr.ApprovingReviews, review.ID) {
+				cr.ApprovingReviews = append(cr.ApprovingReviews, review.ID)
+			}
+		case changerequests.ReviewStateChangesRequested:
+			cr.ChangesRequestedCount++
+		}
+
+	case changerequests.EventReviewDismissed:
+		review := e.Payload.Review
+
+		if review == nil {
+			break
+		}
+
+		// Dismissing anything but an approval we've counted changes nothing.
+		// ApprovedAt is when it was first approved, so stays as it is.
+		if i := slices.Index(cr.ApprovingReviews, review.ID); i >= 0 {
+			cr.ApprovingReviews = slices.Delete(cr.ApprovingReviews, i, i+1)
+			cr.ApprovalCount--
+		}
+
+	case changerequests.EventReviewCommentAdded:
+		cr.CommentCount++
+	}
+
+	// Not every integration sends the commits (e.g. github only sends the new
+	// head), but there must have been one when it was opened, so that's the
+	// latest the first commit can have been.
+	cr.FirstCommitAt = earliest(cr.FirstCommitAt, cr.OpenedAt)
+
+	if latest {
+		cr.LastEventAt = e.OccurredAt
+	}
+
+	cr.LastEventSeq = e.Sequence
+
+	return true
+}
+
+// setIfNotEmpty sets dest to val, if there is a val. Unless overwrite is true
+// it only fills dest in when it's empty.
+func setIfNotEmpty(dest *string, val string, overwrite bool) {
+	if val != "" && (overwrite || *dest == "") {
+		*dest = val
+	}
+}
+
+// setIfNotZero is setIfNotEmpty for ints.
+func setIfNotZero(dest *int, val int, overwrite bool) {
+	if val != 0 && (overwrite || *dest == 0) {
+		*dest = val
+	}
+}
+
+// latestOf is the opposite of earliest.
+func latestOf(current *time.Time, candidate *time.Time) *time.Time {
+	if candidate == nil {
+		return current
+	}
+
+	if current == nil || candidate.After(*current) {
+		ts := *candidate
+		return &ts
+	}
+
+	return current
+}
+
+func earliest(current *time.Time, candidate *time.Time) *time.Time {
+	if candidate == nil {
+		return current
+	}
+
+	if current == nil || candidate.Before(*current) {
+		ts := *candidate
+		return &ts
+	}
+
+	return current
+}
+
+type ChangeRequestsStore interface {
+	// GetMany returns the change requests that exist for the given keys.
+	GetMany(keys []ChangeRequestKey) (map[ChangeRequestKey]*ChangeRequest, error)
+	SaveMany(crs []*ChangeRequest) error
+	Truncate() error
+}
+
+// ChangeRequestsProjector maintains the change requests read model, the store
+// decides where it actually lives.
+type ChangeRequestsProjector struct {
+	store ChangeRequestsStore
+}
+
+func (p *ChangeRequestsProjector) Name() string {
+	return ChangeRequestsProjection
+}
+
+func (p *ChangeRequestsProjector) Reset() error {
+	return p.store.Truncate()
+}
+
+func (p *ChangeRequestsProjector) Apply(events []changerequests.Event) error {
+	keys := []ChangeRequestKey{}
+	seen := map[ChangeRequestKey]bool{}
+
+	for _, e := range events {
+		k := ChangeRequestKey{SourceIntegration: e.SourceIntegration, ID: e.AggregateID}
+
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+
+	existing, err := p.store.GetMany(keys)
+
+	if err != nil {
+		return err
+	}
+
+	changed := map[ChangeRequestKey]*ChangeRequest{}
+
+	for _, e := range events {
+		k := ChangeRequestKey{SourceIntegration: e.SourceIntegration, ID: e.AggregateID}
+		cr, ok := existing[k]
+
+		if !ok {
+			cr = &ChangeRequest{
+				ID:                e.AggregateID,
+				SourceIntegration: e.SourceIntegration,
+			}
+			existing[k] = cr
+		}
+
+		if cr.Apply(e) {
+			changed[k] = cr
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	toSave := make([]*ChangeRequest, 0, len(changed))
+
+	// Keep the order stable, makes the writes more predictable.
+	for _, k := range keys {
+		if cr, ok := changed[k]; ok {
+			toSave = append(toSave, cr)
+		}
+	}
+
+	return p.store.SaveMany(toSave)
+}
+
+func NewChangeRequestsProjector(store ChangeRequestsStore) *ChangeRequestsProjector {
+	return &ChangeRequestsProjector{
+		store: store,
+	}
+}
@@ -0,0 +1,421 @@
+package projections
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+var base = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func at(hours int) time.Time {
+	return base.Add(time.Duration(hours) * time.Hour)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func event(seq int64, t changerequests.EventType, occurredAt time.Time, payload changerequests.Payload) changerequests.Event {
+	return changerequests.Event{
+		AggregateID:       "1",
+		SourceIntegration: "github",
+		Type:              t,
+		OccurredAt:        occurredAt,
+		Sequence:          seq,
+		Payload:           payload,
+	}
+}
+
+func snapshot(title string) changerequests.Payload {
+	return changerequests.Payload{
+		ChangeRequest: changerequests.ChangeRequest{
+			Number: 7,
+			Title:  title,
+			Author: "alice",
+		},
+	}
+}
+
+func review(author string, state changerequests.ReviewState) changerequests.Payload {
+	return reviewWithID("", author, state)
+}
+
+func reviewWithID(id string, author string, state changerequests.ReviewState) changerequests.Payload {
+	p := snapshot("")
+	p.Review = &changerequests.Review{ID: id, Author: author, State: state}
+
+	return p
+}
+
+func commits(times ...time.Time) changerequests.Payload {
+	p := snapshot("")
+
+	for _, ts := range times {
+		p.Commits = append(p.Commits, changerequests.Commit{CommittedAt: ptr(ts)})
+	}
+
+	return p
+}
+
+func sameTime(a *time.Time, b time.Time) bool {
+	return a != nil && a.Equal(b)
+}
+
+func TestChangeRequestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []changerequests.Event
+		check  func(t *testing.T, cr *ChangeRequest)
+	}{
+		{
+			name: "full lifecycle",
+			events: []changerequests.Event{
+				event(1, changerequests.EventOpened, at(0), snapshot("Add the thing")),
+				event(2, changerequests.EventCommitsPushed, at(1), commits(at(-1))),
+				event(3, changerequests.EventReviewSubmitted, at(2), review("bob", changerequests.ReviewStateChangesRequested)),
+				event(4, changerequests.EventReviewSubmitted, at(3), review("bob", changerequests.ReviewStateApproved)),
+				event(5, changerequests.EventReviewCommentAdded, at(3), snapshot("")),
+				event(6, changerequests.EventMerged, at(4), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateMerged || cr.Title != "Add the thing" || cr.Number != 7 {
+					t.Errorf("unexpected change request %+v", cr)
+				}
+
+				if !sameTime(cr.OpenedAt, at(0)) || !sameTime(cr.FirstCommitAt, at(-1)) || !sameTime(cr.FirstReviewAt, at(2)) || !sameTime(cr.ApprovedAt, at(3)) || !sameTime(cr.MergedAt, at(4)) {
+					t.Errorf("unexpected times %+v", cr)
+				}
+
+				if cr.ReviewCount != 2 || cr.ApprovalCount != 1 || cr.ChangesRequestedCount != 1 || cr.CommentCount != 1 {
+					t.Errorf("unexpected counts %+v", cr)
+				}
+
+				if cr.LastEventSeq != 6 || !cr.LastEventAt.Equal(at(4)) {
+					t.Errorf("unexpected last event %d at %v", cr.LastEventSeq, cr.LastEventAt)
+				}
+			},
+		},
+		{
+			name: "the same event twice is only counted once",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewSubmitted, at(1), review("bob", changerequests.ReviewStateApproved)),
+				event(1, changerequests.EventReviewSubmitted, at(1), review("bob", changerequests.ReviewStateApproved)),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.ReviewCount != 1 || cr.ApprovalCount != 1 {
+					t.Errorf("expected a single approval, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "events before the last sequence are ignored",
+			events: []changerequests.Event{
+				event(5, changerequests.EventReviewCommentAdded, at(1), snapshot("")),
+				event(3, changerequests.EventReviewCommentAdded, at(2), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.CommentCount != 1 || cr.LastEventSeq != 5 {
+					t.Errorf("expected only the first comment, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "a late close doesn't undo a reopen",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReopened, at(3), snapshot("")),
+				event(2, changerequests.EventClosed, at(2), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateOpen || cr.ClosedAt != nil {
+					t.Errorf("expected it to stay open, got %+v", cr)
+				}
+
+				if !cr.LastEventAt.Equal(at(3)) {
+					t.Errorf("expected the last event to stay at the reopen, got %v", cr.LastEventAt)
+				}
+			},
+		},
+		{
+			name: "a late close still closes",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewCommentAdded, at(5), snapshot("")),
+				event(2, changerequests.EventClosed, at(4), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateClosed || !sameTime(cr.ClosedAt, at(4)) {
+					t.Errorf("expected it to be closed, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "a late close after an earlier reopen still closes",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReopened, at(2), snapshot("")),
+				event(2, changerequests.EventReviewCommentAdded, at(5), snapshot("")),
+				event(3, changerequests.EventClosed, at(4), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateClosed || !sameTime(cr.ClosedAt, at(4)) {
+					t.Errorf("expected it to be closed, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "a late reopen undoes an earlier close",
+			events: []changerequests.Event{
+				event(1, changerequests.EventClosed, at(2), snapshot("")),
+				event(2, changerequests.EventReviewCommentAdded, at(5), snapshot("")),
+				event(3, changerequests.EventReopened, at(4), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateOpen || cr.ClosedAt != nil {
+					t.Errorf("expected it to be open, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "a late reopen doesn't undo a later close",
+			events: []changerequests.Event{
+				event(1, changerequests.EventClosed, at(4), snapshot("")),
+				event(2, changerequests.EventReopened, at(2), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateClosed || !sameTime(cr.ClosedAt, at(4)) {
+					t.Errorf("expected it to stay closed, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "a late close doesn't undo a merge",
+			events: []changerequests.Event{
+				event(1, changerequests.EventMerged, at(4), snapshot("")),
+				event(2, changerequests.EventClosed, at(2), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateMerged || !sameTime(cr.ClosedAt, at(4)) {
+					t.Errorf("expected it to stay merged, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "dismissing an approval takes it off",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewSubmitted, at(1), reviewWithID("1", "bob", changerequests.ReviewStateApproved)),
+				event(2, changerequests.EventReviewSubmitted, at(2), reviewWithID("2", "carol", changerequests.ReviewStateApproved)),
+				event(3, changerequests.EventReviewDismissed, at(3), reviewWithID("1", "bob", changerequests.ReviewStateApproved)),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.ApprovalCount != 1 || len(cr.ApprovingReviews) != 1 || cr.ApprovingReviews[0] != "2" {
+					t.Errorf("expected only the second approval, got %+v", cr)
+				}
+
+				if cr.ReviewCount != 2 || !sameTime(cr.ApprovedAt, at(1)) {
+					t.Errorf("expected the reviews and when it was first approved to stay, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "dismissing the same approval twice only takes it off once",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewSubmitted, at(1), reviewWithID("1", "bob", changerequests.ReviewStateApproved)),
+				event(2, changerequests.EventReviewSubmitted, at(2), reviewWithID("2", "carol", changerequests.ReviewStateApproved)),
+				event(3, changerequests.EventReviewDismissed, at(3), reviewWithID("1", "bob", changerequests.ReviewStateApproved)),
+				event(4, changerequests.EventReviewDismissed, at(4), reviewWithID("1", "bob", changerequests.ReviewStateApproved)),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.ApprovalCount != 1 {
+					t.Errorf("expected one approval, got %d", cr.ApprovalCount)
+				}
+			},
+		},
+		{
+			name: "dismissing anything but an approval changes nothing",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewSubmitted, at(1), reviewWithID("1", "bob", changerequests.ReviewStateApproved)),
+				event(2, changerequests.EventReviewSubmitted, at(2), reviewWithID("2", "carol", changerequests.ReviewStateChangesRequested)),
+				event(3, changerequests.EventReviewDismissed, at(3), reviewWithID("2", "carol", changerequests.ReviewStateChangesRequested)),
+				event(4, changerequests.EventReviewDismissed, at(4), reviewWithID("3", "dave", changerequests.ReviewStateApproved)),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.ApprovalCount != 1 || cr.ChangesRequestedCount != 1 {
+					t.Errorf("expected the counts to stay, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "the first commit is no later than when it was opened",
+			events: []changerequests.Event{
+				event(1, changerequests.EventOpened, at(0), snapshot("Add the thing")),
+				event(2, changerequests.EventCommitsPushed, at(2), commits(at(2))),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if !sameTime(cr.FirstCommitAt, at(0)) {
+					t.Errorf("expected the first commit at %v, got %v", at(0), cr.FirstCommitAt)
+				}
+			},
+		},
+		{
+			name: "the first commit is no later than when it was created",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewCommentAdded, at(5), changerequests.Payload{ChangeRequest: changerequests.ChangeRequest{CreatedAt: ptr(at(-24))}}),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if !sameTime(cr.FirstCommitAt, at(-24)) {
+					t.Errorf("expected the first commit at %v, got %v", at(-24), cr.FirstCommitAt)
+				}
+			},
+		},
+		{
+			name: "a late event only fills in what's missing",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReadyForReview, at(3), changerequests.Payload{ChangeRequest: changerequests.ChangeRequest{Title: "New title"}}),
+				event(2, changerequests.EventOpened, at(0), changerequests.Payload{ChangeRequest: changerequests.ChangeRequest{Title: "Old title", Number: 7, IsDraft: true}}),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.Title != "New title" || cr.Number != 7 || cr.IsDraft {
+					t.Errorf("expected the newer snapshot to win, got %+v", cr)
+				}
+
+				if !sameTime(cr.OpenedAt, at(0)) {
+					t.Errorf("expected it to be opened at the late event, got %v", cr.OpenedAt)
+				}
+			},
+		},
+		{
+			name: "a late merge still merges",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewCommentAdded, at(5), snapshot("")),
+				event(2, changerequests.EventMerged, at(4), snapshot("")),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateMerged || !sameTime(cr.MergedAt, at(4)) {
+					t.Errorf("expected it to be merged, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "the author reviewing their own change isn't a review",
+			events: []changerequests.Event{
+				event(1, changerequests.EventOpened, at(0), snapshot("Add the thing")),
+				event(2, changerequests.EventReviewSubmitted, at(1), review("alice", changerequests.ReviewStateCommented)),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.ReviewCount != 0 || cr.FirstReviewAt != nil {
+					t.Errorf("expected no reviews, got %+v", cr)
+				}
+			},
+		},
+		{
+			name: "backfilled commits move the first commit earlier",
+			events: []changerequests.Event{
+				event(1, changerequests.EventCommitsPushed, at(5), commits(at(5))),
+				event(2, changerequests.EventCommitsPushed, at(1), commits(at(2), at(1))),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if !sameTime(cr.FirstCommitAt, at(1)) {
+					t.Errorf("expected the first commit at %v, got %v", at(1), cr.FirstCommitAt)
+				}
+			},
+		},
+		{
+			name: "opened before we heard of it",
+			events: []changerequests.Event{
+				event(1, changerequests.EventReviewCommentAdded, at(5), changerequests.Payload{ChangeRequest: changerequests.ChangeRequest{CreatedAt: ptr(at(-24))}}),
+			},
+			check: func(t *testing.T, cr *ChangeRequest) {
+				if cr.State != ChangeRequestStateOpen || !sameTime(cr.OpenedAt, at(-24)) {
+					t.Errorf("expected it open since it was created, got %+v", cr)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &ChangeRequest{}
+
+			for _, e := range tt.events {
+				cr.Apply(e)
+			}
+
+			tt.check(t, cr)
+		})
+	}
+}
+
+func TestChangeRequestApplyReportsReplays(t *testing.T) {
+	cr := &ChangeRequest{}
+	e := event(1, changerequests.EventOpened, at(0), snapshot("Add the thing"))
+
+	if !cr.Apply(e) {
+		t.Fatal("expected the first apply to change the change request")
+	}
+
+	if cr.Apply(e) {
+		t.Error("expected applying the same event again to do nothing")
+	}
+}
+
+type changeRequestsStore struct {
+	saved map[ChangeRequestKey]*ChangeRequest
+	saves int
+}
+
+func (s *changeRequestsStore) GetMany(keys []ChangeRequestKey) (map[ChangeRequestKey]*ChangeRequest, error) {
+	found := map[ChangeRequestKey]*ChangeRequest{}
+
+	for _, k := range keys {
+		if cr, ok := s.saved[k]; ok {
+			copied := *cr
+			found[k] = &copied
+		}
+	}
+
+	return found, nil
+}
+
+func (s *changeRequestsStore) SaveMany(crs []*ChangeRequest) error {
+	s.saves++
+
+	for _, cr := range crs {
+		s.saved[cr.Key()] = cr
+	}
+
+	return nil
+}
+
+func (s *changeRequestsStore) Truncate() error {
+	s.saved = map[ChangeRequestKey]*ChangeRequest{}
+
+	return nil
+}
+
+// Catching up after a crash can apply a batch that was already applied, which
+// mustn't change anything.
+func TestChangeRequestsProjectorReplayedBatch(t *testing.T) {
+	store := &changeRequestsStore{saved: map[ChangeRequestKey]*ChangeRequest{}}
+	p := NewChangeRequestsProjector(store)
+
+	batch := []changerequests.Event{
+		event(1, changerequests.EventOpened, at(0), snapshot("Add the thing")),
+		event(2, changerequests.EventReviewSubmitted, at(1), review("bob", changerequests.ReviewStateApproved)),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Apply(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if store.saves != 1 {
+		t.Errorf("expected the replayed batch not to be saved, saved %d times", store.saves)
+	}
+
+	cr := store.saved[ChangeRequestKey{SourceIntegration: "github", ID: "1"}]
+
+	if cr == nil || cr.ReviewCount != 1 || cr.ApprovalCount != 1 {
+		t.Errorf("expected a single approval, got %+v", cr)
+	}
+}
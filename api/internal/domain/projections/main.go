@@ -0,0 +1,257 @@
+// Package projections builds read models from the change requests stream.
+//
+// Each projection keeps a checkpoint of the last event it applied, so it can
+// pick up where it left off. Events are read in the order they were recorded,
+// by their sequence, not the order they occurred in, so events recorded late
+// (e.g. from a backfill) are still picked up. Projections must not rely on
+// events arriving in occurred_at order.
+//
+// Only one process works on a projection at a time, so that a rebuild from the
+// CLI can't interleave with the API catching the same projection up.
+package projections
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+)
+
+var ErrUnknownProjection = errors.New("unknown projection")
+
+type Projector interface {
+	// Name uniquely identifies the projection, it's used for the checkpoint
+	// and to refer to it from the CLI.
+	Name() string
+
+	// Reset removes everything the projection has built, so that it can be
+	// replayed from scratch.
+	Reset() error
+
+	// Apply applies a batch of events, in the order they were recorded.
+	Apply(events []changerequests.Event) error
+}
+
+type Checkpoint struct {
+	Projection string
+
+	// Sequence is that of the last event applied.
+	Sequence  int64
+	UpdatedAt time.Time
+}
+
+type CheckpointsRepo interface {
+	// Get returns nil if the projection has no checkpoint yet.
+	Get(projection string) (*Checkpoint, error)
+	Save(c *Checkpoint) error
+	Delete(projection string) error
+}
+
+type EventStore interface {
+	// After returns up to limit events that come after the checkpoint, ordered
+	// by sequence. A nil checkpoint starts from the beginning.
+	After(c *Checkpoint, limit int) ([]changerequests.Event, error)
+}
+
+type Locker interface {
+	// Lock waits until nothing else holds the named lock, which is shared by
+	// every process using the event store, and takes it. Calling the returned
+	// func releases it.
+	Lock(name string) (unlock func() error, err error)
+}
+
+type EngineOpt func(*Engine)
+
+// WithBatchSize sets how many events are read from the stream and applied to
+// a projection at a time.
+func WithBatchSize(size int) EngineOpt {
+	return func(e *Engine) {
+		e.batchSize = size
+	}
+}
+
+// WithCustomNowProvider allows you to override the way we generate a timestamp
+// for now. By default it will use the dt.NowUTC function.
+func WithCustomNowProvider(f func() time.Time) EngineOpt {
+	return func(e *Engine) {
+		e.getNow = f
+	}
+}
+
+type Engine struct {
+	projectors  []Projector
+	events      EventStore
+	checkpoints CheckpointsRepo
+	locker      Locker
+	batchSize   int
+	getNow      func() time.Time
+}
+
+// Names returns the names of all the registered projections.
+func (e *Engine) Names() []string {
+	names := make([]string, len(e.projectors))
+
+	for i, p := range e.projectors {
+		names[i] = p.Name()
+	}
+
+	return names
+}
+
+func (e *Engine) projector(name string) (Projector, error) {
+	for _, p := range e.projectors {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+}
+
+// lock takes the projection's lock, the returned func releases it and logs if
+// that fails, as there's nothing more the caller could do about it.
+func (e *Engine) lock(name string) (func(), error) {
+	unlock, err := e.locker.Lock("projections:" + name)
+
+	if err != nil {
+		return nil, fmt.Errorf("locking projection %s: %w", name, err)
+	}
+
+	return func() {
+		if err := unlock(); err != nil {
+			slog.Error("failed to unlock projection", "projection", name, "error", err)
+		}
+	}, nil
+}
+
+// CatchUp applies any events that have been recorded since the projection's
+// checkpoint, returning how many were applied.
+func (e *Engine) CatchUp(name string) (int, error) {
+	p, err := e.projector(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	unlock, err := e.lock(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	return e.catchUp(p)
+}
+
+// catchUp does the work of CatchUp, the caller must hold the projection's lock.
+func (e *Engine) catchUp(p Projector) (int, error) {
+	name := p.Name()
+	checkpoint, err := e.checkpoints.Get(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Without a checkpoint we can't know what, if anything, has been applied,
+	// so start from scratch rather than risk applying events twice.
+	if checkpoint == nil {
+		if err := p.Reset(); err != nil {
+			return 0, err
+		}
+	}
+
+	applied := 0
+
+	for {
+		events, err := e.events.After(checkpoint, e.batchSize)
+
+		if err != nil {
+			return applied, err
+		}
+
+		if len(events) == 0 {
+			return applied, nil
+		}
+
+		if err := p.Apply(events); err != nil {
+			return applied, fmt.Errorf("projection %s: %w", name, err)
+		}
+
+		last := events[len(events)-1]
+		checkpoint = &Checkpoint{
+			Projection: name,
+			Sequence:   last.Sequence,
+			UpdatedAt:  e.getNow(),
+		}
+
+		// If this fails the batch will be applied again next time, so
+		// projections need to cope with seeing an event more than once.
+		if err := e.checkpoints.Save(checkpoint); err != nil {
+			return applied, err
+		}
+
+		applied += len(events)
+		slog.Debug("applied events to projection", "projection", name, "count", len(events))
+	}
+}
+
+// CatchUpAll catches up every registered projection.
+func (e *Engine) CatchUpAll() error {
+	errs := []error{}
+
+	for _, p := range e.projectors {
+		if _, err := e.CatchUp(p.Name()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Rebuild throws away everything the projection has built and replays the
+// whole stream into it, returning how many events were applied. The projection
+// is locked throughout, so nothing else can apply events to it part way
+// through.
+func (e *Engine) Rebuild(name string) (int, error) {
+	p, err := e.projector(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	unlock, err := e.lock(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	// Catching up without a checkpoint resets the projection first.
+	if err := e.checkpoints.Delete(name); err != nil {
+		return 0, err
+	}
+
+	return e.catchUp(p)
+}
+
+func NewEngine(projectors []Projector, events EventStore, checkpoints CheckpointsRepo, locker Locker, opts ...EngineOpt) *Engine {
+	e := &Engine{
+		projectors:  projectors,
+		events:      events,
+		checkpoints: checkpoints,
+		locker:      locker,
+		batchSize:   500,
+		getNow:      dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
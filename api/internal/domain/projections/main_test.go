@@ -0,0 +1,232 @@
+package projections
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+)
+
+type eventStore struct {
+	events []changerequests.Event
+}
+
+func (s *eventStore) After(c *Checkpoint, limit int) ([]changerequests.Event, error) {
+	after := []changerequests.Event{}
+
+	for _, e := range s.events {
+		if (c == nil || e.Sequence > c.Sequence) && len(after) < limit {
+			after = append(after, e)
+		}
+	}
+
+	return after, nil
+}
+
+type checkpointsRepo struct {
+	checkpoints map[string]*Checkpoint
+}
+
+func (r *checkpointsRepo) Get(projection string) (*Checkpoint, error) {
+	return r.checkpoints[projection], nil
+}
+
+func (r *checkpointsRepo) Save(c *Checkpoint) error {
+	r.checkpoints[c.Projection] = c
+
+	return nil
+}
+
+func (r *checkpointsRepo) Delete(projection string) error {
+	delete(r.checkpoints, projection)
+
+	return nil
+}
+
+type locker struct {
+	held   map[string]bool
+	locked []string
+}
+
+func (l *locker) Lock(name string) (func() error, error) {
+	if l.held[name] {
+		return nil, errors.New("already locked: " + name)
+	}
+
+	l.held[name] = true
+	l.locked = append(l.locked, name)
+
+	return func() error {
+		delete(l.held, name)
+
+		return nil
+	}, nil
+}
+
+type projector struct {
+	applied []int64
+	batches int
+	resets  int
+
+	// failAt fails the batch containing the event with this sequence.
+	failAt int64
+}
+
+func (p *projector) Name() string {
+	return "test"
+}
+
+func (p *projector) Reset() error {
+	p.resets++
+	p.applied = nil
+
+	return nil
+}
+
+func (p *projector) Apply(events []changerequests.Event) error {
+	for _, e := range events {
+		if e.Sequence == p.failAt {
+			return errors.New("failed to apply")
+		}
+	}
+
+	p.batches++
+
+	for _, e := range events {
+		p.applied = append(p.applied, e.Sequence)
+	}
+
+	return nil
+}
+
+func stream(seqs ...int64) []changerequests.Event {
+	events := make([]changerequests.Event, len(seqs))
+
+	for i, seq := range seqs {
+		events[i] = changerequests.Event{Sequence: seq}
+	}
+
+	return events
+}
+
+func TestEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		rebuild    bool
+		events     []changerequests.Event
+		checkpoint *Checkpoint
+		failAt     int64
+
+		wantApplied    []int64
+		wantBatches    int
+		wantResets     int
+		wantCheckpoint int64
+		wantErr        bool
+	}{
+		{
+			name:           "catching up without a checkpoint starts from scratch",
+			events:         stream(1, 2, 3, 4, 5),
+			wantApplied:    []int64{1, 2, 3, 4, 5},
+			wantBatches:    3,
+			wantResets:     1,
+			wantCheckpoint: 5,
+		},
+		{
+			name:           "catching up carries on from the checkpoint",
+			events:         stream(1, 2, 3, 4, 5),
+			checkpoint:     &Checkpoint{Projection: "test", Sequence: 3},
+			wantApplied:    []int64{4, 5},
+			wantBatches:    1,
+			wantCheckpoint: 5,
+		},
+		{
+			name:           "gaps in the sequence are skipped over",
+			events:         stream(2, 5, 9),
+			checkpoint:     &Checkpoint{Projection: "test", Sequence: 2},
+			wantApplied:    []int64{5, 9},
+			wantBatches:    1,
+			wantCheckpoint: 9,
+		},
+		{
+			name:           "nothing new",
+			events:         stream(1, 2),
+			checkpoint:     &Checkpoint{Projection: "test", Sequence: 2},
+			wantCheckpoint: 2,
+		},
+		{
+			name:           "a failed batch isn't checkpointed",
+			events:         stream(1, 2, 3, 4, 5),
+			failAt:         4,
+			wantApplied:    []int64{1, 2},
+			wantBatches:    1,
+			wantResets:     1,
+			wantCheckpoint: 2,
+			wantErr:        true,
+		},
+		{
+			name:           "rebuilding replays everything",
+			rebuild:        true,
+			events:         stream(1, 2, 3),
+			checkpoint:     &Checkpoint{Projection: "test", Sequence: 3},
+			wantApplied:    []int64{1, 2, 3},
+			wantBatches:    2,
+			wantResets:     1,
+			wantCheckpoint: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &projector{failAt: tt.failAt}
+			checkpoints := &checkpointsRepo{checkpoints: map[string]*Checkpoint{}}
+			l := &locker{held: map[string]bool{}}
+
+			if tt.checkpoint != nil {
+				checkpoints.checkpoints["test"] = tt.checkpoint
+			}
+
+			e := NewEngine([]Projector{p}, &eventStore{events: tt.events}, checkpoints, l, WithBatchSize(2))
+
+			var err error
+
+			if tt.rebuild {
+				_, err = e.Rebuild("test")
+			} else {
+				_, err = e.CatchUp("test")
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(p.applied, tt.wantApplied) {
+				t.Errorf("applied %v, want %v", p.applied, tt.wantApplied)
+			}
+
+			if p.batches != tt.wantBatches || p.resets != tt.wantResets {
+				t.Errorf("applied %d batches with %d resets, want %d with %d", p.batches, p.resets, tt.wantBatches, tt.wantResets)
+			}
+
+			if c := checkpoints.checkpoints["test"]; c == nil || c.Sequence != tt.wantCheckpoint {
+				t.Errorf("checkpoint = %+v, want sequence %d", c, tt.wantCheckpoint)
+			}
+
+			if !slices.Equal(l.locked, []string{"projections:test"}) || len(l.held) != 0 {
+				t.Errorf("expected the projection to be locked once and released, locked %v, still holding %v", l.locked, l.held)
+			}
+		})
+	}
+}
+
+func TestEngineUnknownProjection(t *testing.T) {
+	e := NewEngine([]Projector{&projector{}}, &eventStore{}, &checkpointsRepo{}, &locker{held: map[string]bool{}})
+
+	if _, err := e.CatchUp("missing"); !errors.Is(err, ErrUnknownProjection) {
+		t.Errorf("CatchUp() = %v, want %v", err, ErrUnknownProjection)
+	}
+
+	if _, err := e.Rebuild("missing"); !errors.Is(err, ErrUnknownProjection) {
+		t.Errorf("Rebuild() = %v, want %v", err, ErrUnknownProjection)
+	}
+}
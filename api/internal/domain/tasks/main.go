@@ -0,0 +1,93 @@
+// Package tasks defines the normalised events that make up the tasks stream.
+// Task management integrations (jira etc.) translate their own payloads into
+// these, in the same way that VCS integrations do for change requests.
+package tasks
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const EventCreated EventType = "created"
+const EventUpdated EventType = "updated"
+const EventTransitioned EventType = "transitioned"
+const EventAssigned EventType = "assigned"
+const EventSprintChanged EventType = "sprint_changed"
+const EventDeleted EventType = "deleted"
+
+// eventIDNamespace is used to derive event ids, it must never change or
+// previously stored events will no longer be recognised as duplicates.
+var eventIDNamespace = uuid.MustParse("15f715fd-08b2-40ee-bfab-911001aa9e98")
+
+// Task is a snapshot of the task at the time of the event.
+type Task struct {
+	Key            string     `json:"key"`
+	Project        string     `json:"project"`
+	Summary        string     `json:"summary"`
+	Type           string     `json:"type"`
+	Status         string     `json:"status"`
+	StatusCategory string     `json:"status_category"`
+	Assignee       string     `json:"assignee"`
+	Reporter       string     `json:"reporter"`
+	Priority       string     `json:"priority"`
+	CreatedAt      *time.Time `json:"created_at"`
+}
+
+// Change describes a single field changing, using the human readable values.
+type Change struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type Payload struct {
+	Task Task `json:"task"`
+
+	// Actor is whoever caused the event.
+	Actor string `json:"actor"`
+
+	// Only set for events that change a field.
+	Change *Change `json:"change,omitempty"`
+}
+
+type Event struct {
+	ID          uuid.UUID
+	AggregateID string
+	Type        EventType
+	OccurredAt  time.Time
+
+	// SourceID is the id of the raw record (e.g. a webhook) the event was
+	// translated from.
+	SourceID          *uuid.UUID
+	SourceIntegration string
+
+	Payload Payload
+}
+
+// NewEventID derives an id for an event from the things that identify it, so
+// that the same event reaching us twice (say from a webhook and a backfill)
+// ends up with the same id. The discriminator is for anything that identifies
+// the event better than its time e.g. the id of a changelog entry. When given,
+// the time is left out, as sources don't always agree on it (jira webhooks
+// carry the time the webhook fired, not when the change was made).
+func NewEventID(integration string, aggregateID string, t EventType, occurredAt time.Time, discriminator string) uuid.UUID {
+	ts := ""
+
+	if discriminator == "" {
+		ts = occurredAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	name := strings.Join([]string{
+		integration,
+		aggregateID,
+		string(t),
+		ts,
+		discriminator,
+	}, "|")
+
+	return uuid.NewSHA1(eventIDNamespace, []byte(name))
+}
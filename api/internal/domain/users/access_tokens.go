@@ -1,17 +1,32 @@
 package users
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/adamkirk/panoptes/internal/util/dt"
 	"github.com/adamkirk/panoptes/internal/util/random"
 	"github.com/google/uuid"
 )
 
+// PermissionManageTokens lets a user manage service accounts' tokens, without
+// it they can only manage their own. It never covers other people's tokens, a
+// token for a person can only be created by them.
+const PermissionManageTokens = "tokens.manage"
+
+var ErrAccessTokenNotFound = errors.New("access token not found")
+var ErrInvalidExpiry = errors.New("expiry days must be -1 or greater than 0")
+var ErrUserDeactivated = errors.New("user has been deactivated")
+var ErrForbidden = errors.New("not allowed to manage tokens for this user")
+
 type AccessTokensRepo interface {
 	Create(t *AccessToken) error
+	ByID(id string) (*AccessToken, error)
+	ByUser(userID uuid.UUID) ([]*AccessToken, error)
+	Revoke(id string, at time.Time) error
 }
 type AccessTokensService struct {
 	encrypter Encrypter
@@ -19,6 +34,7 @@ type AccessTokensService struct {
 	getNow func() time.Time
 	users UsersRepo
 	repo AccessTokensRepo
+	auditor AuditRecorder
 }
 
 type TokenDefinition struct {
@@ -26,11 +42,23 @@ type TokenDefinition struct {
 	UserID uuid.UUID
 }
 
-func (svc *AccessTokensService) Create(def TokenDefinition) (*AccessToken, error) {
+func (svc *AccessTokensService) Create(ctx context.Context, def TokenDefinition) (*AccessToken, error) {
+	if def.ExpiryDays < -1 || def.ExpiryDays == 0 {
+		return nil, ErrInvalidExpiry
+	}
+
 	u, err := svc.users.Get(def.UserID)
 
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, err
+	}
+
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if !u.IsActive() {
+		return nil, ErrUserDeactivated
 	}
 
 	id := fmt.Sprintf("PAT_%s", svc.genString(16))
@@ -42,10 +70,6 @@ func (svc *AccessTokensService) Create(def TokenDefinition) (*AccessToken, error
 		return nil, err
 	}
 
-	if def.ExpiryDays < -1 || def.ExpiryDays == 0 {
-		return nil, errors.New("expiry days must -1 or greater than 0")
-	} 
-
 	var expireAt *time.Time
 
 	if def.ExpiryDays != -1 {
@@ -53,26 +77,153 @@ func (svc *AccessTokensService) Create(def TokenDefinition) (*AccessToken, error
 		expireAt = &now
 	}
 
+	createdAt := svc.getNow()
+
 	t := &AccessToken{
 		ID: id,
 		Secret: &secret,
 		SecretHash: hash,
 		ExpireAt: expireAt,
+		CreatedAt: &createdAt,
 		User: u,
 	}
 
-	return t, svc.repo.Create(t)
+	if err := svc.repo.Create(t); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "tokens.create",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetAccessToken,
+		TargetID: t.ID,
+		Details: map[string]any{
+			"user_id": u.ID.String(),
+			"user_type": string(u.Type),
+			"expire_at": t.ExpireAt,
+		},
+	})
+
+	return t, nil
+}
+
+// authorize checks that the principal can manage the given user's tokens,
+// anyone can manage their own. Being refused is audited under the action.
+func (svc *AccessTokensService) authorize(ctx context.Context, action string, p *Principal, userID uuid.UUID) error {
+	if p != nil && p.IsUser(userID) {
+		return nil
+	}
+
+	if p != nil && p.Can([]string{PermissionManageTokens}) {
+		u, err := svc.users.Get(userID)
+
+		if err != nil {
+			return err
+		}
+
+		// Anyone who can manage tokens could otherwise act as any person, or as
+		// a superuser, by creating a token for them. Service accounts have no
+		// other way to get one, so those are all they can manage.
+		if u == nil || (u.IsServiceAccount() && !u.HasRole(RoleSuperuser)) {
+			return nil
+		}
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: action,
+		Outcome: audit.OutcomeDenied,
+		TargetType: TargetUser,
+		TargetID: userID.String(),
+	})
+
+	return ErrForbidden
+}
+
+// CreateAs creates a token on behalf of the principal, see Create.
+func (svc *AccessTokensService) CreateAs(ctx context.Context, p *Principal, def TokenDefinition) (*AccessToken, error) {
+	if err := svc.authorize(ctx, "tokens.create", p, def.UserID); err != nil {
+		return nil, err
+	}
+
+	return svc.Create(ctx, def)
+}
+
+// ListAs lists the user's tokens on behalf of the principal, see ListForUser.
+func (svc *AccessTokensService) ListAs(ctx context.Context, p *Principal, userID uuid.UUID) ([]*AccessToken, error) {
+	if err := svc.authorize(ctx, "tokens.list", p, userID); err != nil {
+		return nil, err
+	}
+
+	return svc.ListForUser(userID)
+}
+
+// RevokeAs revokes a token on behalf of the principal, see Revoke. Tokens the
+// principal can't manage are reported as not found, so that their ids can't be
+// discovered.
+func (svc *AccessTokensService) RevokeAs(ctx context.Context, p *Principal, id string) error {
+	t, err := svc.repo.ByID(id)
+
+	if err != nil {
+		return err
+	}
+
+	if t == nil || t.User == nil || svc.authorize(ctx, "tokens.revoke", p, t.User.ID) != nil {
+		return ErrAccessTokenNotFound
+	}
+
+	return svc.Revoke(ctx, id)
+}
+
+// Revoke stops the token from working, it's kept so that we know it existed.
+// Revoking a token that's already revoked does nothing.
+func (svc *AccessTokensService) Revoke(ctx context.Context, id string) error {
+	t, err := svc.repo.ByID(id)
+
+	if err != nil {
+		return err
+	}
+
+	if t == nil {
+		return ErrAccessTokenNotFound
+	}
+
+	if t.IsRevoked() {
+		return nil
+	}
+
+	if err := svc.repo.Revoke(id, svc.getNow()); err != nil {
+		return err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "tokens.revoke",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetAccessToken,
+		TargetID: t.ID,
+		Details: map[string]any{
+			"user_id": t.User.ID.String(),
+		},
+	})
+
+	return nil
+}
+
+// ListForUser returns all of the user's tokens, including those that have
+// expired or been revoked.
+func (svc *AccessTokensService) ListForUser(userID uuid.UUID) ([]*AccessToken, error) {
+	return svc.repo.ByUser(userID)
 }
 
 type AccessTokensServiceOpt func(*AccessTokensService)
 
-func NewAccessTokensService(encrypter Encrypter, users UsersRepo, repo AccessTokensRepo, opts... AccessTokensServiceOpt) *AccessTokensService {
+func NewAccessTokensService(encrypter Encrypter, users UsersRepo, repo AccessTokensRepo, auditor AuditRecorder, opts... AccessTokensServiceOpt) *AccessTokensService {
 	svc := &AccessTokensService{
 		encrypter: encrypter,
 		getNow: dt.NowUTC,
 		genString: random.String,
 		repo: repo,
 		users: users,
+		auditor: auditor,
 	}
 
 	// TODO add opts
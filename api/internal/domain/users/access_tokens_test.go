@@ -0,0 +1,193 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/google/uuid"
+)
+
+type accessTokensStore struct {
+	tokens  map[string]*AccessToken
+	revoked []string
+}
+
+func (s *accessTokensStore) Create(t *AccessToken) error {
+	s.tokens[t.ID] = t
+
+	return nil
+}
+
+func (s *accessTokensStore) ByID(id string) (*AccessToken, error) {
+	return s.tokens[id], nil
+}
+
+func (s *accessTokensStore) ByUser(userID uuid.UUID) ([]*AccessToken, error) {
+	tokens := []*AccessToken{}
+
+	for _, t := range s.tokens {
+		if t.User.ID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (s *accessTokensStore) Revoke(id string, at time.Time) error {
+	s.revoked = append(s.revoked, id)
+	s.tokens[id].RevokedAt = &at
+
+	return nil
+}
+
+func serviceAccount(roles ...string) *User {
+	u := userWithRoles(roles...)
+	u.Type = UserTypeServiceAccount
+	u.Email = ""
+	u.Name = "ci"
+
+	return u
+}
+
+func TestAccessTokensAuthorize(t *testing.T) {
+	caller := userWithRoles("viewer")
+	manager := userWithRoles("tokens")
+	manager.Roles[0].Permissions = []*Permission{{Name: PermissionManageTokens}}
+
+	account := serviceAccount("viewer")
+	superAccount := serviceAccount(RoleSuperuser)
+	other := userWithRoles("viewer")
+	superuser := userWithRoles(RoleSuperuser)
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		owner     *User
+
+		// allowed is whether the principal can list and revoke the owner's
+		// tokens, those it can't revoke should look like they don't exist.
+		allowed bool
+	}{
+		{
+			name: "own tokens",
+			principal: &Principal{User: caller},
+			owner: caller,
+			allowed: true,
+		},
+		{
+			name: "own tokens without tokens.manage",
+			principal: &Principal{User: other},
+			owner: other,
+			allowed: true,
+		},
+		{
+			name: "service account without tokens.manage",
+			principal: &Principal{User: caller},
+			owner: account,
+		},
+		{
+			name: "service account with tokens.manage",
+			principal: &Principal{User: manager},
+			owner: account,
+			allowed: true,
+		},
+		{
+			name: "superuser's service account with tokens.manage",
+			principal: &Principal{User: manager},
+			owner: superAccount,
+		},
+		{
+			name: "another user's tokens with tokens.manage",
+			principal: &Principal{User: manager},
+			owner: other,
+		},
+		{
+			name: "another user's tokens",
+			principal: &Principal{User: caller},
+			owner: other,
+		},
+		{
+			name: "superuser's tokens with tokens.manage",
+			principal: &Principal{User: manager},
+			owner: superuser,
+		},
+		{
+			name: "service account with the master token",
+			principal: &Principal{Master: true},
+			owner: account,
+			allowed: true,
+		},
+		{
+			name: "no principal",
+			owner: caller,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersRepo := newUsersStore(caller, manager, account, superAccount, other, superuser)
+			tokens := &accessTokensStore{tokens: map[string]*AccessToken{
+				"token": {ID: "token", User: tt.owner},
+			}}
+			auditor := &auditRecorder{}
+			svc := NewAccessTokensService(plainEncrypter{}, usersRepo, tokens, auditor, func(svc *AccessTokensService) {
+				svc.getNow = getNow
+			})
+
+			_, err := svc.ListAs(context.Background(), tt.principal, tt.owner.ID)
+
+			if tt.allowed && err != nil {
+				t.Errorf("expected listing to be allowed, got %v", err)
+			}
+
+			if !tt.allowed && !errors.Is(err, ErrForbidden) {
+				t.Errorf("expected listing to be forbidden, got %v", err)
+			}
+
+			err = svc.RevokeAs(context.Background(), tt.principal, "token")
+
+			if tt.allowed && err != nil {
+				t.Errorf("expected revoking to be allowed, got %v", err)
+			}
+
+			if !tt.allowed && !errors.Is(err, ErrAccessTokenNotFound) {
+				t.Errorf("expected the token to be not found, got %v", err)
+			}
+
+			if revoked := len(tokens.revoked) > 0; revoked != tt.allowed {
+				t.Errorf("expected the token revoked to be %t, got %t", tt.allowed, revoked)
+			}
+
+			if tt.allowed {
+				return
+			}
+
+			for _, action := range []string{"tokens.list", "tokens.revoke"} {
+				denied := false
+
+				for _, e := range auditor.events {
+					denied = denied || (e.Action == action && e.Outcome == audit.OutcomeDenied)
+				}
+
+				if !denied {
+					t.Errorf("expected %s to be audited as denied", action)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessTokensRevokeAsUnknown(t *testing.T) {
+	tokens := &accessTokensStore{tokens: map[string]*AccessToken{}}
+	svc := NewAccessTokensService(plainEncrypter{}, newUsersStore(), tokens, &auditRecorder{})
+
+	err := svc.RevokeAs(context.Background(), &Principal{Master: true}, "unknown")
+
+	if !errors.Is(err, ErrAccessTokenNotFound) {
+		t.Errorf("expected the token to be not found, got %v", err)
+	}
+}
@@ -1,15 +1,29 @@
 package users
 
 import (
+	"context"
+	"strings"
 	"time"
 
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/google/uuid"
 )
 
+// Audit target types.
+const (
+	TargetUser           = "user"
+	TargetServiceAccount = "service_account"
+	TargetAccessToken    = "access_token"
+)
+
 type Encrypter interface {
 	Encrypt(string) (string, error)
 }
 
+type AuditRecorder interface {
+	Record(ctx context.Context, e audit.Event)
+}
+
 type AccessToken struct {
 	ID string
 
@@ -18,20 +32,59 @@ type AccessToken struct {
 	SecretHash string
 
 	ExpireAt *time.Time
+	RevokedAt *time.Time
+	LastUsedAt *time.Time
+	CreatedAt *time.Time
 
 	User *User
 }
 
+func (t *AccessToken) IsExpired(now time.Time) bool {
+	return t.ExpireAt != nil && !now.Before(*t.ExpireAt)
+}
+
+func (t *AccessToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// UserType is the kind of principal, people are users while integrations
+// like the github ingestion are service accounts.
+type UserType string
+
+const (
+	UserTypeUser           UserType = "user"
+	UserTypeServiceAccount UserType = "service_account"
+)
+
 type User struct {
 	ID        uuid.UUID
+	Type      UserType
+
+	// Name is only set for service accounts, which have no email or names.
+	Name      string
 	Email     string
 	FirstName string
 	LastName  string
 	PasswordHash string
+	DeactivatedAt *time.Time
+	CreatedAt time.Time
+
+	// OIDCIssuer and OIDCSubject identify the user at the OpenID Connect
+	// provider they log in with, both are empty if they don't.
+	OIDCIssuer  string
+	OIDCSubject string
 
 	Roles []*Role
 }
 
+func (u *User) IsServiceAccount() bool {
+	return u.Type == UserTypeServiceAccount
+}
+
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
 func (u *User) HasRole(search string) bool {
 	for _, role := range u.Roles {
 		if role.Name == search {
@@ -42,11 +95,30 @@ func (u *User) HasRole(search string) bool {
 	return false
 }
 
+// Can reports whether the user's roles grant every one of the actions.
 func (u *User) Can(actions []string) bool {
-	if u.HasRole("superuser") {
+	if u.HasRole(RoleSuperuser) {
 		return true
 	}
 
+	for _, action := range actions {
+		if !u.can(action) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (u *User) can(action string) bool {
+	for _, role := range u.Roles {
+		for _, p := range role.Permissions {
+			if p.Grants(action) {
+				return true
+			}
+		}
+	}
+
 	return false
 }
 
@@ -55,6 +127,19 @@ type Permission struct {
 	Name string
 }
 
+// Grants reports whether the permission allows the action. A permission ending
+// in '.*' grants everything under it e.g. ingest.* grants ingest.github, and
+// '*' on its own grants everything.
+func (p *Permission) Grants(action string) bool {
+	if p.Name == action || p.Name == "*" {
+		return true
+	}
+
+	prefix, isWildcard := strings.CutSuffix(p.Name, "*")
+
+	return isWildcard && strings.HasSuffix(prefix, ".") && strings.HasPrefix(action, prefix)
+}
+
 type Role struct {
 	ID uuid.UUID
 	Name string
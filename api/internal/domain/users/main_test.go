@@ -0,0 +1,143 @@
+package users
+
+import "testing"
+
+func TestPermissionGrants(t *testing.T) {
+	tests := []struct {
+		permission string
+		action     string
+		want       bool
+	}{
+		{permission: "users.read", action: "users.read", want: true},
+		{permission: "users.read", action: "users.write", want: false},
+		{permission: "users.read", action: "users", want: false},
+		{permission: "*", action: "users.read", want: true},
+		{permission: "*", action: "anything", want: true},
+		{permission: "ingest.*", action: "ingest.github", want: true},
+		{permission: "ingest.*", action: "ingest.gitlab.retry", want: true},
+		{permission: "ingest.*", action: "ingest", want: false},
+		{permission: "ingest.*", action: "ingestion.github", want: false},
+		{permission: "ingest.*", action: "users.read", want: false},
+		{permission: "ingest*", action: "ingestion.github", want: false},
+		{permission: "ingest*", action: "ingest.github", want: false},
+		{permission: "users.read", action: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.permission+" "+tt.action, func(t *testing.T) {
+			p := &Permission{Name: tt.permission}
+
+			if got := p.Grants(tt.action); got != tt.want {
+				t.Errorf("Grants(%q) = %t, want %t", tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+func role(name string, permissions ...string) *Role {
+	r := &Role{Name: name}
+
+	for _, p := range permissions {
+		r.Permissions = append(r.Permissions, &Permission{Name: p})
+	}
+
+	return r
+}
+
+func TestUserCan(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []*Role
+		actions []string
+		want    bool
+	}{
+		{
+			name:    "no roles",
+			actions: []string{"users.read"},
+			want:    false,
+		},
+		{
+			name:    "granted directly",
+			roles:   []*Role{role("viewer", "users.read")},
+			actions: []string{"users.read"},
+			want:    true,
+		},
+		{
+			name:    "granted by a wildcard",
+			roles:   []*Role{role("ingestor", "ingest.*")},
+			actions: []string{"ingest.github"},
+			want:    true,
+		},
+		{
+			name:    "every action is required",
+			roles:   []*Role{role("viewer", "users.read")},
+			actions: []string{"users.read", "users.write"},
+			want:    false,
+		},
+		{
+			name:    "actions granted by different roles",
+			roles:   []*Role{role("viewer", "users.read"), role("editor", "users.write")},
+			actions: []string{"users.read", "users.write"},
+			want:    true,
+		},
+		{
+			name:    "role without permissions",
+			roles:   []*Role{role("empty")},
+			actions: []string{"users.read"},
+			want:    false,
+		},
+		{
+			name:    "superuser without permissions",
+			roles:   []*Role{role(RoleSuperuser)},
+			actions: []string{"users.read", "roles.write"},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Roles: tt.roles}
+
+			if got := u.Can(tt.actions); got != tt.want {
+				t.Errorf("Can(%v) = %t, want %t", tt.actions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      bool
+	}{
+		{
+			name:      "master token",
+			principal: &Principal{Master: true},
+			want:      true,
+		},
+		{
+			name:      "without a user",
+			principal: &Principal{},
+			want:      false,
+		},
+		{
+			name:      "user with the permission",
+			principal: &Principal{User: &User{Roles: []*Role{role("viewer", "users.*")}}},
+			want:      true,
+		},
+		{
+			name:      "user without the permission",
+			principal: &Principal{User: &User{Roles: []*Role{role("viewer", "tokens.*")}}},
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Can([]string{"users.read"}); got != tt.want {
+				t.Errorf("Can() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/adamkirk/panoptes/internal/util/oidc"
+	"github.com/google/uuid"
+)
+
+var ErrOIDCDisabled = errors.New("logging in with an identity provider is not enabled")
+
+// ErrOIDCLogin is returned for anything that goes wrong with the provider, the
+// details are logged rather than shown.
+var ErrOIDCLogin = errors.New("failed to log in with the identity provider")
+
+type OIDCConfig interface {
+	AuthOidcEnabled() bool
+	AuthOidcGroupsClaim() string
+	AuthOidcDefaultRoles() []string
+	AuthOidcRoleMappings() map[string][]string
+	AuthOidcAutoLinkAccounts() bool
+}
+
+type OIDCProvider interface {
+	Begin() (*oidc.Login, error)
+	Finish(cookie string, state string, code string) (*oidc.Claims, error)
+}
+
+type OIDCUsersRepo interface {
+	Get(id uuid.UUID) (*User, error)
+	ByOIDCSubject(issuer string, subject string) (*User, error)
+	ByEmail(email string) (*User, error)
+	Create(u *User) error
+	Update(u *User) error
+	SetRoles(u *User) error
+}
+
+type SessionStarter interface {
+	Start(u *User) (*SessionTokens, error)
+}
+
+type OIDCService struct {
+	cfg      OIDCConfig
+	provider OIDCProvider
+	users    OIDCUsersRepo
+	roles    RolesRepo
+	sessions SessionStarter
+	auditor  AuditRecorder
+	getNow   func() time.Time
+}
+
+// Begin starts logging in, see oidc.Client.Begin.
+func (svc *OIDCService) Begin() (*oidc.Login, error) {
+	if !svc.cfg.AuthOidcEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	login, err := svc.provider.Begin()
+
+	if err != nil {
+		slog.Error("failed to start logging in with the identity provider", "error", err)
+
+		return nil, ErrOIDCLogin
+	}
+
+	return login, nil
+}
+
+type OIDCCallbackDTO struct {
+	Cookie string
+	State  string
+	Code   string
+}
+
+// Complete finishes logging in once the provider has redirected back, creating
+// the user if it's the first time they've logged in.
+func (svc *OIDCService) Complete(ctx context.Context, dto OIDCCallbackDTO) (*SessionTokens, error) {
+	if !svc.cfg.AuthOidcEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	claims, err := svc.provider.Finish(dto.Cookie, dto.State, dto.Code)
+
+	if err != nil {
+		slog.Error("failed to finish logging in with the identity provider", "error", err)
+
+		return nil, ErrOIDCLogin
+	}
+
+	u, err := svc.provision(ctx, claims)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.IsActive() {
+		return nil, ErrUserDeactivated
+	}
+
+	// Changes are made by the user logging in, rather than whoever is in the
+	// context, which is nobody.
+	ctx = audit.WithActor(ctx, audit.Actor{
+		Type: string(u.Type),
+		ID: u.ID.String(),
+	})
+
+	if err := svc.syncRoles(ctx, u, claims); err != nil {
+		return nil, err
+	}
+
+	return svc.sessions.Start(u)
+}
+
+// provision finds the user for the identity, or creates a user if there isn't
+// one. When enabled, an identity with a verified email is linked to an existing
+// user with the same email, unless they're a superuser.
+func (svc *OIDCService) provision(ctx context.Context, c *oidc.Claims) (*User, error) {
+	u, err := svc.users.ByOIDCSubject(c.Issuer, c.Subject)
+
+	if err != nil || u != nil {
+		return u, err
+	}
+
+	if c.Email == "" {
+		slog.Error("the identity provider didn't include an email in the id token, check the email scope is requested", "subject", c.Subject)
+
+		return nil, ErrOIDCLogin
+	}
+
+	existing, err := svc.users.ByEmail(c.Email)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		// ByEmail doesn't include roles.
+		if existing, err = svc.users.Get(existing.ID); err != nil {
+			return nil, err
+		}
+
+		// Linking trusts the provider with the account, so it has to be asked
+		// for. Otherwise anyone able to set an unverified email at the
+		// provider could take over the user, and anyone able to set any email
+		// there could take over a superuser.
+		if existing == nil || !svc.cfg.AuthOidcAutoLinkAccounts() || !c.EmailVerified || existing.OIDCSubject != "" || existing.HasRole(RoleSuperuser) {
+			return nil, ErrEmailInUse
+		}
+
+		existing.OIDCIssuer = c.Issuer
+		existing.OIDCSubject = c.Subject
+
+		if err := svc.users.Update(existing); err != nil {
+			return nil, err
+		}
+
+		svc.auditor.Record(ctx, audit.Event{
+			Action: "users.update",
+			Outcome: audit.OutcomeSuccess,
+			Actor: &audit.Actor{
+				Type: string(existing.Type),
+				ID: existing.ID.String(),
+			},
+			TargetType: TargetUser,
+			TargetID: existing.ID.String(),
+			Details: map[string]any{
+				"fields": []string{"oidc_identity"},
+				"oidc_issuer": c.Issuer,
+			},
+		})
+
+		return existing, nil
+	}
+
+	firstName, lastName := splitName(c)
+
+	u = &User{
+		ID: uuid.New(),
+		Type: UserTypeUser,
+		Email: c.Email,
+		FirstName: firstName,
+		LastName: lastName,
+		CreatedAt: svc.getNow(),
+		OIDCIssuer: c.Issuer,
+		OIDCSubject: c.Subject,
+		Roles: []*Role{},
+	}
+
+	if err := svc.users.Create(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "users.create",
+		Outcome: audit.OutcomeSuccess,
+		Actor: &audit.Actor{
+			Type: string(u.Type),
+			ID: u.ID.String(),
+		},
+		TargetType: TargetUser,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"email": u.Email,
+			"oidc_issuer": c.Issuer,
+		},
+	})
+
+	return u, nil
+}
+
+// splitName falls back to the full name, then the email, as not every
+// provider splits names up.
+func splitName(c *oidc.Claims) (string, string) {
+	if c.GivenName != "" {
+		return c.GivenName, c.FamilyName
+	}
+
+	if first, last, found := strings.Cut(c.Name, " "); found {
+		return first, last
+	}
+
+	if c.Name != "" {
+		return c.Name, ""
+	}
+
+	local, _, _ := strings.Cut(c.Email, "@")
+
+	return local, ""
+}
+
+// syncsRoles reports whether the roles of users that log in with the provider
+// come from it. If so they're managed at the provider, rather than assigned
+// here, as anything assigned here would be replaced the next time they log in.
+func syncsRoles(cfg OIDCConfig) bool {
+	return cfg.AuthOidcEnabled() && (len(cfg.AuthOidcDefaultRoles()) > 0 || len(cfg.AuthOidcRoleMappings()) > 0)
+}
+
+// syncRoles sets the user's roles from their groups, when roles are
+// configured. Superuser is left as is, it can only be managed with the CLI.
+func (svc *OIDCService) syncRoles(ctx context.Context, u *User, c *oidc.Claims) error {
+	if !syncsRoles(svc.cfg) {
+		return nil
+	}
+
+	mappings := svc.cfg.AuthOidcRoleMappings()
+	names := append([]string{}, svc.cfg.AuthOidcDefaultRoles()...)
+
+	for _, group := range c.Strings(svc.cfg.AuthOidcGroupsClaim()) {
+		names = append(names, mappings[group]...)
+	}
+
+	names = slices.DeleteFunc(names, func(name string) bool { return name == RoleSuperuser })
+
+	if u.HasRole(RoleSuperuser) {
+		names = append(names, RoleSuperuser)
+	}
+
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	roles, err := svc.roles.ByNames(names)
+
+	if err != nil {
+		return err
+	}
+
+	// A typo in the config shouldn't stop everyone logging in.
+	for _, name := range names {
+		if !slices.ContainsFunc(roles, func(r *Role) bool { return r.Name == name }) {
+			slog.Warn("role from the oidc config doesn't exist", "role", name)
+		}
+	}
+
+	if slices.Equal(roleNames(u.Roles), roleNames(roles)) {
+		return nil
+	}
+
+	u.Roles = roles
+
+	if err := svc.users.SetRoles(u); err != nil {
+		return fmt.Errorf("failed to set roles: %w", err)
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "users.roles.assign",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetUser,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"roles": roleNames(roles),
+			"oidc_issuer": c.Issuer,
+		},
+	})
+
+	return nil
+}
+
+func roleNames(roles []*Role) []string {
+	names := []string{}
+
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+func NewOIDCService(cfg OIDCConfig, provider OIDCProvider, users OIDCUsersRepo, roles RolesRepo, sessions SessionStarter, auditor AuditRecorder) *OIDCService {
+	return &OIDCService{
+		cfg: cfg,
+		provider: provider,
+		users: users,
+		roles: roles,
+		sessions: sessions,
+		auditor: auditor,
+		getNow: dt.NowUTC,
+	}
+}
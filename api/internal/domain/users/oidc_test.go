@@ -0,0 +1,145 @@
+package users
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/util/oidc"
+)
+
+type oidcConfig struct {
+	defaultRoles []string
+	mappings     map[string][]string
+}
+
+func (c oidcConfig) AuthOidcEnabled() bool {
+	return true
+}
+
+func (c oidcConfig) AuthOidcGroupsClaim() string {
+	return "groups"
+}
+
+func (c oidcConfig) AuthOidcDefaultRoles() []string {
+	return c.defaultRoles
+}
+
+func (c oidcConfig) AuthOidcRoleMappings() map[string][]string {
+	return c.mappings
+}
+
+func (c oidcConfig) AuthOidcAutoLinkAccounts() bool {
+	return false
+}
+
+// syncingRoles gives everyone viewer, and platform engineers ingestor.
+var syncingRoles = oidcConfig{
+	defaultRoles: []string{"viewer"},
+	mappings: map[string][]string{"platform": {"ingestor"}},
+}
+
+func oidcUser(roles ...string) *User {
+	u := userWithRoles(roles...)
+	u.OIDCIssuer = "https://idp.example.com"
+	u.OIDCSubject = u.ID.String()
+
+	return u
+}
+
+func TestOIDCSyncRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    oidcConfig
+		user   *User
+		groups []any
+		want   []string
+	}{
+		{
+			name: "no roles configured",
+			cfg: oidcConfig{},
+			user: oidcUser("editor"),
+			want: []string{"editor"},
+		},
+		{
+			name: "default roles",
+			cfg: syncingRoles,
+			user: oidcUser(),
+			want: []string{"viewer"},
+		},
+		{
+			name: "mapped groups",
+			cfg: syncingRoles,
+			user: oidcUser(),
+			groups: []any{"platform", "unmapped"},
+			want: []string{"ingestor", "viewer"},
+		},
+		{
+			name: "roles that aren't configured are taken off",
+			cfg: syncingRoles,
+			user: oidcUser("editor", "ingestor"),
+			want: []string{"viewer"},
+		},
+		{
+			name: "superuser is kept",
+			cfg: syncingRoles,
+			user: oidcUser(RoleSuperuser, "editor"),
+			want: []string{RoleSuperuser, "viewer"},
+		},
+		{
+			name: "superuser can't be mapped",
+			cfg: oidcConfig{mappings: map[string][]string{"platform": {RoleSuperuser, "ingestor"}}},
+			user: oidcUser(),
+			groups: []any{"platform"},
+			want: []string{"ingestor"},
+		},
+		{
+			name: "roles that don't exist are skipped",
+			cfg: oidcConfig{defaultRoles: []string{"viewer", "unknown"}},
+			user: oidcUser(),
+			want: []string{"viewer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUsersStore(tt.user)
+			auditor := &auditRecorder{}
+			svc := NewOIDCService(tt.cfg, nil, repo, newRolesStore("viewer", "editor", "ingestor", RoleSuperuser), nil, auditor)
+
+			claims := &oidc.Claims{Issuer: tt.user.OIDCIssuer, Subject: tt.user.OIDCSubject, Raw: map[string]any{"groups": tt.groups}}
+			changed := !slices.Equal(roleNames(tt.user.Roles), tt.want)
+
+			if err := svc.syncRoles(context.Background(), tt.user, claims); err != nil {
+				t.Fatalf("failed to sync roles: %s", err)
+			}
+
+			if got := roleNames(repo.users[tt.user.ID].Roles); !slices.Equal(got, tt.want) {
+				t.Errorf("expected roles %v, got %v", tt.want, got)
+			}
+
+			if saved := repo.updated > 0; saved != changed {
+				t.Errorf("expected the roles saved to be %t, got %t", changed, saved)
+			}
+
+			if len(auditor.events) != repo.updated {
+				t.Errorf("expected every change audited, saved %d times, audited %d times", repo.updated, len(auditor.events))
+			}
+		})
+	}
+}
+
+func TestOIDCSyncRolesUnchanged(t *testing.T) {
+	u := oidcUser("viewer")
+	repo := newUsersStore(u)
+	auditor := &auditRecorder{}
+	svc := NewOIDCService(syncingRoles, nil, repo, newRolesStore("viewer", "ingestor"), nil, auditor)
+
+	if err := svc.syncRoles(context.Background(), u, &oidc.Claims{}); err != nil {
+		t.Fatalf("failed to sync roles: %s", err)
+	}
+
+	if repo.updated != 0 || len(auditor.events) != 0 {
+		t.Error("expected roles that haven't changed not to be saved")
+	}
+}
@@ -0,0 +1,73 @@
+package users
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type principalKey struct{}
+
+// Principal is whoever made the request, as established by the auth
+// middleware.
+type Principal struct {
+	// User is nil when authenticated with the master token.
+	User *User
+
+	// TokenID is the access token used to authenticate, if one was.
+	TokenID string
+
+	// SessionID is the session used to authenticate, if one was.
+	SessionID string
+
+	// Master is true when authenticated with the master token, which can do
+	// anything.
+	Master bool
+}
+
+func (p *Principal) Can(actions []string) bool {
+	if p.Master {
+		return true
+	}
+
+	return p.User != nil && p.User.Can(actions)
+}
+
+// Type is the kind of principal, for access logs and audit records.
+func (p *Principal) Type() string {
+	if p.Master {
+		return "master"
+	}
+
+	if p.User == nil {
+		return "anonymous"
+	}
+
+	return string(p.User.Type)
+}
+
+// ID is the id of the user or service account, empty for the master token.
+func (p *Principal) ID() string {
+	if p.User == nil {
+		return ""
+	}
+
+	return p.User.ID.String()
+}
+
+// IsUser reports whether the principal is the given user.
+func (p *Principal) IsUser(id uuid.UUID) bool {
+	return p.User != nil && p.User.ID == id
+}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal for the request, or nil if the request
+// wasn't authenticated.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+
+	return p
+}
@@ -0,0 +1,181 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/google/uuid"
+)
+
+var ErrRoleExists = errors.New("role already exists")
+var ErrPermissionNotFound = errors.New("permission not found")
+
+type RolesStore interface {
+	ByNames(names []string) ([]*Role, error)
+	All() ([]*Role, error)
+	Create(r *Role) error
+	AddPermissions(r *Role, permissions []*Permission) error
+	RemovePermissions(r *Role, permissions []*Permission) error
+}
+
+type PermissionsRepo interface {
+	ByNames(names []string) ([]*Permission, error)
+}
+
+type RolesService struct {
+	repo        RolesStore
+	permissions PermissionsRepo
+	validator   *validation.Validator
+}
+
+func (svc *RolesService) permissionsByNames(names []string) ([]*Permission, error) {
+	permissions, err := svc.permissions.ByNames(names)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(permissions, func(p *Permission) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("%w: %s", ErrPermissionNotFound, name)
+		}
+	}
+
+	return permissions, nil
+}
+
+// byName returns the role, the superuser role is refused as it has every
+// permission regardless of what it's been granted.
+func (svc *RolesService) byName(name string) (*Role, error) {
+	if name == RoleSuperuser {
+		return nil, ErrSuperuserRole
+	}
+
+	roles, err := svc.repo.ByNames([]string{name})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+	}
+
+	return roles[0], nil
+}
+
+// List returns every role with its permissions, ordered by name.
+func (svc *RolesService) List() ([]*Role, error) {
+	return svc.repo.All()
+}
+
+type CreateRoleDTO struct {
+	Name        string `validate:"required"`
+	Permissions []string
+}
+
+func (svc *RolesService) Create(dto CreateRoleDTO) (*Role, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	if dto.Name == RoleSuperuser {
+		return nil, ErrSuperuserRole
+	}
+
+	if existing, err := svc.repo.ByNames([]string{dto.Name}); err != nil {
+		return nil, err
+	} else if len(existing) > 0 {
+		return nil, ErrRoleExists
+	}
+
+	permissions, err := svc.permissionsByNames(dto.Permissions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Role{
+		ID: uuid.New(),
+		Name: dto.Name,
+		Permissions: permissions,
+	}
+
+	return r, svc.repo.Create(r)
+}
+
+type RolePermissionsDTO struct {
+	Role        string   `validate:"required"`
+	Permissions []string `validate:"required,min=1"`
+}
+
+// Grant adds the permissions to the role, any it already has are ignored.
+func (svc *RolesService) Grant(dto RolePermissionsDTO) (*Role, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	r, err := svc.byName(dto.Role)
+
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := svc.permissionsByNames(dto.Permissions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.repo.AddPermissions(r, permissions); err != nil {
+		return nil, err
+	}
+
+	for _, p := range permissions {
+		if !slices.ContainsFunc(r.Permissions, func(existing *Permission) bool { return existing.ID == p.ID }) {
+			r.Permissions = append(r.Permissions, p)
+		}
+	}
+
+	return r, nil
+}
+
+// Revoke takes the permissions away from the role, any it doesn't have are
+// ignored.
+func (svc *RolesService) Revoke(dto RolePermissionsDTO) (*Role, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	r, err := svc.byName(dto.Role)
+
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := svc.permissionsByNames(dto.Permissions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.repo.RemovePermissions(r, permissions); err != nil {
+		return nil, err
+	}
+
+	r.Permissions = slices.DeleteFunc(r.Permissions, func(existing *Permission) bool {
+		return slices.ContainsFunc(permissions, func(p *Permission) bool { return p.ID == existing.ID })
+	})
+
+	return r, nil
+}
+
+func NewRolesService(repo RolesStore, permissions PermissionsRepo, validator *validation.Validator) *RolesService {
+	return &RolesService{
+		repo: repo,
+		permissions: permissions,
+		validator: validator,
+	}
+}
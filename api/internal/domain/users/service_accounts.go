@@ -0,0 +1,251 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/google/uuid"
+)
+
+var ErrServiceAccountNotFound = errors.New("service account not found")
+var ErrNameInUse = errors.New("name already in use")
+
+type ServiceAccountsRepo interface {
+	ByName(name string) (*User, error)
+	Create(u *User) error
+	Get(id uuid.UUID) (*User, error)
+	List(t UserType, offset int, limit int) ([]*User, int, error)
+	Update(u *User) error
+	SetRoles(u *User) error
+}
+
+// ServiceAccountsService manages service accounts, which are for integrations
+// rather than people. They're stored as users, so can own access tokens and
+// have roles, but have no email or password so can't log in.
+type ServiceAccountsService struct {
+	repo      ServiceAccountsRepo
+	roles     RolesRepo
+	auditor   AuditRecorder
+	getNow    func() time.Time
+	validator *validation.Validator
+}
+
+type CreateServiceAccountDTO struct {
+	Name  string `validate:"required,max=100"`
+	Roles []string
+}
+
+func (svc *ServiceAccountsService) Create(ctx context.Context, dto CreateServiceAccountDTO) (*User, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(dto.Roles, RoleSuperuser) {
+		return nil, ErrSuperuserRole
+	}
+
+	roles, err := rolesByNames(svc.roles, dto.Roles)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u, err := svc.repo.ByName(dto.Name); err != nil {
+		return nil, err
+	} else if u != nil {
+		return nil, ErrNameInUse
+	}
+
+	u := &User{
+		ID: uuid.New(),
+		Type: UserTypeServiceAccount,
+		Name: dto.Name,
+		CreatedAt: svc.getNow(),
+		Roles: roles,
+	}
+
+	if err := svc.repo.Create(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "service_accounts.create",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetServiceAccount,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"name": u.Name,
+			"roles": dto.Roles,
+		},
+	})
+
+	return u, nil
+}
+
+func (svc *ServiceAccountsService) Get(dto GetDTO) (*User, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	u, err := svc.repo.Get(dto.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u == nil || !u.IsServiceAccount() {
+		return nil, ErrServiceAccountNotFound
+	}
+
+	return u, nil
+}
+
+// List returns a page of service accounts, oldest first.
+func (svc *ServiceAccountsService) List(dto ListDTO) (*UsersPage, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	accounts, total, err := svc.repo.List(UserTypeServiceAccount, (dto.Page-1)*dto.PerPage, dto.PerPage)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UsersPage{
+		Users: accounts,
+		Total: total,
+	}, nil
+}
+
+// UpdateServiceAccountDTO only changes the fields that are set.
+type UpdateServiceAccountDTO struct {
+	ID     uuid.UUID `validate:"required"`
+	Name   *string   `validate:"omitempty,min=1,max=100"`
+	Active *bool
+}
+
+func (svc *ServiceAccountsService) Update(ctx context.Context, dto UpdateServiceAccountDTO) (*User, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	u, err := svc.Get(GetDTO{ID: dto.ID})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.Name != nil && *dto.Name != u.Name {
+		if existing, err := svc.repo.ByName(*dto.Name); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, ErrNameInUse
+		}
+
+		u.Name = *dto.Name
+	}
+
+	if dto.Active != nil {
+		if !*dto.Active && u.IsActive() {
+			now := svc.getNow()
+			u.DeactivatedAt = &now
+		} else if *dto.Active {
+			u.DeactivatedAt = nil
+		}
+	}
+
+	if err := svc.repo.Update(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "service_accounts.update",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetServiceAccount,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"name": u.Name,
+			"active": u.IsActive(),
+		},
+	})
+
+	return u, nil
+}
+
+// Deactivate stops the service account's tokens from authenticating.
+func (svc *ServiceAccountsService) Deactivate(ctx context.Context, id uuid.UUID) error {
+	active := false
+
+	_, err := svc.Update(ctx, UpdateServiceAccountDTO{
+		ID: id,
+		Active: &active,
+	})
+
+	return err
+}
+
+// AssignRoles replaces the service account's roles with the given ones.
+// Service accounts can never be superusers.
+func (svc *ServiceAccountsService) AssignRoles(ctx context.Context, dto AssignRolesDTO) (*User, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(dto.Roles, RoleSuperuser) {
+		return nil, ErrSuperuserRole
+	}
+
+	u, err := svc.Get(GetDTO{ID: dto.ID})
+
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := rolesByNames(svc.roles, dto.Roles)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u.Roles = roles
+
+	if err := svc.repo.SetRoles(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "service_accounts.roles.assign",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetServiceAccount,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"roles": dto.Roles,
+		},
+	})
+
+	return u, nil
+}
+
+type ServiceAccountsServiceOpt func(*ServiceAccountsService)
+
+func NewServiceAccountsService(repo ServiceAccountsRepo, roles RolesRepo, auditor AuditRecorder, validator *validation.Validator, opts... ServiceAccountsServiceOpt) *ServiceAccountsService {
+	svc := &ServiceAccountsService{
+		repo: repo,
+		roles: roles,
+		auditor: auditor,
+		getNow: dt.NowUTC,
+		validator: validator,
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	return svc
+}
@@ -0,0 +1,112 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+)
+
+func TestServiceAccountsCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		dto     CreateServiceAccountDTO
+		err     error
+		invalid bool
+	}{
+		{
+			name: "no roles",
+			dto: CreateServiceAccountDTO{Name: "deploys"},
+		},
+		{
+			name: "roles",
+			dto: CreateServiceAccountDTO{Name: "deploys", Roles: []string{"ingestor", "viewer"}},
+		},
+		{
+			name: "superuser",
+			dto: CreateServiceAccountDTO{Name: "deploys", Roles: []string{RoleSuperuser}},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "superuser amongst other roles",
+			dto: CreateServiceAccountDTO{Name: "deploys", Roles: []string{"ingestor", RoleSuperuser}},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "unknown role",
+			dto: CreateServiceAccountDTO{Name: "deploys", Roles: []string{"unknown"}},
+			err: ErrRoleNotFound,
+		},
+		{
+			name: "name in use",
+			dto: CreateServiceAccountDTO{Name: "ci"},
+			err: ErrNameInUse,
+		},
+		{
+			name: "no name",
+			dto: CreateServiceAccountDTO{},
+			invalid: true,
+		},
+		{
+			name: "name too long",
+			dto: CreateServiceAccountDTO{Name: strings.Repeat("a", 101)},
+			invalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUsersStore(serviceAccount("viewer"))
+			auditor := &auditRecorder{}
+			svc := NewServiceAccountsService(repo, newRolesStore("ingestor", "viewer", RoleSuperuser), auditor, validation.NewValidator(), func(svc *ServiceAccountsService) {
+				svc.getNow = getNow
+			})
+
+			u, err := svc.Create(context.Background(), tt.dto)
+
+			var validationErr validation.ValidationError
+
+			if tt.invalid && !errors.As(err, &validationErr) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+
+			if !tt.invalid && !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if err != nil {
+				if len(repo.users) != 1 || len(auditor.events) != 0 {
+					t.Errorf("expected nothing to be created or audited, got %d users and %d events", len(repo.users), len(auditor.events))
+				}
+
+				return
+			}
+
+			if !u.IsServiceAccount() || u.Name != tt.dto.Name || u.Email != "" || u.PasswordHash != "" || !u.CreatedAt.Equal(now) {
+				t.Errorf("unexpected service account %+v", u)
+			}
+
+			if got := roleNames(u.Roles); !slices.Equal(got, tt.dto.Roles) {
+				t.Errorf("expected roles %v, got %v", tt.dto.Roles, got)
+			}
+
+			if repo.users[u.ID] != u {
+				t.Error("expected the service account to be stored")
+			}
+
+			if len(auditor.events) != 1 {
+				t.Fatalf("expected the creation audited once, got %d events", len(auditor.events))
+			}
+
+			e := auditor.events[0]
+
+			if e.Action != "service_accounts.create" || e.Outcome != audit.OutcomeSuccess || e.TargetType != TargetServiceAccount || e.TargetID != u.ID.String() || e.Details["name"] != tt.dto.Name {
+				t.Errorf("unexpected audit event %+v", e)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/adamkirk/panoptes/internal/util/random"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCredentials deliberately doesn't say whether it was the email or
+// the password that was wrong.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+var ErrInvalidSession = errors.New("invalid or expired session")
+var ErrSessionsDisabled = errors.New("logging in is disabled, as no session signing key is configured")
+
+type Session struct {
+	ID   uuid.UUID
+	User *User
+
+	// RefreshID identifies the session in refresh tokens. It's separate from
+	// the ID, which is in every session token, so that holding a session
+	// token isn't enough to revoke the session with a bad refresh token.
+	RefreshID         string
+	RefreshSecretHash string
+
+	// ExpireAt is when the session can no longer be refreshed.
+	ExpireAt    time.Time
+	RevokedAt   *time.Time
+	RefreshedAt *time.Time
+	CreatedAt   time.Time
+}
+
+func (s *Session) IsValid(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpireAt) && s.User.IsActive()
+}
+
+// SessionTokens are handed to the user when they log in or refresh. The
+// session token authenticates requests until it expires, the refresh token
+// gets a new pair of tokens and can only be used once.
+type SessionTokens struct {
+	Session *Session
+
+	SessionToken    string
+	SessionExpireAt time.Time
+	RefreshToken    string
+}
+
+type SessionsRepo interface {
+	Create(s *Session) error
+	ByID(id uuid.UUID) (*Session, error)
+	ByRefreshID(refreshID string) (*Session, error)
+
+	// Rotate replaces the refresh secret, as long as it's still the previous
+	// one. It reports whether it was replaced.
+	Rotate(id uuid.UUID, previousHash string, hash string, at time.Time) (bool, error)
+	Revoke(id uuid.UUID, at time.Time) error
+}
+
+type SessionSigner interface {
+	Sign(sessionID string, userID string, expireAt time.Time) (string, error)
+
+	// Verify returns the id of the session the token was signed for.
+	Verify(token string) (string, error)
+}
+
+type HashVerifier interface {
+	HashMatches(hash string, val string) bool
+}
+
+type SessionsConfig interface {
+	AuthSessionsSigningKey() string
+	AuthSessionsTTL() time.Duration
+	AuthSessionsRefreshTTL() time.Duration
+}
+
+type SessionsService struct {
+	cfg       SessionsConfig
+	repo      SessionsRepo
+	users     UsersRepo
+	signer    SessionSigner
+	encrypter Encrypter
+	verifier  HashVerifier
+	validator *validation.Validator
+	genString func(length int) string
+	getNow    func() time.Time
+
+	// dummyHash is checked against when there's no user with the email, so
+	// that how long logging in takes doesn't give away which emails exist.
+	dummyHash     string
+	dummyHashOnce sync.Once
+}
+
+type LoginDTO struct {
+	Email    string `validate:"required"`
+	Password string `validate:"required"`
+}
+
+func (svc *SessionsService) passwordMatches(u *User, password string) bool {
+	if u != nil {
+		return svc.verifier.HashMatches(u.PasswordHash, password)
+	}
+
+	svc.dummyHashOnce.Do(func() {
+		svc.dummyHash, _ = svc.encrypter.Encrypt(svc.genString(32))
+	})
+
+	svc.verifier.HashMatches(svc.dummyHash, password)
+
+	return false
+}
+
+// Login starts a session for the user if the password is correct.
+func (svc *SessionsService) Login(dto LoginDTO) (*SessionTokens, error) {
+	if svc.cfg.AuthSessionsSigningKey() == "" {
+		return nil, ErrSessionsDisabled
+	}
+
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	u, err := svc.users.ByEmail(dto.Email)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.passwordMatches(u, dto.Password) || !u.IsActive() {
+		return nil, ErrInvalidCredentials
+	}
+
+	return svc.Start(u)
+}
+
+// Start starts a session for a user that's already been authenticated, e.g.
+// by an identity provider.
+func (svc *SessionsService) Start(u *User) (*SessionTokens, error) {
+	if svc.cfg.AuthSessionsSigningKey() == "" {
+		return nil, ErrSessionsDisabled
+	}
+
+	secret := svc.genString(32)
+	hash, err := svc.encrypter.Encrypt(secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := svc.getNow()
+
+	s := &Session{
+		ID: uuid.New(),
+		User: u,
+		RefreshID: svc.genString(32),
+		RefreshSecretHash: hash,
+		ExpireAt: now.Add(svc.cfg.AuthSessionsRefreshTTL()),
+		CreatedAt: now,
+	}
+
+	if err := svc.repo.Create(s); err != nil {
+		return nil, err
+	}
+
+	return svc.tokens(s, secret)
+}
+
+func (svc *SessionsService) tokens(s *Session, secret string) (*SessionTokens, error) {
+	// The session token shouldn't outlive the session.
+	expireAt := svc.getNow().Add(svc.cfg.AuthSessionsTTL())
+
+	if expireAt.After(s.ExpireAt) {
+		expireAt = s.ExpireAt
+	}
+
+	token, err := svc.signer.Sign(s.ID.String(), s.User.ID.String(), expireAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		Session: s,
+		SessionToken: token,
+		SessionExpireAt: expireAt,
+		RefreshToken: fmt.Sprintf("%s.%s", s.RefreshID, secret),
+	}, nil
+}
+
+// fromRefreshToken returns the session the refresh token is for, and the
+// secret part of the token.
+func (svc *SessionsService) fromRefreshToken(token string) (*Session, string, error) {
+	refreshID, secret, found := strings.Cut(token, ".")
+
+	if !found || refreshID == "" {
+		return nil, "", ErrInvalidSession
+	}
+
+	s, err := svc.repo.ByRefreshID(refreshID)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if s == nil || !s.IsValid(svc.getNow()) {
+		return nil, "", ErrInvalidSession
+	}
+
+	return s, secret, nil
+}
+
+// Refresh swaps the refresh token for a new pair of tokens. A refresh token
+// that's already been used revokes the session, as it's likely been stolen.
+func (svc *SessionsService) Refresh(refreshToken string) (*SessionTokens, error) {
+	if svc.cfg.AuthSessionsSigningKey() == "" {
+		return nil, ErrSessionsDisabled
+	}
+
+	s, secret, err := svc.fromRefreshToken(refreshToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.verifier.HashMatches(s.RefreshSecretHash, secret) {
+		if err := svc.repo.Revoke(s.ID, svc.getNow()); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrInvalidSession
+	}
+
+	newSecret := svc.genString(32)
+	hash, err := svc.encrypter.Encrypt(newSecret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := svc.getNow()
+	rotated, err := svc.repo.Rotate(s.ID, s.RefreshSecretHash, hash, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Refreshed by someone else in the meantime.
+	if !rotated {
+		return nil, ErrInvalidSession
+	}
+
+	s.RefreshSecretHash = hash
+	s.RefreshedAt = &now
+
+	return svc.tokens(s, newSecret)
+}
+
+// Logout ends the session, its session token stops working straight away.
+func (svc *SessionsService) Logout(refreshToken string) error {
+	s, secret, err := svc.fromRefreshToken(refreshToken)
+
+	if err != nil {
+		return err
+	}
+
+	if !svc.verifier.HashMatches(s.RefreshSecretHash, secret) {
+		return ErrInvalidSession
+	}
+
+	return svc.repo.Revoke(s.ID, svc.getNow())
+}
+
+// Authenticate returns the session for a session token.
+func (svc *SessionsService) Authenticate(sessionToken string) (*Session, error) {
+	if svc.cfg.AuthSessionsSigningKey() == "" {
+		return nil, ErrInvalidSession
+	}
+
+	rawID, err := svc.signer.Verify(sessionToken)
+
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	id, err := uuid.Parse(rawID)
+
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	s, err := svc.repo.ByID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil || !s.IsValid(svc.getNow()) {
+		return nil, ErrInvalidSession
+	}
+
+	return s, nil
+}
+
+type SessionsServiceOpt func(*SessionsService)
+
+func NewSessionsService(
+	cfg SessionsConfig,
+	repo SessionsRepo,
+	users UsersRepo,
+	signer SessionSigner,
+	encrypter Encrypter,
+	verifier HashVerifier,
+	validator *validation.Validator,
+	opts... SessionsServiceOpt,
+) *SessionsService {
+	svc := &SessionsService{
+		cfg: cfg,
+		repo: repo,
+		users: users,
+		signer: signer,
+		encrypter: encrypter,
+		verifier: verifier,
+		validator: validator,
+		genString: random.String,
+		getNow: dt.NowUTC,
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	return svc
+}
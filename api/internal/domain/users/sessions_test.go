@@ -0,0 +1,612 @@
+package users
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/google/uuid"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func getNow() time.Time {
+	return now
+}
+
+// counter generates strings that are unique within a test.
+type counter struct {
+	n int
+}
+
+func (c *counter) genString(length int) string {
+	c.n++
+
+	return "s" + strconv.Itoa(c.n)
+}
+
+// plainEncrypter "hashes" by adding a prefix, which plainVerifier checks for.
+type plainEncrypter struct{}
+
+func (e plainEncrypter) Encrypt(val string) (string, error) {
+	return "hash:" + val, nil
+}
+
+type plainVerifier struct{}
+
+func (v plainVerifier) HashMatches(hash string, val string) bool {
+	return hash == "hash:"+val
+}
+
+type sessionsConfig struct {
+	signingKey string
+	ttl        time.Duration
+	refreshTTL time.Duration
+}
+
+func (c *sessionsConfig) AuthSessionsSigningKey() string {
+	return c.signingKey
+}
+
+func (c *sessionsConfig) AuthSessionsTTL() time.Duration {
+	return c.ttl
+}
+
+func (c *sessionsConfig) AuthSessionsRefreshTTL() time.Duration {
+	return c.refreshTTL
+}
+
+type sessionsRepo struct {
+	sessions map[uuid.UUID]*Session
+	revoked  []uuid.UUID
+
+	// beforeRotate runs before the secret is compared, to refresh the
+	// session in the meantime.
+	beforeRotate func()
+}
+
+func (r *sessionsRepo) Create(s *Session) error {
+	copied := *s
+	r.sessions[s.ID] = &copied
+
+	return nil
+}
+
+func (r *sessionsRepo) ByID(id uuid.UUID) (*Session, error) {
+	s, ok := r.sessions[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *s
+
+	return &copied, nil
+}
+
+func (r *sessionsRepo) ByRefreshID(refreshID string) (*Session, error) {
+	for _, s := range r.sessions {
+		if s.RefreshID == refreshID {
+			return r.ByID(s.ID)
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *sessionsRepo) Rotate(id uuid.UUID, previousHash string, hash string, at time.Time) (bool, error) {
+	if r.beforeRotate != nil {
+		r.beforeRotate()
+		r.beforeRotate = nil
+	}
+
+	s := r.sessions[id]
+
+	if s.RefreshSecretHash != previousHash || s.RevokedAt != nil {
+		return false, nil
+	}
+
+	s.RefreshSecretHash = hash
+	s.RefreshedAt = &at
+
+	return true, nil
+}
+
+func (r *sessionsRepo) Revoke(id uuid.UUID, at time.Time) error {
+	r.revoked = append(r.revoked, id)
+	r.sessions[id].RevokedAt = &at
+
+	return nil
+}
+
+// emailUsers only finds users by email, which is all logging in needs.
+type emailUsers map[string]*User
+
+func (r emailUsers) ByEmail(email string) (*User, error) {
+	return r[email], nil
+}
+
+func (r emailUsers) Create(u *User) error {
+	return errors.New("not implemented")
+}
+
+func (r emailUsers) Get(id uuid.UUID) (*User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r emailUsers) List(t UserType, offset int, limit int) ([]*User, int, error) {
+	return nil, 0, errors.New("not implemented")
+}
+
+func (r emailUsers) Update(u *User) error {
+	return errors.New("not implemented")
+}
+
+func (r emailUsers) SetRoles(u *User) error {
+	return errors.New("not implemented")
+}
+
+// sessionSigner "signs" by adding a prefix.
+type sessionSigner struct{}
+
+func (s sessionSigner) Sign(sessionID string, userID string, expireAt time.Time) (string, error) {
+	return "jwt:" + sessionID, nil
+}
+
+func (s sessionSigner) Verify(token string) (string, error) {
+	id, found := strings.CutPrefix(token, "jwt:")
+
+	if !found {
+		return "", errors.New("invalid token")
+	}
+
+	return id, nil
+}
+
+func newTestSessionsService(cfg *sessionsConfig, repo *sessionsRepo, users emailUsers) *SessionsService {
+	c := &counter{}
+
+	return NewSessionsService(
+		cfg,
+		repo,
+		users,
+		sessionSigner{},
+		plainEncrypter{},
+		plainVerifier{},
+		validation.NewValidator(),
+		func(svc *SessionsService) {
+			svc.genString = c.genString
+			svc.getNow = getNow
+		},
+	)
+}
+
+func newSessionsRepo() *sessionsRepo {
+	return &sessionsRepo{sessions: map[uuid.UUID]*Session{}}
+}
+
+func sessionsEnabled() *sessionsConfig {
+	return &sessionsConfig{
+		signingKey: "key",
+		ttl: 15 * time.Minute,
+		refreshTTL: 24 * time.Hour,
+	}
+}
+
+func activeUser() *User {
+	return &User{ID: uuid.New(), Type: UserTypeUser, Email: "a@example.com", PasswordHash: "hash:password"}
+}
+
+func TestSessionsLogin(t *testing.T) {
+	deactivated := activeUser()
+	deactivated.DeactivatedAt = &now
+
+	tests := []struct {
+		name     string
+		cfg      *sessionsConfig
+		users    emailUsers
+		email    string
+		password string
+		err      error
+	}{
+		{
+			name: "valid",
+			cfg: sessionsEnabled(),
+			users: emailUsers{"a@example.com": activeUser()},
+			email: "a@example.com",
+			password: "password",
+		},
+		{
+			name: "wrong password",
+			cfg: sessionsEnabled(),
+			users: emailUsers{"a@example.com": activeUser()},
+			email: "a@example.com",
+			password: "wrong",
+			err: ErrInvalidCredentials,
+		},
+		{
+			name: "unknown email",
+			cfg: sessionsEnabled(),
+			users: emailUsers{},
+			email: "a@example.com",
+			password: "password",
+			err: ErrInvalidCredentials,
+		},
+		{
+			name: "deactivated",
+			cfg: sessionsEnabled(),
+			users: emailUsers{"a@example.com": deactivated},
+			email: "a@example.com",
+			password: "password",
+			err: ErrInvalidCredentials,
+		},
+		{
+			name: "no signing key",
+			cfg: &sessionsConfig{},
+			users: emailUsers{"a@example.com": activeUser()},
+			email: "a@example.com",
+			password: "password",
+			err: ErrSessionsDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSessionsRepo()
+			svc := newTestSessionsService(tt.cfg, repo, tt.users)
+
+			tokens, err := svc.Login(LoginDTO{Email: tt.email, Password: tt.password})
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				if len(repo.sessions) != 0 {
+					t.Errorf("expected no session to be started, got %d", len(repo.sessions))
+				}
+
+				return
+			}
+
+			if len(repo.sessions) != 1 {
+				t.Fatalf("expected a session to be started, got %d", len(repo.sessions))
+			}
+
+			if tokens.SessionToken != "jwt:"+tokens.Session.ID.String() {
+				t.Errorf("expected the session token to be signed for the session, got %s", tokens.SessionToken)
+			}
+		})
+	}
+}
+
+func TestSessionTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		refreshTTL time.Duration
+		want       time.Time
+	}{
+		{
+			name: "session ttl",
+			ttl: 15 * time.Minute,
+			refreshTTL: time.Hour,
+			want: now.Add(15 * time.Minute),
+		},
+		{
+			name: "clamped to when the session expires",
+			ttl: time.Hour,
+			refreshTTL: 15 * time.Minute,
+			want: now.Add(15 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &sessionsConfig{signingKey: "key", ttl: tt.ttl, refreshTTL: tt.refreshTTL}
+			svc := newTestSessionsService(cfg, newSessionsRepo(), emailUsers{})
+
+			tokens, err := svc.Start(activeUser())
+
+			if err != nil {
+				t.Fatalf("failed to start session: %s", err)
+			}
+
+			if !tokens.SessionExpireAt.Equal(tt.want) {
+				t.Errorf("expected the session token to expire at %s, got %s", tt.want, tokens.SessionExpireAt)
+			}
+
+			if !tokens.Session.ExpireAt.Equal(now.Add(tt.refreshTTL)) {
+				t.Errorf("expected the session to expire at %s, got %s", now.Add(tt.refreshTTL), tokens.Session.ExpireAt)
+			}
+		})
+	}
+}
+
+func TestSessionsRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// token is the refresh token to use, given the tokens from starting
+		// the session and from refreshing it once.
+		token        func(first *SessionTokens, second *SessionTokens) string
+		beforeRotate func(repo *sessionsRepo, first *SessionTokens)
+
+		// logout ends the session before refreshing.
+		logout  bool
+		err     error
+		revoked bool
+	}{
+		{
+			name: "latest token",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return second.RefreshToken
+			},
+		},
+		{
+			name: "reused token revokes the session",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return first.RefreshToken
+			},
+			err: ErrInvalidSession,
+			revoked: true,
+		},
+		{
+			name: "session id with a bad secret",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return first.Session.ID.String() + ".bad"
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "unknown refresh id",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return "unknown.bad"
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "malformed",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return "malformed"
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "refreshed by someone else in the meantime",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return second.RefreshToken
+			},
+			beforeRotate: func(repo *sessionsRepo, first *SessionTokens) {
+				repo.sessions[first.Session.ID].RefreshSecretHash = "hash:other"
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "revoked session",
+			token: func(first *SessionTokens, second *SessionTokens) string {
+				return second.RefreshToken
+			},
+			logout: true,
+			err: ErrInvalidSession,
+			revoked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSessionsRepo()
+			svc := newTestSessionsService(sessionsEnabled(), repo, emailUsers{})
+
+			first, err := svc.Start(activeUser())
+
+			if err != nil {
+				t.Fatalf("failed to start session: %s", err)
+			}
+
+			second, err := svc.Refresh(first.RefreshToken)
+
+			if err != nil {
+				t.Fatalf("failed to refresh session: %s", err)
+			}
+
+			if second.RefreshToken == first.RefreshToken {
+				t.Fatal("expected refreshing to rotate the refresh token")
+			}
+
+			if tt.logout {
+				if err := svc.Logout(second.RefreshToken); err != nil {
+					t.Fatalf("failed to log out: %s", err)
+				}
+			}
+
+			if tt.beforeRotate != nil {
+				repo.beforeRotate = func() { tt.beforeRotate(repo, first) }
+			}
+
+			third, err := svc.Refresh(tt.token(first, second))
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			revoked := repo.sessions[first.Session.ID].RevokedAt != nil
+
+			if revoked != tt.revoked {
+				t.Errorf("expected the session revoked to be %t, got %t", tt.revoked, revoked)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if third.Session.ID != first.Session.ID {
+				t.Error("expected refreshing to keep the session")
+			}
+
+			if third.Session.RefreshedAt == nil || !third.Session.RefreshedAt.Equal(now) {
+				t.Errorf("expected the session to be refreshed at %s, got %v", now, third.Session.RefreshedAt)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenIsNotInSessionToken(t *testing.T) {
+	svc := newTestSessionsService(sessionsEnabled(), newSessionsRepo(), emailUsers{})
+
+	tokens, err := svc.Start(activeUser())
+
+	if err != nil {
+		t.Fatalf("failed to start session: %s", err)
+	}
+
+	refreshID, _, _ := strings.Cut(tokens.RefreshToken, ".")
+
+	if refreshID == tokens.Session.ID.String() || strings.Contains(tokens.SessionToken, refreshID) {
+		t.Errorf("expected the refresh token not to be identifiable from the session token, got refresh token %s and session token %s", tokens.RefreshToken, tokens.SessionToken)
+	}
+}
+
+func TestSessionsLogout(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   func(tokens *SessionTokens) string
+		err     error
+		revoked bool
+	}{
+		{
+			name: "valid",
+			token: func(tokens *SessionTokens) string {
+				return tokens.RefreshToken
+			},
+			revoked: true,
+		},
+		{
+			name: "bad secret",
+			token: func(tokens *SessionTokens) string {
+				refreshID, _, _ := strings.Cut(tokens.RefreshToken, ".")
+				return refreshID + ".bad"
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "session id",
+			token: func(tokens *SessionTokens) string {
+				_, secret, _ := strings.Cut(tokens.RefreshToken, ".")
+				return tokens.Session.ID.String() + "." + secret
+			},
+			err: ErrInvalidSession,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSessionsRepo()
+			svc := newTestSessionsService(sessionsEnabled(), repo, emailUsers{})
+
+			tokens, err := svc.Start(activeUser())
+
+			if err != nil {
+				t.Fatalf("failed to start session: %s", err)
+			}
+
+			if err := svc.Logout(tt.token(tokens)); !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if revoked := len(repo.revoked) > 0; revoked != tt.revoked {
+				t.Errorf("expected the session revoked to be %t, got %t", tt.revoked, revoked)
+			}
+		})
+	}
+}
+
+func TestSessionsAuthenticate(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  func(tokens *SessionTokens) string
+		change func(s *Session)
+		err    error
+	}{
+		{
+			name: "valid",
+			token: func(tokens *SessionTokens) string {
+				return tokens.SessionToken
+			},
+		},
+		{
+			name: "invalid signature",
+			token: func(tokens *SessionTokens) string {
+				return tokens.Session.ID.String()
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "unknown session",
+			token: func(tokens *SessionTokens) string {
+				return "jwt:" + uuid.NewString()
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "revoked",
+			token: func(tokens *SessionTokens) string {
+				return tokens.SessionToken
+			},
+			change: func(s *Session) {
+				s.RevokedAt = &now
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "expired",
+			token: func(tokens *SessionTokens) string {
+				return tokens.SessionToken
+			},
+			change: func(s *Session) {
+				s.ExpireAt = now
+			},
+			err: ErrInvalidSession,
+		},
+		{
+			name: "deactivated user",
+			token: func(tokens *SessionTokens) string {
+				return tokens.SessionToken
+			},
+			change: func(s *Session) {
+				s.User.DeactivatedAt = &now
+			},
+			err: ErrInvalidSession,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSessionsRepo()
+			svc := newTestSessionsService(sessionsEnabled(), repo, emailUsers{})
+
+			tokens, err := svc.Start(activeUser())
+
+			if err != nil {
+				t.Fatalf("failed to start session: %s", err)
+			}
+
+			if tt.change != nil {
+				tt.change(repo.sessions[tokens.Session.ID])
+			}
+
+			s, err := svc.Authenticate(tt.token(tokens))
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err == nil && s.ID != tokens.Session.ID {
+				t.Errorf("expected session %s, got %s", tokens.Session.ID, s.ID)
+			}
+		})
+	}
+}
@@ -1,17 +1,37 @@
 package users
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/adamkirk/panoptes/internal/domain/audit"
 	"github.com/adamkirk/panoptes/internal/domain/validation"
 	"github.com/adamkirk/panoptes/internal/util/dt"
 	"github.com/adamkirk/panoptes/internal/util/random"
 	"github.com/google/uuid"
 )
 
+const RoleSuperuser = "superuser"
+
+var ErrUserNotFound = errors.New("user not found")
+var ErrEmailInUse = errors.New("email already in use")
+var ErrRoleNotFound = errors.New("role not found")
+
+// ErrSuperuserRole is returned when trying to grant or take away the superuser
+// role, or change a superuser, through anything but the superusers CLI,
+// otherwise anyone allowed to assign roles or update users could make
+// themselves a superuser, or take over one.
+var ErrSuperuserRole = errors.New("the superuser role can only be managed with the superusers command")
+
+// ErrRolesManagedByIdP is returned when assigning roles to a user that logs in
+// with an identity provider that their roles are synced from.
+var ErrRolesManagedByIdP = errors.New("the user's roles are managed by the identity provider")
+
 type CreateDTO struct {
-	Email     string `validate:"required"`
+	Email     string `validate:"required,email"`
 	FirstName string `validate:"required"`
 	LastName  string `validate:"required"`
 	Password string `validate:"required"`
@@ -22,6 +42,9 @@ type UsersRepo interface {
 	ByEmail(email string) (*User, error)
 	Create(u *User) error
 	Get(id uuid.UUID) (*User, error)
+	List(t UserType, offset int, limit int) ([]*User, int, error)
+	Update(u *User) error
+	SetRoles(u *User) error
 }
 
 type RolesRepo interface {
@@ -31,28 +54,46 @@ type RolesRepo interface {
 type UsersService struct {
 	repo UsersRepo
 	roles RolesRepo
+	oidc OIDCConfig
+	auditor AuditRecorder
 	encrypter Encrypter
 	genString func(length int) (string)
 	getNow func() time.Time
 	validator *validation.Validator
 }
 
-func (svc *UsersService) Create(dto CreateDTO) (*User, error) {
+// rolesByNames errors if any of the roles don't exist.
+func rolesByNames(repo RolesRepo, names []string) ([]*Role, error) {
+	roles, err := repo.ByNames(names)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(roles, func(r *Role) bool { return r.Name == name }) {
+			return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, name)
+		}
+	}
+
+	return roles, nil
+}
+
+func (svc *UsersService) Create(ctx context.Context, dto CreateDTO) (*User, error) {
 	if err := svc.validator.Validate(dto); err != nil {
 		return nil, err
 	}
 
-	roles, err := svc.roles.ByNames(dto.Roles)
+	roles, err := rolesByNames(svc.roles, dto.Roles)
 
-	if len(roles) != len(dto.Roles) {
-		// TOOD improve error message
-		return nil, errors.New("some roles we're not found")
+	if err != nil {
+		return nil, err
 	}
 
 	if u, err := svc.repo.ByEmail(dto.Email); err != nil {
 		return nil, err
 	} else if u != nil {
-		return nil, errors.New("email already in use")
+		return nil, ErrEmailInUse
 	}
 
 	passwordHash, err := svc.encrypter.Encrypt(dto.Password)
@@ -63,14 +104,31 @@ func (svc *UsersService) Create(dto CreateDTO) (*User, error) {
 
 	u := &User{
 		ID: uuid.New(),
+		Type: UserTypeUser,
 		Email: dto.Email,
 		FirstName: dto.FirstName,
 		LastName: dto.LastName,
 		PasswordHash: passwordHash,
+		CreatedAt: svc.getNow(),
 		Roles: roles,
 	}
 
-	return u, svc.repo.Create(u)
+	if err := svc.repo.Create(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "users.create",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetUser,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"email": u.Email,
+			"roles": dto.Roles,
+		},
+	})
+
+	return u, nil
 }
 
 type GetDTO struct {
@@ -82,12 +140,211 @@ func (svc *UsersService) Get(dto GetDTO) (*User, error) {
 		return nil, err
 	}
 
-	return svc.repo.Get(dto.ID)
+	u, err := svc.repo.Get(dto.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Service accounts are managed separately.
+	if u == nil || u.IsServiceAccount() {
+		return nil, ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+type ListDTO struct {
+	Page    int `validate:"min=1"`
+	PerPage int `validate:"min=1,max=100"`
+}
+
+type UsersPage struct {
+	Users []*User
+	Total int
+}
+
+// List returns a page of users, oldest first. Service accounts aren't
+// included.
+func (svc *UsersService) List(dto ListDTO) (*UsersPage, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	users, total, err := svc.repo.List(UserTypeUser, (dto.Page-1)*dto.PerPage, dto.PerPage)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UsersPage{
+		Users: users,
+		Total: total,
+	}, nil
+}
+
+// UpdateDTO only changes the fields that are set.
+type UpdateDTO struct {
+	ID        uuid.UUID `validate:"required"`
+	Email     *string   `validate:"omitempty,email"`
+	FirstName *string   `validate:"omitempty,min=1"`
+	LastName  *string   `validate:"omitempty,min=1"`
+	Password  *string   `validate:"omitempty,min=1"`
+	Active    *bool
+}
+
+func (svc *UsersService) Update(ctx context.Context, dto UpdateDTO) (*User, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	u, err := svc.Get(GetDTO{ID: dto.ID})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Changing a superuser's email or password would let the caller log in
+	// as them, and deactivating them all would leave no one to manage roles.
+	if u.HasRole(RoleSuperuser) {
+		return nil, ErrSuperuserRole
+	}
+
+	if dto.Email != nil && *dto.Email != u.Email {
+		if existing, err := svc.repo.ByEmail(*dto.Email); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, ErrEmailInUse
+		}
+
+		u.Email = *dto.Email
+	}
+
+	// Only which fields changed is recorded, never the password.
+	changed := []string{}
+
+	if dto.Email != nil {
+		changed = append(changed, "email")
+	}
+
+	if dto.FirstName != nil {
+		u.FirstName = *dto.FirstName
+		changed = append(changed, "first_name")
+	}
+
+	if dto.LastName != nil {
+		u.LastName = *dto.LastName
+		changed = append(changed, "last_name")
+	}
+
+	if dto.Password != nil {
+		hash, err := svc.encrypter.Encrypt(*dto.Password)
+
+		if err != nil {
+			return nil, err
+		}
+
+		u.PasswordHash = hash
+		changed = append(changed, "password")
+	}
+
+	if dto.Active != nil {
+		if !*dto.Active && u.IsActive() {
+			now := svc.getNow()
+			u.DeactivatedAt = &now
+		} else if *dto.Active {
+			u.DeactivatedAt = nil
+		}
+
+		changed = append(changed, "active")
+	}
+
+	if err := svc.repo.Update(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "users.update",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetUser,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"fields": changed,
+			"active": u.IsActive(),
+		},
+	})
+
+	return u, nil
+}
+
+// Deactivate stops the user (and their tokens) from authenticating. Users are
+// never deleted, as other records refer to them. Like Update, superusers can't
+// be deactivated.
+func (svc *UsersService) Deactivate(ctx context.Context, id uuid.UUID) error {
+	active := false
+
+	_, err := svc.Update(ctx, UpdateDTO{
+		ID: id,
+		Active: &active,
+	})
+
+	return err
+}
+
+type AssignRolesDTO struct {
+	ID    uuid.UUID `validate:"required"`
+	Roles []string  `validate:"required"`
+}
+
+// AssignRoles replaces the user's roles with the given ones. Users whose roles
+// are synced from an identity provider have them managed there instead.
+func (svc *UsersService) AssignRoles(ctx context.Context, dto AssignRolesDTO) (*User, error) {
+	if err := svc.validator.Validate(dto); err != nil {
+		return nil, err
+	}
+
+	u, err := svc.Get(GetDTO{ID: dto.ID})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.HasRole(RoleSuperuser) != slices.Contains(dto.Roles, RoleSuperuser) {
+		return nil, ErrSuperuserRole
+	}
+
+	if u.OIDCSubject != "" && syncsRoles(svc.oidc) {
+		return nil, ErrRolesManagedByIdP
+	}
+
+	roles, err := rolesByNames(svc.roles, dto.Roles)
+
+	if err != nil {
+		return nil, err
+	}
+
+	u.Roles = roles
+
+	if err := svc.repo.SetRoles(u); err != nil {
+		return nil, err
+	}
+
+	svc.auditor.Record(ctx, audit.Event{
+		Action: "users.roles.assign",
+		Outcome: audit.OutcomeSuccess,
+		TargetType: TargetUser,
+		TargetID: u.ID.String(),
+		Details: map[string]any{
+			"roles": dto.Roles,
+		},
+	})
+
+	return u, nil
 }
 
 type UsersServiceOpt func(*UsersService)
 
-func NewUsersService(encrypter Encrypter, repo UsersRepo, roles RolesRepo, validator *validation.Validator, opts... UsersServiceOpt) *UsersService {
+func NewUsersService(encrypter Encrypter, repo UsersRepo, roles RolesRepo, oidcCfg OIDCConfig, auditor AuditRecorder, validator *validation.Validator, opts... UsersServiceOpt) *UsersService {
 	svc := &UsersService{
 		encrypter: encrypter,
 		getNow: dt.NowUTC,
@@ -95,6 +352,8 @@ func NewUsersService(encrypter Encrypter, repo UsersRepo, roles RolesRepo, valid
 		repo: repo,
 		validator: validator,
 		roles: roles,
+		oidc: oidcCfg,
+		auditor: auditor,
 	}
 
 	// TODO add opts
@@ -103,4 +362,4 @@ func NewUsersService(encrypter Encrypter, repo UsersRepo, roles RolesRepo, valid
 	}
 
 	return svc
-}
\ No newline at end of file
+}
@@ -0,0 +1,299 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/domain/validation"
+	"github.com/google/uuid"
+)
+
+type usersStore struct {
+	users   map[uuid.UUID]*User
+	updated int
+}
+
+func newUsersStore(users ...*User) *usersStore {
+	s := &usersStore{users: map[uuid.UUID]*User{}}
+
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+
+	return s
+}
+
+func (s *usersStore) ByEmail(email string) (*User, error) {
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *usersStore) ByName(name string) (*User, error) {
+	for _, u := range s.users {
+		if u.IsServiceAccount() && u.Name == name {
+			return u, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *usersStore) ByOIDCSubject(issuer string, subject string) (*User, error) {
+	for _, u := range s.users {
+		if u.OIDCIssuer == issuer && u.OIDCSubject == subject {
+			return u, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *usersStore) Create(u *User) error {
+	s.users[u.ID] = u
+
+	return nil
+}
+
+func (s *usersStore) Get(id uuid.UUID) (*User, error) {
+	u, ok := s.users[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	// A copy, so that changes the service makes without saving don't count.
+	copied := *u
+	copied.Roles = slices.Clone(u.Roles)
+
+	return &copied, nil
+}
+
+func (s *usersStore) List(t UserType, offset int, limit int) ([]*User, int, error) {
+	return nil, 0, errors.New("not implemented")
+}
+
+func (s *usersStore) Update(u *User) error {
+	s.updated++
+	s.users[u.ID] = u
+
+	return nil
+}
+
+func (s *usersStore) SetRoles(u *User) error {
+	s.updated++
+	s.users[u.ID] = u
+
+	return nil
+}
+
+type rolesStore map[string]*Role
+
+func newRolesStore(names ...string) rolesStore {
+	s := rolesStore{}
+
+	for _, name := range names {
+		s[name] = role(name)
+	}
+
+	return s
+}
+
+func (s rolesStore) ByNames(names []string) ([]*Role, error) {
+	roles := []*Role{}
+
+	for _, name := range names {
+		if r, ok := s[name]; ok {
+			roles = append(roles, r)
+		}
+	}
+
+	return roles, nil
+}
+
+type auditRecorder struct {
+	events []audit.Event
+}
+
+func (r *auditRecorder) Record(ctx context.Context, e audit.Event) {
+	r.events = append(r.events, e)
+}
+
+func userWithRoles(roles ...string) *User {
+	u := &User{ID: uuid.New(), Type: UserTypeUser, Email: uuid.NewString() + "@example.com"}
+
+	for _, name := range roles {
+		u.Roles = append(u.Roles, role(name))
+	}
+
+	return u
+}
+
+func newTestUsersService(repo *usersStore, roles rolesStore, auditor *auditRecorder) *UsersService {
+	return NewUsersService(plainEncrypter{}, repo, roles, syncingRoles, auditor, validation.NewValidator(), func(svc *UsersService) {
+		svc.getNow = getNow
+	})
+}
+
+func TestUsersUpdate(t *testing.T) {
+	email := "new@example.com"
+	active := false
+
+	tests := []struct {
+		name string
+		user *User
+		dto  UpdateDTO
+		err  error
+	}{
+		{
+			name: "user",
+			user: userWithRoles("viewer"),
+			dto: UpdateDTO{Email: &email},
+		},
+		{
+			name: "superuser's email",
+			user: userWithRoles(RoleSuperuser),
+			dto: UpdateDTO{Email: &email},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "superuser's password",
+			user: userWithRoles("viewer", RoleSuperuser),
+			dto: UpdateDTO{Password: &email},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "deactivating a superuser",
+			user: userWithRoles(RoleSuperuser),
+			dto: UpdateDTO{Active: &active},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "service account",
+			user: &User{ID: uuid.New(), Type: UserTypeServiceAccount, Name: "ci"},
+			dto: UpdateDTO{Email: &email},
+			err: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUsersStore(tt.user)
+			auditor := &auditRecorder{}
+			svc := newTestUsersService(repo, newRolesStore(), auditor)
+
+			tt.dto.ID = tt.user.ID
+			_, err := svc.Update(context.Background(), tt.dto)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if updated := repo.updated > 0; updated != (tt.err == nil) {
+				t.Errorf("expected the user updated to be %t, got %t", tt.err == nil, updated)
+			}
+
+			if audited := len(auditor.events) > 0; audited != (tt.err == nil) {
+				t.Errorf("expected the update audited to be %t, got %t", tt.err == nil, audited)
+			}
+		})
+	}
+}
+
+func TestUsersAssignRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  *User
+		roles []string
+		err   error
+	}{
+		{
+			name: "user",
+			user: userWithRoles("viewer"),
+			roles: []string{"editor"},
+		},
+		{
+			name: "granting superuser",
+			user: userWithRoles("viewer"),
+			roles: []string{"viewer", RoleSuperuser},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "removing superuser",
+			user: userWithRoles("viewer", RoleSuperuser),
+			roles: []string{"viewer"},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "removing every role from a superuser",
+			user: userWithRoles(RoleSuperuser),
+			roles: []string{},
+			err: ErrSuperuserRole,
+		},
+		{
+			name: "keeping superuser",
+			user: userWithRoles(RoleSuperuser),
+			roles: []string{RoleSuperuser, "editor"},
+		},
+		{
+			name: "unknown role",
+			user: userWithRoles("viewer"),
+			roles: []string{"unknown"},
+			err: ErrRoleNotFound,
+		},
+		{
+			name: "user whose roles come from the identity provider",
+			user: oidcUser("viewer"),
+			roles: []string{"editor"},
+			err: ErrRolesManagedByIdP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUsersStore(tt.user)
+			svc := newTestUsersService(repo, newRolesStore("viewer", "editor", RoleSuperuser), &auditRecorder{})
+
+			u, err := svc.AssignRoles(context.Background(), AssignRolesDTO{ID: tt.user.ID, Roles: tt.roles})
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				if repo.updated != 0 {
+					t.Error("expected the roles not to be changed")
+				}
+
+				return
+			}
+
+			for _, name := range tt.roles {
+				if !u.HasRole(name) {
+					t.Errorf("expected the user to have role %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestUsersAssignRolesWithoutRoleSync(t *testing.T) {
+	u := oidcUser("viewer")
+	repo := newUsersStore(u)
+	svc := NewUsersService(plainEncrypter{}, repo, newRolesStore("viewer", "editor"), oidcConfig{}, &auditRecorder{}, validation.NewValidator())
+
+	if _, err := svc.AssignRoles(context.Background(), AssignRolesDTO{ID: u.ID, Roles: []string{"editor"}}); err != nil {
+		t.Fatalf("expected the roles to be assigned, got %v", err)
+	}
+
+	if !repo.users[u.ID].HasRole("editor") {
+		t.Error("expected the user to have role editor")
+	}
+}
@@ -0,0 +1,310 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+)
+
+const changeRequestsIndex = projections.ChangeRequestsProjection
+
+var changeRequestsMappings = map[string]any{
+	// Anything we haven't mapped explicitly is kept in the source but not
+	// indexed, rather than guessing at a type.
+	"dynamic": false,
+	"properties": map[string]any{
+		"id":                 map[string]any{"type": "keyword"},
+		"source_integration": map[string]any{"type": "keyword"},
+		"number":             map[string]any{"type": "integer"},
+		"title": map[string]any{
+			"type": "text",
+			"fields": map[string]any{
+				"keyword": map[string]any{"type": "keyword", "ignore_above": 256},
+			},
+		},
+		"url":                     map[string]any{"type": "keyword", "index": false},
+		"author":                  map[string]any{"type": "keyword"},
+		"repository":              map[string]any{"type": "keyword"},
+		"base_branch":             map[string]any{"type": "keyword"},
+		"head_branch":             map[string]any{"type": "keyword"},
+		"state":                   map[string]any{"type": "keyword"},
+		"is_draft":                map[string]any{"type": "boolean"},
+		"opened_at":               map[string]any{"type": "date"},
+		"first_commit_at":         map[string]any{"type": "date"},
+		"first_review_at":         map[string]any{"type": "date"},
+		"approved_at":             map[string]any{"type": "date"},
+		"merged_at":               map[string]any{"type": "date"},
+		"closed_at":               map[string]any{"type": "date"},
+		"reopened_at":             map[string]any{"type": "date"},
+		"additions":               map[string]any{"type": "integer"},
+		"deletions":               map[string]any{"type": "integer"},
+		"changed_files":           map[string]any{"type": "integer"},
+		"commits":                 map[string]any{"type": "integer"},
+		"review_count":            map[string]any{"type": "integer"},
+		"approval_count":          map[string]any{"type": "integer"},
+		"changes_requested_count": map[string]any{"type": "integer"},
+		"comment_count":           map[string]any{"type": "integer"},
+		"approving_reviews":       map[string]any{"type": "keyword", "index": false},
+		"last_event_at":           map[string]any{"type": "date"},
+		"last_event_seq":          map[string]any{"type": "long"},
+	},
+}
+
+type changeRequestDocument struct {
+	ID                string `json:"id"`
+	SourceIntegration string `json:"source_integration"`
+
+	Number     int    `json:"number"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Author     string `json:"author"`
+	Repository string `json:"repository"`
+	BaseBranch string `json:"base_branch"`
+	HeadBranch string `json:"head_branch"`
+	State      string `json:"state"`
+	IsDraft    bool   `json:"is_draft"`
+
+	OpenedAt      *time.Time `json:"opened_at"`
+	FirstCommitAt *time.Time `json:"first_commit_at"`
+	FirstReviewAt *time.Time `json:"first_review_at"`
+	ApprovedAt    *time.Time `json:"approved_at"`
+	MergedAt      *time.Time `json:"merged_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+	ReopenedAt    *time.Time `json:"reopened_at"`
+
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	ChangedFiles int `json:"changed_files"`
+	Commits      int `json:"commits"`
+
+	ReviewCount           int `json:"review_count"`
+	ApprovalCount         int `json:"approval_count"`
+	ChangesRequestedCount int `json:"changes_requested_count"`
+	CommentCount          int `json:"comment_count"`
+
+	ApprovingReviews []string `json:"approving_reviews"`
+
+	LastEventAt  time.Time `json:"last_event_at"`
+	LastEventSeq int64     `json:"last_event_seq"`
+}
+
+type mgetResponse[T any] struct {
+	Docs []getDocumentResponse[T] `json:"docs"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// changeRequestDocumentID is unique across integrations, as aggregate ids are
+// only unique within one.
+func changeRequestDocumentID(k projections.ChangeRequestKey) string {
+	return k.SourceIntegration + ":" + k.ID
+}
+
+// ChangeRequestsProjectionRepository stores the change requests read model as
+// documents in the change_requests index.
+type ChangeRequestsProjectionRepository struct {
+	client *Client
+}
+
+func (r *ChangeRequestsProjectionRepository) GetMany(keys []projections.ChangeRequestKey) (map[projections.ChangeRequestKey]*projections.ChangeRequest, error) {
+	found := map[projections.ChangeRequestKey]*projections.ChangeRequest{}
+
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	ids := make([]string, len(keys))
+
+	for i, k := range keys {
+		ids[i] = changeRequestDocumentID(k)
+	}
+
+	res := mgetResponse[changeRequestDocument]{}
+
+	// _mget is realtime, so we see documents from the previous batch even if
+	// the index hasn't been refreshed. A 404 means the index doesn't exist yet.
+	status, err := r.client.request(
+		http.MethodPost,
+		"/"+r.client.Index(changeRequestsIndex)+"/_mget",
+		map[string]any{"ids": ids},
+		&res,
+		http.StatusNotFound,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusNotFound {
+		return found, nil
+	}
+
+	for _, doc := range res.Docs {
+		if !doc.Found {
+			continue
+		}
+
+		cr := changeRequestFromDocument(doc.Source)
+		found[cr.Key()] = cr
+	}
+
+	return found, nil
+}
+
+// SaveMany indexes the change requests in a single bulk request, replacing any
+// existing documents for them.
+func (r *ChangeRequestsProjectionRepository) SaveMany(crs []*projections.ChangeRequest) error {
+	if len(crs) == 0 {
+		return nil
+	}
+
+	if err := r.client.ensureTemplate(changeRequestsIndex, changeRequestsMappings); err != nil {
+		return err
+	}
+
+	index := r.client.Index(changeRequestsIndex)
+	body := bytes.Buffer{}
+	enc := json.NewEncoder(&body)
+
+	for _, cr := range crs {
+		action := map[string]any{
+			"index": map[string]any{
+				"_index": index,
+				"_id":    changeRequestDocumentID(cr.Key()),
+			},
+		}
+
+		// Encode adds the newline that the bulk api needs after each line.
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+
+		if err := enc.Encode(changeRequestToDocument(cr)); err != nil {
+			return err
+		}
+	}
+
+	res := bulkResponse{}
+
+	if _, err := r.client.send(http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes(), &res); err != nil {
+		return err
+	}
+
+	if !res.Errors {
+		return nil
+	}
+
+	// The request as a whole succeeds even when individual documents fail.
+	errs := []error{}
+
+	for _, item := range res.Items {
+		for _, result := range item {
+			if result.Status >= 300 {
+				errs = append(errs, fmt.Errorf("failed to index change request %s (%d): %s", result.ID, result.Status, result.Error))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Truncate deletes the whole index rather than the documents in it, so that
+// it picks up any changes to the template when it is recreated.
+func (r *ChangeRequestsProjectionRepository) Truncate() error {
+	return r.client.deleteIndex(changeRequestsIndex)
+}
+
+func changeRequestToDocument(cr *projections.ChangeRequest) changeRequestDocument {
+	return changeRequestDocument{
+		ID:                    cr.ID,
+		SourceIntegration:     cr.SourceIntegration,
+		Number:                cr.Number,
+		Title:                 cr.Title,
+		URL:                   cr.URL,
+		Author:                cr.Author,
+		Repository:            cr.Repository,
+		BaseBranch:            cr.BaseBranch,
+		HeadBranch:            cr.HeadBranch,
+		State:                 cr.State,
+		IsDraft:               cr.IsDraft,
+		OpenedAt:              cr.OpenedAt,
+		FirstCommitAt:         cr.FirstCommitAt,
+		FirstReviewAt:         cr.FirstReviewAt,
+		ApprovedAt:            cr.ApprovedAt,
+		MergedAt:              cr.MergedAt,
+		ClosedAt:              cr.ClosedAt,
+		ReopenedAt:            cr.ReopenedAt,
+		Additions:             cr.Additions,
+		Deletions:             cr.Deletions,
+		ChangedFiles:          cr.ChangedFiles,
+		Commits:               cr.Commits,
+		ReviewCount:           cr.ReviewCount,
+		ApprovalCount:         cr.ApprovalCount,
+		ChangesRequestedCount: cr.ChangesRequestedCount,
+		CommentCount:          cr.CommentCount,
+		ApprovingReviews:      cr.ApprovingReviews,
+		LastEventAt:           cr.LastEventAt,
+		LastEventSeq:          cr.LastEventSeq,
+	}
+}
+
+func changeRequestFromDocument(doc changeRequestDocument) *projections.ChangeRequest {
+	return &projections.ChangeRequest{
+		ID:                    doc.ID,
+		SourceIntegration:     doc.SourceIntegration,
+		Number:                doc.Number,
+		Title:                 doc.Title,
+		URL:                   doc.URL,
+		Author:                doc.Author,
+		Repository:            doc.Repository,
+		BaseBranch:            doc.BaseBranch,
+		HeadBranch:            doc.HeadBranch,
+		State:                 doc.State,
+		IsDraft:               doc.IsDraft,
+		OpenedAt:              utc(doc.OpenedAt),
+		FirstCommitAt:         utc(doc.FirstCommitAt),
+		FirstReviewAt:         utc(doc.FirstReviewAt),
+		ApprovedAt:            utc(doc.ApprovedAt),
+		MergedAt:              utc(doc.MergedAt),
+		ClosedAt:              utc(doc.ClosedAt),
+		ReopenedAt:            utc(doc.ReopenedAt),
+		Additions:             doc.Additions,
+		Deletions:             doc.Deletions,
+		ChangedFiles:          doc.ChangedFiles,
+		Commits:               doc.Commits,
+		ReviewCount:           doc.ReviewCount,
+		ApprovalCount:         doc.ApprovalCount,
+		ChangesRequestedCount: doc.ChangesRequestedCount,
+		CommentCount:          doc.CommentCount,
+		ApprovingReviews:      doc.ApprovingReviews,
+		LastEventAt:           doc.LastEventAt.UTC(),
+		LastEventSeq:          doc.LastEventSeq,
+	}
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	ts := t.UTC()
+	return &ts
+}
+
+func NewChangeRequestsProjectionRepository(client *Client) *ChangeRequestsProjectionRepository {
+	return &ChangeRequestsProjectionRepository{
+		client: client,
+	}
+}
@@ -0,0 +1,115 @@
+package opensearch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+)
+
+func TestChangeRequestsProjectionRepository(t *testing.T) {
+	f := newFakeOpensearch(t)
+	repo := NewChangeRequestsProjectionRepository(f.client())
+
+	github := projections.ChangeRequestKey{SourceIntegration: "github", ID: "1"}
+	gitlab := projections.ChangeRequestKey{SourceIntegration: "gitlab", ID: "1"}
+	keys := []projections.ChangeRequestKey{github, gitlab}
+
+	found, err := repo.GetMany(keys)
+
+	if err != nil || len(found) != 0 {
+		t.Fatalf("expected nothing before the index exists, got %v, %v", found, err)
+	}
+
+	openedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	crs := []*projections.ChangeRequest{
+		{
+			ID:                "1",
+			SourceIntegration: "github",
+			Title:             "Add the thing",
+			State:             projections.ChangeRequestStateOpen,
+			OpenedAt:          &openedAt,
+			ReopenedAt:        &openedAt,
+			ReviewCount:       2,
+			ApprovalCount:     1,
+			ApprovingReviews:  []string{"3"},
+			LastEventAt:       openedAt,
+			LastEventSeq:      7,
+		},
+		{
+			ID:                "1",
+			SourceIntegration: "gitlab",
+			Title:             "Another thing",
+			State:             projections.ChangeRequestStateMerged,
+			LastEventSeq:      9,
+		},
+	}
+
+	if err := repo.SaveMany(crs); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = repo.GetMany(append(keys, projections.ChangeRequestKey{SourceIntegration: "github", ID: "2"}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Aggregate ids are only unique within an integration.
+	if len(found) != 2 || found[github] == nil || found[gitlab] == nil {
+		t.Fatalf("expected both change requests, got %v", found)
+	}
+
+	got := found[github]
+
+	if got.Title != "Add the thing" || got.ReviewCount != 2 || got.LastEventSeq != 7 || !got.OpenedAt.Equal(openedAt) || got.FirstCommitAt != nil {
+		t.Errorf("unexpected change request %+v", got)
+	}
+
+	if !got.ReopenedAt.Equal(openedAt) || len(got.ApprovingReviews) != 1 || got.ApprovingReviews[0] != "3" {
+		t.Errorf("expected the reopen and approvals to round trip, got %+v", got)
+	}
+
+	if found[gitlab].State != projections.ChangeRequestStateMerged || found[gitlab].LastEventSeq != 9 {
+		t.Errorf("unexpected change request %+v", found[gitlab])
+	}
+
+	if err := repo.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := repo.GetMany(keys); err != nil || len(found) != 0 {
+		t.Errorf("expected nothing once truncated, got %v, %v", found, err)
+	}
+
+	if err := repo.Truncate(); err != nil {
+		t.Errorf("expected truncating a missing index to be fine, got %v", err)
+	}
+
+	if f.templates["panoptes_change_requests"] != 1 {
+		t.Errorf("expected the template to be put once, got %v", f.templates)
+	}
+}
+
+func TestChangeRequestsProjectionRepositoryBulkErrors(t *testing.T) {
+	f := newFakeOpensearch(t)
+	f.bulkError = "github:2"
+	repo := NewChangeRequestsProjectionRepository(f.client())
+
+	err := repo.SaveMany([]*projections.ChangeRequest{
+		{ID: "1", SourceIntegration: "github"},
+		{ID: "2", SourceIntegration: "github"},
+	})
+
+	// The request succeeds as a whole, even though a document failed.
+	if err == nil || !strings.Contains(err.Error(), "github:2") {
+		t.Errorf("expected the failed document to be reported, got %v", err)
+	}
+
+	found, _ := repo.GetMany([]projections.ChangeRequestKey{{SourceIntegration: "github", ID: "1"}})
+
+	if len(found) != 1 {
+		t.Errorf("expected the other document to be saved, got %v", found)
+	}
+}
@@ -0,0 +1,208 @@
+// Package opensearch stores projections in OpenSearch, so they can be explored
+// with OpenSearch Dashboards.
+//
+// It talks to the REST API directly rather than pulling in a client library,
+// we only need a handful of endpoints.
+package opensearch
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config interface {
+	OpensearchAddresses() []string
+	OpensearchUsername() string
+	OpensearchPassword() string
+	OpensearchIndexPrefix() string
+	OpensearchInsecureSkipVerify() bool
+}
+
+var ErrNoAddresses = errors.New("no opensearch addresses configured")
+
+type ErrUnexpectedStatus struct {
+	Method string
+	Path   string
+	Status int
+	Body   string
+}
+
+func (err ErrUnexpectedStatus) Error() string {
+	return fmt.Sprintf("opensearch %s %s: unexpected status %d: %s", err.Method, err.Path, err.Status, err.Body)
+}
+
+// Client is a minimal client for the OpenSearch REST API. Requests are sent to
+// each of the configured addresses in turn until one of them responds.
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu        sync.Mutex
+	templates map[string]bool
+}
+
+// Index returns the full name of an index, including the configured prefix.
+func (c *Client) Index(name string) string {
+	return c.cfg.OpensearchIndexPrefix() + name
+}
+
+func (c *Client) do(method string, path string, contentType string, body []byte) (*http.Response, error) {
+	addresses := c.cfg.OpensearchAddresses()
+
+	if len(addresses) == 0 {
+		return nil, ErrNoAddresses
+	}
+
+	errs := []error{}
+
+	for _, address := range addresses {
+		req, err := http.NewRequest(method, strings.TrimRight(address, "/")+path, bytes.NewReader(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		if c.cfg.OpensearchUsername() != "" {
+			req.SetBasicAuth(c.cfg.OpensearchUsername(), c.cfg.OpensearchPassword())
+		}
+
+		res, err := c.http.Do(req)
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		return res, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// request sends the body as json and decodes the response into dest, unless
+// dest is nil. Any status not in allowed (or 2xx) is returned as an error.
+func (c *Client) request(method string, path string, body any, dest any, allowed ...int) (int, error) {
+	var payload []byte
+
+	if body != nil {
+		encoded, err := json.Marshal(body)
+
+		if err != nil {
+			return 0, err
+		}
+
+		payload = encoded
+	}
+
+	return c.send(method, path, "application/json", payload, dest, allowed...)
+}
+
+func (c *Client) send(method string, path string, contentType string, payload []byte, dest any, allowed ...int) (int, error) {
+	res, err := c.do(method, path, contentType, payload)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return res.StatusCode, err
+	}
+
+	ok := res.StatusCode >= 200 && res.StatusCode < 300
+
+	for _, status := range allowed {
+		if res.StatusCode == status {
+			ok = true
+		}
+	}
+
+	if !ok {
+		return res.StatusCode, ErrUnexpectedStatus{
+			Method: method,
+			Path:   path,
+			Status: res.StatusCode,
+			Body:   string(raw),
+		}
+	}
+
+	if dest != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, dest); err != nil {
+			return res.StatusCode, err
+		}
+	}
+
+	return res.StatusCode, nil
+}
+
+// ensureTemplate puts an index template for the (prefixed) index, so that the
+// index gets the right mappings whenever it is created, including after it is
+// deleted for a rebuild. It's only done once per process, unless it fails.
+func (c *Client) ensureTemplate(index string, mappings map[string]any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := c.Index(index)
+
+	if c.templates[name] {
+		return nil
+	}
+
+	tmpl := map[string]any{
+		"index_patterns": []string{name},
+		"template": map[string]any{
+			"settings": map[string]any{
+				"number_of_shards": 1,
+			},
+			"mappings": mappings,
+		},
+	}
+
+	if _, err := c.request(http.MethodPut, "/_index_template/"+name, tmpl, nil); err != nil {
+		return err
+	}
+
+	c.templates[name] = true
+
+	return nil
+}
+
+// deleteIndex removes the (prefixed) index, it isn't an error if it doesn't
+// exist.
+func (c *Client) deleteIndex(index string) error {
+	_, err := c.request(http.MethodDelete, "/"+c.Index(index), nil, nil, http.StatusNotFound)
+
+	return err
+}
+
+func NewClient(cfg Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.OpensearchInsecureSkipVerify() {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &Client{
+		cfg: cfg,
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		templates: map[string]bool{},
+	}
+}
@@ -0,0 +1,258 @@
+package opensearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type config struct {
+	addresses []string
+	username  string
+	password  string
+}
+
+func (c config) OpensearchAddresses() []string {
+	return c.addresses
+}
+
+func (c config) OpensearchUsername() string {
+	return c.username
+}
+
+func (c config) OpensearchPassword() string {
+	return c.password
+}
+
+func (c config) OpensearchIndexPrefix() string {
+	return "panoptes_"
+}
+
+func (c config) OpensearchInsecureSkipVerify() bool {
+	return false
+}
+
+// fakeOpensearch keeps documents in memory, implementing just enough of the
+// REST API for the repositories.
+type fakeOpensearch struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	indices   map[string]map[string]json.RawMessage
+	templates map[string]int
+
+	// bulkError fails any document with the id in bulk requests.
+	bulkError string
+}
+
+func newFakeOpensearch(t *testing.T) *fakeOpensearch {
+	f := &fakeOpensearch{
+		indices:   map[string]map[string]json.RawMessage{},
+		templates: map[string]int{},
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeOpensearch) client() *Client {
+	return NewClient(config{addresses: []string{f.URL}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeOpensearch) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	index := parts[0]
+	docs, exists := f.indices[index]
+
+	switch {
+	case r.Method == http.MethodPut && index == "_index_template":
+		f.templates[parts[1]]++
+		writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true})
+
+	case r.Method == http.MethodPost && index == "_bulk":
+		f.bulk(w, body)
+
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		if !exists {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "index_not_found_exception"})
+			return
+		}
+
+		delete(f.indices, index)
+		writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true})
+
+	case !exists && r.Method != http.MethodPut:
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "index_not_found_exception"})
+
+	case r.Method == http.MethodPost && parts[1] == "_mget":
+		req := struct {
+			IDs []string `json:"ids"`
+		}{}
+		json.Unmarshal(body, &req)
+
+		res := []map[string]any{}
+
+		for _, id := range req.IDs {
+			doc, found := docs[id]
+			res = append(res, map[string]any{"_id": id, "found": found, "_source": doc})
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"docs": res})
+
+	case r.Method == http.MethodPut && parts[1] == "_doc":
+		f.put(index, parts[2], body)
+		writeJSON(w, http.StatusCreated, map[string]any{"result": "created"})
+
+	case r.Method == http.MethodGet && parts[1] == "_doc":
+		doc, found := docs[parts[2]]
+
+		if !found {
+			writeJSON(w, http.StatusNotFound, map[string]any{"found": false})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"found": true, "_source": doc})
+
+	case r.Method == http.MethodDelete && parts[1] == "_doc":
+		if _, found := docs[parts[2]]; !found {
+			writeJSON(w, http.StatusNotFound, map[string]any{"result": "not_found"})
+			return
+		}
+
+		delete(docs, parts[2])
+		writeJSON(w, http.StatusOK, map[string]any{"result": "deleted"})
+
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "unexpected request " + r.Method + " " + r.URL.Path})
+	}
+}
+
+func (f *fakeOpensearch) put(index string, id string, doc []byte) {
+	if f.indices[index] == nil {
+		f.indices[index] = map[string]json.RawMessage{}
+	}
+
+	f.indices[index][id] = append(json.RawMessage{}, doc...)
+}
+
+func (f *fakeOpensearch) bulk(w http.ResponseWriter, body []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, 1024*1024)
+
+	items := []map[string]any{}
+	failed := false
+
+	for scanner.Scan() {
+		action := struct {
+			Index struct {
+				Index string `json:"_index"`
+				ID    string `json:"_id"`
+			} `json:"index"`
+		}{}
+
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+
+		result := map[string]any{"_id": action.Index.ID, "status": http.StatusCreated}
+
+		if action.Index.ID == f.bulkError {
+			failed = true
+			result["status"] = http.StatusBadRequest
+			result["error"] = map[string]any{"type": "mapper_parsing_exception"}
+		} else {
+			f.put(action.Index.Index, action.Index.ID, scanner.Bytes())
+		}
+
+		items = append(items, map[string]any{"index": result})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"errors": failed, "items": items})
+}
+
+func TestClientRequest(t *testing.T) {
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+
+		if user != "admin" || pass != "secret" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	}))
+	defer live.Close()
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	tests := []struct {
+		name    string
+		cfg     config
+		wantErr func(error) bool
+	}{
+		{
+			name: "single address",
+			cfg:  config{addresses: []string{live.URL}, username: "admin", password: "secret"},
+		},
+		{
+			name: "falls back to the next address",
+			cfg:  config{addresses: []string{dead.URL, live.URL + "/"}, username: "admin", password: "secret"},
+		},
+		{
+			name:    "no addresses",
+			cfg:     config{},
+			wantErr: func(err error) bool { return errors.Is(err, ErrNoAddresses) },
+		},
+		{
+			name:    "every address down",
+			cfg:     config{addresses: []string{dead.URL}},
+			wantErr: func(err error) bool { return err != nil },
+		},
+		{
+			name: "unexpected status",
+			cfg:  config{addresses: []string{live.URL}, username: "admin", password: "wrong"},
+			wantErr: func(err error) bool {
+				var unexpected ErrUnexpectedStatus
+				return errors.As(err, &unexpected) && unexpected.Status == http.StatusUnauthorized
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := map[string]bool{}
+			_, err := NewClient(tt.cfg).request(http.MethodGet, "/", nil, &res)
+
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err != nil || !res["ok"] {
+				t.Errorf("request() = %v, %v", res, err)
+			}
+		})
+	}
+}
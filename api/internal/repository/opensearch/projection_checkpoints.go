@@ -0,0 +1,97 @@
+package opensearch
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+)
+
+const checkpointsIndex = "projection_checkpoints"
+
+var checkpointsMappings = map[string]any{
+	"properties": map[string]any{
+		"projection": map[string]any{"type": "keyword"},
+		"sequence":   map[string]any{"type": "long"},
+		"updated_at": map[string]any{"type": "date"},
+	},
+}
+
+type checkpointDocument struct {
+	Projection string `json:"projection"`
+
+	// Sequence is missing from checkpoints saved before projections were
+	// checkpointed by sequence.
+	Sequence  *int64    `json:"sequence"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type getDocumentResponse[T any] struct {
+	Found  bool `json:"found"`
+	Source T    `json:"_source"`
+}
+
+// ProjectionCheckpointsRepository keeps projection checkpoints in their own
+// index, one document per projection, so they live alongside the projections
+// they describe.
+type ProjectionCheckpointsRepository struct {
+	client *Client
+}
+
+func (r *ProjectionCheckpointsRepository) path(projection string) string {
+	return "/" + r.client.Index(checkpointsIndex) + "/_doc/" + url.PathEscape(projection)
+}
+
+func (r *ProjectionCheckpointsRepository) Get(projection string) (*projections.Checkpoint, error) {
+	res := getDocumentResponse[checkpointDocument]{}
+
+	// A 404 means either the document or the index doesn't exist yet.
+	status, err := r.client.request(http.MethodGet, r.path(projection), nil, &res, http.StatusNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// An old checkpoint can't tell us where to carry on from, so the projection
+	// is rebuilt as though it had none.
+	if status == http.StatusNotFound || !res.Found || res.Source.Sequence == nil {
+		return nil, nil
+	}
+
+	return &projections.Checkpoint{
+		Projection: res.Source.Projection,
+		Sequence:   *res.Source.Sequence,
+		UpdatedAt:  res.Source.UpdatedAt.UTC(),
+	}, nil
+}
+
+func (r *ProjectionCheckpointsRepository) Save(c *projections.Checkpoint) error {
+	if err := r.client.ensureTemplate(checkpointsIndex, checkpointsMappings); err != nil {
+		return err
+	}
+
+	sequence := c.Sequence
+
+	doc := checkpointDocument{
+		Projection: c.Projection,
+		Sequence:   &sequence,
+		UpdatedAt:  c.UpdatedAt,
+	}
+
+	_, err := r.client.request(http.MethodPut, r.path(c.Projection), doc, nil)
+
+	return err
+}
+
+func (r *ProjectionCheckpointsRepository) Delete(projection string) error {
+	_, err := r.client.request(http.MethodDelete, r.path(projection), nil, nil, http.StatusNotFound)
+
+	return err
+}
+
+func NewProjectionCheckpointsRepository(client *Client) *ProjectionCheckpointsRepository {
+	return &ProjectionCheckpointsRepository{
+		client: client,
+	}
+}
@@ -0,0 +1,66 @@
+package opensearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+)
+
+func TestProjectionCheckpointsRepository(t *testing.T) {
+	f := newFakeOpensearch(t)
+	repo := NewProjectionCheckpointsRepository(f.client())
+
+	if c, err := repo.Get("change_requests"); err != nil || c != nil {
+		t.Fatalf("expected no checkpoint before the index exists, got %+v, %v", c, err)
+	}
+
+	saved := &projections.Checkpoint{
+		Projection: "change_requests",
+		Sequence:   42,
+		UpdatedAt:  time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	if err := repo.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := repo.Get("change_requests")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c == nil || *c != *saved {
+		t.Errorf("Get() = %+v, want %+v", c, saved)
+	}
+
+	if f.templates["panoptes_projection_checkpoints"] != 1 {
+		t.Errorf("expected the template to be put once, got %v", f.templates)
+	}
+
+	if err := repo.Delete("change_requests"); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := repo.Get("change_requests"); err != nil || c != nil {
+		t.Errorf("expected no checkpoint once deleted, got %+v, %v", c, err)
+	}
+
+	if err := repo.Delete("change_requests"); err != nil {
+		t.Errorf("expected deleting a missing checkpoint to be fine, got %v", err)
+	}
+}
+
+// Checkpoints saved before they had a sequence can't say where to carry on
+// from, so they count as no checkpoint.
+func TestProjectionCheckpointsRepositoryOldCheckpoint(t *testing.T) {
+	f := newFakeOpensearch(t)
+	f.put("panoptes_projection_checkpoints", "change_requests", []byte(`{"projection": "change_requests", "occurred_at": "2024-01-01T10:00:00Z", "event_id": "abc"}`))
+
+	c, err := NewProjectionCheckpointsRepository(f.client()).Get("change_requests")
+
+	if err != nil || c != nil {
+		t.Errorf("Get() = %+v, %v, want no checkpoint", c, err)
+	}
+}
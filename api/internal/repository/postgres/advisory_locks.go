@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+// AdvisoryLocker takes postgres advisory locks, which are shared by everything
+// connected to the same database, so they work across processes.
+type AdvisoryLocker struct {
+	conn *Connector
+}
+
+// Lock waits for the named lock. Advisory locks belong to the session that
+// took them, so a connection is kept aside until the lock is released.
+func (l *AdvisoryLocker) Lock(name string) (func() error, error) {
+	db, err := l.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	session, err := db.Conn(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := postgres.RawArgs{"#name": name}
+	lock := postgres.RawStatement("SELECT pg_advisory_lock(hashtext(#name))", args)
+
+	if _, err := lock.ExecContext(ctx, session); err != nil {
+		return nil, errors.Join(err, session.Close())
+	}
+
+	return func() error {
+		unlock := postgres.RawStatement("SELECT pg_advisory_unlock(hashtext(#name))", args)
+		_, err := unlock.ExecContext(ctx, session)
+
+		return errors.Join(err, session.Close())
+	}, nil
+}
+
+func NewAdvisoryLocker(conn *Connector) *AdvisoryLocker {
+	return &AdvisoryLocker{
+		conn: conn,
+	}
+}
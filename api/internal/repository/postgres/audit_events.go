@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"encoding/json"
+
+	"github.com/adamkirk/panoptes/internal/domain/audit"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+type AuditEventsRepository struct {
+	conn *Connector
+}
+
+// Create appends the event, the seq is assigned by the database.
+func (r *AuditEventsRepository) Create(e *audit.Event) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	details, err := json.Marshal(e.Details)
+
+	if err != nil {
+		return err
+	}
+
+	row := model.AuditEvents{
+		ID: e.ID,
+		OccurredAt: e.OccurredAt,
+		Action: e.Action,
+		Outcome: string(e.Outcome),
+		ActorType: e.Actor.Type,
+		ActorID: nullString(e.Actor.ID),
+		ActorTokenID: nullString(e.Actor.TokenID),
+		TargetType: nullString(e.TargetType),
+		TargetID: nullString(e.TargetID),
+		RequestID: nullString(e.Request.ID),
+		SourceIP: nullString(e.Request.SourceIP),
+		Details: string(details),
+	}
+
+	stmt := table.AuditEvents.INSERT(table.AuditEvents.AllColumns.Except(table.AuditEvents.Seq)).
+		MODEL(row).
+		RETURNING(table.AuditEvents.Seq)
+
+	dest := model.AuditEvents{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return err
+	}
+
+	e.Seq = dest.Seq
+
+	return nil
+}
+
+func (r *AuditEventsRepository) List(f audit.Filter) ([]*audit.Event, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []postgres.BoolExpression{}
+
+	equals := []struct {
+		col postgres.ColumnString
+		val string
+	}{
+		{table.AuditEvents.Action, f.Action},
+		{table.AuditEvents.Outcome, f.Outcome},
+		{table.AuditEvents.ActorType, f.ActorType},
+		{table.AuditEvents.ActorID, f.ActorID},
+		{table.AuditEvents.TargetType, f.TargetType},
+		{table.AuditEvents.TargetID, f.TargetID},
+	}
+
+	for _, eq := range equals {
+		if eq.val != "" {
+			conditions = append(conditions, eq.col.EQ(postgres.String(eq.val)))
+		}
+	}
+
+	if f.Since != nil {
+		conditions = append(conditions, table.AuditEvents.OccurredAt.GT_EQ(postgres.TimestampzT(*f.Since)))
+	}
+
+	if f.Until != nil {
+		conditions = append(conditions, table.AuditEvents.OccurredAt.LT(postgres.TimestampzT(*f.Until)))
+	}
+
+	order := table.AuditEvents.Seq.DESC()
+
+	if f.AfterSeq != nil {
+		conditions = append(conditions, table.AuditEvents.Seq.GT(postgres.Int64(*f.AfterSeq)))
+		order = table.AuditEvents.Seq.ASC()
+	}
+
+	stmt := table.AuditEvents.SELECT(table.AuditEvents.AllColumns).
+		FROM(table.AuditEvents)
+
+	if len(conditions) > 0 {
+		stmt = stmt.WHERE(postgres.AND(conditions...))
+	}
+
+	stmt = stmt.ORDER_BY(order).
+		LIMIT(int64(f.Limit))
+
+	dest := []model.AuditEvents{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	events := make([]*audit.Event, len(dest))
+
+	for i, row := range dest {
+		e, err := auditEventFromModel(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events[i] = e
+	}
+
+	return events, nil
+}
+
+func auditEventFromModel(row model.AuditEvents) (*audit.Event, error) {
+	e := &audit.Event{
+		ID: row.ID,
+		Seq: row.Seq,
+		OccurredAt: row.OccurredAt.UTC(),
+		Action: row.Action,
+		Outcome: audit.Outcome(row.Outcome),
+		Actor: &audit.Actor{
+			Type: row.ActorType,
+			ID: stringValue(row.ActorID),
+			TokenID: stringValue(row.ActorTokenID),
+		},
+		TargetType: stringValue(row.TargetType),
+		TargetID: stringValue(row.TargetID),
+		Request: audit.Request{
+			ID: stringValue(row.RequestID),
+			SourceIP: stringValue(row.SourceIP),
+		},
+	}
+
+	if err := json.Unmarshal([]byte(row.Details), &e.Details); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func NewAuditEventsRepository(conn *Connector) *AuditEventsRepository {
+	return &AuditEventsRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const migrationsDir = "../../../../db/migrations"
+
+// TestAuditEventsAppendOnlyMigration checks the trigger stopping the audit log
+// being changed is created, and that no later migration drops it.
+func TestAuditEventsAppendOnlyMigration(t *testing.T) {
+	created := regexp.MustCompile(`(?s)CREATE TRIGGER "audit_events_append_only"\s+BEFORE UPDATE OR DELETE OR TRUNCATE ON "audit_events"`)
+	dropped := regexp.MustCompile(`(?i)DROP (TRIGGER|FUNCTION)[^;]*"audit_events_append_only"|DROP TABLE[^;]*"audit_events"`)
+
+	ups, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
+
+	if err != nil || len(ups) == 0 {
+		t.Fatalf("failed to find the migrations: %v", err)
+	}
+
+	found := false
+
+	for _, path := range ups {
+		raw, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := filepath.Base(path)
+
+		if strings.HasPrefix(name, "22_") {
+			found = created.Match(raw)
+
+			continue
+		}
+
+		if dropped.Match(raw) {
+			t.Errorf("expected the audit log to stay append-only, %s drops it", name)
+		}
+	}
+
+	if !found {
+		t.Error("expected migration 22 to create the append-only trigger for updates, deletes and truncates")
+	}
+}
+
+// TestAuditEventsAppendOnly needs a migrated database, set
+// PANOPTES_TEST_POSTGRES_DSN to run it. Nothing is left behind.
+func TestAuditEventsAppendOnly(t *testing.T) {
+	dsn := os.Getenv("PANOPTES_TEST_POSTGRES_DSN")
+
+	if dsn == "" {
+		t.Skip("PANOPTES_TEST_POSTGRES_DSN isn't set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	tests := []struct {
+		name string
+		stmt string
+	}{
+		{name: "update", stmt: `UPDATE "audit_events" SET "outcome" = 'success' WHERE "id" = $1`},
+		{name: "delete", stmt: `DELETE FROM "audit_events" WHERE "id" = $1`},
+		{name: "truncate", stmt: `TRUNCATE "audit_events"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := db.Begin()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer tx.Rollback()
+
+			id := uuid.New()
+
+			_, err = tx.Exec(
+				`INSERT INTO "audit_events" ("id", "occurred_at", "action", "outcome", "actor_type") VALUES ($1, now(), 'test', 'failure', 'anonymous')`,
+				id,
+			)
+
+			if err != nil {
+				t.Fatalf("failed to record an event: %s", err)
+			}
+
+			args := []any{}
+
+			if strings.Contains(tt.stmt, "$1") {
+				args = append(args, id)
+			}
+
+			if _, err := tx.Exec(tt.stmt, args...); err == nil || !strings.Contains(err.Error(), "append-only") {
+				t.Errorf("expected the %s to be rejected, got %v", tt.name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"github.com/adamkirk/panoptes/internal/domain/backfill"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+type BackfillCheckpointsRepository struct {
+	conn *Connector
+}
+
+func (r *BackfillCheckpointsRepository) Get(name string) (*backfill.Checkpoint, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.BackfillCheckpoints.SELECT(table.BackfillCheckpoints.AllColumns).
+		FROM(table.BackfillCheckpoints).
+		WHERE(table.BackfillCheckpoints.Name.EQ(postgres.String(name))).
+		LIMIT(1)
+
+	dest := []model.BackfillCheckpoints{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	c := dest[0]
+
+	checkpoint := &backfill.Checkpoint{
+		Name: c.Name,
+		Cursor: stringValue(c.Cursor),
+		UpdatedAt: c.UpdatedAt.UTC(),
+	}
+
+	if c.CompletedAt != nil {
+		completedAt := c.CompletedAt.UTC()
+		checkpoint.CompletedAt = &completedAt
+	}
+
+	return checkpoint, nil
+}
+
+func (r *BackfillCheckpointsRepository) Save(c *backfill.Checkpoint) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.BackfillCheckpoints.INSERT(table.BackfillCheckpoints.AllColumns).
+		MODEL(model.BackfillCheckpoints{
+			Name: c.Name,
+			Cursor: nullString(c.Cursor),
+			CompletedAt: c.CompletedAt,
+			UpdatedAt: c.UpdatedAt,
+		}).
+		ON_CONFLICT(table.BackfillCheckpoints.Name).
+		DO_UPDATE(postgres.SET(
+			table.BackfillCheckpoints.Cursor.SET(table.BackfillCheckpoints.EXCLUDED.Cursor),
+			table.BackfillCheckpoints.CompletedAt.SET(table.BackfillCheckpoints.EXCLUDED.CompletedAt),
+			table.BackfillCheckpoints.UpdatedAt.SET(table.BackfillCheckpoints.EXCLUDED.UpdatedAt),
+		))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func NewBackfillCheckpointsRepository(conn *Connector) *BackfillCheckpointsRepository {
+	return &BackfillCheckpointsRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"encoding/json"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+)
+
+type BitbucketWebhooksRepository struct {
+	conn *Connector
+}
+
+func (r *BitbucketWebhooksRepository) Create(w *ingestion.BitbucketWebhook, events []changerequests.Event) (bool, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return false, err
+	}
+
+	var payloadJSON []byte
+	if payloadJSON, err = json.Marshal(w.Payload); err != nil {
+		return false, err
+	}
+
+	var deliveryID *string
+
+	// Store null rather than an empty string so that webhooks without a
+	// delivery id don't clash on the unique index.
+	if w.DeliveryID != "" {
+		deliveryID = &w.DeliveryID
+	}
+
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return false, err
+	}
+
+	stmt := table.BitbucketWebhooks.INSERT(table.BitbucketWebhooks.AllColumns).
+		MODEL(model.BitbucketWebhooks{
+			ID: w.ID,
+			DeliveryID: deliveryID,
+			Event: &w.Event,
+			OccurredAt: &w.OccurredAt,
+			Payload: string(payloadJSON),
+		}).
+		ON_CONFLICT(table.BitbucketWebhooks.DeliveryID).DO_NOTHING()
+
+	res, err := stmt.Exec(tx)
+
+	if err != nil {
+		return false, rollback(tx, err)
+	}
+
+	affected, err := res.RowsAffected()
+
+	if err != nil {
+		return false, rollback(tx, err)
+	}
+
+	if affected == 0 {
+		return false, tx.Rollback()
+	}
+
+	if err := appendChangeRequestEvents(tx, events); err != nil {
+		return false, rollback(tx, err)
+	}
+
+	return true, tx.Commit()
+}
+
+func NewBitbucketWebhooksRepository(conn *Connector) *BitbucketWebhooksRepository {
+	return &BitbucketWebhooksRepository{
+		conn: conn,
+	}
+}
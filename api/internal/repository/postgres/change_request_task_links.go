@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"github.com/adamkirk/panoptes/internal/domain/correlation"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/adamkirk/panoptes/internal/util"
+)
+
+type ChangeRequestTaskLinksRepository struct {
+	conn *Connector
+}
+
+func (r *ChangeRequestTaskLinksRepository) Create(links []*correlation.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	rows := util.Map[*correlation.Link, model.ChangeRequestTaskLinks](func(l *correlation.Link) model.ChangeRequestTaskLinks {
+		return model.ChangeRequestTaskLinks{
+			ID: l.ID,
+			ChangeRequestID: l.ChangeRequestID,
+			SourceIntegration: l.SourceIntegration,
+			TaskKey: l.TaskKey,
+			FoundIn: l.FoundIn,
+			CreatedAt: l.CreatedAt,
+		}
+	}, links)
+
+	// We'll see the same keys on every event for a change request, the
+	// first place we found it is the one we keep.
+	stmt := table.ChangeRequestTaskLinks.INSERT(table.ChangeRequestTaskLinks.AllColumns).
+		MODELS(rows).
+		ON_CONFLICT(
+			table.ChangeRequestTaskLinks.ChangeRequestID,
+			table.ChangeRequestTaskLinks.SourceIntegration,
+			table.ChangeRequestTaskLinks.TaskKey,
+		).DO_NOTHING()
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func NewChangeRequestTaskLinksRepository(conn *Connector) *ChangeRequestTaskLinksRepository {
+	return &ChangeRequestTaskLinksRepository{
+		conn: conn,
+	}
+}
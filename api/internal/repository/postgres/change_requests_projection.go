@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"encoding/json"
+
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+// ChangeRequestsProjectionRepository stores the change requests read model in
+// the change_requests table of the projection database.
+type ChangeRequestsProjectionRepository struct {
+	conn *Connector
+}
+
+func (r *ChangeRequestsProjectionRepository) GetMany(keys []projections.ChangeRequestKey) (map[projections.ChangeRequestKey]*projections.ChangeRequest, error) {
+	found := map[projections.ChangeRequestKey]*projections.ChangeRequest{}
+
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := util.Map[projections.ChangeRequestKey, postgres.BoolExpression](func(k projections.ChangeRequestKey) postgres.BoolExpression {
+		return table.ChangeRequests.SourceIntegration.EQ(postgres.String(k.SourceIntegration)).
+			AND(table.ChangeRequests.ID.EQ(postgres.String(k.ID)))
+	}, keys)
+
+	stmt := table.ChangeRequests.SELECT(table.ChangeRequests.AllColumns).
+		FROM(table.ChangeRequests).
+		WHERE(postgres.OR(conditions...))
+
+	dest := []model.ChangeRequests{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	for _, row := range dest {
+		cr, err := changeRequestFromModel(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		found[cr.Key()] = cr
+	}
+
+	return found, nil
+}
+
+func (r *ChangeRequestsProjectionRepository) SaveMany(crs []*projections.ChangeRequest) error {
+	if len(crs) == 0 {
+		return nil
+	}
+
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	rows := make([]model.ChangeRequests, 0, len(crs))
+
+	for _, cr := range crs {
+		row, err := changeRequestToModel(cr)
+
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, row)
+	}
+
+	excluded := util.Map[postgres.Column, postgres.Expression](func(c postgres.Column) postgres.Expression {
+		return c
+	}, table.ChangeRequests.EXCLUDED.MutableColumns)
+
+	stmt := table.ChangeRequests.INSERT(table.ChangeRequests.AllColumns).
+		MODELS(rows).
+		ON_CONFLICT(table.ChangeRequests.SourceIntegration, table.ChangeRequests.ID).
+		DO_UPDATE(postgres.SET(
+			table.ChangeRequests.MutableColumns.SET(postgres.ROW(excluded...)),
+		))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func (r *ChangeRequestsProjectionRepository) Truncate() error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec("TRUNCATE TABLE change_requests")
+
+	return err
+}
+
+func changeRequestFromModel(row model.ChangeRequests) (*projections.ChangeRequest, error) {
+	cr := &projections.ChangeRequest{
+		ID: row.ID,
+		SourceIntegration: row.SourceIntegration,
+		Number: int(row.Number),
+		Title: row.Title,
+		URL: row.URL,
+		Author: row.Author,
+		Repository: row.Repository,
+		BaseBranch: row.BaseBranch,
+		HeadBranch: row.HeadBranch,
+		State: row.State,
+		IsDraft: row.IsDraft,
+		OpenedAt: row.OpenedAt,
+		FirstCommitAt: row.FirstCommitAt,
+		FirstReviewAt: row.FirstReviewAt,
+		ApprovedAt: row.ApprovedAt,
+		MergedAt: row.MergedAt,
+		ClosedAt: row.ClosedAt,
+		ReopenedAt: row.ReopenedAt,
+		Additions: int(row.Additions),
+		Deletions: int(row.Deletions),
+		ChangedFiles: int(row.ChangedFiles),
+		Commits: int(row.Commits),
+		ReviewCount: int(row.ReviewCount),
+		ApprovalCount: int(row.ApprovalCount),
+		ChangesRequestedCount: int(row.ChangesRequestedCount),
+		CommentCount: int(row.CommentCount),
+		LastEventAt: row.LastEventAt.UTC(),
+		LastEventSeq: row.LastEventSeq,
+	}
+
+	if err := json.Unmarshal([]byte(row.ApprovingReviews), &cr.ApprovingReviews); err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+}
+
+func changeRequestToModel(cr *projections.ChangeRequest) (model.ChangeRequests, error) {
+	approvingReviews := cr.ApprovingReviews
+
+	if approvingReviews == nil {
+		approvingReviews = []string{}
+	}
+
+	reviews, err := json.Marshal(approvingReviews)
+
+	if err != nil {
+		return model.ChangeRequests{}, err
+	}
+
+	return model.ChangeRequests{
+		ID: cr.ID,
+		SourceIntegration: cr.SourceIntegration,
+		Number: int32(cr.Number),
+		Title: cr.Title,
+		URL: cr.URL,
+		Author: cr.Author,
+		Repository: cr.Repository,
+		BaseBranch: cr.BaseBranch,
+		HeadBranch: cr.HeadBranch,
+		State: cr.State,
+		IsDraft: cr.IsDraft,
+		OpenedAt: cr.OpenedAt,
+		FirstCommitAt: cr.FirstCommitAt,
+		FirstReviewAt: cr.FirstReviewAt,
+		ApprovedAt: cr.ApprovedAt,
+		MergedAt: cr.MergedAt,
+		ClosedAt: cr.ClosedAt,
+		ReopenedAt: cr.ReopenedAt,
+		Additions: int32(cr.Additions),
+		Deletions: int32(cr.Deletions),
+		ChangedFiles: int32(cr.ChangedFiles),
+		Commits: int32(cr.Commits),
+		ReviewCount: int32(cr.ReviewCount),
+		ApprovalCount: int32(cr.ApprovalCount),
+		ChangesRequestedCount: int32(cr.ChangesRequestedCount),
+		CommentCount: int32(cr.CommentCount),
+		LastEventAt: cr.LastEventAt,
+		LastEventSeq: cr.LastEventSeq,
+		ApprovingReviews: string(reviews),
+	}, nil
+}
+
+func NewChangeRequestsProjectionRepository(conn *Connector) *ChangeRequestsProjectionRepository {
+	return &ChangeRequestsProjectionRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+// streamAppendLock is the advisory lock taken while appending to the stream.
+const streamAppendLock = "change_requests_stream:append"
+
+// appendChangeRequestEvents writes the events to the stream as part of the
+// transaction. Event ids are derived from the event itself, so an event we've
+// already got is skipped.
+//
+// Appends are serialised by a lock that's held until the transaction ends, so
+// events are committed in sequence order. Otherwise a projection could read
+// past an event whose transaction hadn't committed yet, and never see it.
+func appendChangeRequestEvents(tx *sql.Tx, events []changerequests.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	lock := postgres.RawStatement(
+		"SELECT pg_advisory_xact_lock(hashtext(#name))",
+		postgres.RawArgs{"#name": streamAppendLock},
+	)
+
+	if _, err := lock.Exec(tx); err != nil {
+		return err
+	}
+
+	rows := make([]model.ChangeRequestsStream, len(events))
+
+	for i, e := range events {
+		payloadJSON, err := json.Marshal(e.Payload)
+
+		if err != nil {
+			return err
+		}
+
+		t := string(e.Type)
+		occurredAt := e.OccurredAt
+
+		rows[i] = model.ChangeRequestsStream{
+			ID: e.ID,
+			AggregateID: e.AggregateID,
+			OccurredAt: &occurredAt,
+			Payload: string(payloadJSON),
+			Type: &t,
+			SourceID: e.SourceID,
+			SourceIntegration: e.SourceIntegration,
+		}
+	}
+
+	// The sequence is assigned by the database.
+	stmt := table.ChangeRequestsStream.INSERT(table.ChangeRequestsStream.AllColumns.Except(table.ChangeRequestsStream.Seq)).
+		MODELS(rows).
+		ON_CONFLICT(table.ChangeRequestsStream.ID).DO_NOTHING()
+
+	_, err := stmt.Exec(tx)
+
+	return err
+}
+
+type ChangeRequestsStreamRepository struct {
+	conn *Connector
+}
+
+func (r *ChangeRequestsStreamRepository) After(c *projections.Checkpoint, limit int) ([]changerequests.Event, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.ChangeRequestsStream.SELECT(table.ChangeRequestsStream.AllColumns).
+		FROM(table.ChangeRequestsStream)
+
+	if c != nil {
+		stmt = stmt.WHERE(table.ChangeRequestsStream.Seq.GT(postgres.Int64(c.Sequence)))
+	}
+
+	stmt = stmt.ORDER_BY(table.ChangeRequestsStream.Seq.ASC()).
+		LIMIT(int64(limit))
+
+	dest := []model.ChangeRequestsStream{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	events := make([]changerequests.Event, len(dest))
+
+	for i, row := range dest {
+		e, err := changeRequestEventFromModel(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events[i] = e
+	}
+
+	return events, nil
+}
+
+func changeRequestEventFromModel(row model.ChangeRequestsStream) (changerequests.Event, error) {
+	e := changerequests.Event{
+		ID: row.ID,
+		AggregateID: row.AggregateID,
+		SourceID: row.SourceID,
+		SourceIntegration: row.SourceIntegration,
+		Sequence: row.Seq,
+	}
+
+	if row.Type != nil {
+		e.Type = changerequests.EventType(*row.Type)
+	}
+
+	if row.OccurredAt != nil {
+		e.OccurredAt = row.OccurredAt.UTC()
+	}
+
+	if err := json.Unmarshal([]byte(row.Payload), &e.Payload); err != nil {
+		return e, err
+	}
+
+	return e, nil
+}
+
+func NewChangeRequestsStreamRepository(conn *Connector) *ChangeRequestsStreamRepository {
+	return &ChangeRequestsStreamRepository{
+		conn: conn,
+	}
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	_ "github.com/lib/pq"
@@ -57,6 +58,16 @@ func (c *Connector) Connection() (*sql.DB, error) {
 	return db, nil
 }
 
+// rollback aborts the transaction, returning the error that caused it along
+// with any error from the rollback itself.
+func rollback(tx *sql.Tx, err error) error {
+	if txErr := tx.Rollback(); txErr != nil {
+		return errors.New(fmt.Sprintf("%s: %s", txErr.Error(), err.Error()))
+	}
+
+	return err
+}
+
 func NewConnector(cfg Config) *Connector {
 	return &Connector{
 		cfg: cfg,
@@ -2,40 +2,217 @@ package postgres
 
 import (
 	"encoding/json"
-	"time"
 
+	"github.com/adamkirk/panoptes/internal/domain/changerequests"
+	"github.com/adamkirk/panoptes/internal/domain/dora"
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
 	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
-	"github.com/google/uuid"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/go-jet/jet/v2/postgres"
 )
 
 type GithubWebhooksRepository struct {
 	conn *Connector
 }
 
-func (r *GithubWebhooksRepository) Create(id uuid.UUID, ts time.Time, payload map[string]any) error {
+func (r *GithubWebhooksRepository) Create(w *ingestion.GithubWebhook, events []changerequests.Event) (bool, error) {
 	conn, err := r.conn.Connection()
 
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	var payloadJSON []byte
-	if payloadJSON, err = json.Marshal(payload); err != nil {
-		return err
+	if payloadJSON, err = json.Marshal(w.Payload); err != nil {
+		return false, err
 	}
 
-	stmt := table.GithubWebhooks.INSERT(table.GithubWebhooks.ID, table.GithubWebhooks.OccurredAt, table.GithubWebhooks.Payload).
-		VALUES(uuid.New(), ts, string(payloadJSON))
+	tx, err := conn.Begin()
 
-	if _, err := stmt.Exec(conn); err != nil {
-		return err
+	if err != nil {
+		return false, err
+	}
+
+	stmt := table.GithubWebhooks.INSERT(table.GithubWebhooks.ID, table.GithubWebhooks.DeliveryID, table.GithubWebhooks.Event, table.GithubWebhooks.OccurredAt, table.GithubWebhooks.Payload).
+		MODEL(model.GithubWebhooks{
+			ID: w.ID,
+			DeliveryID: &w.DeliveryID,
+			Event: &w.Event,
+			OccurredAt: &w.OccurredAt,
+			Payload: string(payloadJSON),
+		}).
+		// The unique index on delivery_id is what guarantees we only store each
+		// delivery once, checking first would be racy.
+		ON_CONFLICT(table.GithubWebhooks.DeliveryID).DO_NOTHING()
+
+	res, err := stmt.Exec(tx)
+
+	if err != nil {
+		return false, rollback(tx, err)
 	}
 
-	return nil
+	affected, err := res.RowsAffected()
+
+	if err != nil {
+		return false, rollback(tx, err)
+	}
+
+	if affected == 0 {
+		return false, tx.Rollback()
+	}
+
+	if err := appendChangeRequestEvents(tx, events); err != nil {
+		return false, rollback(tx, err)
+	}
+
+	return true, tx.Commit()
+}
+
+// doraPayload cuts the payload down to the fields dora reads, whole payloads
+// are large (e.g. pushes list every file changed) and there can be a lot of
+// them in a report's window.
+const doraPayload = `json_build_object(
+	'action', payload->'action',
+	'ref', payload->'ref',
+	'deleted', payload->'deleted',
+	'repository', json_build_object(
+		'full_name', payload->'repository'->'full_name',
+		'default_branch', payload->'repository'->'default_branch'
+	),
+	'head_commit', CASE WHEN json_typeof(payload->'head_commit') = 'object' THEN json_build_object(
+		'id', payload->'head_commit'->'id',
+		'timestamp', payload->'head_commit'->'timestamp'
+	) END,
+	'commits', (
+		SELECT json_agg(json_build_object('id', c->'id', 'timestamp', c->'timestamp'))
+		FROM json_array_elements(CASE WHEN json_typeof(payload->'commits') = 'array' THEN payload->'commits' ELSE '[]'::json END) AS c
+	),
+	'deployment', json_build_object(
+		'id', payload->'deployment'->'id',
+		'sha', payload->'deployment'->'sha',
+		'environment', payload->'deployment'->'environment'
+	),
+	'deployment_status', json_build_object(
+		'id', payload->'deployment_status'->'id',
+		'state', payload->'deployment_status'->'state',
+		'environment', payload->'deployment_status'->'environment',
+		'created_at', payload->'deployment_status'->'created_at'
+	),
+	'release', json_build_object(
+		'id', payload->'release'->'id',
+		'target_commitish', payload->'release'->'target_commitish',
+		'draft', payload->'release'->'draft',
+		'prerelease', payload->'release'->'prerelease',
+		'published_at', payload->'release'->'published_at'
+	),
+	'pull_request', json_build_object(
+		'merged', payload->'pull_request'->'merged',
+		'merge_commit_sha', payload->'pull_request'->'merge_commit_sha',
+		'created_at', payload->'pull_request'->'created_at'
+	)
+)`
+
+// List returns the webhooks matching the filter, oldest first, with only the
+// parts of the payloads that dora reads.
+func (r *GithubWebhooksRepository) List(f dora.GithubWebhooksFilter) ([]*ingestion.GithubWebhook, error) {
+	if len(f.Events) == 0 {
+		return []*ingestion.GithubWebhook{}, nil
+	}
+
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	action := postgres.StringExp(postgres.Raw("payload->>'action'"))
+	repository := postgres.StringExp(postgres.Raw("payload->'repository'->>'full_name'"))
+
+	events := []postgres.BoolExpression{}
+
+	for event, actions := range f.Events {
+		condition := table.GithubWebhooks.Event.EQ(postgres.String(event))
+
+		if len(actions) > 0 {
+			condition = condition.AND(action.IN(util.Map(stringExpression, actions)...))
+		}
+
+		events = append(events, condition)
+	}
+
+	condition := postgres.OR(events...)
+
+	if len(f.Repositories) > 0 {
+		condition = condition.AND(repository.IN(util.Map(stringExpression, f.Repositories)...))
+	}
+
+	if !f.Since.IsZero() {
+		condition = condition.AND(table.GithubWebhooks.OccurredAt.GT_EQ(postgres.TimestampzT(f.Since)))
+	}
+
+	stmt := table.GithubWebhooks.SELECT(
+		table.GithubWebhooks.ID,
+		table.GithubWebhooks.OccurredAt,
+		table.GithubWebhooks.DeliveryID,
+		table.GithubWebhooks.Event,
+		postgres.Raw(doraPayload).AS("github_webhooks.payload"),
+	).
+		FROM(table.GithubWebhooks).
+		WHERE(condition).
+		ORDER_BY(table.GithubWebhooks.OccurredAt.ASC(), table.GithubWebhooks.ID.ASC())
+
+	dest := []model.GithubWebhooks{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]*ingestion.GithubWebhook, len(dest))
+
+	for i, row := range dest {
+		w, err := githubWebhookFromModel(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks[i] = w
+	}
+
+	return webhooks, nil
+}
+
+func githubWebhookFromModel(m model.GithubWebhooks) (*ingestion.GithubWebhook, error) {
+	w := &ingestion.GithubWebhook{
+		ID: m.ID,
+	}
+
+	if err := json.Unmarshal([]byte(m.Payload), &w.Payload); err != nil {
+		return nil, err
+	}
+
+	if m.DeliveryID != nil {
+		w.DeliveryID = *m.DeliveryID
+	}
+
+	if m.Event != nil {
+		w.Event = *m.Event
+	}
+
+	if m.OccurredAt != nil {
+		w.OccurredAt = m.OccurredAt.UTC()
+	}
+
+	return w, nil
+}
+
+func stringExpression(s string) postgres.Expression {
+	return postgres.String(s)
 }
 
 func NewGithubWebhooksRepository(conn *Connector) *GithubWebhooksRepository {
 	return &GithubWebhooksRepository{
 		conn: conn,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,354 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/ingestion"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/google/uuid"
+)
+
+type IngestionQueueRepository struct {
+	conn *Connector
+}
+
+// queuedDeliveryPredicate matches the ingestion_queue_delivery_id_unique_idx
+// partial index, it's raw as postgres can't match the index to a bound
+// parameter. Deliveries without an id can't be told apart, so are all queued.
+var queuedDeliveryPredicate = postgres.RawBool(`"delivery_id" <> ''`)
+
+func (r *IngestionQueueRepository) Enqueue(item *ingestion.QueueItem) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	row, err := queueItemToModel(item)
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.IngestionQueue.INSERT(table.IngestionQueue.AllColumns).
+		MODEL(row).
+		ON_CONFLICT(table.IngestionQueue.Integration, table.IngestionQueue.DeliveryID).
+		WHERE(queuedDeliveryPredicate).
+		DO_NOTHING()
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+// Claim locks the next available item in a single statement, skipping any
+// that other workers are in the middle of claiming.
+func (r *IngestionQueueRepository) Claim(now time.Time, lockedUntil time.Time) (*ingestion.QueueItem, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	next := table.IngestionQueue.SELECT(table.IngestionQueue.ID).
+		FROM(table.IngestionQueue).
+		WHERE(
+			table.IngestionQueue.AvailableAt.LT_EQ(postgres.TimestampzT(now)).
+				AND(
+					table.IngestionQueue.LockedUntil.IS_NULL().
+						OR(table.IngestionQueue.LockedUntil.LT(postgres.TimestampzT(now))),
+				),
+		).
+		ORDER_BY(table.IngestionQueue.AvailableAt.ASC()).
+		LIMIT(1).
+		FOR(postgres.UPDATE().SKIP_LOCKED())
+
+	stmt := table.IngestionQueue.UPDATE(table.IngestionQueue.Attempts, table.IngestionQueue.LockedUntil).
+		SET(table.IngestionQueue.Attempts.ADD(postgres.Int(1)), postgres.TimestampzT(lockedUntil)).
+		WHERE(table.IngestionQueue.ID.IN(next)).
+		RETURNING(table.IngestionQueue.AllColumns)
+
+	dest := model.IngestionQueue{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return queueItemFromModel(dest)
+}
+
+func (r *IngestionQueueRepository) Complete(id uuid.UUID) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.IngestionQueue.DELETE().
+		WHERE(table.IngestionQueue.ID.EQ(postgres.UUID(id)))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func (r *IngestionQueueRepository) Retry(item *ingestion.QueueItem) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.IngestionQueue.UPDATE(table.IngestionQueue.LastError, table.IngestionQueue.AvailableAt, table.IngestionQueue.LockedUntil).
+		SET(postgres.String(item.LastError), postgres.TimestampzT(item.AvailableAt), postgres.NULL).
+		WHERE(table.IngestionQueue.ID.EQ(postgres.UUID(item.ID)))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func (r *IngestionQueueRepository) Bury(item *ingestion.QueueItem, failedAt time.Time) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(item.Payload)
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	insert := table.IngestionDeadLetters.INSERT(table.IngestionDeadLetters.AllColumns).
+		MODEL(model.IngestionDeadLetters{
+			ID: item.ID,
+			Integration: item.Integration,
+			DeliveryID: item.DeliveryID,
+			Event: item.Event,
+			Payload: string(payload),
+			Attempts: int32(item.Attempts),
+			LastError: item.LastError,
+			ReceivedAt: item.ReceivedAt,
+			FailedAt: failedAt,
+		})
+
+	if _, err := insert.Exec(tx); err != nil {
+		return rollback(tx, err)
+	}
+
+	remove := table.IngestionQueue.DELETE().
+		WHERE(table.IngestionQueue.ID.EQ(postgres.UUID(item.ID)))
+
+	if _, err := remove.Exec(tx); err != nil {
+		return rollback(tx, err)
+	}
+
+	return tx.Commit()
+}
+
+// DeadLetters returns the most recently failed first.
+func (r *IngestionQueueRepository) DeadLetters(limit int) ([]*ingestion.DeadLetter, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.IngestionDeadLetters.SELECT(table.IngestionDeadLetters.AllColumns).
+		FROM(table.IngestionDeadLetters).
+		ORDER_BY(table.IngestionDeadLetters.FailedAt.DESC()).
+		LIMIT(int64(limit))
+
+	dest := []model.IngestionDeadLetters{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	letters := make([]*ingestion.DeadLetter, len(dest))
+
+	for i, row := range dest {
+		dl, err := deadLetterFromModel(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		letters[i] = dl
+	}
+
+	return letters, nil
+}
+
+func (r *IngestionQueueRepository) DeadLetter(id uuid.UUID) (*ingestion.DeadLetter, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.IngestionDeadLetters.SELECT(table.IngestionDeadLetters.AllColumns).
+		FROM(table.IngestionDeadLetters).
+		WHERE(table.IngestionDeadLetters.ID.EQ(postgres.UUID(id)))
+
+	dest := model.IngestionDeadLetters{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return deadLetterFromModel(dest)
+}
+
+// Requeue removes the dead letters and adds them back to the queue, in one
+// transaction so that nothing is lost or duplicated. Only the removed rows are
+// queued, so dead letters added in the meantime are left alone.
+func (r *IngestionQueueRepository) Requeue(ids []uuid.UUID, now time.Time) (int, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return 0, err
+	}
+
+	var condition postgres.BoolExpression = postgres.Bool(true)
+
+	if len(ids) > 0 {
+		condition = table.IngestionDeadLetters.ID.IN(util.Map(func(id uuid.UUID) postgres.Expression {
+			return postgres.UUID(id)
+		}, ids)...)
+	}
+
+	remove := table.IngestionDeadLetters.DELETE().
+		WHERE(condition).
+		RETURNING(table.IngestionDeadLetters.AllColumns)
+
+	dest := []model.IngestionDeadLetters{}
+
+	if err := remove.Query(tx, &dest); err != nil {
+		return 0, rollback(tx, err)
+	}
+
+	if len(dest) == 0 {
+		return 0, tx.Rollback()
+	}
+
+	rows := util.Map(func(dl model.IngestionDeadLetters) model.IngestionQueue {
+		return model.IngestionQueue{
+			ID: dl.ID,
+			Integration: dl.Integration,
+			DeliveryID: dl.DeliveryID,
+			Event: dl.Event,
+			Payload: dl.Payload,
+			Attempts: 0,
+			LastError: &dl.LastError,
+			AvailableAt: now,
+			ReceivedAt: dl.ReceivedAt,
+		}
+	}, dest)
+
+	// A redelivery may have been queued since it was buried, in which case
+	// that one is processed instead.
+	insert := table.IngestionQueue.INSERT(table.IngestionQueue.AllColumns).
+		MODELS(rows).
+		ON_CONFLICT(table.IngestionQueue.Integration, table.IngestionQueue.DeliveryID).
+		WHERE(queuedDeliveryPredicate).
+		DO_NOTHING()
+
+	if _, err := insert.Exec(tx); err != nil {
+		return 0, rollback(tx, err)
+	}
+
+	return len(rows), tx.Commit()
+}
+
+func queueItemToModel(item *ingestion.QueueItem) (model.IngestionQueue, error) {
+	payload, err := json.Marshal(item.Payload)
+
+	if err != nil {
+		return model.IngestionQueue{}, err
+	}
+
+	return model.IngestionQueue{
+		ID: item.ID,
+		Integration: item.Integration,
+		DeliveryID: item.DeliveryID,
+		Event: item.Event,
+		Payload: string(payload),
+		Attempts: int32(item.Attempts),
+		LastError: nullString(item.LastError),
+		AvailableAt: item.AvailableAt,
+		ReceivedAt: item.ReceivedAt,
+	}, nil
+}
+
+func queueItemFromModel(row model.IngestionQueue) (*ingestion.QueueItem, error) {
+	item := &ingestion.QueueItem{
+		ID: row.ID,
+		Integration: row.Integration,
+		DeliveryID: row.DeliveryID,
+		Event: row.Event,
+		Attempts: int(row.Attempts),
+		LastError: stringValue(row.LastError),
+		AvailableAt: row.AvailableAt.UTC(),
+		ReceivedAt: row.ReceivedAt.UTC(),
+	}
+
+	if err := json.Unmarshal([]byte(row.Payload), &item.Payload); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func deadLetterFromModel(row model.IngestionDeadLetters) (*ingestion.DeadLetter, error) {
+	dl := &ingestion.DeadLetter{
+		ID: row.ID,
+		Integration: row.Integration,
+		DeliveryID: row.DeliveryID,
+		Event: row.Event,
+		Attempts: int(row.Attempts),
+		LastError: row.LastError,
+		ReceivedAt: row.ReceivedAt.UTC(),
+		FailedAt: row.FailedAt.UTC(),
+	}
+
+	if err := json.Unmarshal([]byte(row.Payload), &dl.Payload); err != nil {
+		return nil, err
+	}
+
+	return dl, nil
+}
+
+func NewIngestionQueueRepository(conn *Connector) *IngestionQueueRepository {
+	return &IngestionQueueRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/adamkirk/panoptes/internal/util"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+type PermissionsRepository struct {
+	conn *Connector
+}
+
+func (r *PermissionsRepository) Names() ([]string, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.Permissions.SELECT(table.Permissions.AllColumns).
+		FROM(table.Permissions).
+		ORDER_BY(table.Permissions.Name.ASC())
+
+	dest := []model.Permissions{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(dest))
+
+	for i, p := range dest {
+		names[i] = p.Name
+	}
+
+	return names, nil
+}
+
+func (r *PermissionsRepository) ByNames(names []string) ([]*users.Permission, error) {
+	if len(names) == 0 {
+		return []*users.Permission{}, nil
+	}
+
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	namesIn := util.Map[string, postgres.Expression](func (v string) postgres.Expression {
+		return postgres.String(v)
+	}, names)
+
+	stmt := table.Permissions.SELECT(table.Permissions.AllColumns).
+		FROM(table.Permissions).
+		WHERE(table.Permissions.Name.IN(namesIn...))
+
+	dest := []model.Permissions{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	return util.Map[model.Permissions, *users.Permission](func (p model.Permissions) *users.Permission {
+		return &users.Permission{
+			ID: p.ID,
+			Name: p.Name,
+		}
+	}, dest), nil
+}
+
+func NewPermissionsRepository(conn *Connector) *PermissionsRepository {
+	return &PermissionsRepository{
+		conn: conn,
+	}
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"github.com/adamkirk/panoptes/internal/domain/projections"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/go-jet/jet/v2/postgres"
+)
+
+type ProjectionCheckpointsRepository struct {
+	conn *Connector
+}
+
+func (r *ProjectionCheckpointsRepository) Get(projection string) (*projections.Checkpoint, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.ProjectionCheckpoints.SELECT(table.ProjectionCheckpoints.AllColumns).
+		FROM(table.ProjectionCheckpoints).
+		WHERE(table.ProjectionCheckpoints.Name.EQ(postgres.String(projection))).
+		LIMIT(1)
+
+	dest := []model.ProjectionCheckpoints{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	c := dest[0]
+
+	return &projections.Checkpoint{
+		Projection: c.Name,
+		Sequence: c.Seq,
+		UpdatedAt: c.UpdatedAt.UTC(),
+	}, nil
+}
+
+func (r *ProjectionCheckpointsRepository) Save(c *projections.Checkpoint) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.ProjectionCheckpoints.INSERT(table.ProjectionCheckpoints.AllColumns).
+		MODEL(model.ProjectionCheckpoints{
+			Name: c.Projection,
+			Seq: c.Sequence,
+			UpdatedAt: c.UpdatedAt,
+		}).
+		ON_CONFLICT(table.ProjectionCheckpoints.Name).
+		DO_UPDATE(postgres.SET(
+			table.ProjectionCheckpoints.Seq.SET(table.ProjectionCheckpoints.EXCLUDED.Seq),
+			table.ProjectionCheckpoints.UpdatedAt.SET(table.ProjectionCheckpoints.EXCLUDED.UpdatedAt),
+		))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func (r *ProjectionCheckpointsRepository) Delete(projection string) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.ProjectionCheckpoints.DELETE().
+		WHERE(table.ProjectionCheckpoints.Name.EQ(postgres.String(projection)))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func NewProjectionCheckpointsRepository(conn *Connector) *ProjectionCheckpointsRepository {
+	return &ProjectionCheckpointsRepository{
+		conn: conn,
+	}
+}
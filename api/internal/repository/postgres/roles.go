@@ -6,6 +6,8 @@ import (
 	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
 	"github.com/adamkirk/panoptes/internal/util"
 	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/google/uuid"
 )
 
 type RolesRepository struct {
@@ -32,9 +34,10 @@ func (r *RolesRepository) ByNames(names []string) ([]*users.Role, error) {
 		return postgres.String(v)
 	}, names)
 
-	stmt := table.Roles.SELECT(table.Roles.AllColumns).
+	stmt := table.Roles.SELECT(table.Roles.AllColumns, table.Permissions.AllColumns).
 		FROM(table.Roles.
-			LEFT_JOIN(table.Permissions, table.Roles.ID.EQ(table.Permissions.ID))).
+			LEFT_JOIN(table.RolesPermissions, table.Roles.ID.EQ(table.RolesPermissions.RoleID)).
+			LEFT_JOIN(table.Permissions, table.RolesPermissions.PermissionID.EQ(table.Permissions.ID))).
 		WHERE(table.Roles.Name.IN(namesIn...))
 
 	var dest []dbRole
@@ -47,21 +50,133 @@ func (r *RolesRepository) ByNames(names []string) ([]*users.Role, error) {
 		return nil, nil
 	}
 
-	roles := util.Map[dbRole, *users.Role](func (in dbRole) *users.Role {
-		return &users.Role{
-			ID: in.ID,
-			Name: in.Name,
+	return util.Map[dbRole, *users.Role](roleFromModel, dest), nil
+}
+
+func (r *RolesRepository) All() ([]*users.Role, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.Roles.SELECT(table.Roles.AllColumns, table.Permissions.AllColumns).
+		FROM(table.Roles.
+			LEFT_JOIN(table.RolesPermissions, table.Roles.ID.EQ(table.RolesPermissions.RoleID)).
+			LEFT_JOIN(table.Permissions, table.RolesPermissions.PermissionID.EQ(table.Permissions.ID))).
+		ORDER_BY(table.Roles.Name.ASC(), table.Permissions.Name.ASC())
+
+	dest := []dbRole{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	return util.Map[dbRole, *users.Role](roleFromModel, dest), nil
+}
+
+func (r *RolesRepository) Create(role *users.Role) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.Roles.INSERT(table.Roles.ID, table.Roles.Name).
+		VALUES(role.ID, role.Name)
+
+	if _, err := stmt.Exec(tx); err != nil {
+		return rollback(tx, err)
+	}
+
+	if err := addPermissions(tx, role, role.Permissions); err != nil {
+		return rollback(tx, err)
+	}
+
+	return tx.Commit()
+}
 
-			Permissions: util.Map[model.Permissions, *users.Permission](func (in model.Permissions) *users.Permission{
-				return &users.Permission{
-					ID: in.ID,
-					Name: in.Name,
-				}
-			}, in.Permissions),
+func addPermissions(db qrm.Executable, role *users.Role, permissions []*users.Permission) error {
+	for _, p := range permissions {
+		stmt := table.RolesPermissions.INSERT(table.RolesPermissions.ID, table.RolesPermissions.RoleID, table.RolesPermissions.PermissionID).
+			VALUES(uuid.New(), role.ID, p.ID).
+			ON_CONFLICT(table.RolesPermissions.RoleID, table.RolesPermissions.PermissionID).
+			DO_NOTHING()
+
+		if _, err := stmt.Exec(db); err != nil {
+			return err
 		}
-	}, dest)
-		
-	return roles, nil
+	}
+
+	return nil
+}
+
+// AddPermissions grants the permissions to the role, any it already has are
+// left alone.
+func (r *RolesRepository) AddPermissions(role *users.Role, permissions []*users.Permission) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	if err := addPermissions(tx, role, permissions); err != nil {
+		return rollback(tx, err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *RolesRepository) RemovePermissions(role *users.Role, permissions []*users.Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	ids := util.Map[*users.Permission, postgres.Expression](func (p *users.Permission) postgres.Expression {
+		return postgres.UUID(p.ID)
+	}, permissions)
+
+	stmt := table.RolesPermissions.DELETE().
+		WHERE(
+			table.RolesPermissions.RoleID.EQ(postgres.UUID(role.ID)).
+				AND(table.RolesPermissions.PermissionID.IN(ids...)),
+		)
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func roleFromModel(in dbRole) *users.Role {
+	return &users.Role{
+		ID: in.ID,
+		Name: in.Name,
+
+		Permissions: util.Map[model.Permissions, *users.Permission](func (in model.Permissions) *users.Permission{
+			return &users.Permission{
+				ID: in.ID,
+				Name: in.Name,
+			}
+		}, in.Permissions),
+	}
 }
 
 func NewRolesRepository(conn *Connector) *RolesRepository {
@@ -20,4 +20,5 @@ type ChangeRequestsStream struct {
 	Type              *string
 	SourceID          *uuid.UUID
 	SourceIntegration string
+	Seq               int64
 }
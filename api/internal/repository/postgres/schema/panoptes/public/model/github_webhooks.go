@@ -16,4 +16,6 @@ type GithubWebhooks struct {
 	ID         uuid.UUID `sql:"primary_key"`
 	OccurredAt *time.Time
 	Payload    string
+	DeliveryID *string
+	Event      *string
 }
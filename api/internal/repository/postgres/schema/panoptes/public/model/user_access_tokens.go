@@ -13,8 +13,11 @@ import (
 )
 
 type UserAccessTokens struct {
-	ID        string `sql:"primary_key"`
-	Secret    string
-	ExpiresAt *time.Time
-	UserID    uuid.UUID
+	ID         string `sql:"primary_key"`
+	Secret     string
+	ExpiresAt  *time.Time
+	UserID     uuid.UUID
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  *time.Time
 }
@@ -9,12 +9,19 @@ package model
 
 import (
 	"github.com/google/uuid"
+	"time"
 )
 
 type Users struct {
-	ID        uuid.UUID `sql:"primary_key"`
-	Email     string
-	FirstName string
-	LastName  string
-	Password  string
+	ID            uuid.UUID `sql:"primary_key"`
+	Email         *string
+	FirstName     string
+	LastName      string
+	Password      string
+	DeactivatedAt *time.Time
+	CreatedAt     time.Time
+	OIDCIssuer    *string
+	OIDCSubject   *string
+	Type          string
+	Name          *string
 }
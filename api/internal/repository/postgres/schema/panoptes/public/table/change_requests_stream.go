@@ -24,6 +24,7 @@ type changeRequestsStreamTable struct {
 	Type              postgres.ColumnString
 	SourceID          postgres.ColumnString
 	SourceIntegration postgres.ColumnString
+	Seq               postgres.ColumnInteger
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -71,8 +72,9 @@ func newChangeRequestsStreamTableImpl(schemaName, tableName, alias string) chang
 		TypeColumn              = postgres.StringColumn("type")
 		SourceIDColumn          = postgres.StringColumn("source_id")
 		SourceIntegrationColumn = postgres.StringColumn("source_integration")
-		allColumns              = postgres.ColumnList{IDColumn, AggregateIDColumn, OccurredAtColumn, PayloadColumn, TypeColumn, SourceIDColumn, SourceIntegrationColumn}
-		mutableColumns          = postgres.ColumnList{AggregateIDColumn, OccurredAtColumn, PayloadColumn, TypeColumn, SourceIDColumn, SourceIntegrationColumn}
+		SeqColumn               = postgres.IntegerColumn("seq")
+		allColumns              = postgres.ColumnList{IDColumn, AggregateIDColumn, OccurredAtColumn, PayloadColumn, TypeColumn, SourceIDColumn, SourceIntegrationColumn, SeqColumn}
+		mutableColumns          = postgres.ColumnList{AggregateIDColumn, OccurredAtColumn, PayloadColumn, TypeColumn, SourceIDColumn, SourceIntegrationColumn, SeqColumn}
 	)
 
 	return changeRequestsStreamTable{
@@ -86,6 +88,7 @@ func newChangeRequestsStreamTableImpl(schemaName, tableName, alias string) chang
 		Type:              TypeColumn,
 		SourceID:          SourceIDColumn,
 		SourceIntegration: SourceIntegrationColumn,
+		Seq:               SeqColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -20,6 +20,8 @@ type githubWebhooksTable struct {
 	ID         postgres.ColumnString
 	OccurredAt postgres.ColumnTimestampz
 	Payload    postgres.ColumnString
+	DeliveryID postgres.ColumnString
+	Event      postgres.ColumnString
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -63,8 +65,10 @@ func newGithubWebhooksTableImpl(schemaName, tableName, alias string) githubWebho
 		IDColumn         = postgres.StringColumn("id")
 		OccurredAtColumn = postgres.TimestampzColumn("occurred_at")
 		PayloadColumn    = postgres.StringColumn("payload")
-		allColumns       = postgres.ColumnList{IDColumn, OccurredAtColumn, PayloadColumn}
-		mutableColumns   = postgres.ColumnList{OccurredAtColumn, PayloadColumn}
+		DeliveryIDColumn = postgres.StringColumn("delivery_id")
+		EventColumn      = postgres.StringColumn("event")
+		allColumns       = postgres.ColumnList{IDColumn, OccurredAtColumn, PayloadColumn, DeliveryIDColumn, EventColumn}
+		mutableColumns   = postgres.ColumnList{OccurredAtColumn, PayloadColumn, DeliveryIDColumn, EventColumn}
 	)
 
 	return githubWebhooksTable{
@@ -74,6 +78,8 @@ func newGithubWebhooksTableImpl(schemaName, tableName, alias string) githubWebho
 		ID:         IDColumn,
 		OccurredAt: OccurredAtColumn,
 		Payload:    PayloadColumn,
+		DeliveryID: DeliveryIDColumn,
+		Event:      EventColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
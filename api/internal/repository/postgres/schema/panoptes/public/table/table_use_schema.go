@@ -10,13 +10,25 @@ package table
 // UseSchema sets a new schema name for all generated table SQL builder types. It is recommended to invoke
 // this method only once at the beginning of the program.
 func UseSchema(schema string) {
+	AuditEvents = AuditEvents.FromSchema(schema)
+	BackfillCheckpoints = BackfillCheckpoints.FromSchema(schema)
+	BitbucketWebhooks = BitbucketWebhooks.FromSchema(schema)
+	ChangeRequestTaskLinks = ChangeRequestTaskLinks.FromSchema(schema)
+	ChangeRequests = ChangeRequests.FromSchema(schema)
 	ChangeRequestsStream = ChangeRequestsStream.FromSchema(schema)
 	GithubWebhooks = GithubWebhooks.FromSchema(schema)
+	GitlabWebhooks = GitlabWebhooks.FromSchema(schema)
+	IngestionDeadLetters = IngestionDeadLetters.FromSchema(schema)
+	IngestionQueue = IngestionQueue.FromSchema(schema)
+	JiraWebhooks = JiraWebhooks.FromSchema(schema)
 	Permissions = Permissions.FromSchema(schema)
+	ProjectionCheckpoints = ProjectionCheckpoints.FromSchema(schema)
 	Roles = Roles.FromSchema(schema)
 	RolesPermissions = RolesPermissions.FromSchema(schema)
 	SchemaMigrations = SchemaMigrations.FromSchema(schema)
+	TasksStream = TasksStream.FromSchema(schema)
 	UserAccessTokens = UserAccessTokens.FromSchema(schema)
 	UserRoles = UserRoles.FromSchema(schema)
+	UserSessions = UserSessions.FromSchema(schema)
 	Users = Users.FromSchema(schema)
 }
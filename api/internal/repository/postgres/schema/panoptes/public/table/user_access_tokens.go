@@ -17,10 +17,13 @@ type userAccessTokensTable struct {
 	postgres.Table
 
 	// Columns
-	ID        postgres.ColumnString
-	Secret    postgres.ColumnString
-	ExpiresAt postgres.ColumnTimestampz
-	UserID    postgres.ColumnString
+	ID         postgres.ColumnString
+	Secret     postgres.ColumnString
+	ExpiresAt  postgres.ColumnTimestampz
+	UserID     postgres.ColumnString
+	RevokedAt  postgres.ColumnTimestampz
+	LastUsedAt postgres.ColumnTimestampz
+	CreatedAt  postgres.ColumnTimestampz
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -61,22 +64,28 @@ func newUserAccessTokensTable(schemaName, tableName, alias string) *UserAccessTo
 
 func newUserAccessTokensTableImpl(schemaName, tableName, alias string) userAccessTokensTable {
 	var (
-		IDColumn        = postgres.StringColumn("id")
-		SecretColumn    = postgres.StringColumn("secret")
-		ExpiresAtColumn = postgres.TimestampzColumn("expires_at")
-		UserIDColumn    = postgres.StringColumn("user_id")
-		allColumns      = postgres.ColumnList{IDColumn, SecretColumn, ExpiresAtColumn, UserIDColumn}
-		mutableColumns  = postgres.ColumnList{SecretColumn, ExpiresAtColumn, UserIDColumn}
+		IDColumn         = postgres.StringColumn("id")
+		SecretColumn     = postgres.StringColumn("secret")
+		ExpiresAtColumn  = postgres.TimestampzColumn("expires_at")
+		UserIDColumn     = postgres.StringColumn("user_id")
+		RevokedAtColumn  = postgres.TimestampzColumn("revoked_at")
+		LastUsedAtColumn = postgres.TimestampzColumn("last_used_at")
+		CreatedAtColumn  = postgres.TimestampzColumn("created_at")
+		allColumns       = postgres.ColumnList{IDColumn, SecretColumn, ExpiresAtColumn, UserIDColumn, RevokedAtColumn, LastUsedAtColumn, CreatedAtColumn}
+		mutableColumns   = postgres.ColumnList{SecretColumn, ExpiresAtColumn, UserIDColumn, RevokedAtColumn, LastUsedAtColumn, CreatedAtColumn}
 	)
 
 	return userAccessTokensTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:        IDColumn,
-		Secret:    SecretColumn,
-		ExpiresAt: ExpiresAtColumn,
-		UserID:    UserIDColumn,
+		ID:         IDColumn,
+		Secret:     SecretColumn,
+		ExpiresAt:  ExpiresAtColumn,
+		UserID:     UserIDColumn,
+		RevokedAt:  RevokedAtColumn,
+		LastUsedAt: LastUsedAtColumn,
+		CreatedAt:  CreatedAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -17,11 +17,17 @@ type usersTable struct {
 	postgres.Table
 
 	// Columns
-	ID        postgres.ColumnString
-	Email     postgres.ColumnString
-	FirstName postgres.ColumnString
-	LastName  postgres.ColumnString
-	Password  postgres.ColumnString
+	ID            postgres.ColumnString
+	Email         postgres.ColumnString
+	FirstName     postgres.ColumnString
+	LastName      postgres.ColumnString
+	Password      postgres.ColumnString
+	DeactivatedAt postgres.ColumnTimestampz
+	CreatedAt     postgres.ColumnTimestampz
+	OIDCIssuer    postgres.ColumnString
+	OIDCSubject   postgres.ColumnString
+	Type          postgres.ColumnString
+	Name          postgres.ColumnString
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -62,24 +68,36 @@ func newUsersTable(schemaName, tableName, alias string) *UsersTable {
 
 func newUsersTableImpl(schemaName, tableName, alias string) usersTable {
 	var (
-		IDColumn        = postgres.StringColumn("id")
-		EmailColumn     = postgres.StringColumn("email")
-		FirstNameColumn = postgres.StringColumn("first_name")
-		LastNameColumn  = postgres.StringColumn("last_name")
-		PasswordColumn  = postgres.StringColumn("password")
-		allColumns      = postgres.ColumnList{IDColumn, EmailColumn, FirstNameColumn, LastNameColumn, PasswordColumn}
-		mutableColumns  = postgres.ColumnList{EmailColumn, FirstNameColumn, LastNameColumn, PasswordColumn}
+		IDColumn            = postgres.StringColumn("id")
+		EmailColumn         = postgres.StringColumn("email")
+		FirstNameColumn     = postgres.StringColumn("first_name")
+		LastNameColumn      = postgres.StringColumn("last_name")
+		PasswordColumn      = postgres.StringColumn("password")
+		DeactivatedAtColumn = postgres.TimestampzColumn("deactivated_at")
+		CreatedAtColumn     = postgres.TimestampzColumn("created_at")
+		OIDCIssuerColumn    = postgres.StringColumn("oidc_issuer")
+		OIDCSubjectColumn   = postgres.StringColumn("oidc_subject")
+		TypeColumn          = postgres.StringColumn("type")
+		NameColumn          = postgres.StringColumn("name")
+		allColumns          = postgres.ColumnList{IDColumn, EmailColumn, FirstNameColumn, LastNameColumn, PasswordColumn, DeactivatedAtColumn, CreatedAtColumn, OIDCIssuerColumn, OIDCSubjectColumn, TypeColumn, NameColumn}
+		mutableColumns      = postgres.ColumnList{EmailColumn, FirstNameColumn, LastNameColumn, PasswordColumn, DeactivatedAtColumn, CreatedAtColumn, OIDCIssuerColumn, OIDCSubjectColumn, TypeColumn, NameColumn}
 	)
 
 	return usersTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:        IDColumn,
-		Email:     EmailColumn,
-		FirstName: FirstNameColumn,
-		LastName:  LastNameColumn,
-		Password:  PasswordColumn,
+		ID:            IDColumn,
+		Email:         EmailColumn,
+		FirstName:     FirstNameColumn,
+		LastName:      LastNameColumn,
+		Password:      PasswordColumn,
+		DeactivatedAt: DeactivatedAtColumn,
+		CreatedAt:     CreatedAtColumn,
+		OIDCIssuer:    OIDCIssuerColumn,
+		OIDCSubject:   OIDCSubjectColumn,
+		Type:          TypeColumn,
+		Name:          NameColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"encoding/json"
+
+	"github.com/adamkirk/panoptes/internal/domain/tasks"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/go-jet/jet/v2/qrm"
+)
+
+// appendTaskEvents writes the events to the stream, it takes an executable so
+// that it can be part of a wider transaction. Event ids are derived from the
+// event itself, so an event we've already got is skipped.
+func appendTaskEvents(db qrm.Executable, events []tasks.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows := make([]model.TasksStream, len(events))
+
+	for i, e := range events {
+		payloadJSON, err := json.Marshal(e.Payload)
+
+		if err != nil {
+			return err
+		}
+
+		t := string(e.Type)
+		occurredAt := e.OccurredAt
+
+		rows[i] = model.TasksStream{
+			ID: e.ID,
+			AggregateID: e.AggregateID,
+			OccurredAt: &occurredAt,
+			Payload: string(payloadJSON),
+			Type: &t,
+			SourceID: e.SourceID,
+			SourceIntegration: e.SourceIntegration,
+		}
+	}
+
+	stmt := table.TasksStream.INSERT(table.TasksStream.AllColumns).
+		MODELS(rows).
+		ON_CONFLICT(table.TasksStream.ID).DO_NOTHING()
+
+	_, err := stmt.Exec(db)
+
+	return err
+}
@@ -1,27 +1,24 @@
 package postgres
 
 import (
+	"time"
+
 	"github.com/adamkirk/panoptes/internal/domain/users"
 	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
 	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
 	"github.com/adamkirk/panoptes/internal/util"
 	"github.com/go-jet/jet/v2/postgres"
+	"github.com/google/uuid"
 )
 
 type UserAccessTokensRepository struct {
 	conn *Connector
 }
 
-type dbUserAccessTokenUserRole struct {
-	model.Roles
-
-	Permissions []model.Permissions
-}
-
 type dbUserAccessTokenUser struct {
 	model.Users
 
-	Roles []dbUserAccessTokenUserRole
+	Roles []dbRole
 }
 
 type dbUserAccessToken struct {
@@ -37,12 +34,13 @@ func (r *UserAccessTokensRepository) Create(t *users.AccessToken) error {
 		return err
 	}
 
-	stmt := table.UserAccessTokens.INSERT(table.UserAccessTokens.ID, table.UserAccessTokens.UserID, table.UserAccessTokens.Secret, table.UserAccessTokens.ExpiresAt).
+	stmt := table.UserAccessTokens.INSERT(table.UserAccessTokens.ID, table.UserAccessTokens.UserID, table.UserAccessTokens.Secret, table.UserAccessTokens.ExpiresAt, table.UserAccessTokens.CreatedAt).
 	MODEL(model.UserAccessTokens{
 			ID: t.ID,
 			UserID: t.User.ID,
 			Secret: t.SecretHash,
 			ExpiresAt: t.ExpireAt,
+			CreatedAt: t.CreatedAt,
 		})
 
 	_, err = stmt.Exec(conn)
@@ -84,30 +82,92 @@ func (r *UserAccessTokensRepository) ByID(id string) (*users.AccessToken, error)
 		ID: t.ID,
 		SecretHash: t.Secret,
 		ExpireAt: t.ExpiresAt,
-		User: &users.User{
-			ID: t.User.ID,
-			FirstName: t.User.FirstName,
-			LastName: t.User.LastName,
-			Email: t.User.Email,
-			PasswordHash: t.User.Password,
-
-			Roles: util.Map[dbUserAccessTokenUserRole, *users.Role](func(in dbUserAccessTokenUserRole) *users.Role {
-				return &users.Role{
-					ID: in.ID,
-					Name: in.Name,
-
-					Permissions: util.Map[model.Permissions](func (in model.Permissions) *users.Permission {
-						return &users.Permission{
-							ID: in.ID,
-							Name: in.Name,
-						}
-					}, in.Permissions),
-				}
-			}, t.User.Roles),
-		},
+		RevokedAt: t.RevokedAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt: t.CreatedAt,
+		User: userFromModel(dbUser{
+			Users: t.User.Users,
+			Roles: t.User.Roles,
+		}),
 	}, nil
 }
 
+func (r *UserAccessTokensRepository) ByUser(userID uuid.UUID) ([]*users.AccessToken, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.UserAccessTokens.SELECT(table.UserAccessTokens.AllColumns).
+		FROM(table.UserAccessTokens).
+		WHERE(table.UserAccessTokens.UserID.EQ(postgres.UUID(userID))).
+		ORDER_BY(table.UserAccessTokens.CreatedAt.DESC().NULLS_LAST(), table.UserAccessTokens.ID.ASC())
+
+	dest := []model.UserAccessTokens{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	return util.Map[model.UserAccessTokens, *users.AccessToken](func (t model.UserAccessTokens) *users.AccessToken {
+		return &users.AccessToken{
+			ID: t.ID,
+			SecretHash: t.Secret,
+			ExpireAt: t.ExpiresAt,
+			RevokedAt: t.RevokedAt,
+			LastUsedAt: t.LastUsedAt,
+			CreatedAt: t.CreatedAt,
+			User: &users.User{
+				ID: t.UserID,
+			},
+		}
+	}, dest), nil
+}
+
+func (r *UserAccessTokensRepository) Revoke(id string, at time.Time) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.UserAccessTokens.UPDATE(table.UserAccessTokens.RevokedAt).
+		SET(postgres.TimestampzT(at)).
+		WHERE(
+			table.UserAccessTokens.ID.EQ(postgres.String(id)).
+				AND(table.UserAccessTokens.RevokedAt.IS_NULL()),
+		)
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+// Touch records that the token was used, skipping the write if it was already
+// recorded as used since the given time.
+func (r *UserAccessTokensRepository) Touch(id string, at time.Time, since time.Time) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.UserAccessTokens.UPDATE(table.UserAccessTokens.LastUsedAt).
+		SET(postgres.TimestampzT(at)).
+		WHERE(
+			table.UserAccessTokens.ID.EQ(postgres.String(id)).
+				AND(
+					table.UserAccessTokens.LastUsedAt.IS_NULL().
+						OR(table.UserAccessTokens.LastUsedAt.LT(postgres.TimestampzT(since))),
+				),
+		)
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
 func NewUserAccessTokensRepository(conn *Connector) *UserAccessTokensRepository {
 	return &UserAccessTokensRepository{
 		conn: conn,
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/domain/users"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/model"
+	"github.com/adamkirk/panoptes/internal/repository/postgres/schema/panoptes/public/table"
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/google/uuid"
+)
+
+type UserSessionsRepository struct {
+	conn *Connector
+}
+
+type dbUserSession struct {
+	model.UserSessions
+
+	User dbUser
+}
+
+func (r *UserSessionsRepository) Create(s *users.Session) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.UserSessions.INSERT(table.UserSessions.ID, table.UserSessions.UserID, table.UserSessions.RefreshID, table.UserSessions.RefreshSecret, table.UserSessions.ExpiresAt, table.UserSessions.CreatedAt).
+		MODEL(model.UserSessions{
+			ID: s.ID,
+			UserID: s.User.ID,
+			RefreshID: s.RefreshID,
+			RefreshSecret: s.RefreshSecretHash,
+			ExpiresAt: s.ExpireAt,
+			CreatedAt: s.CreatedAt,
+		})
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func (r *UserSessionsRepository) ByID(id uuid.UUID) (*users.Session, error) {
+	return r.one(table.UserSessions.ID.EQ(postgres.UUID(id)))
+}
+
+func (r *UserSessionsRepository) ByRefreshID(refreshID string) (*users.Session, error) {
+	return r.one(table.UserSessions.RefreshID.EQ(postgres.String(refreshID)))
+}
+
+func (r *UserSessionsRepository) one(where postgres.BoolExpression) (*users.Session, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.UserSessions.SELECT(table.UserSessions.AllColumns, table.Users.AllColumns, table.Roles.AllColumns, table.Permissions.AllColumns).
+		FROM(
+			table.UserSessions.
+			INNER_JOIN(table.Users, table.UserSessions.UserID.EQ(table.Users.ID)).
+			LEFT_JOIN(table.UserRoles, table.Users.ID.EQ(table.UserRoles.UserID)).
+			LEFT_JOIN(table.Roles, table.UserRoles.RoleID.EQ(table.Roles.ID)).
+			LEFT_JOIN(table.RolesPermissions, table.Roles.ID.EQ(table.RolesPermissions.RoleID)).
+			LEFT_JOIN(table.Permissions, table.RolesPermissions.PermissionID.EQ(table.Permissions.ID)),
+		).WHERE(where)
+
+	dest := []dbUserSession{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	s := dest[0]
+
+	return &users.Session{
+		ID: s.ID,
+		User: userFromModel(s.User),
+		RefreshID: s.RefreshID,
+		RefreshSecretHash: s.RefreshSecret,
+		ExpireAt: s.ExpiresAt.UTC(),
+		RevokedAt: s.RevokedAt,
+		RefreshedAt: s.RefreshedAt,
+		CreatedAt: s.CreatedAt.UTC(),
+	}, nil
+}
+
+func (r *UserSessionsRepository) Rotate(id uuid.UUID, previousHash string, hash string, at time.Time) (bool, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return false, err
+	}
+
+	stmt := table.UserSessions.UPDATE(table.UserSessions.RefreshSecret, table.UserSessions.RefreshedAt).
+		SET(postgres.String(hash), postgres.TimestampzT(at)).
+		WHERE(
+			table.UserSessions.ID.EQ(postgres.UUID(id)).
+				AND(table.UserSessions.RefreshSecret.EQ(postgres.String(previousHash))).
+				AND(table.UserSessions.RevokedAt.IS_NULL()),
+		)
+
+	res, err := stmt.Exec(conn)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *UserSessionsRepository) Revoke(id uuid.UUID, at time.Time) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.UserSessions.UPDATE(table.UserSessions.RevokedAt).
+		SET(postgres.TimestampzT(at)).
+		WHERE(
+			table.UserSessions.ID.EQ(postgres.UUID(id)).
+				AND(table.UserSessions.RevokedAt.IS_NULL()),
+		)
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+func NewUserSessionsRepository(conn *Connector) *UserSessionsRepository {
+	return &UserSessionsRepository{
+		conn: conn,
+	}
+}
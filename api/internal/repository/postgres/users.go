@@ -20,7 +20,7 @@ type UsersRepository struct {
 type dbUser struct {
 	model.Users
 
-	Roles []model.Roles
+	Roles []dbRole
 }
 
 func (r *UsersRepository) Create(u *users.User) error {
@@ -36,14 +36,8 @@ func (r *UsersRepository) Create(u *users.User) error {
 		return err
 	}
 
-	stmt := table.Users.INSERT(table.Users.ID, table.Users.Email, table.Users.FirstName, table.Users.LastName, table.Users.Password).
-	MODEL(model.Users{
-			ID: u.ID,
-			Email: u.Email,
-			FirstName: u.FirstName,
-			LastName: u.LastName,
-			Password: u.PasswordHash,
-		})
+	stmt := table.Users.INSERT(table.Users.AllColumns).
+	MODEL(userToModel(u))
 
 	if _, err := stmt.Exec(tx); err != nil {
 		if txErr := tx.Rollback(); txErr != nil {
@@ -94,21 +88,7 @@ func (r *UsersRepository) Get(id uuid.UUID) (*users.User, error) {
 		return nil, nil
 	}
 
-	u := dest[0]
-
-	return &users.User{
-		ID: u.ID,
-		FirstName: u.FirstName,
-		LastName: u.LastName,
-		Email: u.Email,
-		PasswordHash: u.Password,
-		Roles: util.Map[model.Roles, *users.Role](func (v model.Roles) *users.Role {
-			return &users.Role{
-				ID: v.ID,
-				Name: v.Name,
-			}
-		}, u.Roles),
-	}, nil
+	return userFromModel(dest[0]), nil
 }
 
 func (r *UsersRepository) ByEmail(email string) (*users.User, error) {
@@ -132,15 +112,216 @@ func (r *UsersRepository) ByEmail(email string) (*users.User, error) {
 		return nil, nil
 	}
 
-	u := dest[0]
+	return userFromModel(dbUser{Users: dest[0]}), nil
+}
+
+// ByName returns the service account with the name.
+func (r *UsersRepository) ByName(name string) (*users.User, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	q := table.Users.SELECT(table.Users.AllColumns).
+		FROM(table.Users).
+		WHERE(table.Users.Name.EQ(postgres.String(name))).
+		LIMIT(1)
+
+	dest := []model.Users{}
+	if err := q.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	return userFromModel(dbUser{Users: dest[0]}), nil
+}
+
+// ByOIDCSubject returns the user with the identity at the OpenID Connect
+// provider, along with their roles and permissions.
+func (r *UsersRepository) ByOIDCSubject(issuer string, subject string) (*users.User, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := table.Users.SELECT(table.Users.AllColumns, table.Roles.AllColumns, table.Permissions.AllColumns).
+		FROM(table.Users.
+			LEFT_JOIN(table.UserRoles, table.UserRoles.UserID.EQ(table.Users.ID)).
+			LEFT_JOIN(table.Roles, table.Roles.ID.EQ(table.UserRoles.RoleID)).
+			LEFT_JOIN(table.RolesPermissions, table.Roles.ID.EQ(table.RolesPermissions.RoleID)).
+			LEFT_JOIN(table.Permissions, table.RolesPermissions.PermissionID.EQ(table.Permissions.ID)),
+		).
+		WHERE(
+			table.Users.OIDCIssuer.EQ(postgres.String(issuer)).
+				AND(table.Users.OIDCSubject.EQ(postgres.String(subject))),
+		)
+
+	dest := []dbUser{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, err
+	}
+
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	return userFromModel(dest[0]), nil
+}
+
+// List returns a page of users of the type (with their roles, but not the
+// roles' permissions) ordered by when they were created, along with the total
+// number of users of the type.
+func (r *UsersRepository) List(t users.UserType, offset int, limit int) ([]*users.User, int, error) {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	isType := table.Users.Type.EQ(postgres.String(string(t)))
+
+	countStmt := table.Users.SELECT(postgres.COUNT(postgres.STAR).AS("total")).
+		FROM(table.Users).
+		WHERE(isType)
+
+	var count struct {
+		Total int
+	}
+
+	if err := countStmt.Query(conn, &count); err != nil {
+		return nil, 0, err
+	}
 
+	// Paginate the users on their own, limiting the joined rows would cut off
+	// the roles of the last user.
+	page := table.Users.SELECT(table.Users.ID).
+		FROM(table.Users).
+		WHERE(isType).
+		ORDER_BY(table.Users.CreatedAt.ASC(), table.Users.ID.ASC()).
+		LIMIT(int64(limit)).
+		OFFSET(int64(offset))
+
+	stmt := table.Users.SELECT(table.Users.AllColumns, table.Roles.AllColumns).
+		FROM(table.Users.
+			LEFT_JOIN(table.UserRoles, table.UserRoles.UserID.EQ(table.Users.ID)).
+			LEFT_JOIN(table.Roles, table.Roles.ID.EQ(table.UserRoles.RoleID)),
+		).
+		WHERE(table.Users.ID.IN(page)).
+		ORDER_BY(table.Users.CreatedAt.ASC(), table.Users.ID.ASC())
+
+	dest := []dbUser{}
+
+	if err := stmt.Query(conn, &dest); err != nil {
+		return nil, 0, err
+	}
+
+	return util.Map[dbUser, *users.User](userFromModel, dest), count.Total, nil
+}
+
+// Update saves the user's details, but not their roles.
+func (r *UsersRepository) Update(u *users.User) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	stmt := table.Users.UPDATE(table.Users.Name, table.Users.Email, table.Users.FirstName, table.Users.LastName, table.Users.Password, table.Users.DeactivatedAt, table.Users.OIDCIssuer, table.Users.OIDCSubject).
+		MODEL(userToModel(u)).
+		WHERE(table.Users.ID.EQ(postgres.UUID(u.ID)))
+
+	_, err = stmt.Exec(conn)
+
+	return err
+}
+
+// SetRoles replaces the user's roles with the ones on the user.
+func (r *UsersRepository) SetRoles(u *users.User) error {
+	conn, err := r.conn.Connection()
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	deleteStmt := table.UserRoles.DELETE().
+		WHERE(table.UserRoles.UserID.EQ(postgres.UUID(u.ID)))
+
+	if _, err := deleteStmt.Exec(tx); err != nil {
+		return rollback(tx, err)
+	}
+
+	for _, role := range u.Roles {
+		rolesStmt := table.UserRoles.INSERT(table.UserRoles.ID, table.UserRoles.RoleID, table.UserRoles.UserID).
+			VALUES(uuid.New(), role.ID, u.ID)
+
+		if _, err := rolesStmt.Exec(tx); err != nil {
+			return rollback(tx, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// nullString stores empty strings as null, so that unique indexes ignore them.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func userToModel(u *users.User) model.Users {
+	return model.Users{
+		ID: u.ID,
+		Type: string(u.Type),
+		Name: nullString(u.Name),
+		Email: nullString(u.Email),
+		FirstName: u.FirstName,
+		LastName: u.LastName,
+		Password: u.PasswordHash,
+		DeactivatedAt: u.DeactivatedAt,
+		CreatedAt: u.CreatedAt,
+		OIDCIssuer: nullString(u.OIDCIssuer),
+		OIDCSubject: nullString(u.OIDCSubject),
+	}
+}
+
+func userFromModel(u dbUser) *users.User {
 	return &users.User{
 		ID: u.ID,
+		Type: users.UserType(u.Type),
+		Name: stringValue(u.Name),
 		FirstName: u.FirstName,
 		LastName: u.LastName,
-		Email: u.Email,
+		Email: stringValue(u.Email),
 		PasswordHash: u.Password,
-	}, nil
+		DeactivatedAt: u.DeactivatedAt,
+		CreatedAt: u.CreatedAt.UTC(),
+		OIDCIssuer: stringValue(u.OIDCIssuer),
+		OIDCSubject: stringValue(u.OIDCSubject),
+		Roles: util.Map[dbRole, *users.Role](roleFromModel, u.Roles),
+	}
 }
 
 func NewUsersRepository(conn *Connector) *UsersRepository {
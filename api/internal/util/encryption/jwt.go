@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/util/dt"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const jwtIssuer = "panoptes"
+
+var ErrInvalidJWT = errors.New("invalid jwt")
+
+// JWTSigner signs and verifies HS256 JWTs identifying a session.
+type JWTSigner struct {
+	key    []byte
+	getNow func() time.Time
+}
+
+func (s *JWTSigner) Sign(sessionID string, userID string, expireAt time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        sessionID,
+		Subject:   userID,
+		Issuer:    jwtIssuer,
+		IssuedAt:  jwt.NewNumericDate(s.getNow()),
+		ExpiresAt: jwt.NewNumericDate(expireAt),
+	})
+
+	return token.SignedString(s.key)
+}
+
+// Verify checks the token's signature and expiry, returning the id of the
+// session it was signed for.
+func (s *JWTSigner) Verify(token string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+
+	_, err := jwt.ParseWithClaims(
+		token,
+		claims,
+		func(t *jwt.Token) (interface{}, error) {
+			return s.key, nil
+		},
+		// Otherwise the token could pick the algorithm, e.g. none.
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.getNow),
+	)
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidJWT, err.Error())
+	}
+
+	if claims.ID == "" {
+		return "", ErrInvalidJWT
+	}
+
+	return claims.ID, nil
+}
+
+func NewJWTSigner(key string) *JWTSigner {
+	return &JWTSigner{
+		key:    []byte(key),
+		getNow: dt.NowUTC,
+	}
+}
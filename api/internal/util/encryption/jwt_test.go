@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestSigner(key string) *JWTSigner {
+	s := NewJWTSigner(key)
+	s.getNow = func() time.Time { return now }
+
+	return s
+}
+
+func validClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		ID: "session",
+		Subject: "user",
+		Issuer: jwtIssuer,
+		IssuedAt: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err)
+	}
+
+	return token
+}
+
+func TestJWTSignerRoundTrip(t *testing.T) {
+	s := newTestSigner("key")
+
+	token, err := s.Sign("session", "user", now.Add(time.Minute))
+
+	if err != nil {
+		t.Fatalf("failed to sign: %s", err)
+	}
+
+	id, err := s.Verify(token)
+
+	if err != nil {
+		t.Fatalf("failed to verify: %s", err)
+	}
+
+	if id != "session" {
+		t.Errorf("expected session id 'session', got '%s'", id)
+	}
+}
+
+func TestJWTSignerVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		valid bool
+	}{
+		{
+			name: "valid",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodHS256, []byte("key"), validClaims())
+			},
+			valid: true,
+		},
+		{
+			name: "wrong key",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodHS256, []byte("other"), validClaims())
+			},
+		},
+		{
+			name: "none algorithm",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+		},
+		{
+			name: "other hmac algorithm",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodHS384, []byte("key"), validClaims())
+			},
+		},
+		{
+			name: "rsa algorithm",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, rsaKey, validClaims())
+			},
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Second))
+				return sign(t, jwt.SigningMethodHS256, []byte("key"), c)
+			},
+		},
+		{
+			name: "no expiry",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return sign(t, jwt.SigningMethodHS256, []byte("key"), c)
+			},
+		},
+		{
+			name: "other issuer",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Issuer = "someone-else"
+				return sign(t, jwt.SigningMethodHS256, []byte("key"), c)
+			},
+		},
+		{
+			name: "no session id",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ID = ""
+				return sign(t, jwt.SigningMethodHS256, []byte("key"), c)
+			},
+		},
+		{
+			name: "not a jwt",
+			token: func(t *testing.T) string {
+				return "not-a-jwt"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := newTestSigner("key").Verify(tt.token(t))
+
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("expected the token to be valid, got: %s", err)
+				}
+
+				if id != "session" {
+					t.Errorf("expected session id 'session', got '%s'", id)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidJWT) {
+				t.Errorf("expected ErrInvalidJWT, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package githubapi is a minimal client for github's REST API, for reading
+// history that we missed the webhooks for. It only covers what we need, GET
+// requests with paging and waiting out rate limits.
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/util/dt"
+)
+
+const DefaultBaseURL = "https://api.github.com"
+
+// maxRateLimitRetries is how many times a request refused because of a rate
+// limit is retried, before giving up.
+const maxRateLimitRetries = 5
+
+// secondaryRateLimitWait is how long to wait when github refuses a request
+// because of a secondary rate limit without saying for how long.
+const secondaryRateLimitWait = time.Minute
+
+var ErrUnexpectedStatus = errors.New("unexpected status from github")
+
+type Config struct {
+	// BaseURL defaults to api.github.com, it's only different for github
+	// enterprise server (e.g. https://github.example.com/api/v3).
+	BaseURL string
+	Token   string
+}
+
+type Client struct {
+	cfg    Config
+	client *http.Client
+	getNow func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+type ClientOpt func(*Client)
+
+func WithHTTPClient(hc *http.Client) ClientOpt {
+	return func(c *Client) {
+		c.client = hc
+	}
+}
+
+func WithCustomNowProvider(f func() time.Time) ClientOpt {
+	return func(c *Client) {
+		c.getNow = f
+	}
+}
+
+// sleep waits for the duration, unless the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// resolve makes paths relative to the base url, urls from Link headers are
+// already absolute.
+func (c *Client) resolve(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+
+	base := c.cfg.BaseURL
+
+	if base == "" {
+		base = DefaultBaseURL
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextPage is the url of the next page from the Link header, empty on the last
+// page.
+func nextPage(resp *http.Response) string {
+	m := linkNextPattern.FindStringSubmatch(resp.Header.Get("Link"))
+
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+// rateLimitReset is how long until the rate limit resets, when the response
+// says there are no requests remaining.
+func (c *Client) rateLimitReset(resp *http.Response) (time.Duration, bool) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	if err != nil {
+		return secondaryRateLimitWait, true
+	}
+
+	// A second extra allows for our clock being behind github's.
+	return max(time.Unix(reset, 0).Sub(c.getNow()), 0) + time.Second, true
+}
+
+// rateLimited is how long to wait before retrying a refused request, if it
+// was refused because of a rate limit rather than e.g. a bad token.
+func (c *Client) rateLimited(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if wait, ok := c.rateLimitReset(resp); ok {
+		return wait, true
+	}
+
+	// Secondary rate limits don't always come with headers, only a message.
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return secondaryRateLimitWait, true
+	}
+
+	return 0, false
+}
+
+// Get decodes the response into dest, returning the url of the next page if
+// there is one. The path can be relative to the base url, or a url returned as
+// the next page. Requests refused because of a rate limit are retried once the
+// limit resets, and when a response uses up the limit, Get waits for it to
+// reset before returning so that the next request isn't refused.
+func (c *Client) Get(ctx context.Context, path string, dest any) (string, error) {
+	u := c.resolve(path)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+
+		if err != nil {
+			return "", err
+		}
+
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		if c.cfg.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+		}
+
+		resp, err := c.client.Do(req)
+
+		if err != nil {
+			return "", err
+		}
+
+		if wait, limited := c.rateLimited(resp); limited && attempt < maxRateLimitRetries {
+			resp.Body.Close()
+
+			if err := c.sleep(ctx, wait); err != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+			return "", fmt.Errorf("%w: GET %s returned %d: %s", ErrUnexpectedStatus, u, resp.StatusCode, string(body))
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+			return "", err
+		}
+
+		if wait, exhausted := c.rateLimitReset(resp); exhausted {
+			if err := c.sleep(ctx, wait); err != nil {
+				return "", err
+			}
+		}
+
+		return nextPage(resp), nil
+	}
+}
+
+func NewClient(cfg Config, opts ...ClientOpt) *Client {
+	c := &Client{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		getNow: dt.NowUTC,
+		sleep:  sleep,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
@@ -0,0 +1,137 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// response is what the fake github sends back for one request.
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+func TestClientGet(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+	ok := response{status: http.StatusOK, body: `[{"id": 1}]`}
+
+	tests := []struct {
+		name      string
+		responses []response
+		wantSlept []time.Duration
+		wantNext  bool
+		wantErr   error
+	}{
+		{
+			name:      "last page",
+			responses: []response{ok},
+		},
+		{
+			name: "more pages",
+			responses: []response{
+				{status: http.StatusOK, headers: map[string]string{"Link": `<NEXT>; rel="next", <LAST>; rel="last"`}, body: `[]`},
+			},
+			wantNext: true,
+		},
+		{
+			name: "waits out the rate limit",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}},
+				ok,
+			},
+			wantSlept: []time.Duration{31 * time.Second},
+		},
+		{
+			name: "waits as long as it's told to",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "5"}},
+				ok,
+			},
+			wantSlept: []time.Duration{5 * time.Second},
+		},
+		{
+			name: "secondary rate limit without headers",
+			responses: []response{
+				{status: http.StatusTooManyRequests},
+				ok,
+			},
+			wantSlept: []time.Duration{secondaryRateLimitWait},
+		},
+		{
+			name: "waits when the response uses up the limit",
+			responses: []response{
+				{status: http.StatusOK, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, body: `[]`},
+			},
+			wantSlept: []time.Duration{31 * time.Second},
+		},
+		{
+			name: "forbidden for another reason",
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message": "Resource not accessible by integration"}`},
+			},
+			wantErr: ErrUnexpectedStatus,
+		},
+		{
+			name: "gives up after too many retries",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "1"}},
+			},
+			wantSlept: []time.Duration{time.Second, time.Second, time.Second, time.Second, time.Second},
+			wantErr:   ErrUnexpectedStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("expected the token to be sent, got %q", r.Header.Get("Authorization"))
+				}
+
+				// The last response repeats for any further requests.
+				resp := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+
+				for k, v := range resp.headers {
+					w.Header().Set(k, v)
+				}
+
+				w.WriteHeader(resp.status)
+				w.Write([]byte(resp.body))
+			}))
+			defer srv.Close()
+
+			slept := []time.Duration{}
+			c := NewClient(Config{BaseURL: srv.URL, Token: "token"}, WithCustomNowProvider(func() time.Time { return now }))
+			c.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			}
+
+			dest := []map[string]any{}
+			next, err := c.Get(context.Background(), "repos/acme/app/pulls", &dest)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() = %v, want %v", err, tt.wantErr)
+			}
+
+			if (next == "NEXT") != tt.wantNext {
+				t.Errorf("next = %q", next)
+			}
+
+			if !slices.Equal(slept, tt.wantSlept) {
+				t.Errorf("slept %v, want %v", slept, tt.wantSlept)
+			}
+		})
+	}
+}
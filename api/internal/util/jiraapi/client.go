@@ -0,0 +1,142 @@
+// Package jiraapi is a minimal client for jira's REST API, for reading history
+// that we missed the webhooks for. It only covers what we need, GET requests
+// and waiting out rate limits.
+package jiraapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRateLimitRetries is how many times a request refused because of a rate
+// limit is retried, before giving up.
+const maxRateLimitRetries = 5
+
+// rateLimitWait is how long to wait when jira refuses a request because of a
+// rate limit without saying for how long.
+const rateLimitWait = 30 * time.Second
+
+var ErrUnexpectedStatus = errors.New("unexpected status from jira")
+
+type Config struct {
+	// URL of the site e.g. https://acme.atlassian.net
+	URL string
+
+	// Email is for jira cloud, which takes the account's email and an API
+	// token. Leave it empty to send the token as a personal access token,
+	// which is what jira server and data center take.
+	Email string
+	Token string
+}
+
+type Client struct {
+	cfg    Config
+	client *http.Client
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+type ClientOpt func(*Client)
+
+func WithHTTPClient(hc *http.Client) ClientOpt {
+	return func(c *Client) {
+		c.client = hc
+	}
+}
+
+// sleep waits for the duration, unless the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.cfg.Token == "" {
+		return
+	}
+
+	if c.cfg.Email != "" {
+		req.SetBasicAuth(c.cfg.Email, c.cfg.Token)
+		return
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+}
+
+// Get decodes the response to the path, which is relative to the site's url,
+// into dest. Requests refused because of a rate limit are retried after
+// however long jira says to wait.
+func (c *Client) Get(ctx context.Context, path string, dest any) error {
+	u := strings.TrimSuffix(c.cfg.URL, "/") + "/" + strings.TrimPrefix(path, "/")
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Accept", "application/json")
+		c.authorize(req)
+
+		resp, err := c.client.Do(req)
+
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			resp.Body.Close()
+			wait := rateLimitWait
+
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+
+			if err := c.sleep(ctx, wait); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+			return fmt.Errorf("%w: GET %s returned %d: %s", ErrUnexpectedStatus, u, resp.StatusCode, string(body))
+		}
+
+		return json.NewDecoder(resp.Body).Decode(dest)
+	}
+}
+
+func NewClient(cfg Config, opts ...ClientOpt) *Client {
+	c := &Client{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		sleep: sleep,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
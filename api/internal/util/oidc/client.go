@@ -0,0 +1,316 @@
+// Package oidc is a minimal OpenID Connect relying party, for logging in with
+// the authorization code flow (with PKCE). It only covers what we need, the
+// claims in the id token are all that's used, the userinfo endpoint isn't.
+package oidc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamkirk/panoptes/internal/util/dt"
+)
+
+// loginTTL is how long the user has to log in with the provider.
+const loginTTL = 10 * time.Minute
+
+var ErrUnexpectedStatus = errors.New("unexpected status from identity provider")
+var ErrInvalidState = errors.New("invalid or expired login state")
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// StateKey signs the login state kept in a cookie between starting to log
+	// in and the provider redirecting back.
+	StateKey string
+}
+
+// Login is where to send the user to log in, and the value of the cookie to
+// set so that the login can be finished when they come back.
+type Login struct {
+	URL      string
+	Cookie   string
+	ExpireAt time.Time
+}
+
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ExpireAt int64  `json:"exp"`
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type Client struct {
+	cfg    Config
+	client *http.Client
+	getNow func() time.Time
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+func (c *Client) get(u string, dest any) error {
+	resp, err := c.client.Get(u)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("%w: GET %s returned %d: %s", ErrUnexpectedStatus, u, resp.StatusCode, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+// provider fetches the provider's configuration the first time it's needed.
+func (c *Client) provider() (*discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(c.cfg.IssuerURL, "/")
+	d := &discovery{}
+
+	if err := c.get(issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("issuer '%s' from the provider's configuration doesn't match '%s'", d.Issuer, c.cfg.IssuerURL)
+	}
+
+	c.discovery = d
+
+	return d, nil
+}
+
+// randomValue is for the state, nonce and verifier, which need to be
+// unguessable.
+func randomValue() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (c *Client) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(c.cfg.StateKey))
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Client) encodeState(s loginState) (string, error) {
+	raw, err := json.Marshal(s)
+
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+
+	return payload + "." + c.sign(payload), nil
+}
+
+func (c *Client) decodeState(cookie string) (*loginState, error) {
+	payload, signature, found := strings.Cut(cookie, ".")
+
+	if !found || !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return nil, ErrInvalidState
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	s := &loginState{}
+
+	if err := json.Unmarshal(raw, s); err != nil {
+		return nil, ErrInvalidState
+	}
+
+	if c.getNow().Unix() >= s.ExpireAt {
+		return nil, ErrInvalidState
+	}
+
+	return s, nil
+}
+
+// Begin starts logging in, the user should be redirected to the URL with the
+// cookie set.
+func (c *Client) Begin() (*Login, error) {
+	p, err := c.provider()
+
+	if err != nil {
+		return nil, err
+	}
+
+	expireAt := c.getNow().Add(loginTTL)
+	s := loginState{ExpireAt: expireAt.Unix()}
+
+	for _, v := range []*string{&s.State, &s.Nonce, &s.Verifier} {
+		if *v, err = randomValue(); err != nil {
+			return nil, err
+		}
+	}
+
+	cookie, err := c.encodeState(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := sha256.Sum256([]byte(s.Verifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", c.cfg.RedirectURL)
+	q.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	q.Set("state", s.State)
+	q.Set("nonce", s.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return &Login{
+		URL:      p.AuthorizationEndpoint + sep + q.Encode(),
+		Cookie:   cookie,
+		ExpireAt: expireAt,
+	}, nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (c *Client) exchange(p *discovery, code string, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	if c.cfg.ClientSecret == "" {
+		form.Set("client_id", c.cfg.ClientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.client.Do(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	tr := &tokenResponse{}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(tr); err != nil && resp.StatusCode == http.StatusOK {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token endpoint returned %d: %s %s", ErrUnexpectedStatus, resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+
+	if tr.IDToken == "" {
+		return "", fmt.Errorf("%w: no id token in the token response, is the openid scope requested?", ErrInvalidIDToken)
+	}
+
+	return tr.IDToken, nil
+}
+
+// Finish swaps the code the provider redirected back with for the id token,
+// returning its claims once verified. The cookie is the one from Begin.
+func (c *Client) Finish(cookie string, state string, code string) (*Claims, error) {
+	s, err := c.decodeState(cookie)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(s.State), []byte(state)) {
+		return nil, ErrInvalidState
+	}
+
+	p, err := c.provider()
+
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := c.exchange(p, code, s.Verifier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.verify(p, idToken, s.Nonce)
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		getNow: dt.NowUTC,
+	}
+}
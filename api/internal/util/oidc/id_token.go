@@ -0,0 +1,297 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keysRefetchInterval is the least time between fetching the provider's keys
+// to look for one we don't have.
+const keysRefetchInterval = time.Minute
+
+// Claims are the claims from a verified id token.
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+
+	// Raw has every claim, for provider specific ones like groups.
+	Raw map[string]any
+}
+
+// Strings returns a claim that's either a string or a list of strings, like
+// groups usually are.
+func (c *Claims) Strings(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		vals := []string{}
+
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				vals = append(vals, s)
+			}
+		}
+
+		return vals
+	}
+
+	return []string{}
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	s, _ := claims[name].(string)
+
+	return s
+}
+
+// audiences handles aud being either a string or a list of them.
+func audiences(claims jwt.MapClaims) []string {
+	switch v := claims["aud"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		auds := []string{}
+
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+
+		return auds
+	}
+
+	return []string{}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}
+
+		curve, ok := curves[k.Crv]
+
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+func (c *Client) fetchKeys(p *discovery) error {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+
+	if err := c.get(p.JwksURI, &set); err != nil {
+		return err
+	}
+
+	keys := map[string]any{}
+
+	for _, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+
+		// Keys we can't use are skipped rather than failing, the provider may
+		// publish ones we don't need.
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	return nil
+}
+
+// lookup finds the key with the id, the caller must hold the lock.
+func (c *Client) lookup(kid string) (any, bool) {
+	if k, ok := c.keys[kid]; ok {
+		return k, true
+	}
+
+	// Providers with a single key don't always give it an id.
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Client) key(p *discovery, kid string) (any, error) {
+	c.mu.Lock()
+	k, ok := c.lookup(kid)
+
+	// The provider may have rotated its keys since we last fetched them. They
+	// aren't fetched again within keysRefetchInterval, so a key id that wasn't
+	// found is treated as missing until then, otherwise anyone could make us
+	// fetch them on every request by making up key ids.
+	refetch := !ok && c.getNow().Sub(c.keysFetchedAt) >= keysRefetchInterval
+
+	if refetch {
+		c.keysFetchedAt = c.getNow()
+	}
+
+	c.mu.Unlock()
+
+	if ok {
+		return k, nil
+	}
+
+	if !refetch {
+		return nil, fmt.Errorf("no key found with id '%s'", kid)
+	}
+
+	if err := c.fetchKeys(p); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+
+	return nil, fmt.Errorf("no key found with id '%s'", kid)
+}
+
+func (c *Client) verify(p *discovery, idToken string, nonce string) (*Claims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := c.key(p, kid)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Otherwise the token could pick the algorithm, e.g. HS256 with the
+		// public key as the secret.
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA:
+			if _, ok := key.(*rsa.PublicKey); ok {
+				return key, nil
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := key.(*ecdsa.PublicKey); ok {
+				return key, nil
+			}
+		}
+
+		return nil, fmt.Errorf("unexpected signing method: %s", t.Header["alg"])
+	}, jwt.WithTimeFunc(c.getNow))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err.Error())
+	}
+
+	if _, hasExp := claims["exp"]; !hasExp {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	}
+
+	if claimString(claims, "iss") != p.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+
+	if !slices.Contains(audiences(claims), c.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidIDToken)
+	}
+
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce doesn't match", ErrInvalidIDToken)
+	}
+
+	if claimString(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	verified, _ := claims["email_verified"].(bool)
+
+	return &Claims{
+		Issuer:        claimString(claims, "iss"),
+		Subject:       claimString(claims, "sub"),
+		Email:         claimString(claims, "email"),
+		EmailVerified: verified,
+		Name:          claimString(claims, "name"),
+		GivenName:     claimString(claims, "given_name"),
+		FamilyName:    claimString(claims, "family_name"),
+		Raw:           claims,
+	}, nil
+}
@@ -0,0 +1,318 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://id.example.com"
+const testClientID = "panoptes"
+const testNonce = "nonce"
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", N: encodeBigInt(k.N), E: encodeBigInt(big.NewInt(int64(k.E)))}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) jwk {
+	return jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encodeBigInt(k.X), Y: encodeBigInt(k.Y)}
+}
+
+// jwks serves a key set, counting how many times it's fetched.
+type jwks struct {
+	mu      sync.Mutex
+	keys    []jwk
+	fetches int
+}
+
+func (s *jwks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetches++
+	json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+}
+
+func (s *jwks) serve(keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"nonce":          testNonce,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"email":          "alice@example.com",
+		"email_verified": true,
+	}
+}
+
+func with(changes map[string]any) jwt.MapClaims {
+	claims := validClaims()
+
+	for k, v := range changes {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+
+		claims[k] = v
+	}
+
+	return claims
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	otherKey := generateRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := &jwks{}
+	keys.serve(rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey))
+	srv := httptest.NewServer(keys)
+	defer srv.Close()
+
+	hmacKey, _ := json.Marshal(rsaJWK("rsa", &rsaKey.PublicKey))
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr bool
+	}{
+		{
+			name:  "valid rsa token",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()) },
+		},
+		{
+			name:  "valid ec token",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodES256, "ec", ecKey, validClaims()) },
+		},
+		{
+			name: "one of several audiences",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"aud": []string{"other", testClientID}}))
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"iss": "https://evil.example.com"}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "issued for another client",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"aud": "other"}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong nonce",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"nonce": "replayed"}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"exp": time.Now().Add(-time.Minute).Unix()}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "no expiry",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"exp": nil}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "no subject",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(map[string]any{"sub": nil}))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "signed by another key",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa", otherKey, validClaims()) },
+			wantErr: true,
+		},
+		{
+			name:    "hmac signed with the public key",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodHS256, "rsa", hmacKey, validClaims()) },
+			wantErr: true,
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name:    "rsa algorithm with the ec key",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "ec", rsaKey, validClaims()) },
+			wantErr: true,
+		},
+		{
+			name:    "unknown key id",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "missing", rsaKey, validClaims()) },
+			wantErr: true,
+		},
+	}
+
+	p := &discovery{Issuer: testIssuer, JwksURI: srv.URL}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(Config{IssuerURL: testIssuer, ClientID: testClientID})
+			claims, err := c.verify(p, tt.token(t), testNonce)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Errorf("verify() = %v, want %v", err, ErrInvalidIDToken)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if claims.Subject != "user-1" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.Issuer != testIssuer {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	oldKey := generateRSAKey(t)
+	newKey := generateRSAKey(t)
+
+	keys := &jwks{}
+	keys.serve(rsaJWK("old", &oldKey.PublicKey))
+	srv := httptest.NewServer(keys)
+	defer srv.Close()
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	c := NewClient(Config{IssuerURL: testIssuer, ClientID: testClientID})
+	c.getNow = func() time.Time { return now }
+	p := &discovery{Issuer: testIssuer, JwksURI: srv.URL}
+
+	steps := []struct {
+		name        string
+		advance     time.Duration
+		token       string
+		wantErr     bool
+		wantFetches int
+	}{
+		{
+			name:        "keys are fetched the first time they're needed",
+			token:       sign(t, jwt.SigningMethodRS256, "old", oldKey, validClaims()),
+			wantFetches: 1,
+		},
+		{
+			name:        "known keys aren't fetched again",
+			advance:     2 * time.Minute,
+			token:       sign(t, jwt.SigningMethodRS256, "old", oldKey, validClaims()),
+			wantFetches: 1,
+		},
+		{
+			name:        "an unknown key id fetches them again",
+			token:       sign(t, jwt.SigningMethodRS256, "made-up", oldKey, validClaims()),
+			wantErr:     true,
+			wantFetches: 2,
+		},
+		{
+			name:        "but not again within a minute",
+			advance:     30 * time.Second,
+			token:       sign(t, jwt.SigningMethodRS256, "new", newKey, validClaims()),
+			wantErr:     true,
+			wantFetches: 2,
+		},
+		{
+			name:        "rotated keys are picked up after a minute",
+			advance:     30 * time.Second,
+			token:       sign(t, jwt.SigningMethodRS256, "new", newKey, validClaims()),
+			wantFetches: 3,
+		},
+	}
+
+	for i, step := range steps {
+		// The provider rotates its keys after the first fetches.
+		if i == 3 {
+			keys.serve(rsaJWK("old", &oldKey.PublicKey), rsaJWK("new", &newKey.PublicKey))
+		}
+
+		now = now.Add(step.advance)
+		_, err := c.verify(p, step.token, testNonce)
+
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+
+		if keys.fetches != step.wantFetches {
+			t.Fatalf("%s: fetched the keys %d times, want %d", step.name, keys.fetches, step.wantFetches)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "projection_checkpoints"(
+   "name" TEXT PRIMARY KEY,
+   "occurred_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL,
+   "event_id" UUID NOT NULL,
+   "updated_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL
+);
+
+COMMENT ON TABLE "projection_checkpoints" IS 'Lives in the projection database, alongside the projections themselves.';
+COMMENT ON COLUMN "projection_checkpoints"."name" IS 'The name of the projection.';
+COMMENT ON COLUMN "projection_checkpoints"."occurred_at" IS 'The occurred_at of the last event applied to the projection.';
+COMMENT ON COLUMN "projection_checkpoints"."event_id" IS 'The id of the last event applied, events are ordered by occurred_at then id so both are needed to know where to carry on from.';
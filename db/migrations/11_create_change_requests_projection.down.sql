@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "change_requests";
\ No newline at end of file
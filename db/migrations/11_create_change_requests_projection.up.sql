@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS "change_requests"(
+   "id" TEXT NOT NULL,
+   "source_integration" TEXT NOT NULL,
+   "number" INTEGER NOT NULL,
+   "title" TEXT NOT NULL,
+   "url" TEXT NOT NULL,
+   "author" TEXT NOT NULL,
+   "repository" TEXT NOT NULL,
+   "base_branch" TEXT NOT NULL,
+   "head_branch" TEXT NOT NULL,
+   "state" TEXT NOT NULL,
+   "is_draft" BOOLEAN NOT NULL,
+   "opened_at" TIMESTAMP (6) WITH TIME ZONE,
+   "first_commit_at" TIMESTAMP (6) WITH TIME ZONE,
+   "first_review_at" TIMESTAMP (6) WITH TIME ZONE,
+   "approved_at" TIMESTAMP (6) WITH TIME ZONE,
+   "merged_at" TIMESTAMP (6) WITH TIME ZONE,
+   "closed_at" TIMESTAMP (6) WITH TIME ZONE,
+   "additions" INTEGER NOT NULL,
+   "deletions" INTEGER NOT NULL,
+   "changed_files" INTEGER NOT NULL,
+   "commits" INTEGER NOT NULL,
+   "review_count" INTEGER NOT NULL,
+   "approval_count" INTEGER NOT NULL,
+   "changes_requested_count" INTEGER NOT NULL,
+   "comment_count" INTEGER NOT NULL,
+   "last_event_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL,
+   "last_event_id" UUID NOT NULL,
+   PRIMARY KEY ("source_integration", "id")
+);
+
+COMMENT ON TABLE "change_requests" IS 'Projection of the change_requests_stream, one row per change request. Lives in the projection database.';
+COMMENT ON COLUMN "change_requests"."id" IS 'The aggregate_id from change_requests_stream.';
+COMMENT ON COLUMN "change_requests"."state" IS 'One of open, closed or merged.';
+COMMENT ON COLUMN "change_requests"."first_review_at" IS 'The first review by someone other than the author.';
+COMMENT ON COLUMN "change_requests"."approved_at" IS 'The first approval, later approvals (e.g. after a re-review) do not move this.';
+COMMENT ON COLUMN "change_requests"."last_event_at" IS 'Along with last_event_id, marks the last event applied to the row, so that events seen again are ignored.';
+
+CREATE INDEX IF NOT EXISTS "change_requests_repository_idx" ON "change_requests" ("repository");
+CREATE INDEX IF NOT EXISTS "change_requests_opened_at_idx" ON "change_requests" ("opened_at");
+CREATE INDEX IF NOT EXISTS "change_requests_merged_at_idx" ON "change_requests" ("merged_at");
@@ -0,0 +1,2 @@
+DELETE FROM "roles_permissions" WHERE "permission_id" IN (SELECT "id" FROM "permissions" WHERE "name" IN ('ingest.github', 'ingest.jira', 'users.get', 'metrics.dora.get', 'ingest.*', 'users.*', 'metrics.*'));
+DELETE FROM "permissions" WHERE "name" IN ('ingest.github', 'ingest.jira', 'users.get', 'metrics.dora.get', 'ingest.*', 'users.*', 'metrics.*');
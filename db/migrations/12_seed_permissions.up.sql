@@ -0,0 +1,13 @@
+-- One permission for every scope an operation can require, the api won't start
+-- if an operation requires a scope that isn't here. Static ids for the same
+-- reason as the superuser role.
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('a76a429d-42e1-4bc1-9fa9-7d087ae43438', 'ingest.github'),
+   ('08a61cc4-375b-4ecf-8782-9e20c4d84d5c', 'ingest.jira'),
+   ('1266c005-4165-4d08-bc5d-76e9acb82676', 'users.get'),
+   ('e7a7b52d-8bfb-4912-8d8d-c190a730b146', 'metrics.dora.get'),
+   -- Wildcards grant every scope under the prefix, including ones added later.
+   ('016a0d0d-3986-4b02-9ff3-7f766d3fd93d', 'ingest.*'),
+   ('967ca2ed-a1d3-4524-adf2-856a17f31945', 'users.*'),
+   ('3866aaef-3d62-4aae-b577-8a35a91f9c70', 'metrics.*')
+ON CONFLICT ("name") DO NOTHING;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "user_access_tokens_user_id_idx";
+
+ALTER TABLE "user_access_tokens" DROP COLUMN IF EXISTS "created_at";
+ALTER TABLE "user_access_tokens" DROP COLUMN IF EXISTS "last_used_at";
+ALTER TABLE "user_access_tokens" DROP COLUMN IF EXISTS "revoked_at";
@@ -0,0 +1,9 @@
+ALTER TABLE "user_access_tokens" ADD COLUMN IF NOT EXISTS "revoked_at" TIMESTAMP (6) WITH TIME ZONE;
+ALTER TABLE "user_access_tokens" ADD COLUMN IF NOT EXISTS "last_used_at" TIMESTAMP (6) WITH TIME ZONE;
+ALTER TABLE "user_access_tokens" ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMP (6) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS "user_access_tokens_user_id_idx" ON "user_access_tokens" ("user_id");
+
+COMMENT ON COLUMN "user_access_tokens"."revoked_at" IS 'When the token was revoked, a revoked token no longer works even if it has not expired.';
+COMMENT ON COLUMN "user_access_tokens"."last_used_at" IS 'Roughly when the token was last used to authenticate, only updated every few minutes to save on writes.';
+COMMENT ON COLUMN "user_access_tokens"."created_at" IS 'When the token was generated, null for tokens generated before this was tracked.';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "users_created_at_id_idx";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "created_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deactivated_at";
@@ -0,0 +1,7 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deactivated_at" TIMESTAMP (6) WITH TIME ZONE;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS "users_created_at_id_idx" ON "users" ("created_at", "id");
+
+COMMENT ON COLUMN "users"."deactivated_at" IS 'When the user was deactivated, deactivated users cannot authenticate. Users are never deleted as other records refer to them.';
+COMMENT ON COLUMN "users"."created_at" IS 'When the user was created, users that existed before this was tracked have the time of the migration.';
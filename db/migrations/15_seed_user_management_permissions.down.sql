@@ -0,0 +1,2 @@
+DELETE FROM "roles_permissions" WHERE "permission_id" IN (SELECT "id" FROM "permissions" WHERE "name" IN ('users.list', 'users.create', 'users.update', 'users.delete', 'users.roles.assign'));
+DELETE FROM "permissions" WHERE "name" IN ('users.list', 'users.create', 'users.update', 'users.delete', 'users.roles.assign');
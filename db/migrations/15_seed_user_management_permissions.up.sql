@@ -0,0 +1,7 @@
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('756916fd-fc31-4a7e-8d94-eeaa454f943b', 'users.list'),
+   ('6b415454-6876-4e30-8b67-ece14db1a3fd', 'users.create'),
+   ('7d20e88e-f0fa-4cef-96eb-32ab8f0cc9ef', 'users.update'),
+   ('37237d07-d9bb-4235-977e-294d84ce00ea', 'users.delete'),
+   ('3811b947-b045-471d-afd5-1d16567fa4bc', 'users.roles.assign')
+ON CONFLICT ("name") DO NOTHING;
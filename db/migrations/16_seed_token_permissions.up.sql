@@ -0,0 +1,9 @@
+-- tokens.manage is needed to manage other users' tokens, on top of the scope
+-- for the operation.
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('465a8258-a039-4233-8af2-7f6923cc4257', 'tokens.create'),
+   ('3b4ad94c-17b2-43b6-8676-bc5652f84d99', 'tokens.list'),
+   ('b5472961-cb82-4275-9522-01efe1d9987a', 'tokens.revoke'),
+   ('30f3c32d-efd9-4e20-8f18-7662807bdf98', 'tokens.manage'),
+   ('303c306b-9827-4bf7-9216-c1701de56cd5', 'tokens.*')
+ON CONFLICT ("name") DO NOTHING;
@@ -0,0 +1,5 @@
+DELETE FROM "user_roles" WHERE "role_id" IN (SELECT "id" FROM "roles" WHERE "name" IN ('ingestor', 'viewer'));
+DELETE FROM "roles_permissions" WHERE "role_id" IN (SELECT "id" FROM "roles" WHERE "name" IN ('ingestor', 'viewer'));
+DELETE FROM "roles" WHERE "name" IN ('ingestor', 'viewer');
+DELETE FROM "roles_permissions" WHERE "permission_id" IN (SELECT "id" FROM "permissions" WHERE "name" IN ('roles.list', 'roles.create', 'roles.permissions.grant', 'roles.permissions.revoke', 'roles.*'));
+DELETE FROM "permissions" WHERE "name" IN ('roles.list', 'roles.create', 'roles.permissions.grant', 'roles.permissions.revoke', 'roles.*');
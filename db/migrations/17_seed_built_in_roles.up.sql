@@ -0,0 +1,24 @@
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('35eb0ae0-5862-4205-843f-2ced54b6d96d', 'roles.list'),
+   ('da1bfe62-32be-45f8-a74a-b1ea5efcd294', 'roles.create'),
+   ('5595e0dc-6883-4182-8b1f-a9e235b9bc2f', 'roles.permissions.grant'),
+   ('0a7b4cc1-2428-4490-b8e2-b05cb4e41fc8', 'roles.permissions.revoke'),
+   ('fa5015e1-acc5-484f-91bf-e3244b33c936', 'roles.*')
+ON CONFLICT ("name") DO NOTHING;
+
+-- Built in roles, so that tokens for things like webhooks don't need to be
+-- superuser tokens. Static ids for the same reason as the superuser role.
+INSERT INTO "roles" ("id", "name") VALUES
+   ('25a337f0-3d45-43fc-b4b3-5f1d8e509663', 'ingestor'),
+   ('78d644fd-de73-4808-8a17-e2f17397eb73', 'viewer')
+ON CONFLICT ("name") DO NOTHING;
+
+INSERT INTO "roles_permissions" ("id", "role_id", "permission_id")
+SELECT '46daea7f-d7cb-434b-9b3d-cf16cb10fbea', r."id", p."id" FROM "roles" r, "permissions" p
+WHERE r."name" = 'ingestor' AND p."name" = 'ingest.*'
+ON CONFLICT ("role_id", "permission_id") DO NOTHING;
+
+INSERT INTO "roles_permissions" ("id", "role_id", "permission_id")
+SELECT '7cc974bd-367f-49a0-864e-ab1c9fc1e1de', r."id", p."id" FROM "roles" r, "permissions" p
+WHERE r."name" = 'viewer' AND p."name" = 'metrics.*'
+ON CONFLICT ("role_id", "permission_id") DO NOTHING;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "user_sessions"(
+   "id" UUID PRIMARY KEY,
+   "user_id" UUID NOT NULL,
+   "refresh_secret" TEXT NOT NULL,
+   "expires_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL,
+   "revoked_at" TIMESTAMP (6) WITH TIME ZONE,
+   "refreshed_at" TIMESTAMP (6) WITH TIME ZONE,
+   "created_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   CONSTRAINT fk_user_id FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS "user_sessions_user_id_idx" ON "user_sessions" ("user_id");
+
+COMMENT ON TABLE "user_sessions" IS 'Sessions started by logging in with a password, the session tokens themselves are signed rather than stored.';
+COMMENT ON COLUMN "user_sessions"."refresh_secret" IS 'Hash of the current refresh token secret, replaced every time the session is refreshed.';
+COMMENT ON COLUMN "user_sessions"."expires_at" IS 'When the session can no longer be refreshed, it is not extended by refreshing.';
+COMMENT ON COLUMN "user_sessions"."revoked_at" IS 'When the user logged out, or a refresh token was reused.';
@@ -0,0 +1,4 @@
+COMMENT ON COLUMN "users"."password" IS 'The users password (hashed), cannot be read at a database level.';
+DROP INDEX IF EXISTS "users_oidc_identity_unique_idx";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "oidc_subject";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "oidc_issuer";
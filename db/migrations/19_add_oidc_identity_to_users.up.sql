@@ -0,0 +1,8 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "oidc_issuer" TEXT;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "oidc_subject" TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "users_oidc_identity_unique_idx" ON "users" ("oidc_issuer", "oidc_subject");
+
+COMMENT ON COLUMN "users"."oidc_issuer" IS 'The OpenID Connect provider the user logs in with, if any.';
+COMMENT ON COLUMN "users"."oidc_subject" IS 'The users id at the OpenID Connect provider, stable unlike their email.';
+COMMENT ON COLUMN "users"."password" IS 'The users password (hashed), cannot be read at a database level. Empty for users created by logging in with an OpenID Connect provider, who cannot log in with a password until one is set.';
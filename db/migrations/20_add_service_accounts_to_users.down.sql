@@ -0,0 +1,10 @@
+DELETE FROM "user_roles" WHERE "user_id" IN (SELECT "id" FROM "users" WHERE "type" = 'service_account');
+DELETE FROM "user_access_tokens" WHERE "user_id" IN (SELECT "id" FROM "users" WHERE "type" = 'service_account');
+DELETE FROM "user_sessions" WHERE "user_id" IN (SELECT "id" FROM "users" WHERE "type" = 'service_account');
+DELETE FROM "users" WHERE "type" = 'service_account';
+
+DROP INDEX IF EXISTS "users_name_unique_idx";
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_type_check";
+ALTER TABLE "users" ALTER COLUMN "email" SET NOT NULL;
+ALTER TABLE "users" DROP COLUMN IF EXISTS "name";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "type";
@@ -0,0 +1,14 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "type" TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "name" TEXT;
+ALTER TABLE "users" ALTER COLUMN "email" DROP NOT NULL;
+
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_type_check";
+ALTER TABLE "users" ADD CONSTRAINT "users_type_check" CHECK (
+   ("type" = 'user' AND "email" IS NOT NULL)
+   OR ("type" = 'service_account' AND "name" IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "users_name_unique_idx" ON "users" ("name");
+
+COMMENT ON COLUMN "users"."type" IS 'Either user, for people, or service_account, for integrations. Service accounts have no email, names or password, so can only authenticate with access tokens.';
+COMMENT ON COLUMN "users"."name" IS 'The name of a service account, always null for users.';
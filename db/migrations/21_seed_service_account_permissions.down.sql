@@ -0,0 +1,2 @@
+DELETE FROM "roles_permissions" WHERE "permission_id" IN (SELECT "id" FROM "permissions" WHERE "name" IN ('service_accounts.list', 'service_accounts.create', 'service_accounts.get', 'service_accounts.update', 'service_accounts.delete', 'service_accounts.roles.assign', 'service_accounts.*'));
+DELETE FROM "permissions" WHERE "name" IN ('service_accounts.list', 'service_accounts.create', 'service_accounts.get', 'service_accounts.update', 'service_accounts.delete', 'service_accounts.roles.assign', 'service_accounts.*');
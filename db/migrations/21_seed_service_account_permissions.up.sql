@@ -0,0 +1,9 @@
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('b31574d5-a7e8-4f87-841f-b8eefe7839f7', 'service_accounts.list'),
+   ('e20c871f-bac9-466d-898f-bf7b022fb557', 'service_accounts.create'),
+   ('18c685f7-55b3-4a50-a075-514f86b959b3', 'service_accounts.get'),
+   ('a3422142-abcf-4dbe-817c-8888f5a1a649', 'service_accounts.update'),
+   ('deb28f9a-1487-4e87-9b85-a5b50092fd5d', 'service_accounts.delete'),
+   ('71b9262c-2269-465d-ad3e-b5fa87135c34', 'service_accounts.roles.assign'),
+   ('c0fe94f2-8e82-4ff0-a4a8-6819d6da06ed', 'service_accounts.*')
+ON CONFLICT ("name") DO NOTHING;
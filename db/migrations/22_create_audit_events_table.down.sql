@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "audit_events";
+DROP FUNCTION IF EXISTS "audit_events_append_only";
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS "audit_events"(
+   "id" UUID PRIMARY KEY,
+   "seq" BIGINT GENERATED ALWAYS AS IDENTITY,
+   "occurred_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL,
+   "action" TEXT NOT NULL,
+   "outcome" TEXT NOT NULL,
+   "actor_type" TEXT NOT NULL,
+   "actor_id" TEXT,
+   "actor_token_id" TEXT,
+   "target_type" TEXT,
+   "target_id" TEXT,
+   "request_id" TEXT,
+   "source_ip" TEXT,
+   "details" JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "audit_events_seq_idx" ON "audit_events" ("seq");
+CREATE INDEX IF NOT EXISTS "audit_events_actor_id_seq_idx" ON "audit_events" ("actor_id", "seq");
+CREATE INDEX IF NOT EXISTS "audit_events_target_id_seq_idx" ON "audit_events" ("target_id", "seq");
+CREATE INDEX IF NOT EXISTS "audit_events_action_seq_idx" ON "audit_events" ("action", "seq");
+
+-- Nothing, including the app, should be able to change or remove what's been
+-- recorded.
+CREATE OR REPLACE FUNCTION "audit_events_append_only"() RETURNS TRIGGER AS $$
+BEGIN
+   RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "audit_events_append_only" ON "audit_events";
+CREATE TRIGGER "audit_events_append_only"
+   BEFORE UPDATE OR DELETE OR TRUNCATE ON "audit_events"
+   FOR EACH STATEMENT EXECUTE FUNCTION "audit_events_append_only"();
+
+COMMENT ON TABLE "audit_events" IS 'Append-only record of security relevant actions, like managing users and tokens, calls to privileged endpoints and failed authentication.';
+COMMENT ON COLUMN "audit_events"."seq" IS 'Increases with every event, for ordering and following the log.';
+COMMENT ON COLUMN "audit_events"."outcome" IS 'success, failure (e.g. an error or failed authentication) or denied (authenticated, but without permission).';
+COMMENT ON COLUMN "audit_events"."actor_type" IS 'The kind of principal, e.g. user, service_account, master, cli, or anonymous when not authenticated.';
+COMMENT ON COLUMN "audit_events"."actor_token_id" IS 'The access token the actor authenticated with, if any.';
+COMMENT ON COLUMN "audit_events"."request_id" IS 'The X-Request-Id of the API request, empty for the CLI.';
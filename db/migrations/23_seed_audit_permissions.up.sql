@@ -0,0 +1,4 @@
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('ea758680-b4f2-49df-9e02-f808b00b5130', 'audit.list'),
+   ('da00729f-d20f-4032-9a5b-2e9357b998ee', 'audit.*')
+ON CONFLICT ("name") DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "ingestion_dead_letters";
+DROP TABLE IF EXISTS "ingestion_queue";
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS "ingestion_queue"(
+   "id" UUID PRIMARY KEY,
+   "integration" TEXT NOT NULL,
+   "delivery_id" TEXT NOT NULL,
+   "event" TEXT NOT NULL,
+   "payload" JSONB NOT NULL,
+   "attempts" INTEGER NOT NULL DEFAULT 0,
+   "last_error" TEXT,
+   "available_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL,
+   "locked_until" TIMESTAMP (6) WITH TIME ZONE,
+   "received_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ingestion_queue_available_at_idx" ON "ingestion_queue" ("available_at");
+
+COMMENT ON TABLE "ingestion_queue" IS 'Webhook deliveries waiting to be processed into the event store, they are removed once processed.';
+COMMENT ON COLUMN "ingestion_queue"."attempts" IS 'How many times processing has been attempted, counted when claimed so that crashes count too.';
+COMMENT ON COLUMN "ingestion_queue"."available_at" IS 'When the delivery can next be attempted, pushed back after each failure.';
+COMMENT ON COLUMN "ingestion_queue"."locked_until" IS 'Set while a worker is processing the delivery, it can be claimed again afterwards in case the worker died.';
+
+CREATE TABLE IF NOT EXISTS "ingestion_dead_letters"(
+   "id" UUID PRIMARY KEY,
+   "integration" TEXT NOT NULL,
+   "delivery_id" TEXT NOT NULL,
+   "event" TEXT NOT NULL,
+   "payload" JSONB NOT NULL,
+   "attempts" INTEGER NOT NULL,
+   "last_error" TEXT NOT NULL,
+   "received_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL,
+   "failed_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ingestion_dead_letters_failed_at_idx" ON "ingestion_dead_letters" ("failed_at");
+
+COMMENT ON TABLE "ingestion_dead_letters" IS 'Webhook deliveries that failed to process after the maximum attempts, kept until they are retried.';
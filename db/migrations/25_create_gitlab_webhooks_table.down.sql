@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "gitlab_webhooks";
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "gitlab_webhooks"(
+   "id" UUID PRIMARY KEY,
+   "delivery_id" TEXT DEFAULT NULL,
+   "event" TEXT DEFAULT NULL,
+   "occurred_at" TIMESTAMP (6) WITH TIME ZONE,
+   "payload" JSON NOT NULL
+);
+
+COMMENT ON COLUMN "gitlab_webhooks"."delivery_id" IS 'The X-Gitlab-Event-UUID header, the same when a delivery is resent.
+Older versions of GitLab do not send it, so it is nullable.';
+COMMENT ON COLUMN "gitlab_webhooks"."event" IS 'The X-Gitlab-Event header e.g. Merge Request Hook.';
+
+CREATE UNIQUE INDEX IF NOT EXISTS "gitlab_webhooks_delivery_id_unique_idx" ON "gitlab_webhooks" ("delivery_id");
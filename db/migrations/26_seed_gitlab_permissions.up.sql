@@ -0,0 +1,3 @@
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('ed743ffc-7aab-477b-aeb7-fec8488954b5', 'ingest.gitlab')
+ON CONFLICT ("name") DO NOTHING;
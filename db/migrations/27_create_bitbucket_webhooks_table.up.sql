@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "bitbucket_webhooks"(
+   "id" UUID PRIMARY KEY,
+   "delivery_id" TEXT DEFAULT NULL,
+   "event" TEXT DEFAULT NULL,
+   "occurred_at" TIMESTAMP (6) WITH TIME ZONE,
+   "payload" JSON NOT NULL
+);
+
+COMMENT ON COLUMN "bitbucket_webhooks"."delivery_id" IS 'The X-Request-UUID header, the same when a delivery is retried.
+Nullable in case it is not sent.';
+COMMENT ON COLUMN "bitbucket_webhooks"."event" IS 'The X-Event-Key header e.g. pullrequest:created.';
+
+CREATE UNIQUE INDEX IF NOT EXISTS "bitbucket_webhooks_delivery_id_unique_idx" ON "bitbucket_webhooks" ("delivery_id");
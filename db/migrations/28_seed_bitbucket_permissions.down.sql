@@ -0,0 +1,2 @@
+DELETE FROM "roles_permissions" WHERE "permission_id" IN (SELECT "id" FROM "permissions" WHERE "name" IN ('ingest.bitbucket'));
+DELETE FROM "permissions" WHERE "name" IN ('ingest.bitbucket');
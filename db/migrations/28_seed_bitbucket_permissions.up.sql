@@ -0,0 +1,3 @@
+INSERT INTO "permissions" ("id", "name") VALUES
+   ('cf006cad-402e-44ad-b5da-0fc7c419aac6', 'ingest.bitbucket')
+ON CONFLICT ("name") DO NOTHING;
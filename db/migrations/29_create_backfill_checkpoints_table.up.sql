@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "backfill_checkpoints"(
+   "name" TEXT PRIMARY KEY,
+   "cursor" TEXT DEFAULT NULL,
+   "completed_at" TIMESTAMP (6) WITH TIME ZONE DEFAULT NULL,
+   "updated_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL
+);
+
+COMMENT ON COLUMN "backfill_checkpoints"."name" IS 'Identifies what is being backfilled e.g. github:acme/api:pulls:2024-01-01T00:00:00Z.';
+COMMENT ON COLUMN "backfill_checkpoints"."cursor" IS 'Where to carry on from, e.g. the url of the next page, null to start from the beginning.';
+COMMENT ON COLUMN "backfill_checkpoints"."completed_at" IS 'Set once everything has been backfilled, so it is skipped if run again.';
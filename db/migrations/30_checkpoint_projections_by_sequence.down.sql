@@ -0,0 +1,14 @@
+DELETE FROM "projection_checkpoints";
+TRUNCATE "change_requests";
+
+ALTER TABLE "change_requests" DROP COLUMN IF EXISTS "last_event_seq";
+ALTER TABLE "change_requests" ADD COLUMN IF NOT EXISTS "last_event_id" UUID NOT NULL;
+
+COMMENT ON COLUMN "change_requests"."last_event_at" IS 'Along with last_event_id, marks the last event applied to the row, so that events seen again are ignored.';
+
+ALTER TABLE "projection_checkpoints" DROP COLUMN IF EXISTS "seq";
+ALTER TABLE "projection_checkpoints" ADD COLUMN IF NOT EXISTS "occurred_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL;
+ALTER TABLE "projection_checkpoints" ADD COLUMN IF NOT EXISTS "event_id" UUID NOT NULL;
+
+DROP INDEX IF EXISTS "change_requests_stream_seq_idx";
+ALTER TABLE "change_requests_stream" DROP COLUMN IF EXISTS "seq";
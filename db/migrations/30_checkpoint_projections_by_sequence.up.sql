@@ -0,0 +1,41 @@
+-- Events are numbered in the order they're inserted, as that's the only order
+-- a projection can rely on. occurred_at isn't, as events can be recorded long
+-- after they occurred (e.g. from a backfill or a delayed webhook).
+ALTER TABLE "change_requests_stream" ADD COLUMN IF NOT EXISTS "seq" BIGINT;
+
+CREATE SEQUENCE IF NOT EXISTS "change_requests_stream_seq_seq" OWNED BY "change_requests_stream"."seq";
+
+-- Number what's already there in the order it was being projected.
+UPDATE "change_requests_stream" SET "seq" = "numbered"."seq"
+FROM (
+   SELECT "id", nextval('change_requests_stream_seq_seq') AS "seq"
+   FROM (SELECT "id" FROM "change_requests_stream" ORDER BY "occurred_at", "id") AS "ordered"
+) AS "numbered"
+WHERE "change_requests_stream"."id" = "numbered"."id";
+
+ALTER TABLE "change_requests_stream" ALTER COLUMN "seq" SET DEFAULT nextval('change_requests_stream_seq_seq');
+ALTER TABLE "change_requests_stream" ALTER COLUMN "seq" SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "change_requests_stream_seq_idx" ON "change_requests_stream" ("seq");
+
+COMMENT ON COLUMN "change_requests_stream"."seq" IS 'The order events were inserted in, projections are checkpointed against it.
+Appends are serialised so that a lower seq is never committed after a higher one.';
+
+-- Checkpoints can't be translated to a seq, as the projection db may be a
+-- different database to the event store. Without one the projections are
+-- rebuilt from the whole stream, which picks up anything that was skipped by
+-- the old checkpoints.
+DELETE FROM "projection_checkpoints";
+TRUNCATE "change_requests";
+
+ALTER TABLE "change_requests" DROP COLUMN IF EXISTS "last_event_id";
+ALTER TABLE "change_requests" ADD COLUMN IF NOT EXISTS "last_event_seq" BIGINT NOT NULL;
+
+COMMENT ON COLUMN "change_requests"."last_event_at" IS 'When the latest event applied to the row occurred, older events recorded late only fill in what is missing.';
+COMMENT ON COLUMN "change_requests"."last_event_seq" IS 'The highest seq applied to the row, so that events seen again are ignored.';
+
+ALTER TABLE "projection_checkpoints" DROP COLUMN IF EXISTS "occurred_at";
+ALTER TABLE "projection_checkpoints" DROP COLUMN IF EXISTS "event_id";
+ALTER TABLE "projection_checkpoints" ADD COLUMN IF NOT EXISTS "seq" BIGINT NOT NULL;
+
+COMMENT ON COLUMN "projection_checkpoints"."seq" IS 'The seq of the last event applied to the projection.';
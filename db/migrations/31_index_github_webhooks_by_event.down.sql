@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "github_webhooks_event_occurred_at_idx";
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS "github_webhooks_event_occurred_at_idx" ON "github_webhooks" ("event", "occurred_at");
+COMMENT ON INDEX "github_webhooks_event_occurred_at_idx" IS 'For reading the webhooks of the events the DORA metrics need, since a time.';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "user_sessions_refresh_id_idx";
+ALTER TABLE "user_sessions" DROP COLUMN IF EXISTS "refresh_id";
@@ -0,0 +1,11 @@
+ALTER TABLE "user_sessions" ADD COLUMN IF NOT EXISTS "refresh_id" TEXT;
+
+-- Refresh tokens used to start with the session id, so existing ones stop
+-- working and their users need to log in again.
+UPDATE "user_sessions" SET "refresh_id" = md5(random()::text || "id"::text) WHERE "refresh_id" IS NULL;
+
+ALTER TABLE "user_sessions" ALTER COLUMN "refresh_id" SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "user_sessions_refresh_id_idx" ON "user_sessions" ("refresh_id");
+
+COMMENT ON COLUMN "user_sessions"."refresh_id" IS 'Identifies the session in refresh tokens. Unlike the id it is never in session tokens, so the session token can not be used to revoke the session.';
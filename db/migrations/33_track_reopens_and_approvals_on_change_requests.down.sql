@@ -0,0 +1,2 @@
+ALTER TABLE "change_requests" DROP COLUMN IF EXISTS "approving_reviews";
+ALTER TABLE "change_requests" DROP COLUMN IF EXISTS "reopened_at";
@@ -0,0 +1,11 @@
+ALTER TABLE "change_requests" ADD COLUMN IF NOT EXISTS "reopened_at" TIMESTAMP (6) WITH TIME ZONE;
+ALTER TABLE "change_requests" ADD COLUMN IF NOT EXISTS "approving_reviews" JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN "change_requests"."reopened_at" IS 'When it was last reopened, so that an earlier close recorded late does not close it again.';
+COMMENT ON COLUMN "change_requests"."approving_reviews" IS 'The ids of the reviews counted in approval_count, so that dismissing one takes it off again.';
+
+-- Rebuild the projection so that closes that were dropped, dismissed approvals
+-- and first commits are picked up for what's already there. Projections kept in
+-- opensearch have their own checkpoints, so need `projections rebuild` running.
+DELETE FROM "projection_checkpoints";
+TRUNCATE "change_requests";
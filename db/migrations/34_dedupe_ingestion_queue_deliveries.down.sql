@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "ingestion_queue_delivery_id_unique_idx";
@@ -0,0 +1,12 @@
+-- Keep the first of any redeliveries already queued, the rest would only be
+-- ignored once processed.
+DELETE FROM "ingestion_queue" AS "later"
+USING "ingestion_queue" AS "earlier"
+WHERE "later"."integration" = "earlier"."integration"
+   AND "later"."delivery_id" = "earlier"."delivery_id"
+   AND "later"."delivery_id" <> ''
+   AND ("later"."received_at", "later"."id") > ("earlier"."received_at", "earlier"."id");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "ingestion_queue_delivery_id_unique_idx" ON "ingestion_queue" ("integration", "delivery_id") WHERE "delivery_id" <> '';
+
+COMMENT ON INDEX "ingestion_queue_delivery_id_unique_idx" IS 'Redeliveries of a delivery that is still queued are not queued again. Deliveries without an id can not be told apart, so are all queued.';
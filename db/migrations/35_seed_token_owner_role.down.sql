@@ -0,0 +1,3 @@
+DELETE FROM "user_roles" WHERE "role_id" IN (SELECT "id" FROM "roles" WHERE "name" = 'token-owner');
+DELETE FROM "roles_permissions" WHERE "role_id" IN (SELECT "id" FROM "roles" WHERE "name" = 'token-owner');
+DELETE FROM "roles" WHERE "name" = 'token-owner';
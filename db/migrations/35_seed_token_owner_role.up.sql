@@ -0,0 +1,16 @@
+-- Lets users manage their own access tokens. It's not tokens.* as that
+-- includes tokens.manage, which is for managing service accounts' tokens.
+INSERT INTO "roles" ("id", "name") VALUES
+   ('5fafec51-acb9-4aed-8d98-989cd0abc74a', 'token-owner')
+ON CONFLICT ("name") DO NOTHING;
+
+INSERT INTO "roles_permissions" ("id", "role_id", "permission_id")
+SELECT v."id"::uuid, r."id", p."id"
+FROM (VALUES
+   ('d4cbf134-eff9-4a4f-9765-08cbc91641e2', 'tokens.create'),
+   ('601b15b1-db08-42fc-9dfb-110bd9ad28b6', 'tokens.list'),
+   ('a25d4d5e-8f57-49eb-847a-b4f51ae02727', 'tokens.revoke')
+) AS v("id", "permission")
+JOIN "roles" r ON r."name" = 'token-owner'
+JOIN "permissions" p ON p."name" = v."permission"
+ON CONFLICT ("role_id", "permission_id") DO NOTHING;
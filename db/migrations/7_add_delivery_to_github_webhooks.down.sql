@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "github_webhooks_delivery_id_unique_idx";
+
+ALTER TABLE "github_webhooks" DROP COLUMN IF EXISTS "delivery_id";
+ALTER TABLE "github_webhooks" DROP COLUMN IF EXISTS "event";
\ No newline at end of file
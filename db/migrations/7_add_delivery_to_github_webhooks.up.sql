@@ -0,0 +1,8 @@
+ALTER TABLE "github_webhooks" ADD COLUMN IF NOT EXISTS "delivery_id" TEXT DEFAULT NULL;
+ALTER TABLE "github_webhooks" ADD COLUMN IF NOT EXISTS "event" TEXT DEFAULT NULL;
+
+COMMENT ON COLUMN "github_webhooks"."delivery_id" IS 'The X-GitHub-Delivery header, github reuses this when a delivery is retried so it lets us ignore redeliveries.
+Nullable as rows received before this column existed have no delivery id.';
+COMMENT ON COLUMN "github_webhooks"."event" IS 'The X-GitHub-Event header e.g. pull_request, the payload alone doesn''t tell us this.';
+
+CREATE UNIQUE INDEX IF NOT EXISTS "github_webhooks_delivery_id_unique_idx" ON "github_webhooks" ("delivery_id");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "tasks_stream";
+DROP TABLE IF EXISTS "jira_webhooks";
\ No newline at end of file
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS "jira_webhooks"(
+   "id" UUID PRIMARY KEY,
+   "delivery_id" TEXT DEFAULT NULL,
+   "event" TEXT DEFAULT NULL,
+   "occurred_at" TIMESTAMP (6) WITH TIME ZONE,
+   "payload" JSON NOT NULL
+);
+
+COMMENT ON COLUMN "jira_webhooks"."delivery_id" IS 'The X-Atlassian-Webhook-Identifier header, the same across retries of a delivery.
+Not all versions of Jira send it, so it is nullable.';
+COMMENT ON COLUMN "jira_webhooks"."event" IS 'The webhookEvent from the payload e.g. jira:issue_updated.';
+
+CREATE UNIQUE INDEX IF NOT EXISTS "jira_webhooks_delivery_id_unique_idx" ON "jira_webhooks" ("delivery_id");
+
+CREATE TABLE IF NOT EXISTS "tasks_stream"(
+   "id" UUID PRIMARY KEY,
+   "aggregate_id" TEXT NOT NULL,
+   "occurred_at" TIMESTAMP (6) WITH TIME ZONE,
+   "payload" JSON NOT NULL,
+   "type" TEXT,
+   "source_id" UUID DEFAULT NULL,
+   "source_integration" TEXT NOT NULL
+);
+
+COMMENT ON COLUMN "tasks_stream"."aggregate_id" IS 'The id of the task in the source system. E.g. 10001 for a jira issue.
+The issue id is used rather than the key, as the key changes when an issue moves project.';
+COMMENT ON COLUMN "tasks_stream"."occurred_at" IS 'Used for the projection, this controls where it sits in the timeline.';
+COMMENT ON COLUMN "tasks_stream"."payload" IS 'The actual payload for the event.';
+COMMENT ON COLUMN "tasks_stream"."type" IS 'The type of event that occurred.';
+COMMENT ON COLUMN "tasks_stream"."source_id" IS 'The source id of the webhook that produced this event (if applicable).';
+COMMENT ON COLUMN "tasks_stream"."source_integration" IS 'The source integration name e.g. ''jira''.';
+
+CREATE INDEX IF NOT EXISTS "tasks_stream_aggregate_id_idx" ON "tasks_stream" ("aggregate_id");
+CREATE INDEX IF NOT EXISTS "tasks_stream_occurred_at_idx" ON "tasks_stream" ("occurred_at");
+CREATE INDEX IF NOT EXISTS "tasks_stream_source_relation_idx" ON "tasks_stream" ("source_id", "source_integration");
+CREATE INDEX IF NOT EXISTS "tasks_stream_aggregate_id_type_idx" ON "tasks_stream" ("aggregate_id", "type");
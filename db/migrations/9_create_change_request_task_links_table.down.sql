@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "change_request_task_links";
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "change_request_task_links"(
+   "id" UUID PRIMARY KEY,
+   "change_request_id" TEXT NOT NULL,
+   "source_integration" TEXT NOT NULL,
+   "task_key" TEXT NOT NULL,
+   "found_in" TEXT NOT NULL,
+   "created_at" TIMESTAMP (6) WITH TIME ZONE NOT NULL
+);
+
+COMMENT ON COLUMN "change_request_task_links"."change_request_id" IS 'The aggregate_id of the change request in change_requests_stream.';
+COMMENT ON COLUMN "change_request_task_links"."source_integration" IS 'The integration the change request came from, aggregate ids are only unique per integration.';
+COMMENT ON COLUMN "change_request_task_links"."task_key" IS 'The key of the task e.g. ABC-123 for jira. Keys are what people write in titles and branches, so we link on that rather than the task id.';
+COMMENT ON COLUMN "change_request_task_links"."found_in" IS 'Where the key was first found e.g. title, branch, body or commit.';
+
+CREATE UNIQUE INDEX IF NOT EXISTS "change_request_task_links_unique_combination" ON "change_request_task_links" ("change_request_id", "source_integration", "task_key");
+CREATE INDEX IF NOT EXISTS "change_request_task_links_task_key_idx" ON "change_request_task_links" ("task_key");